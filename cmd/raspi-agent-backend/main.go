@@ -8,8 +8,11 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
+	// devices are located in time zones the container may not know
+	_ "time/tzdata"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/go-chi/chi/v5"
@@ -17,14 +20,26 @@ import (
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
 	"github.com/ownerofglory/raspi-agent/config"
+	"github.com/ownerofglory/raspi-agent/internal/agenttools"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
 	"github.com/ownerofglory/raspi-agent/internal/core/services"
+	"github.com/ownerofglory/raspi-agent/internal/document"
+	"github.com/ownerofglory/raspi-agent/internal/homeassistant"
 	"github.com/ownerofglory/raspi-agent/internal/http/v1/handler"
+	"github.com/ownerofglory/raspi-agent/internal/mcp"
 	"github.com/ownerofglory/raspi-agent/internal/middleware"
+	"github.com/ownerofglory/raspi-agent/internal/mqtt"
+	"github.com/ownerofglory/raspi-agent/internal/ollama"
 	"github.com/ownerofglory/raspi-agent/internal/openaiapi"
 	"github.com/ownerofglory/raspi-agent/internal/persistence"
+	"github.com/ownerofglory/raspi-agent/internal/persistence/memory"
 	"github.com/ownerofglory/raspi-agent/internal/persistence/migrations"
+	"github.com/ownerofglory/raspi-agent/internal/piper"
 	"github.com/ownerofglory/raspi-agent/internal/stepca"
+	"github.com/ownerofglory/raspi-agent/internal/whisper"
 	authLib "github.com/ownerofglory/raspi-agent/pkg/auth"
+	"github.com/ownerofglory/raspi-agent/pkg/tools"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"gorm.io/driver/postgres"
@@ -69,27 +84,201 @@ func main() {
 		os.Exit(1)
 		return
 	}
+	err = migrations.AgentMemory(db)
+	if err != nil {
+		slog.Error("Failed to migrate agent memory", "error", err)
+		os.Exit(1)
+		return
+	}
+	err = migrations.Documents(db)
+	if err != nil {
+		slog.Error("Failed to migrate documents", "error", err)
+		os.Exit(1)
+		return
+	}
+	err = migrations.Timers(db)
+	if err != nil {
+		slog.Error("Failed to migrate timers", "error", err)
+		os.Exit(1)
+		return
+	}
+	err = migrations.HomeAssistant(db)
+	if err != nil {
+		slog.Error("Failed to migrate home assistant", "error", err)
+		os.Exit(1)
+		return
+	}
+	err = migrations.MCPGrants(db)
+	if err != nil {
+		slog.Error("Failed to migrate mcp grants", "error", err)
+		os.Exit(1)
+		return
+	}
 
 	// Repo setup
 	deviceRepo := persistence.NewDeviceRepo(db)
 	userRepo := persistence.NewUserRepository(db)
+	memoryRepo := persistence.NewMemoryRepo(db)
+	documentRepo := persistence.NewDocumentRepo(db)
+	timerRepo := persistence.NewTimerRepo(db)
+	homeAssistantRepo := persistence.NewHomeAssistantConfigRepo(db)
+	mcpAccessRepo := persistence.NewMCPAccessRepo(db)
 
 	// service setup
 	userService := services.NewUserService(userRepo)
 
 	// AI client setup
 	openAIClient := openai.NewClient(option.WithAPIKey(cfg.OpenAIAPIKey), option.WithBaseURL(cfg.OpenAIAPIURL))
-	tts := openaiapi.NewTextToSpeechClient(&openAIClient)
-	stt := openaiapi.NewSpeechToTextClient(&openAIClient)
-	cmpl := openaiapi.NewCompletionClient(&openAIClient)
+	var tts ports.SpeechProvider
+	switch cfg.TTSProvider {
+	case "openai":
+		tts = openaiapi.NewTextToSpeechClient(&openAIClient)
+	case "piper":
+		if cfg.PiperURL != "" {
+			tts = piper.NewServerClient(cfg.PiperURL, cfg.PiperVoice, cfg.PiperSpeakingRate)
+		} else {
+			tts = piper.NewCLIClient(cfg.PiperBinary, cfg.PiperModel, cfg.PiperSpeakingRate)
+		}
+	default:
+		slog.Error("Unknown text-to-speech provider", "provider", cfg.TTSProvider)
+		os.Exit(1)
+	}
+	var stt ports.TranscriptionProvider
+	switch cfg.STTProvider {
+	case "openai":
+		stt = openaiapi.NewSpeechToTextClient(&openAIClient)
+	case "whisper":
+		stt = whisper.NewServerClient(cfg.WhisperURL, domain.TranscriptionOptions{Language: cfg.STTLanguage, Prompt: cfg.STTPrompt})
+	default:
+		slog.Error("Unknown speech-to-text provider", "provider", cfg.STTProvider)
+		os.Exit(1)
+	}
+	completionOptions := domain.CompletionOptions{
+		Model:        cfg.LLMModel,
+		Temperature:  cfg.LLMTemperature,
+		MaxTokens:    cfg.LLMMaxTokens,
+		SystemPrompt: cfg.LLMSystemPrompt,
+	}
+	var (
+		cmpl       ports.CompletionProvider
+		embeddings ports.EmbeddingProvider
+		similarity domain.SimilarityThresholds
+	)
+	switch cfg.LLMProvider {
+	case "openai":
+		cmpl = openaiapi.NewCompletionClient(&openAIClient, completionOptions)
+		embeddings = openaiapi.NewEmbeddingClient(&openAIClient)
+		similarity = openaiapi.SimilarityThresholds
+	case "ollama":
+		cmpl = ollama.NewCompletionClient(cfg.OllamaURL, completionOptions)
+		embeddings = ollama.NewEmbeddingClient(cfg.OllamaURL, cfg.OllamaEmbeddingModel)
+		similarity = ollama.SimilarityThresholds
+	default:
+		slog.Error("Unknown LLM provider", "provider", cfg.LLMProvider)
+		os.Exit(1)
+	}
+	similarity = domain.SimilarityThresholds{
+		Recall:    cfg.RecallSimilarity,
+		Duplicate: cfg.DuplicateSimilarity,
+		Forget:    cfg.ForgetSimilarity,
+		Retrieve:  cfg.RetrieveSimilarity,
+	}.WithDefaults(similarity)
 
 	// service setup
 	deviceService := services.NewDeviceService(userRepo, deviceRepo, certProvider)
 	deviceHandler := handler.NewDeviceHandler(deviceService)
 
 	// voice assistant setup
-	va := services.NewVoiceAssistant(stt, tts, cmpl)
-	vh := handler.NewVoiceAssistantHandler(va)
+	summarizer := services.NewCompletionSummarizer(cmpl)
+	conversations := services.NewConversationService(memory.NewConversationRepo(), summarizer, cfg.ConversationIdleTimeout, cfg.ConversationHistoryBudget)
+	agentMemory := services.NewAgentMemory(memoryRepo, embeddings, cmpl, similarity)
+	documentService := services.NewDocumentService(documentRepo, document.NewTextExtractor(), embeddings, similarity)
+	documentHandler := handler.NewDocumentHandler(documentService)
+	homeAssistantService := services.NewHomeAssistantService(homeAssistantRepo, homeassistant.NewClient())
+	homeAssistantHandler := handler.NewHomeAssistantHandler(homeAssistantService)
+	toolRegistry := tools.New()
+	for _, t := range agenttools.NewHomeAssistantTools(homeAssistantService) {
+		toolRegistry.Add(t)
+	}
+
+	// tools of MCP servers, enabled per user
+	var mcpServers []domain.MCPServerConfig
+	if cfg.MCPServersFile != "" {
+		mcpServers, err = mcp.LoadServers(cfg.MCPServersFile)
+		if err != nil {
+			slog.Error("Invalid MCP configuration", "error", err)
+			os.Exit(1)
+		}
+	}
+	mcpServerNames := make([]string, 0, len(mcpServers))
+	for _, server := range mcpServers {
+		mcpServerNames = append(mcpServerNames, server.Name)
+	}
+	mcpAccess := services.NewMCPAccessService(mcpAccessRepo, mcpServerNames)
+	mcpAccessHandler := handler.NewMCPAccessHandler(mcpAccess)
+	for _, server := range mcpServers {
+		mcpClient, err := mcp.NewClient(server)
+		if err != nil {
+			slog.Error("Invalid MCP server", "server", server.Name, "error", err)
+			continue
+		}
+		defer mcpClient.Close()
+
+		listCtx, cancel := context.WithTimeout(context.Background(), mcp.ListToolsTimeout)
+		mcpTools, err := agenttools.NewMCPTools(listCtx, server.Name, mcpClient, mcpAccess, toolRegistry)
+		cancel()
+		if err != nil {
+			// the server stays available to be enabled once the backend is restarted
+			slog.Error("Failed to list MCP server tools", "server", server.Name, "error", err)
+			continue
+		}
+		for _, t := range mcpTools {
+			toolRegistry.Add(t)
+		}
+	}
+	va := services.NewVoiceAssistant(stt, tts, cmpl, conversations, agentMemory, documentService, agenttools.NewRegistryProvider(toolRegistry))
+	vh := handler.NewVoiceAssistantHandler(va, deviceService)
+	vsh := handler.NewVoiceSessionHandler(va, deviceService)
+
+	// timers fire on the device holding a voice session; as due timers are
+	// not claimed, a single backend instance must run them
+	timers := services.NewTimerService(timerRepo, vsh, tts)
+	for _, t := range agenttools.NewTimerTools(timers) {
+		toolRegistry.Add(t)
+	}
+	timersCtx, stopTimers := context.WithCancel(context.Background())
+	defer stopTimers()
+	go func() {
+		if err := timers.Run(timersCtx); err != nil {
+			slog.Error("Timers stopped unexpectedly", "err", err)
+		}
+	}()
+
+	// MQTT publish/read tools and announcements on the devices holding a voice session
+	if cfg.MQTTBrokerURL != "" {
+		mqttClient, err := mqtt.NewClient(cfg.MQTTBrokerURL, cfg.MQTTClientID)
+		if err != nil {
+			slog.Error("Invalid MQTT configuration", "error", err)
+			os.Exit(1)
+		}
+		for _, t := range agenttools.NewMQTTTools(mqttClient, cfg.MQTTUsers) {
+			toolRegistry.Add(t)
+		}
+
+		var announcements []domain.MQTTAnnouncement
+		for filter, devices := range cfg.MQTTAnnounce {
+			announcements = append(announcements, domain.MQTTAnnouncement{
+				TopicFilter: filter,
+				DeviceIDs:   strings.Split(devices, ","),
+			})
+		}
+		announcer := services.NewMQTTAnnouncer(mqttClient, vsh, tts, announcements)
+		go func() {
+			if err := announcer.Run(timersCtx); err != nil {
+				slog.Error("MQTT announcements stopped unexpectedly", "err", err)
+			}
+		}()
+	}
 
 	loginHandler := handler.NewLoginHandler(cfg.JWTKey, userService)
 	signupHandler := handler.NewSignupHandler(userService)
@@ -118,17 +307,67 @@ func main() {
 	r.Post(handler.PostSignupPath, signupHandler.HandleSignup)
 	r.Get(handler.PostAuthOAuth2LoginPath, oauth2Handler.HandleLogin)
 	r.Get(handler.PostAuthOAuth2CallbackPath, oauth2Handler.HandleCallback)
-	r.Post(handler.PostReceiveVoiceAssistance, middleware.WrapFunc(
-		vh.HandleAssist,
-		middleware.Authenticated(middleware.WithDeviceCertHeader(middleware.CertHeaderName)),
-		middleware.Authorized(middleware.HavingDeviceID("deviceId")),
-	).ServeHTTP)
+	registerDeviceRoutes(r, vh.HandleAssist, vsh.HandleSession)
 	r.Post(handler.PostRegisterDeviceURL,
 		middleware.WrapFunc(
 			deviceHandler.HandlePostRegisterDevice,
 			middleware.Authenticated(middleware.WithJWT(cfg.JWTKey)),
 			middleware.Authorized(authLib.WithUserId("userId")),
 		).ServeHTTP)
+	r.Post(handler.DocumentsPath,
+		middleware.WrapFunc(
+			documentHandler.HandlePostDocument,
+			middleware.Authenticated(middleware.WithJWT(cfg.JWTKey)),
+			middleware.Authorized(authLib.WithUserId("userId")),
+		).ServeHTTP)
+	r.Get(handler.DocumentsPath,
+		middleware.WrapFunc(
+			documentHandler.HandleGetDocuments,
+			middleware.Authenticated(middleware.WithJWT(cfg.JWTKey)),
+			middleware.Authorized(authLib.WithUserId("userId")),
+		).ServeHTTP)
+	r.Delete(handler.DeleteDocumentPath,
+		middleware.WrapFunc(
+			documentHandler.HandleDeleteDocument,
+			middleware.Authenticated(middleware.WithJWT(cfg.JWTKey)),
+			middleware.Authorized(authLib.WithUserId("userId")),
+		).ServeHTTP)
+	r.Put(handler.HomeAssistantPath,
+		middleware.WrapFunc(
+			homeAssistantHandler.HandlePutHomeAssistant,
+			middleware.Authenticated(middleware.WithJWT(cfg.JWTKey)),
+			middleware.Authorized(authLib.WithUserId("userId")),
+		).ServeHTTP)
+	r.Get(handler.HomeAssistantPath,
+		middleware.WrapFunc(
+			homeAssistantHandler.HandleGetHomeAssistant,
+			middleware.Authenticated(middleware.WithJWT(cfg.JWTKey)),
+			middleware.Authorized(authLib.WithUserId("userId")),
+		).ServeHTTP)
+	r.Delete(handler.HomeAssistantPath,
+		middleware.WrapFunc(
+			homeAssistantHandler.HandleDeleteHomeAssistant,
+			middleware.Authenticated(middleware.WithJWT(cfg.JWTKey)),
+			middleware.Authorized(authLib.WithUserId("userId")),
+		).ServeHTTP)
+	r.Put(handler.MCPAccessPath,
+		middleware.WrapFunc(
+			mcpAccessHandler.HandlePutMCPAccess,
+			middleware.Authenticated(middleware.WithJWT(cfg.JWTKey)),
+			middleware.Authorized(authLib.WithUserId("userId")),
+		).ServeHTTP)
+	r.Get(handler.MCPAccessPath,
+		middleware.WrapFunc(
+			mcpAccessHandler.HandleGetMCPAccess,
+			middleware.Authenticated(middleware.WithJWT(cfg.JWTKey)),
+			middleware.Authorized(authLib.WithUserId("userId")),
+		).ServeHTTP)
+	r.Post(handler.PostDeviceListenPath,
+		middleware.WrapFunc(
+			vsh.HandleListen,
+			middleware.Authenticated(middleware.WithJWT(cfg.JWTKey)),
+			middleware.Authorized(authLib.WithUserId("userId")),
+		).ServeHTTP)
 	r.Post(handler.PostEnrollDeviceURL, deviceHandler.HandlePostEnrollDevice)
 	r.Get(handler.GetVersionEndpoint, handler.HandleGetVersion)
 	// UI
@@ -159,3 +398,14 @@ func main() {
 
 	slog.Info("App finished")
 }
+
+// registerDeviceRoutes registers the routes called by devices, which are
+// authenticated by the client certificate forwarded by the proxy. The paths
+// hold no device ID to authorize against: the handlers act for the device
+// of the certificate.
+func registerDeviceRoutes(r chi.Router, assist, session http.HandlerFunc) {
+	deviceAuthenticated := middleware.Authenticated(middleware.WithDeviceCertHeader(middleware.CertHeaderName))
+
+	r.Post(handler.PostReceiveVoiceAssistance, middleware.WrapFunc(assist, deviceAuthenticated).ServeHTTP)
+	r.Get(handler.GetVoiceSessionPath, middleware.WrapFunc(session, deviceAuthenticated).ServeHTTP)
+}
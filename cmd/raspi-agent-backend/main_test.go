@@ -0,0 +1,82 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	appAuth "github.com/ownerofglory/raspi-agent/internal/auth"
+	"github.com/ownerofglory/raspi-agent/internal/http/v1/handler"
+	"github.com/ownerofglory/raspi-agent/internal/middleware"
+)
+
+// deviceCert returns a PEM-encoded self-signed certificate of the device.
+func deviceCert(t *testing.T, deviceID string) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: deviceID},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
+func TestRegisterDeviceRoutes(t *testing.T) {
+	var gotDevice string
+	device := func(rw http.ResponseWriter, r *http.Request) {
+		gotDevice, _ = r.Context().Value(appAuth.DeviceKey).(string)
+		rw.WriteHeader(http.StatusOK)
+	}
+
+	r := chi.NewRouter()
+	registerDeviceRoutes(r, device, device)
+	cert := deviceCert(t, "device1")
+
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		cert       string
+		statusCode int
+	}{
+		{name: "voice assistance", method: http.MethodPost, path: handler.PostReceiveVoiceAssistance, cert: cert, statusCode: http.StatusOK},
+		{name: "voice session", method: http.MethodGet, path: handler.GetVoiceSessionPath, cert: cert, statusCode: http.StatusOK},
+		{name: "voice session without certificate", method: http.MethodGet, path: handler.GetVoiceSessionPath, statusCode: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			gotDevice = ""
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.cert != "" {
+				req.Header.Set(middleware.CertHeaderName, tc.cert)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			if rec.Code != tc.statusCode {
+				t.Fatalf("expected status %d, got %d", tc.statusCode, rec.Code)
+			}
+			if tc.statusCode == http.StatusOK && gotDevice != "device1" {
+				t.Errorf("expected the handler to act for device1, got %q", gotDevice)
+			}
+		})
+	}
+}
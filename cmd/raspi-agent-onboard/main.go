@@ -6,21 +6,56 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/ownerofglory/raspi-agent/internal/audio"
+	"github.com/ownerofglory/raspi-agent/internal/audio/codec"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
 	"github.com/ownerofglory/raspi-agent/internal/http/v1/client"
-	"github.com/ownerofglory/raspi-agent/internal/offboard"
+	"github.com/ownerofglory/raspi-agent/internal/orchestrator"
+	"github.com/ownerofglory/raspi-agent/internal/vad"
 	"github.com/ownerofglory/raspi-agent/internal/wakeword"
 )
 
 var (
+	wakeEngine = flag.String("wakeEngine", wakeword.EnginePorcupine, "wake word engine: 'porcupine', 'openwakeword' (wyoming-openwakeword server), 'stdin' (each line wakes up) or 'file' (each line of -wakeFile wakes up)")
+
 	porcupineAccessKey   = flag.String("porcupineAccessKey", "", "porcupine SDK access key from 'https://console.picovoice.ai/'")
 	porcupineLibPath     = flag.String("porcupineLibPath", "", "porcupine library path e.g. 'lib/libpv_porcupine.so'")
 	porcupineModelPath   = flag.String("porcupineModelPath", "", "porcupine model parameters path, e.g. 'resources/porcupine_params.pv'")
 	porcupineKeywordPath = flag.String("porcupineKeywordPath", "", "porcupine keyword path, e.g. 'resources/Hey-Rhaspy_en_raspberry-pi_v3_0_0.ppn'")
 
-	backendBaseURL = flag.String("backendBaseURL", "", "Backend base URL")
+	openWakeWordAddr   = flag.String("openWakeWordAddr", wakeword.DefaultOpenWakeWordAddr, "host:port of the wyoming-openwakeword server")
+	openWakeWordModels = flag.String("openWakeWordModels", "", "comma-separated openWakeWord models to detect, e.g. 'ok_nabu' (empty detects all models of the server)")
+
+	wakeFile  = flag.String("wakeFile", "", "file whose lines each wake the assistant up, optionally after a delay such as '2s' and followed by the name of the wake word")
+	wakeWords = flag.String("wakeWords", "", "JSON file of the wake words to detect, each with its model, sensitivity and persona or action, e.g. 'resources/wakewords.json' (overrides -porcupineKeywordPath and -openWakeWordModels)")
+
+	pushToTalk  = flag.Bool("pushToTalk", false, "pressing Enter on stdin wakes the assistant up, in addition to the wake word engine")
+	triggerAddr = flag.String("triggerAddr", "", "address of the HTTP endpoint waking the assistant up, e.g. 'unix:/run/raspi-agent/trigger.sock' or 'localhost:8091' (empty disables it)")
+
+	backendBaseURL   = flag.String("backendBaseURL", "", "Backend base URL")
+	backendTransport = flag.String("backendTransport", "http", "backend transport: 'http' (request per turn) or 'websocket' (long-lived voice session)")
+
+	silenceTimeout  = flag.Duration("silenceTimeout", 800*time.Millisecond, "trailing silence that ends an utterance")
+	minUtterance    = flag.Duration("minUtterance", 500*time.Millisecond, "minimum utterance length before silence may end the recording")
+	maxUtterance    = flag.Duration("maxUtterance", 15*time.Second, "maximum utterance length")
+	noSpeechTimeout = flag.Duration("noSpeechTimeout", 5*time.Second, "how long to wait for speech after the wake word")
+	preRoll         = flag.Duration("preRoll", 300*time.Millisecond, "audio captured before the recording starts that is kept, so speech right after the wake word is not cut off")
+
+	followUpWindow = flag.Duration("followUpWindow", 0, "how long to listen for a follow-up without the wake word after the assistant asked something (0 disables follow-ups)")
+	followUpAlways = flag.Bool("followUpAlways", false, "listen for a follow-up after every reply, not only after questions")
+
+	bargeIn   = flag.Bool("bargeIn", true, "listen for the wake word while the assistant is talking and interrupt the reply")
+	echoRatio = flag.Float64("echoRatio", 0.1, "minimum energy of the microphone input relative to the playback for a wake word to count during a reply (0 disables echo gating)")
+
+	voice        = flag.String("voice", "", "voice of the replies, as known to the backend's speech provider (empty uses the default)")
+	speakingRate = flag.Float64("speakingRate", 0, "speaking rate of the replies relative to normal speed, e.g. 1.25 (0 uses the default)")
+
+	alertSound = flag.String("alertSound", "", "Audio file (MP3, WAV, Opus or FLAC) played when a timer or alarm fires")
 )
 
 func main() {
@@ -29,26 +64,101 @@ func main() {
 	slog.SetLogLoggerLevel(slog.LevelDebug)
 	slog.Debug("Starting application")
 
-	listener := wakeword.NewPorcupineListener(*porcupineAccessKey, *porcupineModelPath, *porcupineLibPath, *porcupineKeywordPath)
-	recorder := audio.NewRecorder()
-	player := audio.NewPortAudioPlayer()
-	assistant := client.NewVoiceAssistant(*backendBaseURL)
+	// the echo of the replies must not trigger the wake word while listening during playback
+	var echo *vad.EchoGate
+	if *bargeIn && *echoRatio > 0 {
+		echo = vad.NewEchoGate(*echoRatio)
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	orch := offboard.NewOrchestrator(listener, recorder, player, assistant)
+	// the microphone is captured once and shared by the wake word listener and the recorder
+	capture := audio.NewCaptureBus()
 	go func() {
-		err := orch.Run(ctx)
-		if err != nil {
-			slog.Error("Error running onboardorchestrator", "error", err)
-			return
+		if err := capture.Run(ctx); err != nil {
+			slog.Error("Audio capture failed", "error", err)
+			cancel()
 		}
 	}()
 
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-	<-sigCh
+	listener, trigger, err := wakeword.FromConfig(ctx, capture, echo, wakeword.Config{
+		Engine:               *wakeEngine,
+		WakeWords:            *wakeWords,
+		PorcupineAccessKey:   *porcupineAccessKey,
+		PorcupineLibPath:     *porcupineLibPath,
+		PorcupineModelPath:   *porcupineModelPath,
+		PorcupineKeywordPath: *porcupineKeywordPath,
+		OpenWakeWordAddr:     *openWakeWordAddr,
+		OpenWakeWordModels:   strings.Split(*openWakeWordModels, ","),
+		WakeFile:             *wakeFile,
+		PushToTalk:           *pushToTalk,
+		TriggerAddr:          *triggerAddr,
+	})
+	if err != nil {
+		slog.Error("Unable to create wake word listener", "error", err)
+		os.Exit(1)
+	}
+	recorder := audio.NewRecorder(capture, *preRoll)
+	player := audio.NewPortAudioPlayer(echo)
+	if err := codec.CheckGStreamer(); err != nil {
+		slog.Warn("Ogg/Opus and FLAC audio cannot be played", "error", err)
+	}
+
+	voiceOptions := domain.VoiceOptions{Voice: *voice, Rate: *speakingRate}
+	session, err := client.NewVoiceSession(*backendBaseURL, voiceOptions)
+	if err != nil {
+		slog.Error("Unable to create voice session client", "error", err)
+		os.Exit(1)
+	}
+	var assistant ports.VoiceAssistantClient
+	switch *backendTransport {
+	case "http":
+		assistant = client.NewVoiceAssistant(*backendBaseURL, voiceOptions)
+	case "websocket":
+		assistant = session
+	default:
+		slog.Error("Unknown backend transport", "transport", *backendTransport)
+		os.Exit(1)
+	}
+
+	// timers, alarms and requests to start listening are pushed over the
+	// voice session, which is kept open whatever the transport of the turns
+	notifier := audio.NewNotificationPlayer(player, *alertSound)
+	go func() {
+		for n := range session.Notifications(ctx) {
+			if n.Kind == domain.NotificationKindListen {
+				trigger("")
+				continue
+			}
+			if err := notifier.Notify(ctx, "", n); err != nil {
+				slog.Error("Failed to play notification", "error", err)
+			}
+		}
+	}()
+
+	utterance := domain.UtteranceOptions{
+		SilenceTimeout:  *silenceTimeout,
+		MinDuration:     *minUtterance,
+		MaxDuration:     *maxUtterance,
+		NoSpeechTimeout: *noSpeechTimeout,
+	}
+	followUp := domain.FollowUpOptions{Window: *followUpWindow, Always: *followUpAlways}
+
+	orch := orchestrator.NewOrchestrator(listener, recorder, player, assistant, utterance, followUp, *bargeIn)
+	go func() {
+		sigCh := make(chan os.Signal, 1)
+		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+		<-sigCh
+
+		slog.Debug("Received signal, shutting down")
+		cancel()
+	}()
 
-	slog.Debug("Received signal, shutting down")
+	// the orchestrator runs until a signal cancels it or its wake word input
+	// ends; main then returns, so the deferred cleanup runs
+	if err := orch.Run(ctx); err != nil {
+		slog.Error("Error running orchestrator", "error", err)
+	}
+	slog.Debug("Application stopped")
 }
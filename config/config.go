@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 // RaspiAgentConfig application config that maps env variables
 type RaspiAgentConfig struct {
 	// App
@@ -18,6 +20,69 @@ type RaspiAgentConfig struct {
 	OpenAIAPIKey string `env:"OPENAI_API_KEY" envDefault:""`
 	OpenAIAPIURL string `env:"OPENAI_API_URL" envDefault:"https://api.openai.com/v1"`
 
+	// LLM
+	// LLMProvider selects the completion provider: "openai" for the OpenAI API
+	// or a compatible server at OPENAI_API_URL, "ollama" for the native API of
+	// an Ollama server, which also provides the embeddings.
+	LLMProvider     string   `env:"LLM_PROVIDER" envDefault:"openai"`
+	LLMModel        string   `env:"LLM_MODEL" envDefault:""`
+	LLMTemperature  *float64 `env:"LLM_TEMPERATURE"`
+	LLMMaxTokens    int      `env:"LLM_MAX_TOKENS" envDefault:"0"`
+	LLMSystemPrompt string   `env:"LLM_SYSTEM_PROMPT" envDefault:""`
+
+	// Speech-to-text
+	// STTProvider selects the speech-to-text provider: "openai" or "whisper"
+	// for a whisper.cpp server at WHISPER_URL, started with "--port 8081" as
+	// the backend listens on whisper's default port itself.
+	STTProvider string `env:"STT_PROVIDER" envDefault:"openai"`
+	WhisperURL  string `env:"WHISPER_URL" envDefault:"http://localhost:8081"`
+	// STTLanguage and STTPrompt are the language hint and the vocabulary biasing whisper
+	STTLanguage string `env:"STT_LANGUAGE" envDefault:""`
+	STTPrompt   string `env:"STT_PROMPT" envDefault:""`
+
+	// Text-to-speech
+	// TTSProvider selects the text-to-speech provider: "openai" or "piper",
+	// calling the Piper server at PIPER_URL if set, running PIPER_BINARY otherwise.
+	// Devices may ask for another voice and speaking rate.
+	TTSProvider       string  `env:"TTS_PROVIDER" envDefault:"openai"`
+	PiperURL          string  `env:"PIPER_URL" envDefault:""`
+	PiperVoice        string  `env:"PIPER_VOICE" envDefault:""`
+	PiperBinary       string  `env:"PIPER_BINARY" envDefault:"piper"`
+	PiperModel        string  `env:"PIPER_MODEL" envDefault:""`
+	PiperSpeakingRate float64 `env:"PIPER_SPEAKING_RATE" envDefault:"0"`
+
+	// Ollama
+	OllamaURL            string `env:"OLLAMA_URL" envDefault:"http://localhost:11434"`
+	OllamaEmbeddingModel string `env:"OLLAMA_EMBEDDING_MODEL" envDefault:"nomic-embed-text"`
+
+	// Embedding similarity thresholds, 0 for the defaults of the provider's
+	// embedding model, see domain.SimilarityThresholds
+	RecallSimilarity    float64 `env:"RECALL_SIMILARITY" envDefault:"0"`
+	DuplicateSimilarity float64 `env:"DUPLICATE_SIMILARITY" envDefault:"0"`
+	ForgetSimilarity    float64 `env:"FORGET_SIMILARITY" envDefault:"0"`
+	RetrieveSimilarity  float64 `env:"RETRIEVE_SIMILARITY" envDefault:"0"`
+
+	// Conversation
+	ConversationIdleTimeout time.Duration `env:"CONVERSATION_IDLE_TIMEOUT" envDefault:"5m"`
+	// ConversationHistoryBudget is the history size in characters (~4 per token)
+	// above which older turns are summarized; 0 disables summarization.
+	ConversationHistoryBudget int `env:"CONVERSATION_HISTORY_BUDGET" envDefault:"8000"`
+
+	// MQTT
+	MQTTBrokerURL string `env:"MQTT_BROKER_URL" envDefault:""`
+	MQTTClientID  string `env:"MQTT_CLIENT_ID" envDefault:"raspi-agent"`
+	// MQTTUsers are the IDs of the users allowed to publish and read topics by
+	// voice, "*" for all users; nobody is by default.
+	MQTTUsers []string `env:"MQTT_USERS" envSeparator:","`
+	// MQTTAnnounce maps topic filters to the comma-separated IDs of the devices
+	// announcing their messages, e.g. "home/+/doorbell=<deviceId>,<deviceId>;alerts/#=<deviceId>".
+	MQTTAnnounce map[string]string `env:"MQTT_ANNOUNCE" envSeparator:";" envKeyValSeparator:"="`
+
+	// MCP
+	// MCPServersFile is a JSON file configuring the MCP servers whose tools
+	// users may enable, in the "mcpServers" format common to MCP hosts.
+	MCPServersFile string `env:"MCP_SERVERS_FILE" envDefault:""`
+
 	// Step CA
 	StepCAURL              string `env:"STEPCA_URL" envDefault:""`
 	StepCAProvisionerName  string `env:"STEPCA_PROVISIONER_NAME" envDefault:""`
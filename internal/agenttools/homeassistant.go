@@ -0,0 +1,238 @@
+package agenttools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"github.com/ownerofglory/raspi-agent/pkg/tools"
+)
+
+// maxListedEntities bounds the entities returned to the model, as large
+// installations have thousands of them.
+const maxListedEntities = 100
+
+// NewHomeAssistantTools returns the tools to discover, query and control the
+// Home Assistant entities of the calling user, to be added to a tools.Tools registry.
+func NewHomeAssistantTools(ha ports.HomeAssistantService) []tools.Tool {
+	return []tools.Tool{
+		&haListEntitiesTool{ha: ha},
+		&haGetStateTool{ha: ha},
+		&haCallServiceTool{ha: ha},
+	}
+}
+
+// entityInfo is the representation of an entity returned to the model.
+type entityInfo struct {
+	EntityID   string         `json:"entity_id"`
+	Name       string         `json:"name"`
+	State      string         `json:"state"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// callerUserID returns the user on whose behalf a tool runs.
+func callerUserID(ctx context.Context) (string, error) {
+	caller, ok := domain.ToolCallerFrom(ctx)
+	if !ok {
+		return "", errors.New("home assistant needs a calling user")
+	}
+	return caller.UserID, nil
+}
+
+// haListEntitiesTool lists the entities of the user's Home Assistant instance.
+type haListEntitiesTool struct {
+	ha ports.HomeAssistantService
+}
+
+type haListEntitiesArgs struct {
+	Domain string `json:"domain"`
+	Search string `json:"search"`
+}
+
+func (t *haListEntitiesTool) Name() string { return "ha_list_entities" }
+
+func (t *haListEntitiesTool) Description() string {
+	return "List the Home Assistant entities (lights, switches, sensors, scenes, ...) with their current state. " +
+		"Use it to find the entity_id of a device before querying or controlling it."
+}
+
+func (t *haListEntitiesTool) UserMessage() string { return "" }
+
+func (t *haListEntitiesTool) Schema() tools.Schema {
+	return tools.Schema{
+		"type": "object",
+		"properties": map[string]any{
+			"domain": map[string]any{
+				"type":        "string",
+				"description": "Only list entities of this domain, e.g. \"light\", \"sensor\" or \"scene\".",
+			},
+			"search": map[string]any{
+				"type":        "string",
+				"description": "Only list entities whose name or ID contains this text, e.g. \"kitchen\".",
+			},
+		},
+	}
+}
+
+func (t *haListEntitiesTool) Execute(ctx context.Context, args string) (any, error) {
+	userID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var a haListEntitiesArgs
+	if err := json.Unmarshal([]byte(args), &a); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	entities, err := t.ha.Entities(ctx, userID, strings.TrimSpace(a.Domain))
+	if err != nil {
+		return nil, err
+	}
+
+	search := strings.ToLower(strings.TrimSpace(a.Search))
+	infos := make([]entityInfo, 0, min(len(entities), maxListedEntities))
+	for _, e := range entities {
+		if search != "" && !strings.Contains(strings.ToLower(e.EntityID+" "+e.Name()), search) {
+			continue
+		}
+		if len(infos) == maxListedEntities {
+			return map[string]any{"entities": infos, "truncated": true}, nil
+		}
+		infos = append(infos, entityInfo{EntityID: e.EntityID, Name: e.Name(), State: e.State})
+	}
+	return map[string]any{"entities": infos}, nil
+}
+
+// haGetStateTool reads the state of a single entity.
+type haGetStateTool struct {
+	ha ports.HomeAssistantService
+}
+
+type haGetStateArgs struct {
+	EntityID string `json:"entity_id"`
+}
+
+func (t *haGetStateTool) Name() string { return "ha_get_state" }
+
+func (t *haGetStateTool) Description() string {
+	return "Get the current state and attributes of a Home Assistant entity, e.g. a sensor reading."
+}
+
+func (t *haGetStateTool) UserMessage() string { return "" }
+
+func (t *haGetStateTool) Schema() tools.Schema {
+	return tools.Schema{
+		"type": "object",
+		"properties": map[string]any{
+			"entity_id": map[string]any{
+				"type":        "string",
+				"description": "ID of the entity, e.g. \"sensor.outside_temperature\".",
+			},
+		},
+		"required": []string{"entity_id"},
+	}
+}
+
+func (t *haGetStateTool) Execute(ctx context.Context, args string) (any, error) {
+	userID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var a haGetStateArgs
+	if err := json.Unmarshal([]byte(args), &a); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	e, err := t.ha.State(ctx, userID, a.EntityID)
+	if err != nil {
+		return nil, err
+	}
+	return entityInfo{EntityID: e.EntityID, Name: e.Name(), State: e.State, Attributes: e.Attributes}, nil
+}
+
+// haCallServiceTool calls a Home Assistant service, e.g. to switch a light
+// or activate a scene.
+type haCallServiceTool struct {
+	ha ports.HomeAssistantService
+}
+
+type haCallServiceArgs struct {
+	Domain   string         `json:"domain"`
+	Service  string         `json:"service"`
+	EntityID string         `json:"entity_id"`
+	Data     map[string]any `json:"data"`
+}
+
+func (t *haCallServiceTool) Name() string { return "ha_call_service" }
+
+func (t *haCallServiceTool) Description() string {
+	return "Call a Home Assistant service, e.g. domain \"light\" service \"turn_on\" to switch on a light, " +
+		"or domain \"scene\" service \"turn_on\" to activate a scene."
+}
+
+func (t *haCallServiceTool) UserMessage() string { return "" }
+
+func (t *haCallServiceTool) Schema() tools.Schema {
+	return tools.Schema{
+		"type": "object",
+		"properties": map[string]any{
+			"domain": map[string]any{
+				"type":        "string",
+				"description": "Service domain, e.g. \"light\", \"switch\", \"scene\" or \"climate\".",
+			},
+			"service": map[string]any{
+				"type":        "string",
+				"description": "Service name, e.g. \"turn_on\", \"turn_off\" or \"toggle\".",
+			},
+			"entity_id": map[string]any{
+				"type":        "string",
+				"description": "ID of the target entity, e.g. \"light.kitchen\".",
+			},
+			"data": map[string]any{
+				"type":        "object",
+				"description": "Additional service data, e.g. {\"brightness_pct\": 50}.",
+			},
+		},
+		"required": []string{"domain", "service"},
+	}
+}
+
+func (t *haCallServiceTool) Execute(ctx context.Context, args string) (any, error) {
+	userID, err := callerUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var a haCallServiceArgs
+	if err := json.Unmarshal([]byte(args), &a); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if a.Domain == "" || a.Service == "" {
+		return nil, errors.New("domain and service are required")
+	}
+
+	call := domain.HomeAssistantServiceCall{Domain: a.Domain, Service: a.Service, Data: a.Data}
+	if a.EntityID != "" {
+		if call.Data == nil {
+			call.Data = map[string]any{}
+		}
+		call.Data["entity_id"] = a.EntityID
+	}
+
+	changed, err := t.ha.CallService(ctx, userID, call)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]entityInfo, 0, len(changed))
+	for _, e := range changed {
+		infos = append(infos, entityInfo{EntityID: e.EntityID, Name: e.Name(), State: e.State})
+	}
+	return map[string]any{"changed": infos}, nil
+}
@@ -0,0 +1,111 @@
+package agenttools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"github.com/ownerofglory/raspi-agent/pkg/tools"
+)
+
+// maxToolNameLength is the longest tool name accepted by the model API.
+const maxToolNameLength = 64
+
+// invalidToolNameChars matches the characters not allowed in tool names.
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// NewMCPTools returns the tools offered by the MCP server, to be added to
+// the registry. Each tool is only available to the users who have enabled
+// it, see ports.MCPAccessService.
+//
+// Tool names are prefixed with the server's name, sanitized and truncated
+// for the model API, which may make them collide with each other or with
+// the tools already in the registry; colliding names get a numeric suffix.
+func NewMCPTools(ctx context.Context, server string, client ports.MCPClient, access ports.MCPAccessService, registry *tools.Tools) ([]tools.Tool, error) {
+	offered, err := client.ListTools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	taken := make(map[string]bool, len(offered))
+	isTaken := func(name string) bool {
+		if taken[name] {
+			return true
+		}
+		_, err := registry.Get(name)
+		return err == nil
+	}
+
+	mcpTools := make([]tools.Tool, 0, len(offered))
+	for _, t := range offered {
+		base := mcpToolName(server, t.Name)
+		name := base
+		for i := 2; isTaken(name); i++ {
+			suffix := fmt.Sprintf("_%d", i)
+			name = base[:min(len(base), maxToolNameLength-len(suffix))] + suffix
+		}
+		if name != base {
+			slog.Warn("MCP tool name taken, renamed the tool", "server", server, "tool", t.Name, "name", name)
+		}
+		taken[name] = true
+
+		mcpTools = append(mcpTools, &mcpTool{name: name, server: server, tool: t, client: client, access: access})
+	}
+	return mcpTools, nil
+}
+
+// mcpToolName prefixes the tool's name with the server's, as different
+// servers may offer tools of the same name, and makes it a valid tool name.
+func mcpToolName(server, tool string) string {
+	name := invalidToolNameChars.ReplaceAllString(server+"__"+tool, "_")
+	return name[:min(len(name), maxToolNameLength)]
+}
+
+// mcpTool is a tool of an MCP server.
+type mcpTool struct {
+	name   string
+	server string
+	tool   domain.MCPTool
+	client ports.MCPClient
+	access ports.MCPAccessService
+}
+
+// Name returns the name the tool is registered with, see NewMCPTools.
+func (t *mcpTool) Name() string { return t.name }
+
+func (t *mcpTool) Description() string { return t.tool.Description }
+
+func (t *mcpTool) UserMessage() string { return "" }
+
+func (t *mcpTool) Schema() tools.Schema {
+	if t.tool.InputSchema == nil {
+		return tools.Schema{"type": "object", "properties": map[string]any{}}
+	}
+	return tools.Schema(t.tool.InputSchema)
+}
+
+// Allowed reports whether the calling user has enabled the tool.
+func (t *mcpTool) Allowed(ctx context.Context) bool {
+	caller, ok := domain.ToolCallerFrom(ctx)
+	return ok && t.access.Allowed(ctx, caller.UserID, t.server, t.tool.Name)
+}
+
+func (t *mcpTool) Execute(ctx context.Context, args string) (any, error) {
+	res, err := t.client.CallTool(ctx, t.tool.Name, args)
+	if err != nil {
+		return nil, err
+	}
+	if res.IsError {
+		return nil, errors.New(res.Text)
+	}
+
+	result := map[string]any{"result": res.Text}
+	if res.Structured != nil {
+		result["structured"] = res.Structured
+	}
+	return result, nil
+}
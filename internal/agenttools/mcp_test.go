@@ -0,0 +1,83 @@
+package agenttools
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"github.com/ownerofglory/raspi-agent/pkg/tools"
+	"go.uber.org/mock/gomock"
+)
+
+func TestNewMCPToolsNames(t *testing.T) {
+	long := strings.Repeat("a", maxToolNameLength)
+
+	testCases := []struct {
+		name       string
+		server     string
+		offered    []string
+		registered []string
+		expected   []string
+	}{
+		{
+			name:     "sanitized",
+			server:   "files",
+			offered:  []string{"read.file", "write_file"},
+			expected: []string{"files__read_file", "files__write_file"},
+		},
+		{
+			name:     "same name once sanitized",
+			server:   "files",
+			offered:  []string{"read.file", "read_file", "read-file"},
+			expected: []string{"files__read_file", "files__read_file_2", "files__read-file"},
+		},
+		{
+			name:     "same name once truncated",
+			server:   "files",
+			offered:  []string{long + "1", long + "2"},
+			expected: []string{("files__" + long)[:maxToolNameLength], ("files__" + long)[:maxToolNameLength-2] + "_2"},
+		},
+		{
+			name:       "name of another server",
+			server:     "my.files",
+			offered:    []string{"read"},
+			registered: []string{"my_files__read"},
+			expected:   []string{"my_files__read_2"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			client := ports.NewMockMCPClient(ctrl)
+
+			var offered []domain.MCPTool
+			for _, name := range tc.offered {
+				offered = append(offered, domain.MCPTool{Name: name})
+			}
+			client.EXPECT().ListTools(gomock.Any()).Return(offered, nil)
+
+			registry := tools.New()
+			for _, name := range tc.registered {
+				registry.Add(&mcpTool{name: name})
+			}
+
+			mcpTools, err := NewMCPTools(context.Background(), tc.server, client, nil, registry)
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+
+			var names []string
+			for _, tool := range mcpTools {
+				names = append(names, tool.Name())
+				registry.Add(tool)
+			}
+			if !reflect.DeepEqual(names, tc.expected) {
+				t.Errorf("expected names %v, got %v", tc.expected, names)
+			}
+		})
+	}
+}
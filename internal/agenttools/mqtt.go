@@ -0,0 +1,157 @@
+package agenttools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"github.com/ownerofglory/raspi-agent/pkg/tools"
+)
+
+// MQTTAllUsers in the users of NewMQTTTools makes the tools available to
+// every caller, e.g. on a device running the assistant for its owner.
+const MQTTAllUsers = "*"
+
+// NewMQTTTools returns the tools to publish to MQTT topics and read their
+// retained values, to be added to a tools.Tools registry. As the broker is
+// the operator's, the tools are only available to the users with the given
+// IDs, or to every caller if users contains MQTTAllUsers.
+func NewMQTTTools(client ports.MQTTClient, users []string) []tools.Tool {
+	access := mqttAccess{users: users}
+	return []tools.Tool{
+		&mqttPublishTool{mqttAccess: access, client: client},
+		&mqttReadTool{mqttAccess: access, client: client},
+	}
+}
+
+// mqttAccess restricts the MQTT tools to an allow-list of users.
+type mqttAccess struct {
+	users []string
+}
+
+// Allowed reports whether the calling user is allowed to use the broker.
+func (a mqttAccess) Allowed(ctx context.Context) bool {
+	if slices.Contains(a.users, MQTTAllUsers) {
+		return true
+	}
+	caller, ok := domain.ToolCallerFrom(ctx)
+	return ok && caller.UserID != "" && slices.Contains(a.users, caller.UserID)
+}
+
+// mqttPublishTool publishes a message on an MQTT topic.
+type mqttPublishTool struct {
+	mqttAccess
+	client ports.MQTTClient
+}
+
+type mqttPublishArgs struct {
+	Topic   string `json:"topic"`
+	Payload string `json:"payload"`
+	Retain  bool   `json:"retain"`
+}
+
+func (t *mqttPublishTool) Name() string { return "mqtt_publish" }
+
+func (t *mqttPublishTool) Description() string {
+	return "Publish a message on an MQTT topic of the home automation, e.g. payload \"ON\" on topic " +
+		"\"zigbee2mqtt/kitchen_light/set\"."
+}
+
+func (t *mqttPublishTool) UserMessage() string { return "" }
+
+func (t *mqttPublishTool) Schema() tools.Schema {
+	return tools.Schema{
+		"type": "object",
+		"properties": map[string]any{
+			"topic": map[string]any{
+				"type":        "string",
+				"description": "Topic to publish on, without wildcards, e.g. \"home/kitchen/light/set\".",
+			},
+			"payload": map[string]any{
+				"type":        "string",
+				"description": "Message to publish, e.g. \"ON\" or a JSON document such as {\"state\":\"ON\"}.",
+			},
+			"retain": map[string]any{
+				"type":        "boolean",
+				"description": "Whether the broker keeps the message as the last value of the topic.",
+			},
+		},
+		"required": []string{"topic", "payload"},
+	}
+}
+
+func (t *mqttPublishTool) Execute(ctx context.Context, args string) (any, error) {
+	var a mqttPublishArgs
+	if err := json.Unmarshal([]byte(args), &a); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	err := t.client.Publish(ctx, domain.MQTTMessage{
+		Topic:   strings.TrimSpace(a.Topic),
+		Payload: []byte(a.Payload),
+		QoS:     1,
+		Retain:  a.Retain,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]any{"published": true}, nil
+}
+
+// mqttReadTool reads the last value retained for an MQTT topic.
+type mqttReadTool struct {
+	mqttAccess
+	client ports.MQTTClient
+}
+
+type mqttReadArgs struct {
+	Topic string `json:"topic"`
+}
+
+func (t *mqttReadTool) Name() string { return "mqtt_read" }
+
+func (t *mqttReadTool) Description() string {
+	return "Read the last retained value of an MQTT topic of the home automation, e.g. a sensor reading " +
+		"on \"home/outside/temperature\"."
+}
+
+func (t *mqttReadTool) UserMessage() string { return "" }
+
+func (t *mqttReadTool) Schema() tools.Schema {
+	return tools.Schema{
+		"type": "object",
+		"properties": map[string]any{
+			"topic": map[string]any{
+				"type":        "string",
+				"description": "Topic to read, without wildcards, e.g. \"home/outside/temperature\".",
+			},
+		},
+		"required": []string{"topic"},
+	}
+}
+
+func (t *mqttReadTool) Execute(ctx context.Context, args string) (any, error) {
+	var a mqttReadArgs
+	if err := json.Unmarshal([]byte(args), &a); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	msg, err := t.client.Retained(ctx, strings.TrimSpace(a.Topic))
+	if errors.Is(err, domain.ErrNoRetainedMQTTMessage) {
+		return map[string]any{"topic": a.Topic, "value": nil}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !utf8.Valid(msg.Payload) {
+		return map[string]any{"topic": msg.Topic, "binary": true, "size": len(msg.Payload)}, nil
+	}
+	return map[string]any{"topic": msg.Topic, "value": string(msg.Payload)}, nil
+}
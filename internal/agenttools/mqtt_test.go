@@ -0,0 +1,38 @@
+package agenttools
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/pkg/tools"
+)
+
+func TestMQTTToolsAllowed(t *testing.T) {
+	user := domain.WithToolCaller(context.Background(), domain.ToolCaller{UserID: "user1"})
+	local := domain.WithToolCaller(context.Background(), domain.ToolCaller{})
+
+	testCases := []struct {
+		name    string
+		users   []string
+		ctx     context.Context
+		allowed bool
+	}{
+		{name: "nobody by default", users: nil, ctx: user, allowed: false},
+		{name: "listed user", users: []string{"user1"}, ctx: user, allowed: true},
+		{name: "unlisted user", users: []string{"user2"}, ctx: user, allowed: false},
+		{name: "caller without user", users: []string{"user1"}, ctx: local, allowed: false},
+		{name: "no caller", users: []string{"user1"}, ctx: context.Background(), allowed: false},
+		{name: "all users", users: []string{MQTTAllUsers}, ctx: local, allowed: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, tool := range NewMQTTTools(nil, tc.users) {
+				if got := tools.Allowed(tc.ctx, tool); got != tc.allowed {
+					t.Errorf("expected %s allowed to be %v, got %v", tool.Name(), tc.allowed, got)
+				}
+			}
+		})
+	}
+}
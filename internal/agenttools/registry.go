@@ -0,0 +1,53 @@
+// Package agenttools exposes the tools of a pkg/tools registry to the
+// voice assistant.
+package agenttools
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/pkg/tools"
+)
+
+// registryProvider implements ports.ToolProvider on top of a tools.Tools registry.
+type registryProvider struct {
+	registry *tools.Tools
+}
+
+// NewRegistryProvider creates a tool provider offering all tools registered
+// in registry, including those added later.
+func NewRegistryProvider(registry *tools.Tools) *registryProvider {
+	return &registryProvider{registry: registry}
+}
+
+// Tools returns the definitions of the registered tools allowed in ctx.
+func (p *registryProvider) Tools(ctx context.Context) []domain.ToolDefinition {
+	all := p.registry.All()
+
+	defs := make([]domain.ToolDefinition, 0, len(all))
+	for _, t := range all {
+		if !tools.Allowed(ctx, t) {
+			continue
+		}
+		defs = append(defs, domain.ToolDefinition{
+			Name:        t.Name(),
+			Description: t.Description(),
+			Schema:      domain.ToolSchema(t.Schema()),
+			UserMessage: t.UserMessage(),
+		})
+	}
+	return defs
+}
+
+// ExecuteTool runs a registered tool by name, if it is allowed in ctx.
+func (p *registryProvider) ExecuteTool(ctx context.Context, name, args string) (any, error) {
+	t, err := p.registry.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	if !tools.Allowed(ctx, t) {
+		return nil, fmt.Errorf("tool '%s' is not enabled", name)
+	}
+	return t.Execute(ctx, args)
+}
@@ -0,0 +1,245 @@
+package agenttools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"github.com/ownerofglory/raspi-agent/pkg/tools"
+)
+
+// NewTimerTools returns the tools to set, list and cancel timers and alarms
+// of the calling user, to be added to a tools.Tools registry.
+func NewTimerTools(timers ports.TimerService) []tools.Tool {
+	return []tools.Tool{
+		&setTimerTool{timers: timers, now: time.Now},
+		&listTimersTool{timers: timers, now: time.Now},
+		&cancelTimerTool{timers: timers},
+	}
+}
+
+// timerInfo is the representation of a timer returned to the model.
+type timerInfo struct {
+	ID               string `json:"id"`
+	Kind             string `json:"kind"`
+	Label            string `json:"label,omitempty"`
+	FiresAt          string `json:"fires_at"`
+	RemainingSeconds int    `json:"remaining_seconds"`
+}
+
+func toTimerInfo(t domain.Timer, now time.Time) timerInfo {
+	return timerInfo{
+		ID:               t.ID,
+		Kind:             string(t.Kind),
+		Label:            t.Label,
+		FiresAt:          t.FiresAt.In(t.Location()).Format(time.RFC3339),
+		RemainingSeconds: int(max(t.FiresAt.Sub(now), 0).Seconds()),
+	}
+}
+
+// setTimerTool sets a countdown timer or an alarm on the calling device.
+type setTimerTool struct {
+	timers ports.TimerService
+	now    func() time.Time
+}
+
+type setTimerArgs struct {
+	DurationSeconds int    `json:"duration_seconds"`
+	Time            string `json:"time"`
+	Label           string `json:"label"`
+}
+
+func (t *setTimerTool) Name() string { return "set_timer" }
+
+func (t *setTimerTool) Description() string {
+	return "Set a countdown timer (duration_seconds) or an alarm at a time of day (time, HH:MM in 24-hour format, " +
+		"the next occurrence in the time zone of the device is used). The device plays an alert and speaks the label when it fires."
+}
+
+func (t *setTimerTool) UserMessage() string { return "" }
+
+func (t *setTimerTool) Schema() tools.Schema {
+	return tools.Schema{
+		"type": "object",
+		"properties": map[string]any{
+			"duration_seconds": map[string]any{
+				"type":        "integer",
+				"description": "Duration of a countdown timer in seconds.",
+			},
+			"time": map[string]any{
+				"type":        "string",
+				"description": "Time of day of an alarm, HH:MM in 24-hour format.",
+			},
+			"label": map[string]any{
+				"type":        "string",
+				"description": "Short name of the timer, e.g. \"pasta\". Empty if the user gave none.",
+			},
+		},
+	}
+}
+
+func (t *setTimerTool) Execute(ctx context.Context, args string) (any, error) {
+	caller, ok := domain.ToolCallerFrom(ctx)
+	if !ok {
+		return nil, errors.New("timers need a calling device")
+	}
+
+	var a setTimerArgs
+	if err := json.Unmarshal([]byte(args), &a); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	req := domain.TimerRequest{
+		DeviceID: caller.DeviceID,
+		UserID:   caller.UserID,
+		Label:    strings.TrimSpace(a.Label),
+		Duration: time.Duration(a.DurationSeconds) * time.Second,
+		TimeZone: caller.TimeZone,
+	}
+	if a.DurationSeconds <= 0 {
+		at, err := nextTimeOfDay(t.now(), a.Time, domain.LoadLocation(caller.TimeZone))
+		if err != nil {
+			return nil, err
+		}
+		req.At = at
+	}
+
+	timer, err := t.timers.Set(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return toTimerInfo(*timer, t.now()), nil
+}
+
+// nextTimeOfDay returns the next occurrence of a HH:MM time of day in the
+// time zone loc after now.
+func nextTimeOfDay(now time.Time, hhmm string, loc *time.Location) (time.Time, error) {
+	tod, err := time.Parse("15:04", strings.TrimSpace(hhmm))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("either duration_seconds or time (HH:MM) is required: %w", domain.ErrInvalidTimer)
+	}
+
+	now = now.In(loc)
+	at := time.Date(now.Year(), now.Month(), now.Day(), tod.Hour(), tod.Minute(), 0, 0, now.Location())
+	if !at.After(now) {
+		at = at.AddDate(0, 0, 1)
+	}
+	return at, nil
+}
+
+// listTimersTool lists the pending timers and alarms of the calling user.
+type listTimersTool struct {
+	timers ports.TimerService
+	now    func() time.Time
+}
+
+func (t *listTimersTool) Name() string { return "list_timers" }
+
+func (t *listTimersTool) Description() string {
+	return "List the pending timers and alarms of the user with their IDs and remaining time."
+}
+
+func (t *listTimersTool) UserMessage() string { return "" }
+
+func (t *listTimersTool) Schema() tools.Schema {
+	return tools.Schema{"type": "object", "properties": map[string]any{}}
+}
+
+func (t *listTimersTool) Execute(ctx context.Context, args string) (any, error) {
+	caller, ok := domain.ToolCallerFrom(ctx)
+	if !ok {
+		return nil, errors.New("timers need a calling device")
+	}
+
+	timers, err := t.timers.List(ctx, caller.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := t.now()
+	infos := make([]timerInfo, 0, len(timers))
+	for _, timer := range timers {
+		infos = append(infos, toTimerInfo(timer, now))
+	}
+	return map[string]any{"timers": infos}, nil
+}
+
+// cancelTimerTool cancels a pending timer or alarm of the calling user.
+type cancelTimerTool struct {
+	timers ports.TimerService
+}
+
+type cancelTimerArgs struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+}
+
+func (t *cancelTimerTool) Name() string { return "cancel_timer" }
+
+func (t *cancelTimerTool) Description() string {
+	return "Cancel a pending timer or alarm, by its ID or label. " +
+		"Without either, the only pending timer is canceled."
+}
+
+func (t *cancelTimerTool) UserMessage() string { return "" }
+
+func (t *cancelTimerTool) Schema() tools.Schema {
+	return tools.Schema{
+		"type": "object",
+		"properties": map[string]any{
+			"id": map[string]any{
+				"type":        "string",
+				"description": "ID of the timer as returned by list_timers.",
+			},
+			"label": map[string]any{
+				"type":        "string",
+				"description": "Label of the timer.",
+			},
+		},
+	}
+}
+
+func (t *cancelTimerTool) Execute(ctx context.Context, args string) (any, error) {
+	caller, ok := domain.ToolCallerFrom(ctx)
+	if !ok {
+		return nil, errors.New("timers need a calling device")
+	}
+
+	var a cancelTimerArgs
+	if err := json.Unmarshal([]byte(args), &a); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	id := a.ID
+	if id == "" {
+		timers, err := t.timers.List(ctx, caller.UserID)
+		if err != nil {
+			return nil, err
+		}
+
+		var matches []domain.Timer
+		for _, timer := range timers {
+			if a.Label == "" || strings.EqualFold(timer.Label, strings.TrimSpace(a.Label)) {
+				matches = append(matches, timer)
+			}
+		}
+		switch len(matches) {
+		case 0:
+			return nil, domain.ErrTimerNotFound
+		case 1:
+			id = matches[0].ID
+		default:
+			return nil, fmt.Errorf("%d timers match, ask the user which one to cancel", len(matches))
+		}
+	}
+
+	if err := t.timers.Cancel(ctx, caller.UserID, id); err != nil {
+		return nil, err
+	}
+	return map[string]any{"canceled": id}, nil
+}
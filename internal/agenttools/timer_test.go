@@ -0,0 +1,88 @@
+package agenttools
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+func TestNextTimeOfDay(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	// 00:30 of the next day in Berlin, 18:30 of the same day in New York
+	now := time.Date(2026, 10, 16, 22, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name    string
+		hhmm    string
+		loc     *time.Location
+		at      time.Time
+		wantErr bool
+	}{
+		{
+			name: "later today",
+			hhmm: "23:00",
+			loc:  time.UTC,
+			at:   time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "tomorrow",
+			hhmm: "07:00",
+			loc:  time.UTC,
+			at:   time.Date(2026, 10, 17, 7, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "ahead of UTC, already the next day",
+			hhmm: "07:00",
+			loc:  berlin,
+			at:   time.Date(2026, 10, 17, 5, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "behind UTC, still the same day",
+			hhmm: "20:15",
+			loc:  newYork,
+			at:   time.Date(2026, 10, 17, 0, 15, 0, 0, time.UTC),
+		},
+		{
+			name: "behind UTC, rolls over to the next day",
+			hhmm: "07:00",
+			loc:  newYork,
+			at:   time.Date(2026, 10, 17, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "invalid time",
+			hhmm:    "7am",
+			loc:     time.UTC,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			at, err := nextTimeOfDay(now, tc.hhmm, tc.loc)
+			if tc.wantErr {
+				if !errors.Is(err, domain.ErrInvalidTimer) {
+					t.Errorf("expected %v, got %v", domain.ErrInvalidTimer, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+			if !at.Equal(tc.at) {
+				t.Errorf("expected %v, got %v", tc.at, at.UTC())
+			}
+			if at.Location() != tc.loc {
+				t.Errorf("expected the time in %v, got %v", tc.loc, at.Location())
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/gordonklaus/portaudio"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+const (
+	// CaptureSampleRate is the sample rate of the shared capture, as required
+	// by the wake word engines and speech recognizers.
+	CaptureSampleRate = 16000
+
+	// captureFramesPerSecond defines the size of the captured frames (20ms),
+	// the frame size of the VAD.
+	captureFramesPerSecond = 50
+
+	// maxPreRoll is how much audio the bus keeps for late subscribers.
+	maxPreRoll = 2 * time.Second
+
+	// subscriptionBufferFrames is how many frames (10s) are buffered for a
+	// slow subscriber, e.g. a recording being uploaded, before its frames
+	// are dropped.
+	subscriptionBufferFrames = 500
+)
+
+// errCaptureStopped is returned when subscribing after the capture stopped.
+var errCaptureStopped = errors.New("audio capture stopped")
+
+// captureBus implements ports.AudioSource: it owns the single PortAudio
+// input stream and fans the captured frames out to its subscribers.
+//
+// The most recent frames are kept in a ring buffer, so a subscriber
+// starting right after the wake word, e.g. the recorder, still gets the
+// audio captured before it subscribed.
+type captureBus struct {
+	sampleRate int
+
+	mu      sync.Mutex
+	ring    []domain.AudioFrame
+	subs    map[chan domain.AudioFrame]struct{}
+	stopped bool
+
+	// done is closed once the capture stops, ending the subscriptions
+	done chan struct{}
+}
+
+// NewCaptureBus creates the shared capture of the microphone at
+// CaptureSampleRate. Capture starts with Run.
+func NewCaptureBus() *captureBus {
+	return &captureBus{
+		sampleRate: CaptureSampleRate,
+		subs:       make(map[chan domain.AudioFrame]struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// Run captures mono frames from the input device until the context is
+// canceled or capture fails, and closes all subscriptions when it returns.
+func (b *captureBus) Run(ctx context.Context) error {
+	defer b.stop()
+
+	if err := portaudio.Initialize(); err != nil {
+		slog.Error("Unable to initialize portaudio", "err", err)
+		return fmt.Errorf("unable to initialize portaudio: %v", err)
+	}
+	defer portaudio.Terminate()
+
+	inputDevice, err := findInputDevice()
+	if err != nil {
+		return err
+	}
+
+	const channels = 1
+	chunk := make([]int16, b.sampleRate/captureFramesPerSecond*channels)
+	stream, err := openInputStream(inputDevice, channels, float64(b.sampleRate), chunk)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	defer stream.Stop()
+
+	slog.Info("Audio capture started", "device", inputDevice.Name, "rate", b.sampleRate)
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Debug("Audio capture stopped")
+			return nil
+		default:
+		}
+
+		if err := stream.Read(); err != nil {
+			var paErr portaudio.Error
+			if errors.As(err, &paErr) && errors.Is(paErr, portaudio.InputOverflowed) {
+				slog.Debug("Warning: input overflow (skipping some samples)")
+				continue
+			}
+			slog.Error("Streaming error", "err", err)
+			err = fmt.Errorf("streaming error: %v", err)
+			b.publish(domain.AudioFrame{Err: err})
+			return err
+		}
+
+		samples := make([]int16, len(chunk))
+		copy(samples, chunk)
+		b.publish(domain.AudioFrame{
+			Samples:    samples,
+			SampleRate: b.sampleRate,
+			Channels:   channels,
+		})
+	}
+}
+
+// Subscribe delivers the captured frames, preceded by those of the last
+// preRoll (at most maxPreRoll), until ctx is canceled or capture stops.
+func (b *captureBus) Subscribe(ctx context.Context, preRoll time.Duration) (<-chan domain.AudioFrame, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped {
+		return nil, errCaptureStopped
+	}
+
+	ch := make(chan domain.AudioFrame, subscriptionBufferFrames)
+	n := min(int(preRoll*captureFramesPerSecond/time.Second), len(b.ring))
+	for _, frame := range b.ring[len(b.ring)-n:] {
+		ch <- frame
+	}
+	b.subs[ch] = struct{}{}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			b.unsubscribe(ch)
+		case <-b.done:
+		}
+	}()
+
+	return ch, nil
+}
+
+// publish keeps the frame in the ring buffer and sends it to all
+// subscribers, dropping it for those not keeping up.
+func (b *captureBus) publish(frame domain.AudioFrame) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if frame.Err == nil {
+		b.ring = append(b.ring, frame)
+		if maxFrames := int(maxPreRoll * captureFramesPerSecond / time.Second); len(b.ring) > maxFrames {
+			b.ring = b.ring[len(b.ring)-maxFrames:]
+		}
+	}
+
+	for ch := range b.subs {
+		select {
+		case ch <- frame:
+		default:
+			slog.Warn("Audio subscriber too slow, dropping frame")
+		}
+	}
+}
+
+func (b *captureBus) unsubscribe(ch chan domain.AudioFrame) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+// stop closes all subscriptions and refuses new ones.
+func (b *captureBus) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped {
+		return
+	}
+	b.stopped = true
+	close(b.done)
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
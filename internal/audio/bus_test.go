@@ -0,0 +1,74 @@
+package audio
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+func testFrame(i int) domain.AudioFrame {
+	return domain.AudioFrame{
+		Samples:    []int16{int16(i)},
+		SampleRate: CaptureSampleRate,
+		Channels:   1,
+	}
+}
+
+func TestCaptureBus(t *testing.T) {
+	b := NewCaptureBus()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 3 frames (60ms) captured before anyone listens
+	for i := 1; i <= 3; i++ {
+		b.publish(testFrame(i))
+	}
+
+	listener, err := b.Subscribe(ctx, 0)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	recCtx, stopRecording := context.WithCancel(ctx)
+	recording, err := b.Subscribe(recCtx, 40*time.Millisecond)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	b.publish(testFrame(4))
+
+	receive := func(ch <-chan domain.AudioFrame) []int16 {
+		var got []int16
+		for len(ch) > 0 {
+			got = append(got, (<-ch).Samples...)
+		}
+		return got
+	}
+
+	if got := receive(listener); len(got) != 1 || got[0] != 4 {
+		t.Errorf("expected the listener to get frame 4, got %v", got)
+	}
+	if got := receive(recording); len(got) != 3 || got[0] != 2 || got[2] != 4 {
+		t.Errorf("expected the recording to start with the pre-roll of frames 2 and 3, got %v", got)
+	}
+
+	stopRecording()
+	if _, ok := <-recording; ok {
+		t.Errorf("expected the canceled subscription to be closed")
+	}
+
+	b.stop()
+	if _, ok := <-listener; ok {
+		t.Errorf("expected subscriptions to be closed once capture stopped")
+	}
+	select {
+	case <-b.done:
+	default:
+		t.Errorf("expected the subscription goroutines to be released once capture stopped")
+	}
+	b.stop()
+	if _, err := b.Subscribe(ctx, 0); err == nil {
+		t.Errorf("expected an error subscribing after capture stopped")
+	}
+}
@@ -0,0 +1,222 @@
+// Package codec decodes the audio formats played by the device, MP3, WAV,
+// Ogg/Opus, FLAC and raw PCM, into interleaved 16-bit little-endian PCM.
+//
+// MP3 and WAV are decoded in process; Ogg/Opus and FLAC are decoded by a
+// GStreamer subprocess, as the rest of the media pipeline of the device,
+// which requires gst-launch-1.0 to be installed, see CheckGStreamer.
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/ownerofglory/raspi-agent/internal/audio/wav"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/tosone/minimp3"
+)
+
+// gstLaunch is the GStreamer command decoding Ogg/Opus and FLAC.
+var gstLaunch = "gst-launch-1.0"
+
+// Output of the GStreamer decoder, the native rate of Opus.
+const (
+	gstSampleRate = 48000
+	gstChannels   = 2
+)
+
+// Decoded is a stream of interleaved 16-bit little-endian PCM samples.
+type Decoded struct {
+	PCM        io.Reader
+	SampleRate int
+	Channels   int
+
+	close func()
+}
+
+// Close stops decoding and releases the decoder.
+func (d *Decoded) Close() {
+	if d.close != nil {
+		d.close()
+	}
+}
+
+// CheckGStreamer reports whether gst-launch-1.0 is installed, without
+// which Ogg/Opus and FLAC cannot be decoded, e.g. to warn on startup.
+func CheckGStreamer() error {
+	if _, err := exec.LookPath(gstLaunch); err != nil {
+		return fmt.Errorf("%s not found, install GStreamer to play Ogg/Opus and FLAC: %w", gstLaunch, err)
+	}
+	return nil
+}
+
+// Detect returns the format of the audio starting with header, by its
+// magic bytes: an ID3 tag or the sync word of a frame for MP3. The zero
+// value is returned if the format is unknown.
+func Detect(header []byte) domain.AudioFormat {
+	switch {
+	case bytes.HasPrefix(header, []byte("ID3")), len(header) >= 2 && header[0] == 0xff && header[1]&0xe0 == 0xe0:
+		return domain.AudioFormat{ContentType: domain.AudioContentTypeMPEG}
+	case bytes.HasPrefix(header, []byte("RIFF")):
+		return domain.AudioFormat{ContentType: domain.AudioContentTypeWAV}
+	case bytes.HasPrefix(header, []byte("OggS")):
+		return domain.AudioFormat{ContentType: domain.AudioContentTypeOpus}
+	case bytes.HasPrefix(header, []byte("fLaC")):
+		return domain.AudioFormat{ContentType: domain.AudioContentTypeFLAC}
+	default:
+		return domain.AudioFormat{}
+	}
+}
+
+// Decode returns the decoded samples of the audio read from r. If the
+// format is unknown, it is detected from the leading bytes of the audio;
+// audio of no known format, or whose decoder is not installed, fails with
+// domain.ErrUnsupportedAudioFormat. Raw PCM must declare its sample rate;
+// mono is assumed without channels.
+func Decode(r io.Reader, format domain.AudioFormat) (*Decoded, error) {
+	br := bufio.NewReader(r)
+	if format.ContentType == "" {
+		magic, _ := br.Peek(4)
+		format = Detect(magic)
+	}
+
+	switch format.ContentType {
+	case domain.AudioContentTypeWAV:
+		sampleRate, channels, pcm, err := wav.NewPCMReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read wav stream: %w", err)
+		}
+		slog.Info("WAV stream started", "rate", sampleRate, "channels", channels)
+		return &Decoded{PCM: pcm, SampleRate: sampleRate, Channels: channels}, nil
+
+	case domain.AudioContentTypePCM, domain.AudioContentTypeL16:
+		if format.SampleRate <= 0 {
+			return nil, fmt.Errorf("raw audio %q without sample rate", format.ContentType)
+		}
+		channels := format.Channels
+		if channels <= 0 {
+			channels = 1
+		}
+		var pcm io.Reader = br
+		if format.ContentType == domain.AudioContentTypeL16 {
+			pcm = &swapReader{r: br}
+		}
+		slog.Info("PCM stream started", "rate", format.SampleRate, "channels", channels)
+		return &Decoded{PCM: pcm, SampleRate: format.SampleRate, Channels: channels}, nil
+
+	case domain.AudioContentTypeOpus, domain.AudioContentTypeFLAC:
+		if err := CheckGStreamer(); err != nil {
+			return nil, fmt.Errorf("%w %s: %w", domain.ErrUnsupportedAudioFormat, format.ContentType, err)
+		}
+		return decodeGst(br, format)
+
+	case domain.AudioContentTypeMPEG:
+		return decodeMP3(br)
+
+	case "":
+		return nil, fmt.Errorf("%w: not detected from the leading bytes", domain.ErrUnsupportedAudioFormat)
+
+	default:
+		return nil, fmt.Errorf("%w %s", domain.ErrUnsupportedAudioFormat, format.ContentType)
+	}
+}
+
+// decodeMP3 decodes MP3 once the audio has started, failing if there is
+// none, as the decoder would wait for its first frame forever.
+func decodeMP3(r *bufio.Reader) (*Decoded, error) {
+	if _, err := r.Peek(1); err != nil {
+		return nil, fmt.Errorf("failed to read mp3 stream: %w", err)
+	}
+
+	decoder, err := minimp3.NewDecoder(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mp3 decoder: %w", err)
+	}
+
+	// Wait until decoding actually starts
+	<-decoder.Started()
+	slog.Info("MP3 decoder started", "rate", decoder.SampleRate, "channels", decoder.Channels)
+
+	channels := decoder.Channels
+	if channels == 0 {
+		channels = 2
+	}
+	return &Decoded{PCM: decoder, SampleRate: decoder.SampleRate, Channels: channels, close: decoder.Close}, nil
+}
+
+// decodeGst decodes the audio with gst-launch-1.0, which is fed the audio
+// on stdin and writes PCM at a fixed rate and channel count to stdout.
+func decodeGst(r io.Reader, format domain.AudioFormat) (*Decoded, error) {
+	caps := fmt.Sprintf("audio/x-raw,format=S16LE,rate=%d,channels=%d", gstSampleRate, gstChannels)
+	cmd := exec.Command(gstLaunch, "-q",
+		"fdsrc", "fd=0", "!", "decodebin", "!", "audioconvert", "!", "audioresample", "!", caps, "!", "fdsink", "fd=1")
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		slog.Error("Failed to start gst-launch", "err", err)
+		return nil, fmt.Errorf("failed to start %s decoder: %w", format.ContentType, err)
+	}
+	slog.Info("GStreamer decoder started", "format", format.ContentType)
+
+	go func() {
+		defer stdin.Close()
+		if _, err := io.Copy(stdin, r); err != nil && !errors.Is(err, os.ErrClosed) {
+			slog.Debug("Feeding gst-launch stopped", "err", err)
+		}
+	}()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			// the decoder may still be running if playback was canceled
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+		})
+	}
+	return &Decoded{PCM: stdout, SampleRate: gstSampleRate, Channels: gstChannels, close: stop}, nil
+}
+
+// swapReader converts big-endian 16-bit samples to little-endian.
+type swapReader struct {
+	r io.Reader
+}
+
+func (s *swapReader) Read(b []byte) (int, error) {
+	// read whole samples only
+	b = b[:len(b)&^1]
+	if len(b) == 0 {
+		return 0, io.ErrShortBuffer
+	}
+
+	n, err := s.r.Read(b)
+	if n%2 == 1 {
+		if _, rerr := io.ReadFull(s.r, b[n:n+1]); rerr == nil {
+			n++
+		} else {
+			// drop a truncated sample at the end of the stream
+			n--
+			if err == nil {
+				err = rerr
+			}
+		}
+	}
+
+	for i := 0; i+1 < n; i += 2 {
+		b[i], b[i+1] = b[i+1], b[i]
+	}
+	return n, err
+}
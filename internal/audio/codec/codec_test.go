@@ -0,0 +1,151 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ownerofglory/raspi-agent/internal/audio/wav"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+func TestDecode(t *testing.T) {
+	// the fake gst-launch ignores its input and "decodes" four bytes
+	binary := filepath.Join(t.TempDir(), "gst-launch-1.0")
+	script := "#!/bin/sh\ncat > /dev/null\nprintf 'opus'\n"
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	gstLaunch = binary
+
+	var wavAudio bytes.Buffer
+	_ = wav.Encode(&wavAudio, 22050, 1, []int16{1, -2})
+
+	testCases := []struct {
+		name       string
+		audio      []byte
+		format     domain.AudioFormat
+		expectErr  bool
+		sampleRate int
+		channels   int
+		pcm        []byte
+	}{
+		{
+			name:       "wav detected",
+			audio:      wavAudio.Bytes(),
+			sampleRate: 22050,
+			channels:   1,
+			pcm:        []byte{0x01, 0x00, 0xfe, 0xff},
+		},
+		{
+			name:       "declared pcm",
+			audio:      []byte{0x01, 0x00, 0xfe, 0xff},
+			format:     domain.ParseAudioFormat("audio/pcm;rate=24000"),
+			sampleRate: 24000,
+			channels:   1,
+			pcm:        []byte{0x01, 0x00, 0xfe, 0xff},
+		},
+		{
+			name:       "declared big-endian pcm",
+			audio:      []byte{0x00, 0x01, 0xff, 0xfe},
+			format:     domain.ParseAudioFormat("audio/L16; rate=16000; channels=2"),
+			sampleRate: 16000,
+			channels:   2,
+			pcm:        []byte{0x01, 0x00, 0xfe, 0xff},
+		},
+		{
+			name:      "pcm without rate",
+			audio:     []byte{0x01, 0x00},
+			format:    domain.AudioFormat{ContentType: domain.AudioContentTypePCM},
+			expectErr: true,
+		},
+		{
+			name:       "opus detected",
+			audio:      []byte("OggS-fake-opus"),
+			sampleRate: gstSampleRate,
+			channels:   gstChannels,
+			pcm:        []byte("opus"),
+		},
+		{
+			name:       "declared flac",
+			audio:      []byte("fLaC-fake-flac"),
+			format:     domain.ParseAudioFormat("audio/x-flac"),
+			sampleRate: gstSampleRate,
+			channels:   gstChannels,
+			pcm:        []byte("opus"),
+		},
+		{
+			name:      "empty mp3",
+			format:    domain.AudioFormat{ContentType: domain.AudioContentTypeMPEG},
+			expectErr: true,
+		},
+		{
+			name:      "unknown format",
+			audio:     []byte("<html>not audio</html>"),
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decoded, err := Decode(bytes.NewReader(tc.audio), tc.format)
+			if tc.expectErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+			defer decoded.Close()
+
+			if decoded.SampleRate != tc.sampleRate || decoded.Channels != tc.channels {
+				t.Errorf("expected %d Hz with %d channels, got %d Hz with %d channels",
+					tc.sampleRate, tc.channels, decoded.SampleRate, decoded.Channels)
+			}
+			pcm, err := io.ReadAll(decoded.PCM)
+			if err != nil {
+				t.Errorf("expected error to be nil got %v", err)
+			}
+			if !bytes.Equal(pcm, tc.pcm) {
+				t.Errorf("expected pcm %v, got %v", tc.pcm, pcm)
+			}
+		})
+	}
+}
+
+func TestDecodeWithoutGStreamer(t *testing.T) {
+	defer func(binary string) { gstLaunch = binary }(gstLaunch)
+	gstLaunch = filepath.Join(t.TempDir(), "gst-launch-1.0")
+
+	if err := CheckGStreamer(); err == nil {
+		t.Error("expected the missing gst-launch to be reported")
+	}
+	if _, err := Decode(bytes.NewReader([]byte("OggS-fake-opus")), domain.AudioFormat{}); !errors.Is(err, domain.ErrUnsupportedAudioFormat) {
+		t.Errorf("expected an unsupported format error, got %v", err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	testCases := []struct {
+		header      string
+		contentType string
+	}{
+		{header: "RIFF", contentType: domain.AudioContentTypeWAV},
+		{header: "OggS", contentType: domain.AudioContentTypeOpus},
+		{header: "fLaC", contentType: domain.AudioContentTypeFLAC},
+		{header: "ID3\x04", contentType: domain.AudioContentTypeMPEG},
+		{header: "\xff\xfb\x90\x64", contentType: domain.AudioContentTypeMPEG},
+		{header: "<htm", contentType: ""},
+	}
+
+	for _, tc := range testCases {
+		if got := Detect([]byte(tc.header)); got.ContentType != tc.contentType {
+			t.Errorf("expected %q to be detected as %s, got %q", tc.header, tc.contentType, got.ContentType)
+		}
+	}
+}
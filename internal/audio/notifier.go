@@ -0,0 +1,62 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+// notificationPlayer implements ports.DeviceNotifier on the device itself
+// by playing notifications through the local player.
+type notificationPlayer struct {
+	player     ports.Player
+	alertSound string
+}
+
+// NewNotificationPlayer creates a notifier playing the audio file (e.g. MP3, WAV or FLAC) at alertSound,
+// if set, followed by the spoken notification.
+func NewNotificationPlayer(player ports.Player, alertSound string) *notificationPlayer {
+	return &notificationPlayer{
+		player:     player,
+		alertSound: alertSound,
+	}
+}
+
+// Reachable reports true, as the local device is always reachable.
+func (n *notificationPlayer) Reachable(deviceID string) bool { return true }
+
+// Notify plays the notification. The device ID is ignored, as there is
+// only the local device.
+func (n *notificationPlayer) Notify(ctx context.Context, deviceID string, notification domain.DeviceNotification) error {
+	slog.Info("Playing notification", "kind", notification.Kind, "text", notification.Text)
+
+	if n.alertSound != "" {
+		if err := n.playAlert(ctx); err != nil {
+			// the spoken notification alone still alerts the user
+			slog.Error("Failed to play alert sound", "err", err, "file", n.alertSound)
+		}
+	}
+
+	if notification.Audio == nil {
+		return nil
+	}
+	if err := n.player.Playback(ctx, notification.Format, notification.Audio); err != nil {
+		return fmt.Errorf("failed to play notification: %w", err)
+	}
+	return nil
+}
+
+func (n *notificationPlayer) playAlert(ctx context.Context) error {
+	f, err := os.Open(n.alertSound)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// the format of the file is detected
+	return n.player.Playback(ctx, domain.AudioFormat{}, f)
+}
@@ -8,16 +8,23 @@ import (
 	"strings"
 
 	"github.com/gordonklaus/portaudio"
-	"github.com/tosone/minimp3"
+	"github.com/ownerofglory/raspi-agent/internal/audio/codec"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/vad"
 )
 
 // portAudioPlayer handles playback of streamed audio using PortAudio.
-// It consumes chunks of []byte audio data (typically raw PCM or decoded MP3).
-type portAudioPlayer struct{}
+// It consumes chunks of []byte audio data, MP3, WAV (e.g. from Piper),
+// Ogg/Opus, FLAC or raw PCM, see codec.Decode.
+type portAudioPlayer struct {
+	echo *vad.EchoGate
+}
 
-// NewPortAudioPlayer constructs a new audio player instance.
-func NewPortAudioPlayer() *portAudioPlayer {
-	return &portAudioPlayer{}
+// NewPortAudioPlayer constructs a new audio player instance reporting the
+// played audio to the echo gate, if not nil, so the wake word listener can
+// tell the user from the echo of the playback.
+func NewPortAudioPlayer(echo *vad.EchoGate) *portAudioPlayer {
+	return &portAudioPlayer{echo: echo}
 }
 
 // PlaybackStream plays streamed audio chunks in real time using PortAudio.
@@ -25,36 +32,13 @@ func NewPortAudioPlayer() *portAudioPlayer {
 // The audioStream channel should deliver small PCM audio chunks (e.g., 4KB each).
 // It automatically stops playback when the channel closes or the context is canceled.
 //
-// If your backend returns MP3, decode to PCM (int16 samples) before calling this.
-func (p *portAudioPlayer) PlaybackStream(ctx context.Context, audioStream <-chan []byte) error {
-	if err := portaudio.Initialize(); err != nil {
-		slog.Error("Error initializing portaudio")
-		return fmt.Errorf("portaudio initialize failed: %w", err)
-	}
-	defer portaudio.Terminate()
-
-	// Pick output device (pulse/pipewire preferred)
-	devices, err := portaudio.Devices()
-	if err != nil {
-		slog.Error("Error getting devices")
-		return fmt.Errorf("list devices: %w", err)
-	}
-
-	var output *portaudio.DeviceInfo
-	for _, d := range devices {
-		name := strings.ToLower(d.Name)
-		if strings.Contains(name, "pulse") || strings.Contains(name, "pipewire") {
-			output = d
-			break
-		}
-	}
-	if output == nil {
-		slog.Error("no pulse/pipewire output device found")
-		return fmt.Errorf("no pulse/pipewire output device found")
-	}
-
+// The format of the stream is detected from its first bytes unless declared.
+func (p *portAudioPlayer) PlaybackStream(ctx context.Context, format domain.AudioFormat, audioStream <-chan []byte) error {
 	// Pipe for streaming
 	pr, pw := io.Pipe()
+	// unblocks the writer if playback stops before the end of the stream,
+	// e.g. as it was canceled
+	defer pr.Close()
 
 	// Write chunks from audioStream into pipe
 	go func() {
@@ -70,127 +54,67 @@ func (p *portAudioPlayer) PlaybackStream(ctx context.Context, audioStream <-chan
 					return
 				}
 				if len(chunk) > 0 {
-					_, _ = pw.Write(chunk)
+					if _, err := pw.Write(chunk); err != nil {
+						slog.Debug("Playback stopped before the end of the audio stream")
+						return
+					}
 				}
 			}
 		}
 	}()
 
-	// Initialize minimp3 decoder
-	decoder, err := minimp3.NewDecoder(pr)
-	if err != nil {
-		return fmt.Errorf("failed to create mp3 decoder: %w", err)
-	}
-	defer decoder.Close()
-
-	// Wait until decoding actually starts
-	started := decoder.Started()
-	<-started
-
-	slog.Info("MP3 decoder started", "rate", decoder.SampleRate, "channels", decoder.Channels)
-
-	// Open PortAudio stream
-	channels := decoder.Channels
-	if channels == 0 {
-		channels = 2
-	}
-	buf := make([]int16, 512*channels)
-	stream, err := portaudio.OpenStream(portaudio.StreamParameters{
-		Output: portaudio.StreamDeviceParameters{
-			Device:   output,
-			Channels: channels,
-			Latency:  output.DefaultLowOutputLatency,
-		},
-		SampleRate:      float64(decoder.SampleRate),
-		FramesPerBuffer: len(buf) / channels,
-	}, &buf)
-	if err != nil {
-		return fmt.Errorf("failed to open audio stream: %w", err)
-	}
-	defer stream.Close()
-
-	if err := stream.Start(); err != nil {
-		return fmt.Errorf("failed to start stream: %w", err)
-	}
-	defer stream.Stop()
-
-	slog.Info("🎧 Playing decoded audio via PortAudio", "device", output.Name)
-
-	// Playback loop
-	pcmBuf := make([]byte, 4096)
-	for {
-		select {
-		case <-ctx.Done():
-			slog.Info("Playback canceled")
-			return nil
-		default:
-			n, err := decoder.Read(pcmBuf)
-			if n > 0 {
-				// Convert []byte -> []int16 without allocation
-				samples := bytesToInt16(pcmBuf[:n])
-				for len(samples) > 0 {
-					copied := copy(buf, samples)
-					samples = samples[copied:]
-					if err := stream.Write(); err != nil {
-						if strings.Contains(err.Error(), "underflow") {
-							slog.Warn("Output underflow")
-							continue
-						}
-						slog.Error("Stream write failed", "err", err)
-						return err
-					}
-				}
-			}
-			if err != nil {
-				if err == io.EOF {
-					slog.Info("Playback finished")
-					return nil
-				}
-				return fmt.Errorf("decoder read failed: %w", err)
-			}
-		}
-	}
+	return p.Playback(ctx, format, pr)
 }
 
-func (p *portAudioPlayer) Playback(ctx context.Context, reader io.Reader) error {
+// Playback plays the audio read from reader on the output device until it
+// ends or the context is canceled.
+//
+// The format of the audio is detected from its first bytes unless declared.
+func (p *portAudioPlayer) Playback(ctx context.Context, format domain.AudioFormat, reader io.Reader) error {
 	if err := portaudio.Initialize(); err != nil {
 		slog.Error("Error initializing portaudio")
 		return fmt.Errorf("portaudio initialize failed: %w", err)
 	}
 	defer portaudio.Terminate()
 
+	output, err := findOutputDevice()
+	if err != nil {
+		return err
+	}
+
+	return p.play(ctx, output, format, reader)
+}
+
+// findOutputDevice returns the pulse or pipewire output device.
+func findOutputDevice() (*portaudio.DeviceInfo, error) {
 	devices, err := portaudio.Devices()
 	if err != nil {
-		return fmt.Errorf("list devices: %w", err)
+		slog.Error("Error getting devices")
+		return nil, fmt.Errorf("list devices: %w", err)
 	}
 
-	var output *portaudio.DeviceInfo
 	for _, d := range devices {
 		name := strings.ToLower(d.Name)
 		if strings.Contains(name, "pulse") || strings.Contains(name, "pipewire") {
-			output = d
-			break
+			return d, nil
 		}
 	}
-	if output == nil {
-		return fmt.Errorf("no pulse/pipewire output device found")
-	}
-
-	// Initialize decoder directly from reader
-	decoder, err := minimp3.NewDecoder(reader)
-	if err != nil {
-		return fmt.Errorf("failed to create mp3 decoder: %w", err)
-	}
-	defer decoder.Close()
 
-	<-decoder.Started()
-	slog.Info("MP3 decoder started", "rate", decoder.SampleRate, "channels", decoder.Channels)
+	slog.Error("no pulse/pipewire output device found")
+	return nil, fmt.Errorf("no pulse/pipewire output device found")
+}
 
-	channels := decoder.Channels
-	if channels == 0 {
-		channels = 2
+// play decodes the audio and plays it on the output device until it ends
+// or the context is canceled.
+func (p *portAudioPlayer) play(ctx context.Context, output *portaudio.DeviceInfo, format domain.AudioFormat, reader io.Reader) error {
+	audio, err := codec.Decode(reader, format)
+	if err != nil {
+		return err
 	}
+	defer audio.Close()
 
+	// Open PortAudio stream
+	channels := audio.Channels
 	buf := make([]int16, 512*channels)
 	stream, err := portaudio.OpenStream(portaudio.StreamParameters{
 		Output: portaudio.StreamDeviceParameters{
@@ -198,7 +122,7 @@ func (p *portAudioPlayer) Playback(ctx context.Context, reader io.Reader) error
 			Channels: channels,
 			Latency:  output.DefaultLowOutputLatency,
 		},
-		SampleRate:      float64(decoder.SampleRate),
+		SampleRate:      float64(audio.SampleRate),
 		FramesPerBuffer: len(buf) / channels,
 	}, &buf)
 	if err != nil {
@@ -211,47 +135,55 @@ func (p *portAudioPlayer) Playback(ctx context.Context, reader io.Reader) error
 	}
 	defer stream.Stop()
 
-	slog.Info("🎧 Playing MP3 via PortAudio", "device", output.Name)
+	slog.Info("🎧 Playing decoded audio via PortAudio", "device", output.Name)
 
-	pcmBuf := make([]byte, 4096)
+	// Playback loop
+	pcmBuf := make([]byte, 2*len(buf))
 	for {
 		select {
 		case <-ctx.Done():
 			slog.Info("Playback canceled")
 			return nil
 		default:
-			n, err := decoder.Read(pcmBuf)
-			if n > 0 {
-				samples := bytesToInt16(pcmBuf[:n])
-				for len(samples) > 0 {
-					copied := copy(buf, samples)
-					samples = samples[copied:]
-					if err := stream.Write(); err != nil {
-						if strings.Contains(err.Error(), "underflow") {
-							slog.Warn("Output underflow")
-							continue
-						}
-						return fmt.Errorf("stream write failed: %w", err)
-					}
-				}
+			err := readSamples(audio.PCM, pcmBuf, buf)
+			if err == io.EOF {
+				slog.Info("Playback finished")
+				return nil
 			}
 			if err != nil {
-				if err == io.EOF {
-					slog.Info("Playback finished")
-					return nil
-				}
 				return fmt.Errorf("decoder read failed: %w", err)
 			}
+
+			p.echo.Played(buf)
+			if err := stream.Write(); err != nil {
+				if strings.Contains(err.Error(), "underflow") {
+					slog.Warn("Output underflow")
+					continue
+				}
+				slog.Error("Stream write failed", "err", err)
+				return fmt.Errorf("stream write failed: %w", err)
+			}
 		}
 	}
 }
 
-// helper to interpret little-endian bytes as int16 samples
-func bytesToInt16(data []byte) []int16 {
-	n := len(data) / 2
-	samples := make([]int16, n)
-	for i := 0; i < n; i++ {
-		samples[i] = int16(uint16(data[2*i]) | uint16(data[2*i+1])<<8)
+// readSamples fills buf with the next little-endian int16 samples of the
+// PCM stream, using raw, twice the size of buf, to read them. Whole buffers
+// are read, so that samples and frames stay aligned whatever the stream's
+// reads return; only the last buffer of the stream is padded with silence.
+// It returns io.EOF once the stream ended.
+func readSamples(pcm io.Reader, raw []byte, buf []int16) error {
+	n, err := io.ReadFull(pcm, raw)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+
+	for i := range buf {
+		if 2*i+1 >= n {
+			clear(buf[i:])
+			break
+		}
+		buf[i] = int16(uint16(raw[2*i]) | uint16(raw[2*i+1])<<8)
 	}
-	return samples
+	return nil
 }
@@ -0,0 +1,56 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+// oddReader returns at most 3 bytes per read, splitting samples.
+type oddReader struct {
+	r io.Reader
+}
+
+func (o oddReader) Read(p []byte) (int, error) {
+	return o.r.Read(p[:min(len(p), 3)])
+}
+
+func TestReadSamples(t *testing.T) {
+	pcm := []byte{1, 0, 2, 0, 3, 0, 4, 0, 5, 0}
+
+	testCases := []struct {
+		name   string
+		reader io.Reader
+	}{
+		{name: "whole reads", reader: bytes.NewReader(pcm)},
+		{name: "reads splitting samples", reader: oddReader{r: bytes.NewReader(pcm)}},
+		{name: "one byte reads", reader: iotest.OneByteReader(bytes.NewReader(pcm))},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := make([]int16, 4)
+			raw := make([]byte, 2*len(buf))
+
+			var played [][]int16
+			for {
+				err := readSamples(tc.reader, raw, buf)
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("expected error to be nil got %v", err)
+				}
+				played = append(played, append([]int16(nil), buf...))
+			}
+
+			expected := [][]int16{{1, 2, 3, 4}, {5, 0, 0, 0}}
+			if !reflect.DeepEqual(played, expected) {
+				t.Errorf("expected buffers %v, got %v", expected, played)
+			}
+		})
+	}
+}
@@ -2,63 +2,38 @@ package audio
 
 import (
 	"context"
-	"encoding/binary"
-	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"time"
 
 	"github.com/gordonklaus/portaudio"
+	"github.com/ownerofglory/raspi-agent/internal/audio/wav"
 	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"github.com/ownerofglory/raspi-agent/internal/vad"
 )
 
-// WAV format constants define the structure and encoding
-// of PCM (Pulse-Code Modulation) audio data inside a .wav file.
-const (
-	// pcmFormat identifies linear PCM (uncompressed) audio data.
-	// Value 1 means standard PCM encoding.
-	pcmFormat = 1
-
-	// bitsPerSample defines the resolution of each sample.
-	// 16 bits per sample = CD-quality audio.
-	bitsPerSample = 16
-
-	// bytesPerSample is derived from bitsPerSample (16 bits = 2 bytes).
-	// It’s used when calculating byte rates and data lengths.
-	bytesPerSample = bitsPerSample / 8
-
-	// fmtChunkSize is always 16 for PCM format WAV files.
-	// This chunk describes format details such as sample rate and bit depth.
-	fmtChunkSize = 16
-)
-
-// WAV header identifiers are the ASCII tags that mark sections of a .wav file.
-// Each is exactly 4 bytes and identifies a logical block of the file.
-const (
-	// riffHeader identifies the overall file as a RIFF container.
-	// It’s followed by the total file size and the "WAVE" format specifier.
-	riffHeader = "RIFF"
-
-	// waveHeader specifies that the RIFF container stores audio data in WAVE format.
-	waveHeader = "WAVE"
-
-	// fmtHeader marks the beginning of the format chunk,
-	// which stores sample rate, channel count, and encoding type.
-	fmtHeader = "fmt "
-
-	// dataHeader marks the beginning of the actual audio sample data.
-	dataHeader = "data"
-)
-
-type recorder struct{}
+// recorder records from the shared audio capture, so recording starts
+// without reopening the input device, e.g. right after the wake word.
+type recorder struct {
+	source  ports.AudioSource
+	preRoll time.Duration
+}
 
-// NewRecorder creates a new recorder instance
-func NewRecorder() *recorder {
-	return &recorder{}
+// NewRecorder creates a new recorder of the audio source. Recordings start
+// with the audio captured within preRoll before, so the first syllable
+// spoken right after the wake word is kept.
+func NewRecorder(source ports.AudioSource, preRoll time.Duration) *recorder {
+	return &recorder{
+		source:  source,
+		preRoll: preRoll,
+	}
 }
 
-// recordingResult holds raw PCM data and metadata
+// recordingResult holds raw PCM data and metadata.
+//
+// It can be built incrementally from streamed frames via append.
 type recordingResult struct {
 	data []int16
 
@@ -67,50 +42,162 @@ type recordingResult struct {
 	sampleRate int
 }
 
+// append adds a captured frame to the recording.
+func (r *recordingResult) append(frame domain.AudioFrame) {
+	r.data = append(r.data, frame.Samples...)
+	r.channels = frame.Channels
+	r.sampleRate = frame.SampleRate
+	r.chunkSize = len(frame.Samples)
+}
+
 // SaveTo writes the recorded PCM data as a WAV file to an io.Writer
 func (r *recordingResult) SaveTo(f io.Writer) error {
-	channels := r.channels
-	sampleRate := r.sampleRate
-	byteRate := sampleRate * channels * bytesPerSample
-	blockAlign := channels * bytesPerSample
-	dataLen := len(r.data) * bytesPerSample
-	riffLen := 36 + dataLen
-
-	// Write WAV headers
-	f.Write([]byte(riffHeader))
-	binary.Write(f, binary.LittleEndian, uint32(riffLen))
-	f.Write([]byte(waveHeader))
-
-	f.Write([]byte(fmtHeader))
-	binary.Write(f, binary.LittleEndian, uint32(fmtChunkSize))
-	binary.Write(f, binary.LittleEndian, uint16(pcmFormat))
-	binary.Write(f, binary.LittleEndian, uint16(channels))
-	binary.Write(f, binary.LittleEndian, uint32(sampleRate))
-	binary.Write(f, binary.LittleEndian, uint32(byteRate))
-	binary.Write(f, binary.LittleEndian, uint16(blockAlign))
-	binary.Write(f, binary.LittleEndian, uint16(bitsPerSample))
-
-	f.Write([]byte(dataHeader))
-	binary.Write(f, binary.LittleEndian, uint32(dataLen))
-	binary.Write(f, binary.LittleEndian, r.data)
-
-	return nil
+	return wav.Encode(f, r.sampleRate, r.channels, r.data)
 }
 
 // RecordAudio captures audio for a specified duration
 func (r *recorder) RecordAudio(ctx context.Context, duration time.Duration) (domain.RecordingResult, error) {
-	if err := portaudio.Initialize(); err != nil {
-		slog.Error("Unable to initialize portaudio", "err", err)
-		return nil, fmt.Errorf("unable to initialize portaudio: %v", err)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	frames, err := r.source.Subscribe(ctx, 0)
+	if err != nil {
+		slog.Error("Unable to capture audio", "err", err)
+		return nil, fmt.Errorf("unable to capture audio: %w", err)
+	}
+
+	res := &recordingResult{}
+	var recorded time.Duration
+	for recorded < duration {
+		frame, ok := <-frames
+		if !ok {
+			slog.Debug("Audio recording cancelled")
+			return nil, fmt.Errorf("audio recording cancelled")
+		}
+		if frame.Err != nil {
+			return nil, frame.Err
+		}
+		res.append(frame)
+		recorded += frameDuration(frame)
+	}
+
+	slog.Debug("Recording finished successfully")
+	return res, nil
+}
+
+// RecordUtterance captures a single utterance, using voice activity detection
+// to stop once the speaker has been silent for opts.SilenceTimeout.
+//
+// It collects the frames produced by StreamAudio into a single recording.
+// If no speech starts within opts.NoSpeechTimeout, domain.ErrNoSpeechDetected
+// is returned.
+func (r *recorder) RecordUtterance(ctx context.Context, opts domain.UtteranceOptions) (domain.RecordingResult, error) {
+	frames, err := r.StreamAudio(ctx, opts)
+	if err != nil {
+		return nil, err
 	}
-	defer portaudio.Terminate()
 
-	slog.Debug("Portaudio initialized")
+	res := &recordingResult{}
+	for frame := range frames {
+		if frame.Err != nil {
+			return nil, frame.Err
+		}
+		res.append(frame)
+	}
+
+	if ctx.Err() != nil {
+		slog.Debug("Audio recording cancelled")
+		return nil, fmt.Errorf("audio recording cancelled: %w", ctx.Err())
+	}
+
+	return res, nil
+}
+
+// StreamAudio captures a single utterance and delivers it in 20ms frames
+// while recording is still in progress, starting with the pre-roll.
+//
+// Each frame is classified by an energy / zero-crossing VAD. The stream ends
+// when the endpointer reports the end of the utterance, opts.MaxDuration is
+// reached, or the context is cancelled. If no speech starts within
+// opts.NoSpeechTimeout, the last frame carries domain.ErrNoSpeechDetected.
+func (r *recorder) StreamAudio(ctx context.Context, opts domain.UtteranceOptions) (<-chan domain.AudioFrame, error) {
+	subCtx, cancel := context.WithCancel(ctx)
+	captured, err := r.source.Subscribe(subCtx, r.preRoll)
+	if err != nil {
+		cancel()
+		slog.Error("Unable to capture audio", "err", err)
+		return nil, fmt.Errorf("unable to capture audio: %w", err)
+	}
 
-	seconds := duration.Seconds()
-	channels := 1
-	chunkSize := 4096
+	frames := make(chan domain.AudioFrame, streamBufferFrames)
 
+	go func() {
+		defer close(frames)
+		defer cancel()
+
+		var endpointer *vad.Endpointer
+
+		send := func(frame domain.AudioFrame) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case frames <- frame:
+				return true
+			}
+		}
+
+		for frame := range captured {
+			if frame.Err != nil {
+				send(frame)
+				return
+			}
+			if endpointer == nil {
+				endpointer = vad.NewEndpointer(frame.SampleRate, opts, vad.NewEnergyClassifier())
+			}
+
+			state := endpointer.Push(frame.Samples)
+			if state == vad.StateNoSpeech {
+				slog.Debug("No speech detected", "elapsed", endpointer.Elapsed())
+				send(domain.AudioFrame{Err: domain.ErrNoSpeechDetected})
+				return
+			}
+
+			if !send(frame) {
+				return
+			}
+
+			if state == vad.StateDone {
+				slog.Debug("End of utterance detected", "elapsed", endpointer.Elapsed())
+				return
+			}
+		}
+
+		if ctx.Err() == nil {
+			send(domain.AudioFrame{Err: errCaptureStopped})
+			return
+		}
+		slog.Debug("Audio streaming cancelled")
+	}()
+
+	return frames, nil
+}
+
+const (
+	// streamBufferFrames is how many frames StreamAudio buffers for slow
+	// consumers (e.g. a network upload) before they queue up at the capture.
+	streamBufferFrames = 50
+)
+
+// frameDuration returns the duration of the audio of a frame.
+func frameDuration(frame domain.AudioFrame) time.Duration {
+	if frame.SampleRate == 0 || frame.Channels == 0 {
+		return 0
+	}
+	return time.Duration(len(frame.Samples)/frame.Channels) * time.Second / time.Duration(frame.SampleRate)
+}
+
+// findInputDevice returns the first PortAudio device that has input channels.
+func findInputDevice() (*portaudio.DeviceInfo, error) {
 	devices, err := portaudio.Devices()
 	if err != nil {
 		slog.Error("Unable to get devices", "err", err)
@@ -122,78 +209,39 @@ func (r *recorder) RecordAudio(ctx context.Context, duration time.Duration) (dom
 			"name", d.Name, "inputs", d.MaxInputChannels, "outputs", d.MaxOutputChannels, "SR", d.DefaultSampleRate)
 	}
 
-	var inputDevice *portaudio.DeviceInfo
 	for _, d := range devices {
 		if d.MaxInputChannels > 0 {
-			inputDevice = d
-			break
+			slog.Debug("Using input device:", "device", d.Name)
+			return d, nil
 		}
 	}
-	if inputDevice == nil {
-		slog.Error("no input device found")
-		return nil, fmt.Errorf("no input device found")
-	}
-	slog.Debug("Using input device:", "device", inputDevice.Name)
 
-	// use device's default sample rate
-	sampleRate := inputDevice.DefaultSampleRate
-	totalFrames := sampleRate * seconds
-
-	// prepare buffers
-	buffer := make([]int16, 0, int(totalFrames)*channels)
-	chunk := make([]int16, chunkSize*channels)
+	slog.Error("no input device found")
+	return nil, fmt.Errorf("no input device found")
+}
 
+// openInputStream opens and starts a blocking input stream that reads into chunk.
+func openInputStream(device *portaudio.DeviceInfo, channels int, sampleRate float64, chunk []int16) (*portaudio.Stream, error) {
 	stream, err := portaudio.OpenStream(portaudio.StreamParameters{
 		Input: portaudio.StreamDeviceParameters{
-			Device:   inputDevice,
+			Device:   device,
 			Channels: channels,
-			Latency:  inputDevice.DefaultLowInputLatency,
+			Latency:  device.DefaultLowInputLatency,
 		},
 		SampleRate:      sampleRate,
-		FramesPerBuffer: chunkSize,
+		FramesPerBuffer: len(chunk) / channels,
 	}, chunk)
-
 	if err != nil {
 		slog.Error("Unable to open stream", "err", err)
 		return nil, fmt.Errorf("unable to open stream: %v", err)
 	}
-	defer stream.Close()
 	slog.Debug("Stream opened. Recording audio...")
 
 	if err := stream.Start(); err != nil {
+		stream.Close()
 		slog.Error("Unable to start stream", "err", err)
 		return nil, fmt.Errorf("unable to start stream: %v", err)
 	}
 
-	for framesRecorded := 0; framesRecorded < int(totalFrames); framesRecorded += chunkSize {
-		select {
-		case <-ctx.Done():
-			slog.Debug("Audio recording cancelled")
-			return nil, fmt.Errorf("Audio recording cancelled")
-		default:
-			if err := stream.Read(); err != nil {
-				var paErr portaudio.Error
-				if errors.As(err, &paErr) && errors.Is(paErr, portaudio.InputOverflowed) {
-					slog.Debug("Warning: input overflow (skipping some samples)")
-					continue
-				}
-				slog.Error("Streaming error", "err", err)
-				return nil, fmt.Errorf("streaming error: %v", err)
-			}
-			buffer = append(buffer, chunk...)
-		}
-	}
-
-	if err := stream.Stop(); err != nil {
-		slog.Error("Unable to stop stream", "err", err)
-		return nil, fmt.Errorf("unable to stop stream: %v", err)
-	}
-
-	slog.Debug("Recording finished successfully")
-	return &recordingResult{
-		data:       buffer,
-		channels:   channels,
-		chunkSize:  chunkSize,
-		sampleRate: int(sampleRate),
-	}, nil
+	return stream, nil
 }
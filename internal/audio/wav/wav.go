@@ -0,0 +1,328 @@
+package wav
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// WAV format constants define the structure and encoding
+// of PCM (Pulse-Code Modulation) audio data inside a .wav file.
+const (
+	// pcmFormat identifies linear PCM (uncompressed) audio data.
+	// Value 1 means standard PCM encoding.
+	pcmFormat = 1
+
+	// BitsPerSample defines the resolution of each sample.
+	// 16 bits per sample = CD-quality audio.
+	BitsPerSample = 16
+
+	// BytesPerSample is derived from BitsPerSample (16 bits = 2 bytes).
+	// It’s used when calculating byte rates and data lengths.
+	BytesPerSample = BitsPerSample / 8
+
+	// fmtChunkSize is always 16 for PCM format WAV files.
+	// This chunk describes format details such as sample rate and bit depth.
+	fmtChunkSize = 16
+
+	// headerSize is the size of the canonical 44-byte PCM WAV header.
+	headerSize = 44
+
+	// UnknownLength is written into the RIFF and data chunk sizes when the
+	// total length is not known up front (streaming). Most decoders, including
+	// the OpenAI and whisper.cpp ones, read until EOF in that case.
+	UnknownLength = 0xFFFFFFFF
+)
+
+// WAV header identifiers are the ASCII tags that mark sections of a .wav file.
+// Each is exactly 4 bytes and identifies a logical block of the file.
+const (
+	// riffHeader identifies the overall file as a RIFF container.
+	// It’s followed by the total file size and the "WAVE" format specifier.
+	riffHeader = "RIFF"
+
+	// waveHeader specifies that the RIFF container stores audio data in WAVE format.
+	waveHeader = "WAVE"
+
+	// fmtHeader marks the beginning of the format chunk,
+	// which stores sample rate, channel count, and encoding type.
+	fmtHeader = "fmt "
+
+	// dataHeader marks the beginning of the actual audio sample data.
+	dataHeader = "data"
+)
+
+// WriteHeader writes a canonical 16-bit PCM WAV header.
+//
+// dataLen is the length of the sample data in bytes. Pass UnknownLength
+// when streaming audio of unknown duration.
+func WriteHeader(w io.Writer, sampleRate, channels int, dataLen uint32) error {
+	byteRate := sampleRate * channels * BytesPerSample
+	blockAlign := channels * BytesPerSample
+
+	riffLen := uint32(UnknownLength)
+	if dataLen != UnknownLength {
+		riffLen = headerSize - 8 + dataLen
+	}
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, riffHeader...)
+	header = binary.LittleEndian.AppendUint32(header, riffLen)
+	header = append(header, waveHeader...)
+
+	header = append(header, fmtHeader...)
+	header = binary.LittleEndian.AppendUint32(header, fmtChunkSize)
+	header = binary.LittleEndian.AppendUint16(header, pcmFormat)
+	header = binary.LittleEndian.AppendUint16(header, uint16(channels))
+	header = binary.LittleEndian.AppendUint32(header, uint32(sampleRate))
+	header = binary.LittleEndian.AppendUint32(header, uint32(byteRate))
+	header = binary.LittleEndian.AppendUint16(header, uint16(blockAlign))
+	header = binary.LittleEndian.AppendUint16(header, BitsPerSample)
+
+	header = append(header, dataHeader...)
+	header = binary.LittleEndian.AppendUint32(header, dataLen)
+
+	_, err := w.Write(header)
+	return err
+}
+
+// WriteSamples writes 16-bit PCM samples in little-endian byte order.
+func WriteSamples(w io.Writer, samples []int16) error {
+	buf := make([]byte, 0, len(samples)*BytesPerSample)
+	for _, s := range samples {
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(s))
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+// Encode writes a complete WAV file containing the given samples.
+func Encode(w io.Writer, sampleRate, channels int, samples []int16) error {
+	if err := WriteHeader(w, sampleRate, channels, uint32(len(samples)*BytesPerSample)); err != nil {
+		return err
+	}
+	return WriteSamples(w, samples)
+}
+
+// NewStreamReader returns a reader producing a WAV stream from captured frames
+// as they arrive, so the audio can be uploaded or transcribed while the user
+// is still speaking.
+//
+// The header is emitted with the first frame using UnknownLength as size.
+// When a frame carries an error (e.g. domain.ErrNoSpeechDetected), the reader
+// fails with that error instead of returning io.EOF.
+//
+// Closing the reader stops encoding; remaining frames are drained so the
+// producer is never blocked.
+func NewStreamReader(frames <-chan domain.AudioFrame) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		headerWritten := false
+		for frame := range frames {
+			if frame.Err != nil {
+				pw.CloseWithError(frame.Err)
+				drain(frames)
+				return
+			}
+
+			if !headerWritten {
+				if err := WriteHeader(pw, frame.SampleRate, frame.Channels, UnknownLength); err != nil {
+					slog.Debug("WAV stream closed by reader", "err", err)
+					drain(frames)
+					return
+				}
+				headerWritten = true
+			}
+
+			if err := WriteSamples(pw, frame.Samples); err != nil {
+				slog.Debug("WAV stream closed by reader", "err", err)
+				drain(frames)
+				return
+			}
+		}
+		pw.Close()
+	}()
+
+	return pr
+}
+
+// drain discards remaining frames until the producer closes the channel.
+func drain(frames <-chan domain.AudioFrame) {
+	for range frames {
+	}
+}
+
+// Decode reads a 16-bit PCM WAV stream and returns its samples.
+//
+// Chunks other than the format and data chunks are skipped. A data chunk
+// of UnknownLength, as written by NewStreamReader, is read until EOF.
+func Decode(r io.Reader) (sampleRate, channels int, samples []int16, err error) {
+	sampleRate, channels, size, err := readHeader(r)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	data := r
+	if size != UnknownLength {
+		data = io.LimitReader(r, int64(size))
+	}
+	b, err := io.ReadAll(data)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("failed to read wav data: %w", err)
+	}
+
+	samples = make([]int16, len(b)/BytesPerSample)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(b[i*BytesPerSample:]))
+	}
+	return sampleRate, channels, samples, nil
+}
+
+// NewPCMReader reads the header of a 16-bit PCM WAV stream and returns a
+// reader of its sample data.
+//
+// Streams synthesized sentence by sentence are several WAV streams in a
+// row; the headers of the following streams are skipped, so the reader
+// yields their samples as one continuous stream. All streams are expected
+// to have the format of the first one.
+func NewPCMReader(r io.Reader) (sampleRate, channels int, pcm io.Reader, err error) {
+	br := bufio.NewReaderSize(r, pcmBufferSize)
+	sampleRate, channels, _, err = readHeader(br)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	return sampleRate, channels, &pcmReader{r: br, sampleRate: sampleRate, channels: channels}, nil
+}
+
+// pcmBufferSize is the read buffer of a pcmReader.
+const pcmBufferSize = 16 * 1024
+
+// pcmReader yields the samples of consecutive WAV streams.
+type pcmReader struct {
+	r          *bufio.Reader
+	sampleRate int
+	channels   int
+}
+
+func (p *pcmReader) Read(b []byte) (int, error) {
+	// look ahead by a RIFF header, so headers split across reads are found
+	peek, err := p.r.Peek(min(len(b), pcmBufferSize-12) + 12)
+	if len(peek) == 0 {
+		return 0, err
+	}
+
+	i := indexHeader(peek)
+	if i == 0 {
+		sampleRate, channels, _, err := readHeader(p.r)
+		if err != nil {
+			return 0, err
+		}
+		if sampleRate != p.sampleRate || channels != p.channels {
+			slog.Warn("WAV stream changed its format, playing it as before",
+				"rate", sampleRate, "channels", channels, "expected_rate", p.sampleRate, "expected_channels", p.channels)
+		}
+		return p.Read(b)
+	}
+
+	n := min(len(b), len(peek))
+	if i > 0 {
+		n = min(n, i)
+	}
+	return p.r.Read(b[:n])
+}
+
+// indexHeader returns the offset of the first RIFF/WAVE header in data
+// aligned to whole samples, or -1.
+func indexHeader(data []byte) int {
+	for i := 0; i+12 <= len(data); i += BytesPerSample {
+		if string(data[i:i+4]) == riffHeader && string(data[i+8:i+12]) == waveHeader {
+			return i
+		}
+	}
+	return -1
+}
+
+// readHeader reads the header of a WAV stream up to the start of the
+// sample data and returns the format and the size of the data.
+func readHeader(r io.Reader) (sampleRate, channels int, dataSize uint32, err error) {
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, 0, 0, fmt.Errorf("failed to read wav header: %w", err)
+	}
+	if string(header[0:4]) != riffHeader || string(header[8:12]) != waveHeader {
+		return 0, 0, 0, errors.New("not a wav stream")
+	}
+
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return 0, 0, 0, fmt.Errorf("failed to read wav chunk: %w", err)
+		}
+		id, size := string(chunk[0:4]), binary.LittleEndian.Uint32(chunk[4:8])
+
+		switch id {
+		case fmtHeader:
+			format := make([]byte, size)
+			if _, err := io.ReadFull(r, format); err != nil || size < fmtChunkSize {
+				return 0, 0, 0, errors.New("invalid wav format chunk")
+			}
+			if binary.LittleEndian.Uint16(format[0:2]) != pcmFormat || binary.LittleEndian.Uint16(format[14:16]) != BitsPerSample {
+				return 0, 0, 0, errors.New("only 16-bit PCM wav is supported")
+			}
+			channels = int(binary.LittleEndian.Uint16(format[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(format[4:8]))
+		case dataHeader:
+			if sampleRate == 0 {
+				return 0, 0, 0, errors.New("wav data before format chunk")
+			}
+			return sampleRate, channels, size, nil
+		default:
+			// chunks are padded to an even size
+			if _, err := io.CopyN(io.Discard, r, int64(size+size%2)); err != nil {
+				return 0, 0, 0, fmt.Errorf("failed to skip wav chunk: %w", err)
+			}
+		}
+	}
+}
+
+// ToMono16k downmixes interleaved samples to mono and resamples them to
+// 16 kHz by linear interpolation, the input expected by speech recognizers
+// such as whisper.cpp.
+func ToMono16k(samples []int16, sampleRate, channels int) []int16 {
+	const targetRate = 16000
+
+	mono := samples
+	if channels > 1 {
+		mono = make([]int16, len(samples)/channels)
+		for i := range mono {
+			sum := 0
+			for c := 0; c < channels; c++ {
+				sum += int(samples[i*channels+c])
+			}
+			mono[i] = int16(sum / channels)
+		}
+	}
+	if sampleRate == targetRate || len(mono) == 0 {
+		return mono
+	}
+
+	out := make([]int16, int(int64(len(mono))*targetRate/int64(sampleRate)))
+	step := float64(sampleRate) / targetRate
+	for i := range out {
+		pos := float64(i) * step
+		j := int(pos)
+		if j >= len(mono)-1 {
+			out[i] = mono[len(mono)-1]
+			continue
+		}
+		frac := pos - float64(j)
+		out[i] = int16(float64(mono[j])*(1-frac) + float64(mono[j+1])*frac)
+	}
+	return out
+}
@@ -0,0 +1,192 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+func TestEncode(t *testing.T) {
+	samples := []int16{0, 1, -1, 32767, -32768}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, 16000, 1, samples); err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	data := buf.Bytes()
+	if len(data) != headerSize+len(samples)*BytesPerSample {
+		t.Fatalf("unexpected length %d", len(data))
+	}
+	if string(data[0:4]) != riffHeader || string(data[8:12]) != waveHeader || string(data[36:40]) != dataHeader {
+		t.Fatalf("invalid header tags: %q", data[:44])
+	}
+	if got := binary.LittleEndian.Uint32(data[4:8]); got != uint32(len(data)-8) {
+		t.Errorf("expected riff length %d, got %d", len(data)-8, got)
+	}
+	if got := binary.LittleEndian.Uint32(data[24:28]); got != 16000 {
+		t.Errorf("expected sample rate 16000, got %d", got)
+	}
+	if got := int16(binary.LittleEndian.Uint16(data[44+6:])); got != 32767 {
+		t.Errorf("expected sample 32767, got %d", got)
+	}
+}
+
+func TestNewStreamReader(t *testing.T) {
+	errCapture := errors.New("capture failed")
+
+	testCases := []struct {
+		name    string
+		frames  []domain.AudioFrame
+		samples int
+		err     error
+	}{
+		{
+			name: "complete stream",
+			frames: []domain.AudioFrame{
+				{Samples: []int16{1, 2, 3}, SampleRate: 16000, Channels: 1},
+				{Samples: []int16{4, 5}, SampleRate: 16000, Channels: 1},
+			},
+			samples: 5,
+		},
+		{
+			name: "stream with error",
+			frames: []domain.AudioFrame{
+				{Samples: []int16{1, 2, 3}, SampleRate: 16000, Channels: 1},
+				{Err: errCapture},
+			},
+			err: errCapture,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ch := make(chan domain.AudioFrame)
+			go func() {
+				defer close(ch)
+				for _, f := range tc.frames {
+					ch <- f
+				}
+			}()
+
+			r := NewStreamReader(ch)
+			defer r.Close()
+
+			data, err := io.ReadAll(r)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			if tc.err != nil {
+				return
+			}
+
+			if len(data) != headerSize+tc.samples*BytesPerSample {
+				t.Fatalf("unexpected length %d", len(data))
+			}
+			if got := binary.LittleEndian.Uint32(data[40:44]); got != UnknownLength {
+				t.Errorf("expected unknown data length, got %d", got)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	samples := []int16{0, 1, -1, 32767, -32768, 5}
+
+	testCases := []struct {
+		name   string
+		stream func() []byte
+	}{
+		{
+			name: "complete file",
+			stream: func() []byte {
+				var buf bytes.Buffer
+				_ = Encode(&buf, 48000, 2, samples)
+				return buf.Bytes()
+			},
+		},
+		{
+			name: "stream of unknown length",
+			stream: func() []byte {
+				var buf bytes.Buffer
+				_ = WriteHeader(&buf, 48000, 2, UnknownLength)
+				_ = WriteSamples(&buf, samples)
+				return buf.Bytes()
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sampleRate, channels, got, err := Decode(bytes.NewReader(tc.stream()))
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+			if sampleRate != 48000 || channels != 2 {
+				t.Errorf("expected 48000 Hz stereo, got %d Hz and %d channels", sampleRate, channels)
+			}
+			if len(got) != len(samples) || got[3] != 32767 {
+				t.Errorf("expected samples %v, got %v", samples, got)
+			}
+		})
+	}
+
+	if _, _, _, err := Decode(bytes.NewReader([]byte("ID3 not a wav file"))); err == nil {
+		t.Error("expected an error for a stream that is not a wav")
+	}
+}
+
+func TestToMono16k(t *testing.T) {
+	// one second of stereo audio at 48 kHz
+	samples := make([]int16, 2*48000)
+	for i := range samples {
+		samples[i] = 100
+	}
+
+	mono := ToMono16k(samples, 48000, 2)
+	if len(mono) != 16000 {
+		t.Fatalf("expected 16000 samples, got %d", len(mono))
+	}
+	if mono[0] != 100 || mono[len(mono)-1] != 100 {
+		t.Errorf("expected the level to be kept, got %d and %d", mono[0], mono[len(mono)-1])
+	}
+}
+
+func TestNewPCMReader(t *testing.T) {
+	// two sentences synthesized one after the other, the first of unknown length
+	var buf bytes.Buffer
+	_ = WriteHeader(&buf, 22050, 1, UnknownLength)
+	_ = WriteSamples(&buf, []int16{1, 2, 3})
+	_ = Encode(&buf, 22050, 1, []int16{4, 5})
+
+	sampleRate, channels, pcm, err := NewPCMReader(&buf)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if sampleRate != 22050 || channels != 1 {
+		t.Errorf("expected 22050 Hz mono, got %d Hz and %d channels", sampleRate, channels)
+	}
+
+	// read in small pieces, so the second header is split across reads
+	var data []byte
+	b := make([]byte, 4)
+	for {
+		n, err := pcm.Read(b)
+		data = append(data, b[:n]...)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("expected error to be nil got %v", err)
+		}
+	}
+
+	want := []byte{1, 0, 2, 0, 3, 0, 4, 0, 5, 0}
+	if !bytes.Equal(data, want) {
+		t.Errorf("expected samples %v, got %v", want, data)
+	}
+}
@@ -1,14 +1,31 @@
 package domain
 
+import "time"
+
+// Memory is a single fact the assistant remembers about a user,
+// e.g. "The user's daughter is called Mia".
+//
+// Embedding is the vector representation of Text, used to recall
+// memories relevant to what the user is currently talking about.
+type Memory struct {
+	ID        string
+	UserID    string
+	Text      string
+	Embedding []float64
+	CreatedAt time.Time
+}
+
 // RecallRequest represents a request to recall stored information or memories
 // associated with a specific user.
 //
 // UserID identifies the user whose memories are being requested.
 // Text optionally contains a query or context string that narrows down
 // which memories should be recalled.
+// Limit optionally caps the number of recalled memories.
 type RecallRequest struct {
 	UserID string // unique identifier of the user
 	Text   string // optional query text for contextual recall
+	Limit  int    // optional maximum number of memories
 }
 
 // RecallResult holds the response to a RecallRequest.
@@ -17,3 +34,30 @@ type RecallRequest struct {
 type RecallResult struct {
 	Memories []string // recalled memory entries
 }
+
+// MemorizeRequest asks the agent memory to learn from a conversation turn.
+//
+// Messages holds the latest turn, optionally preceded by a few earlier
+// messages so that references such as "forget that" can be resolved.
+type MemorizeRequest struct {
+	UserID   string
+	Messages []Message
+}
+
+// MemorizeResult lists what was learned and forgotten from a turn.
+type MemorizeResult struct {
+	Remembered []string
+	Forgotten  []string
+}
+
+// ForgetRequest asks the agent memory to delete the memory that best
+// matches Text.
+type ForgetRequest struct {
+	UserID string
+	Text   string
+}
+
+// ForgetResult lists the memories that have been deleted.
+type ForgetResult struct {
+	Forgotten []string
+}
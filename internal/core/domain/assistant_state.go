@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+// AssistantState is what the assistant of a device is doing, e.g. to show
+// it on LEDs or a display.
+type AssistantState string
+
+const (
+	// AssistantStateIdle is the state of an assistant which is not running.
+	AssistantStateIdle AssistantState = "idle"
+
+	// AssistantStateListening waits for the wake word.
+	AssistantStateListening AssistantState = "listening"
+
+	// AssistantStateRecording captures the utterance of the user.
+	AssistantStateRecording AssistantState = "recording"
+
+	// AssistantStateThinking waits for the reply once the utterance has
+	// been captured.
+	AssistantStateThinking AssistantState = "thinking"
+
+	// AssistantStateSpeaking plays the reply.
+	AssistantStateSpeaking AssistantState = "speaking"
+
+	// AssistantStateError reports a failed turn or a failure to listen for
+	// the wake word. The assistant recovers and listens again.
+	AssistantStateError AssistantState = "error"
+)
+
+// AssistantTransition reports a change of the state of the assistant.
+type AssistantTransition struct {
+	// From is the state before the transition.
+	From AssistantState
+
+	// To is the state after the transition.
+	To AssistantState
+
+	// Err is the failure causing the transition to AssistantStateError.
+	Err error
+
+	// Time is when the transition happened.
+	Time time.Time
+}
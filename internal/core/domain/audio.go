@@ -0,0 +1,122 @@
+package domain
+
+import (
+	"mime"
+	"strconv"
+	"strings"
+)
+
+// Content types of the audio formats exchanged between backend and devices.
+const (
+	// AudioContentTypeMPEG is MP3 audio, e.g. produced by OpenAI TTS.
+	AudioContentTypeMPEG = "audio/mpeg"
+
+	// AudioContentTypeWAV is PCM audio in a RIFF/WAVE container, e.g. produced by Piper.
+	AudioContentTypeWAV = "audio/wav"
+
+	// AudioContentTypePCM is raw interleaved 16-bit little-endian PCM
+	// without a header; rate and channels must be declared as parameters.
+	AudioContentTypePCM = "audio/pcm"
+
+	// AudioContentTypeL16 is raw interleaved 16-bit big-endian PCM as of
+	// RFC 2586; rate and channels must be declared as parameters.
+	AudioContentTypeL16 = "audio/L16"
+
+	// AudioContentTypeOpus is Opus audio in an Ogg container.
+	AudioContentTypeOpus = "audio/ogg"
+
+	// AudioContentTypeFLAC is FLAC audio.
+	AudioContentTypeFLAC = "audio/flac"
+)
+
+// AudioFormat describes the encoding of an audio stream, as advertised by
+// its content type, e.g. "audio/mpeg" or "audio/pcm;rate=24000;channels=1".
+//
+// The zero value means the format is unknown and has to be detected from
+// the audio itself.
+//
+// Example:
+//
+//	format := domain.ParseAudioFormat(res.Header.Get("Content-Type"))
+//	if format.Raw() && format.SampleRate == 0 {
+//	    return errors.New("raw PCM needs a declared sample rate")
+//	}
+type AudioFormat struct {
+	// ContentType is one of the AudioContentType constants, or empty.
+	ContentType string
+
+	// SampleRate is the sampling rate in Hz of raw PCM.
+	SampleRate int
+
+	// Channels is the number of interleaved channels of raw PCM.
+	Channels int
+}
+
+// ParseAudioFormat parses a content type such as "audio/wav" or
+// "audio/L16; rate=16000; channels=1". Common aliases, e.g. "audio/mp3" or
+// "audio/x-wav", are normalized. Anything unparsable yields the zero value.
+func ParseAudioFormat(contentType string) AudioFormat {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return AudioFormat{}
+	}
+
+	var format AudioFormat
+	switch mediaType {
+	case "audio/mpeg", "audio/mp3":
+		format.ContentType = AudioContentTypeMPEG
+	case "audio/wav", "audio/wave", "audio/x-wav", "audio/vnd.wave":
+		format.ContentType = AudioContentTypeWAV
+	case "audio/pcm", "audio/x-raw":
+		format.ContentType = AudioContentTypePCM
+	case "audio/l16":
+		format.ContentType = AudioContentTypeL16
+	case "audio/ogg", "audio/opus":
+		format.ContentType = AudioContentTypeOpus
+	case "audio/flac", "audio/x-flac":
+		format.ContentType = AudioContentTypeFLAC
+	default:
+		return AudioFormat{}
+	}
+
+	format.SampleRate, _ = strconv.Atoi(params["rate"])
+	format.Channels, _ = strconv.Atoi(params["channels"])
+	return format
+}
+
+// Raw reports whether the audio is headerless PCM, which can only be
+// played with the declared sample rate and channels.
+func (f AudioFormat) Raw() bool {
+	return f.ContentType == AudioContentTypePCM || f.ContentType == AudioContentTypeL16
+}
+
+// String returns the content type of the format, including the rate and
+// channels of raw PCM, or "" if the format is unknown.
+func (f AudioFormat) String() string {
+	if !f.Raw() {
+		return f.ContentType
+	}
+
+	params := []string{f.ContentType}
+	if f.SampleRate > 0 {
+		params = append(params, "rate="+strconv.Itoa(f.SampleRate))
+	}
+	if f.Channels > 0 {
+		params = append(params, "channels="+strconv.Itoa(f.Channels))
+	}
+	return strings.Join(params, ";")
+}
+
+// AudioStream is audio received in chunks, e.g. the spoken reply streamed
+// by the backend, together with its format.
+//
+// Chunks is nil if the reply has no audio, e.g. if it failed or the tools
+// acted without an answer to speak; there is nothing to play then.
+//
+// ExpectsReply, if not nil, reports once Chunks is closed whether the
+// assistant expects the user to reply, see VoiceAssistantEventExpectsReply.
+type AudioStream struct {
+	Format       AudioFormat
+	Chunks       <-chan []byte
+	ExpectsReply func() bool
+}
@@ -1,5 +1,10 @@
 package domain
 
+import (
+	"fmt"
+	"strings"
+)
+
 // MessageRole represents the role of a message sender in a conversation.
 // It distinguishes between user, system, and assistant-generated messages.
 type MessageRole string
@@ -14,6 +19,9 @@ const (
 
 	// MessageRoleAssistant indicates that the message was generated by the assistant (e.g., an AI model).
 	MessageRoleAssistant MessageRole = "assistant"
+
+	// MessageRoleTool indicates that the message carries the result of a tool call.
+	MessageRoleTool MessageRole = "tool"
 )
 
 // Message represents a single message exchanged in a conversation.
@@ -24,15 +32,23 @@ type Message struct {
 
 	// Text contains the raw message content.
 	Text string
+
+	// ToolCalls holds the tools an assistant message asks to invoke.
+	ToolCalls []ToolCall
+
+	// ToolCallID identifies the call a tool message is the result of.
+	ToolCallID string
 }
 
 // CompletionRequest represents the input payload for a language model
 // text generation request.
 //
 // The Prompt field contains the text instruction or query that the model
-// should respond to. Implementations may enrich this with additional
-// metadata (e.g. temperature, max tokens, or conversation context) in
-// future extensions.
+// should respond to. History optionally carries the prior turns of the
+// conversation, so the prompt can be answered in context, Memories the
+// facts recalled about the user and Documents passages of the user's
+// documents to ground the answer in. Generation parameters such as the
+// model or temperature are configured on the provider, see CompletionOptions.
 //
 // Example:
 //
@@ -43,6 +59,82 @@ type CompletionRequest struct {
 	// Prompt is the text input provided to the language model.
 	// It should clearly describe the desired output or question.
 	Prompt string
+
+	// History holds the prior messages of the conversation, oldest first.
+	History []Message
+
+	// Memories holds facts remembered about the user that are relevant to the prompt.
+	Memories []string
+
+	// Documents holds passages of the user's documents relevant to the prompt.
+	// The model is asked to cite them by their 1-based position, e.g. [1].
+	Documents []DocumentReference
+
+	// Tools holds the tools the model may call instead of answering directly.
+	Tools []ToolDefinition
+
+	// ToolMessages holds the tool calls of the model and their results within
+	// the current turn. They follow the prompt, so the model can continue
+	// its answer with the results.
+	ToolMessages []Message
+
+	// MarkReplies asks the model to tell whether its answer awaits a reply
+	// of the user, reported by CompletionResult.ExpectsReply and
+	// CompletionDelta.ExpectsReply.
+	MarkReplies bool
+}
+
+// Messages returns the conversation to send to the model for the request:
+// the system prompt, the recalled memories, the document passages, the
+// history, the prompt and the tool calls made to answer it.
+func (r *CompletionRequest) Messages(systemPrompt string) []Message {
+	messages := make([]Message, 0, len(r.History)+len(r.ToolMessages)+4)
+	if r.MarkReplies {
+		systemPrompt += expectsReplyInstruction
+	}
+	messages = append(messages, Message{Role: MessageRoleSystem, Text: systemPrompt})
+
+	if len(r.Memories) > 0 {
+		messages = append(messages, Message{
+			Role: MessageRoleSystem,
+			Text: "Things you remember about the user:\n- " + strings.Join(r.Memories, "\n- "),
+		})
+	}
+
+	if len(r.Documents) > 0 {
+		var docs strings.Builder
+		docs.WriteString("Answer using the following excerpts of the user's documents where relevant. " +
+			"When you use an excerpt, cite it by its number in square brackets, e.g. [1].\n")
+		for i, d := range r.Documents {
+			fmt.Fprintf(&docs, "\n[%d] %s\n%s\n", i+1, d.Title, d.Text)
+		}
+		messages = append(messages, Message{Role: MessageRoleSystem, Text: docs.String()})
+	}
+
+	messages = append(messages, r.History...)
+	messages = append(messages, Message{Role: MessageRoleUser, Text: r.Prompt})
+	messages = append(messages, r.ToolMessages...)
+
+	return messages
+}
+
+// DefaultSystemPrompt is the system prompt used unless another is configured.
+const DefaultSystemPrompt = "You are an AI agent named Vicky"
+
+// CompletionOptions configures how a CompletionProvider generates text.
+// Zero values leave the choice to the provider or the model.
+type CompletionOptions struct {
+	// Model is the name of the model, e.g. "gpt-4o-mini" or "llama3.2".
+	Model string
+
+	// Temperature controls the randomness of the output; nil uses the model default.
+	Temperature *float64
+
+	// MaxTokens bounds the length of the response; 0 means no limit.
+	MaxTokens int
+
+	// SystemPrompt instructs the model how to behave; empty uses DefaultSystemPrompt.
+	SystemPrompt string
 }
 
 // CompletionResult represents the output returned by a language model
@@ -54,6 +146,84 @@ type CompletionRequest struct {
 type CompletionResult struct {
 	// Text is the generated text output from the completion model.
 	Text string `json:"text"`
+
+	// ExpectsReply reports that the answer awaits a reply of the user, if
+	// the request asked for it, see CompletionRequest.MarkReplies.
+	ExpectsReply bool `json:"expectsReply"`
+}
+
+// CompletionDelta is a fragment of a completion streamed while the model
+// is still generating.
+//
+// Concatenating the Text of all deltas yields the complete response. A delta
+// with a non-nil Err terminates the stream. If the model decides to call
+// tools, the last delta carries the complete calls in ToolCalls.
+type CompletionDelta struct {
+	// Text is the newly generated text (may be empty).
+	Text string
+
+	// ToolCalls holds the tools the model asks to invoke before answering.
+	ToolCalls []ToolCall
+
+	// ExpectsReply is set on the last delta if the answer awaits a reply of
+	// the user and the request asked for it, see CompletionRequest.MarkReplies.
+	ExpectsReply bool
+
+	// Err reports a failure of the stream.
+	Err error
+}
+
+// ExpectsReplyMarker ends the answers of the model awaiting a reply of the
+// user, see CompletionRequest.MarkReplies. It is removed from the answer by
+// the completion providers, see ExpectsReplyFilter.
+const ExpectsReplyMarker = "<await-reply>"
+
+// expectsReplyInstruction asks the model to mark answers awaiting a reply.
+const expectsReplyInstruction = "\n\nIf your answer asks the user something and you wait for their reply, " +
+	"end it with " + ExpectsReplyMarker + "."
+
+// ExpectsReplyFilter removes ExpectsReplyMarker from a streamed answer and
+// reports whether the answer carried it. The zero value is ready to use.
+type ExpectsReplyFilter struct {
+	pending string
+	found   bool
+}
+
+// Push returns the text to pass on for the next fragment of the answer.
+// The end of the text which may be the start of the marker is held back
+// until the next fragment tells.
+func (f *ExpectsReplyFilter) Push(text string) string {
+	text = f.pending + text
+	f.pending = ""
+
+	if i := strings.Index(text, ExpectsReplyMarker); i >= 0 {
+		f.found = true
+		text = text[:i] + text[i+len(ExpectsReplyMarker):]
+	}
+	for n := min(len(text), len(ExpectsReplyMarker)-1); n > 0; n-- {
+		if strings.HasSuffix(text, ExpectsReplyMarker[:n]) {
+			f.pending = text[len(text)-n:]
+			return text[:len(text)-n]
+		}
+	}
+	return text
+}
+
+// Flush returns the text held back at the end of the answer and whether
+// the answer carried the marker.
+func (f *ExpectsReplyFilter) Flush() (string, bool) {
+	text := f.pending
+	f.pending = ""
+	return text, f.found
+}
+
+// CutExpectsReplyMarker removes ExpectsReplyMarker from a complete answer
+// and reports whether the answer carried it.
+func CutExpectsReplyMarker(text string) (string, bool) {
+	var f ExpectsReplyFilter
+	text = f.Push(text)
+	rest, found := f.Flush()
+	return text + rest, found
 }
 
 // SummaryRequest represents a request to summarize a conversation.
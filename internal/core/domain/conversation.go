@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// Conversation is the ongoing dialogue between a user and the assistant on
+// a specific device.
+//
+// It keeps the prior turns as Messages (oldest first) so that follow-up
+// questions such as "and tomorrow?" can be answered in context. A
+// conversation ends once it has been idle for longer than the configured
+// timeout; the next turn then starts a new one.
+type Conversation struct {
+	// ID uniquely identifies the conversation.
+	ID string
+
+	// DeviceID is the device the conversation takes place on.
+	DeviceID string
+
+	// UserID is the owner of the device, if known.
+	UserID string
+
+	// Messages holds the prior turns, oldest first.
+	Messages []Message
+
+	// StartedAt is when the first turn of the conversation happened.
+	StartedAt time.Time
+
+	// UpdatedAt is when the last turn of the conversation happened.
+	UpdatedAt time.Time
+}
@@ -11,9 +11,11 @@ package domain
 // Fields:
 //   - UserID: Identifier of the user who owns this device.
 //   - Name:   Human-friendly name for the device (for UI display).
+//   - TimeZone: IANA time zone the device is located in, e.g. "Europe/Berlin".
 type DeviceRegistration struct {
-	UserID string
-	Name   string
+	UserID   string
+	Name     string
+	TimeZone string
 }
 
 // DeviceRegistrationResult is returned by the backend when a
@@ -109,4 +111,9 @@ type Device struct {
 	// EnrollmentStatus represents the device’s current lifecycle state.
 	// It indicates whether the device is registered, enrolled, or disabled.
 	EnrollmentStatus DeviceEnrollmentState
+
+	// TimeZone is the IANA time zone the device is located in, e.g.
+	// "Europe/Berlin", in which times of day are read and spoken. Empty is
+	// the local time zone of the backend.
+	TimeZone string
 }
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"io"
+	"time"
+)
+
+// Supported document content types.
+const (
+	DocumentTypeText     = "text/plain"
+	DocumentTypeMarkdown = "text/markdown"
+	DocumentTypePDF      = "application/pdf"
+)
+
+// Document is a user-provided document (manual, recipe, house rules, ...)
+// the assistant can use as grounding context when answering.
+//
+// Documents belong to a user; all devices of the user (the household)
+// share them.
+type Document struct {
+	ID          string
+	UserID      string
+	Title       string
+	ContentType string
+	Chunks      int
+	CreatedAt   time.Time
+}
+
+// DocumentUpload is a request to ingest a new document.
+//
+// Content is read in full and must match ContentType
+// (plain text, Markdown or a PDF containing text).
+type DocumentUpload struct {
+	UserID      string
+	Title       string
+	ContentType string
+	Content     io.Reader
+}
+
+// DocumentChunk is a passage of a document together with its embedding.
+type DocumentChunk struct {
+	ID         string
+	DocumentID string
+	UserID     string
+	Index      int
+	Text       string
+	Embedding  []float64
+}
+
+// DocumentReference is a passage retrieved as grounding context,
+// along with the document it was taken from.
+type DocumentReference struct {
+	DocumentID string
+	Title      string
+	Text       string
+}
+
+// Citation identifies a document an answer is based on.
+type Citation struct {
+	DocumentID string
+	Title      string
+}
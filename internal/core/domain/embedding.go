@@ -16,3 +16,44 @@ type EmbeddingResult struct {
 	// Model is the name or identifier of the model used to generate the embedding.
 	Model string
 }
+
+// SimilarityThresholds are the cosine similarities of embeddings deciding
+// what is related. They depend on the embedding model: models spread the
+// similarities of unrelated texts differently, see the defaults of the
+// embedding providers.
+type SimilarityThresholds struct {
+	// Recall is the similarity a memory needs to be considered relevant to
+	// the prompt.
+	Recall float64
+
+	// Duplicate is the similarity above which a new fact is considered
+	// already known and not stored again.
+	Duplicate float64
+
+	// Forget is the similarity a memory needs to be deleted by a forget
+	// request, so unrelated memories are never removed by accident.
+	Forget float64
+
+	// Retrieve is the similarity a document passage needs to be considered
+	// relevant to the prompt.
+	Retrieve float64
+}
+
+// WithDefaults returns the thresholds with their zero values replaced by
+// those of defaults, e.g. configured overrides on top of the defaults of
+// the embedding provider.
+func (t SimilarityThresholds) WithDefaults(defaults SimilarityThresholds) SimilarityThresholds {
+	if t.Recall <= 0 {
+		t.Recall = defaults.Recall
+	}
+	if t.Duplicate <= 0 {
+		t.Duplicate = defaults.Duplicate
+	}
+	if t.Forget <= 0 {
+		t.Forget = defaults.Forget
+	}
+	if t.Retrieve <= 0 {
+		t.Retrieve = defaults.Retrieve
+	}
+	return t
+}
@@ -10,5 +10,52 @@ var (
 
 // Device domain errors
 var (
-	ErrDeviceNotFound = errors.New("device not found")
+	ErrDeviceNotFound     = errors.New("device not found")
+	ErrDeviceNotConnected = errors.New("device not connected")
+)
+
+// Conversation domain errors
+var (
+	ErrConversationNotFound = errors.New("conversation not found")
+)
+
+// Document domain errors
+var (
+	ErrDocumentNotFound        = errors.New("document not found")
+	ErrUnsupportedDocumentType = errors.New("unsupported document type")
+	ErrEmptyDocument           = errors.New("document contains no text")
+)
+
+// Timer domain errors
+var (
+	ErrTimerNotFound = errors.New("timer not found")
+	ErrInvalidTimer  = errors.New("invalid timer")
+)
+
+// Home Assistant domain errors
+var (
+	ErrHomeAssistantNotConfigured  = errors.New("home assistant not configured")
+	ErrHomeAssistantEntityNotFound = errors.New("home assistant entity not found")
+	ErrInvalidHomeAssistantConfig  = errors.New("invalid home assistant config")
+)
+
+// MQTT domain errors
+var (
+	ErrInvalidMQTTTopic      = errors.New("invalid mqtt topic")
+	ErrNoRetainedMQTTMessage = errors.New("no retained mqtt message")
+)
+
+// MCP domain errors
+var (
+	ErrUnknownMCPServer = errors.New("unknown mcp server")
+)
+
+// Recording domain errors
+var (
+	ErrNoSpeechDetected = errors.New("no speech detected")
+)
+
+// Audio domain errors
+var (
+	ErrUnsupportedAudioFormat = errors.New("unsupported audio format")
 )
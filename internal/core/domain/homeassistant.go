@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// HomeAssistantConfig is the connection to the Home Assistant instance of a user.
+type HomeAssistantConfig struct {
+	UserID string
+	// BaseURL is the URL of the instance, e.g. http://homeassistant.local:8123
+	BaseURL string
+	// Token is a long-lived access token created in the user's profile.
+	Token string
+}
+
+// HomeAssistantEntity is the state of a Home Assistant entity, e.g. a light
+// or a sensor.
+type HomeAssistantEntity struct {
+	// EntityID is the ID of the entity, e.g. "light.kitchen"
+	EntityID    string
+	State       string
+	Attributes  map[string]any
+	LastChanged time.Time
+}
+
+// Name returns the friendly name of the entity, or its ID if it has none.
+func (e HomeAssistantEntity) Name() string {
+	if name, ok := e.Attributes["friendly_name"].(string); ok && name != "" {
+		return name
+	}
+	return e.EntityID
+}
+
+// HomeAssistantServiceCall is a call of a Home Assistant service, e.g.
+// light.turn_on with {"entity_id": "light.kitchen", "brightness_pct": 50}.
+type HomeAssistantServiceCall struct {
+	Domain  string
+	Service string
+	Data    map[string]any
+}
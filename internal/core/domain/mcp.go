@@ -0,0 +1,65 @@
+package domain
+
+import "slices"
+
+// MCPServerConfig is a Model Context Protocol server whose tools are
+// offered to the model.
+//
+// A server is either started as a subprocess speaking over stdio (Command)
+// or reached over the streamable HTTP transport (URL).
+type MCPServerConfig struct {
+	// Name identifies the server, e.g. "weather"; it prefixes the names of its tools.
+	Name string
+
+	Command string
+	Args    []string
+	// Env holds environment variables set for the subprocess in addition
+	// to those of the agent.
+	Env map[string]string
+
+	URL string
+	// Headers are sent with each HTTP request, e.g. an Authorization header.
+	Headers map[string]string
+}
+
+// MCPTool is a tool offered by an MCP server.
+type MCPTool struct {
+	Name        string
+	Description string
+	// InputSchema is the JSON Schema of the tool's arguments.
+	InputSchema ToolSchema
+}
+
+// MCPToolResult is the result of an MCP tool call.
+type MCPToolResult struct {
+	// Text is the text content returned by the tool.
+	Text string
+	// Structured is the structured content returned by the tool, if any.
+	Structured any
+	// IsError is set if the tool failed; Text then describes the failure.
+	IsError bool
+}
+
+// MCPAccess lists the MCP servers and tools a user has enabled.
+type MCPAccess struct {
+	UserID string
+	Grants []MCPGrant
+}
+
+// MCPGrant enables tools of an MCP server.
+type MCPGrant struct {
+	Server string
+	// Tools are the names of the enabled tools, as offered by the server;
+	// if empty, all tools of the server are enabled.
+	Tools []string
+}
+
+// Allows reports whether the tool of the server is enabled.
+func (a MCPAccess) Allows(server, tool string) bool {
+	for _, g := range a.Grants {
+		if g.Server == server && (len(g.Tools) == 0 || slices.Contains(g.Tools, tool)) {
+			return true
+		}
+	}
+	return false
+}
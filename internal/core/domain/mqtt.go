@@ -0,0 +1,46 @@
+package domain
+
+import "strings"
+
+// MQTTMessage is a message published on an MQTT topic.
+type MQTTMessage struct {
+	Topic   string
+	Payload []byte
+	// QoS is the quality of service level, 0 (at most once) or 1 (at least once).
+	QoS byte
+	// Retain makes the broker keep the message as the last value of the topic,
+	// handed to clients subscribing later.
+	Retain bool
+}
+
+// NotificationKindAnnouncement is the DeviceNotification kind of announced
+// MQTT messages.
+const NotificationKindAnnouncement = "announcement"
+
+// MQTTAnnouncement maps the messages of MQTT topics to spoken announcements
+// on devices, e.g. "home/doorbell" announced on the kitchen device.
+type MQTTAnnouncement struct {
+	// TopicFilter selects the topics, it may contain the wildcards + and #.
+	TopicFilter string
+	DeviceIDs   []string
+}
+
+// MatchMQTTTopic reports whether the topic matches the topic filter, where
+// + matches a single topic level and a trailing # any number of levels.
+func MatchMQTTTopic(filter, topic string) bool {
+	filterLevels := strings.Split(filter, "/")
+	topicLevels := strings.Split(topic, "/")
+
+	for i, f := range filterLevels {
+		if f == "#" {
+			return i == len(filterLevels)-1
+		}
+		if i >= len(topicLevels) {
+			return false
+		}
+		if f != "+" && f != topicLevels[i] {
+			return false
+		}
+	}
+	return len(filterLevels) == len(topicLevels)
+}
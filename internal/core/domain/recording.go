@@ -1,6 +1,9 @@
 package domain
 
-import "io"
+import (
+	"io"
+	"time"
+)
 
 // RecordingResult represents the result of an audio recording operation.
 //
@@ -27,3 +30,87 @@ type RecordingResult interface {
 	// Returns an error if the write operation fails.
 	SaveTo(writer io.Writer) error
 }
+
+// UtteranceOptions configures voice-activity-driven (VAD) capture of a
+// single spoken utterance.
+//
+// Instead of recording for a fixed duration, the recorder waits for speech
+// to begin and stops once the speaker has been silent for SilenceTimeout.
+// MinDuration and MaxDuration bound the length of the captured utterance.
+//
+// Example:
+//
+//	opts := domain.UtteranceOptions{
+//	    SilenceTimeout:  800 * time.Millisecond,
+//	    MinDuration:     500 * time.Millisecond,
+//	    MaxDuration:     15 * time.Second,
+//	    NoSpeechTimeout: 5 * time.Second,
+//	}
+type UtteranceOptions struct {
+	// SilenceTimeout is the amount of trailing silence after speech
+	// that marks the end of the utterance.
+	SilenceTimeout time.Duration
+
+	// MinDuration is the minimum length of the utterance. Silence before
+	// this point does not end the recording, so short pauses right after
+	// the wake word are tolerated.
+	MinDuration time.Duration
+
+	// MaxDuration is a hard upper bound for the recording, regardless of
+	// whether the speaker is still talking.
+	MaxDuration time.Duration
+
+	// NoSpeechTimeout is how long to wait for speech to begin at all.
+	// When it elapses without any detected speech, recording is aborted
+	// with ErrNoSpeechDetected. Zero means wait until MaxDuration.
+	NoSpeechTimeout time.Duration
+}
+
+// AudioFrame is a chunk of 16-bit PCM audio delivered by a streaming
+// recorder as soon as it has been captured.
+//
+// Samples are interleaved when Channels > 1. The last frame of a stream
+// may carry a non-nil Err (with no samples) describing why capture ended
+// abnormally, e.g. ErrNoSpeechDetected.
+type AudioFrame struct {
+	// Samples holds the captured PCM samples.
+	Samples []int16
+
+	// SampleRate is the sampling rate in Hz.
+	SampleRate int
+
+	// Channels is the number of interleaved channels.
+	Channels int
+
+	// Err is set on the final frame if capture failed or found no speech.
+	Err error
+}
+
+// FollowUpOptions configures listening for a follow-up once a reply has
+// been played, so the user can continue the conversation without saying
+// the wake word again.
+//
+// Example:
+//
+//	opts := domain.FollowUpOptions{Window: 5 * time.Second}
+type FollowUpOptions struct {
+	// Window is how long the user may take to start speaking after the
+	// reply. Zero disables follow-ups.
+	Window time.Duration
+
+	// Always listens after every reply instead of only after replies
+	// the assistant expects an answer to.
+	Always bool
+}
+
+// Listen reports whether to listen for a follow-up after a reply.
+func (f FollowUpOptions) Listen(expectsReply bool) bool {
+	return f.Window > 0 && (expectsReply || f.Always)
+}
+
+// Utterance returns the options capturing the follow-up, which is given up
+// on if the user does not start speaking within the window.
+func (f FollowUpOptions) Utterance(utterance UtteranceOptions) UtteranceOptions {
+	utterance.NoSpeechTimeout = f.Window
+	return utterance
+}
@@ -9,6 +9,10 @@ import "io"
 // large audio files without loading the entire content into memory.
 type TranscribeRequest struct {
 	Audio io.Reader `json:"audio"`
+
+	// Language optionally overrides the language configured on the
+	// recognizer, as ISO 639-1 code, e.g. "de".
+	Language string `json:"language,omitempty"`
 }
 
 // TranscribeResult represents the response returned by the transcription service.
@@ -23,6 +27,24 @@ type TranscribeResult struct {
 	Text string `json:"text"`
 }
 
+// TranscriptionOptions configures a local speech recognizer.
+//
+// Example:
+//
+//	opts := domain.TranscriptionOptions{
+//	    Language: "de",
+//	    Prompt:   "Rhaspy, Home Assistant, Wohnzimmer",
+//	}
+type TranscriptionOptions struct {
+	// Language is the spoken language as ISO 639-1 code, e.g. "en";
+	// empty lets the recognizer detect it.
+	Language string
+
+	// Prompt biases the recognition towards its vocabulary, e.g. names
+	// of people, rooms or devices.
+	Prompt string
+}
+
 // SpeechRequest represents the input payload for speech synthesis (TTS).
 //
 // The Text field contains the text that should be converted into spoken audio.
@@ -33,13 +55,49 @@ type TranscribeResult struct {
 //	}
 type SpeechRequest struct {
 	Text string `json:"text"`
+
+	// Voice optionally overrides the voice configured on the provider.
+	Voice VoiceOptions `json:"voice"`
+}
+
+// VoiceOptions selects how synthesized speech sounds, e.g. as configured
+// on a device. Zero values keep the defaults of the speech provider.
+type VoiceOptions struct {
+	// Voice names the voice, e.g. "shimmer" for OpenAI or the name of a
+	// Piper voice model such as "en_US-lessac-medium".
+	Voice string `json:"voice,omitempty"`
+
+	// Rate is the speaking rate relative to normal speed, e.g. 1.25 for
+	// 25% faster speech.
+	Rate float64 `json:"rate,omitempty"`
+
+	// Language is the language spoken with the user as ISO 639-1 code,
+	// e.g. "de". It hints the speech recognizer; the voice should speak it.
+	Language string `json:"language,omitempty"`
+}
+
+// WithDefaults returns the options with their zero values replaced by
+// those of defaults, e.g. the voice of a persona on top of the voice
+// configured on the device.
+func (v VoiceOptions) WithDefaults(defaults VoiceOptions) VoiceOptions {
+	if v.Voice == "" {
+		v.Voice = defaults.Voice
+	}
+	if v.Rate <= 0 {
+		v.Rate = defaults.Rate
+	}
+	if v.Language == "" {
+		v.Language = defaults.Language
+	}
+	return v
 }
 
 // SpeechResult represents a single chunk or complete piece of generated audio.
 //
 // The Audio field provides the audio data as an io.Reader, enabling the consumer
 // to stream it directly to a player, encoder, or network socket without needing
-// to buffer the entire file in memory.
+// to buffer the entire file in memory. Format tells how the audio is
+// encoded, e.g. MP3 for OpenAI or WAV for Piper.
 //
 // Example usage:
 //
@@ -47,5 +105,6 @@ type SpeechRequest struct {
 //	    io.Copy(outputWriter, result.Audio)
 //	}
 type SpeechResult struct {
-	Audio io.Reader
+	Audio  io.Reader
+	Format AudioFormat
 }
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"io"
+	"time"
+)
+
+// TimerKind distinguishes countdown timers from alarms at a time of day.
+type TimerKind string
+
+const (
+	// TimerKindTimer is a countdown timer, e.g. "set a timer for 10 minutes".
+	TimerKindTimer TimerKind = "timer"
+
+	// TimerKindAlarm is an alarm at a time of day, e.g. "wake me up at 7".
+	TimerKindAlarm TimerKind = "alarm"
+)
+
+// Timer is a timer or alarm set by a user on a device. When it fires,
+// the device it has been set on is notified.
+type Timer struct {
+	ID        string
+	DeviceID  string
+	UserID    string
+	Kind      TimerKind
+	Label     string
+	FiresAt   time.Time
+	CreatedAt time.Time
+	// TimeZone is the IANA time zone of the device the timer is set on, in
+	// which alarms are announced.
+	TimeZone string
+}
+
+// Location returns the time zone of the timer, see LoadLocation.
+func (t Timer) Location() *time.Location {
+	return LoadLocation(t.TimeZone)
+}
+
+// TimerRequest is a request to set a timer. Exactly one of Duration
+// (countdown timer) and At (alarm) is set.
+type TimerRequest struct {
+	DeviceID string
+	UserID   string
+	Label    string
+	Duration time.Duration
+	At       time.Time
+	// TimeZone is the IANA time zone of the device, see Timer.
+	TimeZone string
+}
+
+// LoadLocation returns the time zone of the IANA name, e.g. "Europe/Berlin",
+// or the local time zone of the backend if the name is empty or unknown.
+func LoadLocation(name string) *time.Location {
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// DeviceNotification is a message pushed to a device outside of a turn,
+// e.g. when a timer fires.
+//
+// Kind tells the device how to alert the user (e.g. by playing an alert
+// sound for a timer), Text is the message and Audio, if not nil, the
+// message spoken, encoded as described by Format.
+type DeviceNotification struct {
+	Kind   string
+	Text   string
+	Audio  io.Reader
+	Format AudioFormat
+}
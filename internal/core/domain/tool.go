@@ -20,3 +20,44 @@ type ToolDef struct {
 func (t ToolDef) Execute(ctx context.Context, args string) (any, error) {
 	return t.Tool(ctx, args)
 }
+
+// ToolDefinition describes a tool offered to the language model.
+//
+// Schema is the JSON Schema of the tool's arguments; UserMessage is a short
+// sentence spoken to the user while the tool runs (e.g. "Let me check the
+// weather."), and may be empty.
+type ToolDefinition struct {
+	Name        string
+	Description string
+	Schema      ToolSchema
+	UserMessage string
+}
+
+// ToolCall is a request of the language model to invoke a tool.
+// Arguments holds the arguments as JSON, as generated by the model.
+type ToolCall struct {
+	ID        string
+	Name      string
+	Arguments string
+}
+
+// ToolCaller identifies the device and user on whose behalf a tool is called.
+// TimeZone is the IANA time zone of the device, see Device.
+type ToolCaller struct {
+	DeviceID string
+	UserID   string
+	TimeZone string
+}
+
+type toolCallerKey struct{}
+
+// WithToolCaller returns a copy of ctx carrying the tool caller.
+func WithToolCaller(ctx context.Context, caller ToolCaller) context.Context {
+	return context.WithValue(ctx, toolCallerKey{}, caller)
+}
+
+// ToolCallerFrom returns the tool caller stored in ctx, if any.
+func ToolCallerFrom(ctx context.Context) (ToolCaller, bool) {
+	caller, ok := ctx.Value(toolCallerKey{}).(ToolCaller)
+	return caller, ok
+}
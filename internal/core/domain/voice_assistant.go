@@ -12,21 +12,66 @@ import "io"
 //   - A microphone stream (e.g., PortAudio or ALSA)
 //   - A temporary audio file
 //   - A network or pipe stream .
+//
+// DeviceID and UserID identify who is speaking; they key the conversation
+// context. Both may be empty, e.g. for a single local device. Voice holds
+// the voice the device wants the reply to be spoken in.
 type VoiceAssistantRequest struct {
-	Audio io.Reader
+	Audio    io.Reader
+	DeviceID string
+	UserID   string
+	// TimeZone is the IANA time zone of the device, see Device.
+	TimeZone string
+	Voice    VoiceOptions
 }
 
+// VoiceAssistantEventType identifies what a VoiceAssistantResult carries.
+type VoiceAssistantEventType string
+
+const (
+	// VoiceAssistantEventAudio carries a chunk of synthesized reply audio in Audio.
+	VoiceAssistantEventAudio VoiceAssistantEventType = "audio"
+
+	// VoiceAssistantEventTranscript carries the transcription of the user's speech in Text.
+	VoiceAssistantEventTranscript VoiceAssistantEventType = "transcript"
+
+	// VoiceAssistantEventText carries (partial) response text of the assistant in Text.
+	VoiceAssistantEventText VoiceAssistantEventType = "text"
+
+	// VoiceAssistantEventToolCall reports a tool invocation; Text holds the tool name.
+	VoiceAssistantEventToolCall VoiceAssistantEventType = "tool_call"
+
+	// VoiceAssistantEventExpectsReply reports that the assistant expects the
+	// user to reply, e.g. as it ended its answer with a question, so the
+	// device may listen for the reply without the wake word.
+	VoiceAssistantEventExpectsReply VoiceAssistantEventType = "expects_reply"
+
+	// VoiceAssistantEventError reports in Text that the turn failed, e.g.
+	// as the answer could not be completed after tools had been called.
+	VoiceAssistantEventError VoiceAssistantEventType = "error"
+)
+
 // VoiceAssistantResult represents a single output message from the assistant.
 //
-// It encapsulates the generated audio stream that corresponds to the assistant's
-// spoken reply. Depending on the pipeline design, multiple results may be streamed
-// progressively through a channel to enable low-latency playback.
+// Most results encapsulate a chunk of the generated audio stream that
+// corresponds to the assistant's spoken reply. Depending on the pipeline design,
+// multiple results may be streamed progressively through a channel to enable
+// low-latency playback.
 //
 // The Audio field is an io.Reader that provides raw audio data — typically
-// in a playable format (e.g., MPEG or WAV) suitable for immediate playback.
+// in a playable format (e.g., MPEG or WAV) suitable for immediate playback,
+// described by Format.
+// Results of other types (transcript, text, tool calls) carry no audio and
+// describe the progress of the turn in Text instead; consumers that only play
+// audio may skip them. Text results of answers grounded in the user's
+// documents list the documents in Citations.
 //
 // The consumer (e.g., onboard agent or client) is responsible for reading and
 // playing or saving the audio data as it arrives.
 type VoiceAssistantResult struct {
-	Audio io.Reader
+	Type      VoiceAssistantEventType
+	Audio     io.Reader
+	Format    AudioFormat
+	Text      string
+	Citations []Citation
 }
@@ -0,0 +1,70 @@
+package domain
+
+import "time"
+
+// WakeAction is what the device does when a wake word is detected.
+type WakeAction string
+
+const (
+	// WakeActionAssist records the user's utterance and replies to it.
+	// It is the action of wake words without one.
+	WakeActionAssist WakeAction = "assist"
+
+	// WakeActionStop stops the reply in progress, if any, without
+	// recording. It needs barge-in, since otherwise the wake word is not
+	// listened for while the assistant is talking.
+	WakeActionStop WakeAction = "stop"
+)
+
+// NotificationKindListen is the DeviceNotification kind asking a device to
+// start listening to the user as if its (first) wake word was detected.
+const NotificationKindListen = "listen"
+
+// WakeWord configures one of the wake words a device listens for and what
+// it does when the wake word is detected, e.g. answering in the voice of a
+// persona or stopping the reply.
+//
+// Example:
+//
+//	words := []domain.WakeWord{
+//	    {Name: "jarvis", Model: "resources/jarvis.ppn", Voice: domain.VoiceOptions{Voice: "onyx"}},
+//	    {Name: "hallo", Model: "resources/hallo.ppn", Voice: domain.VoiceOptions{Language: "de"}},
+//	    {Name: "stop", Model: "resources/stop.ppn", Sensitivity: 0.7, Action: domain.WakeActionStop},
+//	}
+type WakeWord struct {
+	// Name identifies the wake word, e.g. in logs and detection events.
+	Name string `json:"name"`
+
+	// Model locates the wake word for the engine, e.g. the path of a
+	// Porcupine keyword file (.ppn) or the name of an openWakeWord model.
+	// Empty uses Name.
+	Model string `json:"model,omitempty"`
+
+	// Sensitivity in [0, 1] trades missed detections (low) for false
+	// alarms (high). Zero keeps the default of the engine; engines that
+	// cannot apply it, e.g. openWakeWord, reject any other value.
+	Sensitivity float64 `json:"sensitivity,omitempty"`
+
+	// Action is what the wake word does; empty is WakeActionAssist.
+	Action WakeAction `json:"action,omitempty"`
+
+	// Voice is the persona answering the turns started by the wake word.
+	// Zero values keep the voice configured on the device.
+	Voice VoiceOptions `json:"voice,omitempty"`
+}
+
+// WakeEvent reports the detection of a wake word.
+type WakeEvent struct {
+	// Keyword is the index of the detected wake word among the configured ones.
+	Keyword int
+
+	// Word is the detected wake word.
+	Word WakeWord
+
+	// Time is when the wake word was detected.
+	Time time.Time
+
+	// Confidence of the detection in [0, 1]. Engines only reporting
+	// detections above their threshold report 1.
+	Confidence float64
+}
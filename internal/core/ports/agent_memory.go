@@ -6,7 +6,7 @@ import (
 	"github.com/ownerofglory/raspi-agent/internal/core/domain"
 )
 
-//go:generate go tool go.uber.org/mock/mockgen -source=agent_memory.go -package=ports -destination=agent_memory_mock.go AgentMemory
+//go:generate go tool go.uber.org/mock/mockgen -source=agent_memory.go -package=ports -destination=agent_memory_mock.go AgentMemory,MemoryRepo
 
 // AgentMemory defines the interface for a component that can recall stored
 // information or "memories" for a given user or context.
@@ -24,4 +24,24 @@ type AgentMemory interface {
 	// The req parameter specifies the user and optional query text.
 	// The method returns a RecallResult with the retrieved memories or an error.
 	Recall(ctx context.Context, req domain.RecallRequest) (*domain.RecallResult, error)
+
+	// Memorize extracts memorable facts from a conversation turn and stores
+	// them. Requests to forget something (e.g. "forget that") are applied
+	// as well.
+	Memorize(ctx context.Context, req domain.MemorizeRequest) (*domain.MemorizeResult, error)
+
+	// Forget deletes the stored memory that best matches the request text.
+	Forget(ctx context.Context, req domain.ForgetRequest) (*domain.ForgetResult, error)
+}
+
+// MemoryRepo defines the persistence interface for agent memories.
+type MemoryRepo interface {
+	// Save persists a new memory and returns it with its generated ID.
+	Save(ctx context.Context, memory domain.Memory) (*domain.Memory, error)
+
+	// FindByUserID returns all memories of the given user, including embeddings.
+	FindByUserID(ctx context.Context, userID string) ([]domain.Memory, error)
+
+	// Delete removes the memory with the given ID.
+	Delete(ctx context.Context, id string) error
 }
@@ -0,0 +1,37 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+//go:generate go tool go.uber.org/mock/mockgen -source=audio_source.go -package=ports -destination=audio_source_mock.go AudioSource
+
+// AudioSource defines the contract for a shared audio input, typically the
+// microphone, captured continuously and fanned out to several consumers
+// such as the wake word listener and the recorder.
+//
+// Sharing a single capture avoids reopening the input device between wake
+// word detection and recording, which loses the first syllable of the
+// request, and contention between components opening the device at once.
+//
+// Example:
+//
+//	// keep the audio of the last 300ms, e.g. spoken right after the wake word
+//	frames, err := source.Subscribe(ctx, 300*time.Millisecond)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for frame := range frames {
+//	    process(frame.Samples)
+//	}
+type AudioSource interface {
+	// Subscribe delivers the captured frames, preceded by the most recent
+	// frames captured within preRoll before the call, until ctx is canceled.
+	//
+	// The channel is closed when ctx is canceled or capture stops; if
+	// capture fails, the last frame carries the error in its Err field.
+	Subscribe(ctx context.Context, preRoll time.Duration) (<-chan domain.AudioFrame, error)
+}
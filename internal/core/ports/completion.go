@@ -24,6 +24,13 @@ type CompletionProvider interface {
 	//   - A pointer to CompletionResult containing the generated text.
 	//   - An error if the generation process fails or the request is invalid.
 	CreateCompletion(ctx context.Context, req *domain.CompletionRequest) (*domain.CompletionResult, error)
+
+	// StreamCompletion generates a text response for the given request and
+	// streams it as deltas while the model is still generating.
+	//
+	// The returned channel is closed when the response is complete, the
+	// context is canceled, or after a delta carrying an error.
+	StreamCompletion(ctx context.Context, req *domain.CompletionRequest) (<-chan domain.CompletionDelta, error)
 }
 
 // SummaryProvider defines the interface for generating summaries of text or conversations.
@@ -0,0 +1,34 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+//go:generate go tool go.uber.org/mock/mockgen -source=conversation.go -package=ports -destination=conversation_mock.go ConversationService,ConversationRepo
+
+// ConversationService keeps track of the ongoing conversation per device and
+// user, so that the assistant can answer follow-up questions in context.
+type ConversationService interface {
+	// History returns the prior messages of the active conversation of the
+	// given device and user, oldest first.
+	//
+	// An empty history is returned when there is no conversation yet or
+	// the previous one has been idle for too long.
+	History(ctx context.Context, deviceID, userID string) ([]domain.Message, error)
+
+	// Record appends the messages of a finished turn to the active
+	// conversation, starting a new conversation if needed.
+	Record(ctx context.Context, deviceID, userID string, messages ...domain.Message) error
+}
+
+// ConversationRepo defines the persistence interface for conversations.
+type ConversationRepo interface {
+	// FindLatest returns the most recent conversation of the given device and user.
+	// Returns domain.ErrConversationNotFound if there is none.
+	FindLatest(ctx context.Context, deviceID, userID string) (*domain.Conversation, error)
+
+	// Save persists a new or existing conversation.
+	Save(ctx context.Context, conversation domain.Conversation) error
+}
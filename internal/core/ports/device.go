@@ -51,6 +51,12 @@ type DeviceService interface {
 	//   - DeviceEnrollmentResult containing the signed certificate
 	//   - Error if OTP validation or signing fails
 	EnrollDevice(ctx context.Context, enr domain.DeviceEnrollment) (*domain.DeviceEnrollmentResult, error)
+
+	// GetDevice returns the device with the given ID, e.g. to find the
+	// user owning an authenticated device.
+	//
+	// Returns domain.ErrDeviceNotFound if no such device exists.
+	GetDevice(ctx context.Context, id string) (*domain.Device, error)
 }
 
 // DeviceRepo defines the data access layer for device records.
@@ -0,0 +1,58 @@
+package ports
+
+import (
+	"context"
+	"io"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+//go:generate go tool go.uber.org/mock/mockgen -source=document.go -package=ports -destination=document_mock.go DocumentService,DocumentRepo,TextExtractor
+
+// DocumentService manages the documents of a user and retrieves passages
+// relevant to a question (retrieval-augmented generation).
+type DocumentService interface {
+	// Upload extracts the text of a document, splits it into chunks,
+	// embeds and stores them.
+	//
+	// Returns domain.ErrUnsupportedDocumentType or domain.ErrEmptyDocument
+	// if no text can be extracted.
+	Upload(ctx context.Context, upload domain.DocumentUpload) (*domain.Document, error)
+
+	// List returns all documents of the user.
+	List(ctx context.Context, userID string) ([]domain.Document, error)
+
+	// Delete removes a document of the user and all of its chunks.
+	// Returns domain.ErrDocumentNotFound if the user has no such document.
+	Delete(ctx context.Context, userID, documentID string) error
+
+	// Retrieve returns up to limit passages of the user's documents most
+	// relevant to the given text.
+	Retrieve(ctx context.Context, userID, text string, limit int) ([]domain.DocumentReference, error)
+}
+
+// DocumentRepo defines the persistence interface for documents and their chunks.
+type DocumentRepo interface {
+	// Save persists a new document with its chunks and returns it with generated IDs.
+	Save(ctx context.Context, document domain.Document, chunks []domain.DocumentChunk) (*domain.Document, error)
+
+	// Find returns a single document.
+	// Returns domain.ErrDocumentNotFound if it does not exist.
+	Find(ctx context.Context, id string) (*domain.Document, error)
+
+	// FindByUserID returns all documents of a user.
+	FindByUserID(ctx context.Context, userID string) ([]domain.Document, error)
+
+	// FindChunksByUserID returns the chunks of all documents of a user, including embeddings.
+	FindChunksByUserID(ctx context.Context, userID string) ([]domain.DocumentChunk, error)
+
+	// Delete removes a document and its chunks.
+	Delete(ctx context.Context, id string) error
+}
+
+// TextExtractor extracts plain text from document content of a given type.
+type TextExtractor interface {
+	// ExtractText returns the text of the document.
+	// Returns domain.ErrUnsupportedDocumentType for unknown content types.
+	ExtractText(ctx context.Context, contentType string, content io.Reader) (string, error)
+}
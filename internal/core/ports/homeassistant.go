@@ -0,0 +1,63 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+//go:generate go tool go.uber.org/mock/mockgen -source=homeassistant.go -package=ports -destination=homeassistant_mock.go HomeAssistantService,HomeAssistantClient,HomeAssistantConfigRepo
+
+// HomeAssistantService controls the Home Assistant instance configured by a user.
+//
+// All methods except Configure return domain.ErrHomeAssistantNotConfigured
+// if the user has not configured an instance.
+type HomeAssistantService interface {
+	// Configure sets the instance of the user, replacing any previous one.
+	// Returns domain.ErrInvalidHomeAssistantConfig for malformed URLs or a missing token.
+	Configure(ctx context.Context, config domain.HomeAssistantConfig) error
+
+	// Config returns the instance configured by the user.
+	Config(ctx context.Context, userID string) (*domain.HomeAssistantConfig, error)
+
+	// Remove deletes the configuration of the user.
+	Remove(ctx context.Context, userID string) error
+
+	// Entities returns the entities of the user's instance, optionally
+	// restricted to a domain such as "light".
+	Entities(ctx context.Context, userID, entityDomain string) ([]domain.HomeAssistantEntity, error)
+
+	// State returns the current state of an entity.
+	// Returns domain.ErrHomeAssistantEntityNotFound for unknown entities.
+	State(ctx context.Context, userID, entityID string) (*domain.HomeAssistantEntity, error)
+
+	// CallService calls a service and returns the entities it changed.
+	CallService(ctx context.Context, userID string, call domain.HomeAssistantServiceCall) ([]domain.HomeAssistantEntity, error)
+}
+
+// HomeAssistantClient talks to a Home Assistant instance.
+type HomeAssistantClient interface {
+	// States returns the states of all entities.
+	States(ctx context.Context, config domain.HomeAssistantConfig) ([]domain.HomeAssistantEntity, error)
+
+	// State returns the state of a single entity.
+	// Returns domain.ErrHomeAssistantEntityNotFound for unknown entities.
+	State(ctx context.Context, config domain.HomeAssistantConfig, entityID string) (*domain.HomeAssistantEntity, error)
+
+	// CallService calls a service and returns the entities it changed.
+	CallService(ctx context.Context, config domain.HomeAssistantConfig, call domain.HomeAssistantServiceCall) ([]domain.HomeAssistantEntity, error)
+}
+
+// HomeAssistantConfigRepo defines the persistence interface for the Home
+// Assistant configurations of users.
+type HomeAssistantConfigRepo interface {
+	// Save creates or replaces the configuration of a user.
+	Save(ctx context.Context, config domain.HomeAssistantConfig) error
+
+	// FindByUserID returns the configuration of a user.
+	// Returns domain.ErrHomeAssistantNotConfigured if there is none.
+	FindByUserID(ctx context.Context, userID string) (*domain.HomeAssistantConfig, error)
+
+	// Delete removes the configuration of a user.
+	Delete(ctx context.Context, userID string) error
+}
@@ -0,0 +1,51 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+//go:generate go tool go.uber.org/mock/mockgen -source=mcp.go -package=ports -destination=mcp_mock.go MCPClient,MCPAccessService,MCPAccessRepo
+
+// MCPClient is a client of a single Model Context Protocol server.
+type MCPClient interface {
+	// ListTools returns the tools offered by the server.
+	ListTools(ctx context.Context) ([]domain.MCPTool, error)
+
+	// CallTool calls a tool of the server with the arguments given as JSON.
+	// Failures of the tool itself are reported in the result, see
+	// domain.MCPToolResult.IsError.
+	CallTool(ctx context.Context, name, args string) (*domain.MCPToolResult, error)
+
+	// Close disconnects from the server, stopping its subprocess, if any.
+	Close() error
+}
+
+// MCPAccessService manages which MCP servers and tools users have enabled.
+type MCPAccessService interface {
+	// Servers returns the names of the configured MCP servers.
+	Servers() []string
+
+	// Grant replaces the enabled servers and tools of the user.
+	// Returns domain.ErrUnknownMCPServer for servers that are not configured.
+	Grant(ctx context.Context, access domain.MCPAccess) error
+
+	// Access returns the enabled servers and tools of the user, none if
+	// the user has not enabled any.
+	Access(ctx context.Context, userID string) (*domain.MCPAccess, error)
+
+	// Allowed reports whether the user has enabled the tool of the server.
+	Allowed(ctx context.Context, userID, server, tool string) bool
+}
+
+// MCPAccessRepo defines the persistence interface for the MCP servers and
+// tools enabled by users.
+type MCPAccessRepo interface {
+	// Save replaces the enabled servers and tools of a user.
+	Save(ctx context.Context, access domain.MCPAccess) error
+
+	// FindByUserID returns the enabled servers and tools of a user,
+	// with no grants if there are none.
+	FindByUserID(ctx context.Context, userID string) (*domain.MCPAccess, error)
+}
@@ -0,0 +1,27 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+//go:generate go tool go.uber.org/mock/mockgen -source=mqtt.go -package=ports -destination=mqtt_mock.go MQTTClient
+
+// MQTTClient publishes and receives messages through an MQTT broker.
+//
+// Publish and Retained return domain.ErrInvalidMQTTTopic for empty topics
+// or topics containing wildcards.
+type MQTTClient interface {
+	// Publish sends the message to the broker.
+	Publish(ctx context.Context, msg domain.MQTTMessage) error
+
+	// Retained returns the last value retained by the broker for the topic.
+	// Returns domain.ErrNoRetainedMQTTMessage if the topic has none.
+	Retained(ctx context.Context, topic string) (*domain.MQTTMessage, error)
+
+	// Subscribe passes the messages published on topics matching the filters
+	// to handle until the context is canceled, in which case it returns nil,
+	// or the connection to the broker is lost.
+	Subscribe(ctx context.Context, filters []string, handle func(domain.MQTTMessage)) error
+}
@@ -3,6 +3,8 @@ package ports
 import (
 	"context"
 	"io"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
 )
 
 //go:generate go tool go.uber.org/mock/mockgen -source=playback.go -package=ports -destination=playback_mock.go Player
@@ -13,16 +15,21 @@ import (
 // and play it through the device’s audio output in real time.
 //
 // Typical use cases include:
-//   - Playing raw PCM or decoded MP3/Opus chunks streamed from a backend.
+//   - Playing MP3, WAV, Opus, FLAC or raw PCM streamed from a backend.
 //   - Acting as the final stage in a voice assistant pipeline after TTS.
 //
+// The format of the audio is given as declared by its source, e.g. the
+// Content-Type of a response. If it is unknown (the zero value), it is
+// detected from the leading bytes of the audio; raw PCM cannot be detected
+// and has to be declared together with its sample rate and channels.
+//
 // The PlaybackStream method must block until playback is complete,
 // or return early if the context is canceled.
 //
 // Example usage:
 //
 //	player := audio.NewPlayer()
-//	err := player.PlaybackStream(ctx, audioStream)
+//	err := player.PlaybackStream(ctx, stream.Format, stream.Chunks)
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
@@ -35,11 +42,11 @@ type Player interface {
 	//
 	// The context allows cancellation of playback (for example, if the
 	// user stops the assistant or starts a new request).
-	PlaybackStream(ctx context.Context, audioStream <-chan []byte) error
+	PlaybackStream(ctx context.Context, format domain.AudioFormat, audioStream <-chan []byte) error
 
 	// Playback plays audio from a single continuous reader (e.g. file, HTTP body).
 	//
 	// Unlike PlaybackStream, this variant does not require chunked input.
 	// Implementations should decode and stream playback until EOF or cancellation.
-	Playback(ctx context.Context, reader io.Reader) error
+	Playback(ctx context.Context, format domain.AudioFormat, reader io.Reader) error
 }
@@ -29,4 +29,25 @@ type Recorder interface {
 	//   - A `domain.RecordingResult` containing the recorded audio samples.
 	//   - An `error` if initialization or recording fails.
 	RecordAudio(ctx context.Context, duration time.Duration) (domain.RecordingResult, error)
+
+	// RecordUtterance records a single spoken utterance using voice activity
+	// detection (VAD) to decide when the speaker has finished.
+	//
+	// It must block until the end of the utterance is detected, the maximum
+	// duration from opts is reached, or the context is cancelled.
+	//
+	// Returns:
+	//   - A `domain.RecordingResult` containing the recorded audio samples.
+	//   - `domain.ErrNoSpeechDetected` if no speech starts within opts.NoSpeechTimeout.
+	//   - An `error` if initialization or recording fails.
+	RecordUtterance(ctx context.Context, opts domain.UtteranceOptions) (domain.RecordingResult, error)
+
+	// StreamAudio captures a single utterance like RecordUtterance, but
+	// delivers the audio frame by frame while the user is still speaking.
+	//
+	// Initialization errors are returned immediately. Once capture runs,
+	// the channel is closed when the utterance ends or the context is
+	// cancelled; if capture fails (or no speech is detected), the last
+	// frame carries the error in its Err field.
+	StreamAudio(ctx context.Context, opts domain.UtteranceOptions) (<-chan domain.AudioFrame, error)
 }
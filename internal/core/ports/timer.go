@@ -0,0 +1,58 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+//go:generate go tool go.uber.org/mock/mockgen -source=timer.go -package=ports -destination=timer_mock.go TimerService,TimerRepo,DeviceNotifier
+
+// TimerService manages timers and alarms and fires them when they are due.
+type TimerService interface {
+	// Set creates a new timer.
+	// Returns domain.ErrInvalidTimer if neither a duration nor a time is given.
+	Set(ctx context.Context, req domain.TimerRequest) (*domain.Timer, error)
+
+	// List returns the pending timers of the user, soonest first.
+	List(ctx context.Context, userID string) ([]domain.Timer, error)
+
+	// Cancel deletes a pending timer of the user.
+	// Returns domain.ErrTimerNotFound if the user has no such timer.
+	Cancel(ctx context.Context, userID, timerID string) error
+
+	// Run fires the timers when they are due until the context is canceled.
+	// Timers that became due while nothing was running fire right away.
+	Run(ctx context.Context) error
+}
+
+// TimerRepo defines the persistence interface for timers.
+type TimerRepo interface {
+	// Save persists a new timer and returns it with a generated ID.
+	Save(ctx context.Context, timer domain.Timer) (*domain.Timer, error)
+
+	// FindByUserID returns the timers of a user, soonest first.
+	FindByUserID(ctx context.Context, userID string) ([]domain.Timer, error)
+
+	// FindDue returns all timers firing at or before the given time.
+	FindDue(ctx context.Context, until time.Time) ([]domain.Timer, error)
+
+	// FindNext returns the timer firing next.
+	// Returns domain.ErrTimerNotFound if there are no timers.
+	FindNext(ctx context.Context) (*domain.Timer, error)
+
+	// Delete removes a timer.
+	Delete(ctx context.Context, id string) error
+}
+
+// DeviceNotifier pushes notifications to devices outside of a turn.
+type DeviceNotifier interface {
+	// Notify delivers the notification to the device.
+	// Returns domain.ErrDeviceNotConnected if the device cannot be reached.
+	Notify(ctx context.Context, deviceID string, notification domain.DeviceNotification) error
+
+	// Reachable reports whether notifications can currently be delivered to
+	// the device.
+	Reachable(deviceID string) bool
+}
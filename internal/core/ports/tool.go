@@ -0,0 +1,21 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+//go:generate go tool go.uber.org/mock/mockgen -source=tool.go -package=ports -destination=tool_mock.go ToolProvider
+
+// ToolProvider offers the tools the assistant may call to act on behalf of
+// the user (e.g. set a timer or switch a light).
+type ToolProvider interface {
+	// Tools returns the definitions of the tools available to the caller
+	// carried by ctx, see domain.ToolCallerFrom.
+	Tools(ctx context.Context) []domain.ToolDefinition
+
+	// ExecuteTool runs the named tool with the arguments given as JSON and
+	// returns its result, which is passed back to the language model.
+	ExecuteTool(ctx context.Context, name, args string) (any, error)
+}
@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"io"
 
 	"github.com/ownerofglory/raspi-agent/internal/core/domain"
 )
@@ -42,27 +43,36 @@ type VoiceAssistant interface {
 // a streamed audio reply.
 //
 // The backend is typically an HTTP or WebSocket endpoint that accepts
-// an audio file (e.g., WAV or MP3) and returns a streaming audio response
-// (for example, an `audio/mpeg` chunked transfer).
+// an audio stream (e.g., WAV) and returns a streaming audio response
+// (for example, an `audio/mpeg` or `audio/wav` chunked transfer). Devices
+// running the assistant themselves use a client of the local VoiceAssistant
+// instead, so the orchestrator is the same in both cases.
 //
 // The ReceiveVoiceAssistance method uploads the user's voice recording
-// to the backend and returns a *receive-only* channel of []byte chunks,
-// each containing a portion of the assistant's spoken response.
+// to the backend while it is being read and returns the format of the
+// assistant's spoken response together with a *receive-only* channel of
+// []byte chunks, each containing a portion of it.
 //
 // Example:
 //
-//	ch, err := client.ReceiveVoiceAssistance(ctx, "/tmp/request.wav")
+//	frames, _ := recorder.StreamAudio(ctx, opts)
+//	stream, err := client.ReceiveVoiceAssistance(ctx, wav.NewStreamReader(frames), domain.VoiceOptions{})
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
 //
 //	// Stream response to speaker
-//	player.PlaybackStream(ctx, ch)
+//	player.PlaybackStream(ctx, stream.Format, stream.Chunks)
 type VoiceAssistantClient interface {
-	// ReceiveVoiceAssistance sends a voice request to the backend and
+	// ReceiveVoiceAssistance streams a voice request to the backend and
 	// returns a stream of audio chunks representing the assistant's reply.
 	//
-	// The returned channel will be closed automatically when the stream ends
-	// or if the context is canceled.
-	ReceiveVoiceAssistance(ctx context.Context, filePath string) (<-chan []byte, error)
+	// The audio is uploaded as it is read, until the reader returns io.EOF.
+	// The channel of the returned stream will be closed automatically when
+	// the stream ends or if the context is canceled. Its format is the zero
+	// value if the backend does not advertise it.
+	//
+	// The non-zero fields of voice override the voice the client was
+	// configured with for this request, e.g. with the persona of the wake word.
+	ReceiveVoiceAssistance(ctx context.Context, audio io.Reader, voice domain.VoiceOptions) (*domain.AudioStream, error)
 }
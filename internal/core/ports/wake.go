@@ -1,26 +1,31 @@
 package ports
 
-import "context"
+import (
+	"context"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
 
 //go:generate go tool go.uber.org/mock/mockgen -source=wake.go -package=ports -destination=wake_mock.go WakeListener
 
 // WakeListener defines the contract for components capable of detecting
-// a predefined wake word or activation phrase (e.g. “Hey Vicky”, “Raspi”)
+// predefined wake words or activation phrases (e.g. “Hey Vicky”, “Raspi”)
 // from live microphone input.
 //
 // Implementations are typically long-running processes that continuously
-// listen to audio input and block until a wake word is detected or the
-// provided context is cancelled.
+// listen to audio input and block until one of the configured wake words
+// (domain.WakeWord) is detected or the provided context is cancelled.
 type WakeListener interface {
-	// Listen starts listening for the configured wake word using the
+	// Listen starts listening for the configured wake words using the
 	// system’s default microphone or other input source.
 	//
 	// The method should block until:
-	//   - The wake word is detected, in which case it returns nil.
+	//   - A wake word is detected, in which case it returns the event
+	//     telling which one, so the caller can act on it.
 	//   - The provided context is cancelled, in which case it returns ctx.Err().
 	//   - An unrecoverable error occurs during initialization or processing.
 	//
 	// Implementations should ensure that any audio resources (e.g. PortAudio streams)
 	// are properly initialized and cleaned up.
-	Listen(ctx context.Context) error
+	Listen(ctx context.Context) (*domain.WakeEvent, error)
 }
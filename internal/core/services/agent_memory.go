@@ -0,0 +1,260 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+const (
+	// defaultRecallLimit is the number of memories recalled when the request sets no limit.
+	defaultRecallLimit = 5
+)
+
+// Line prefixes of the memory extraction answer.
+const (
+	rememberPrefix = "REMEMBER:"
+	forgetPrefix   = "FORGET:"
+)
+
+// memoryExtractionInstruction asks the model which facts of a turn are worth
+// remembering long-term, and which memories the user wants deleted.
+const memoryExtractionInstruction = "You maintain the long-term memory of a voice assistant. " +
+	"Read the conversation below and list facts about the user worth remembering in future " +
+	"conversations (names, relationships, preferences, plans, home setup). Ignore small talk, " +
+	"questions and facts about the world.\n" +
+	"If the user asks to forget something (e.g. \"forget that\"), resolve what they refer to.\n" +
+	"Answer with one line per item, each a short self-contained statement in third person:\n" +
+	rememberPrefix + " <fact>\n" +
+	forgetPrefix + " <fact to forget>\n" +
+	"Answer NONE if there is nothing to remember or forget.\n\n"
+
+// agentMemory implements ports.AgentMemory using embeddings for similarity
+// search over the memories of a user.
+//
+// Memories are ranked in Go by cosine similarity, which is plenty for the few
+// hundred memories a single user accumulates and needs no vector extension
+// in the database.
+type agentMemory struct {
+	repo       ports.MemoryRepo
+	embeddings ports.EmbeddingProvider
+	completion ports.CompletionProvider
+	thresholds domain.SimilarityThresholds
+}
+
+// NewAgentMemory creates an agent memory persisting memories in repo, using
+// the embedding provider for recall, with the similarity thresholds suiting
+// its model, and the completion provider to extract facts from
+// conversations.
+func NewAgentMemory(repo ports.MemoryRepo, embeddings ports.EmbeddingProvider, cmpl ports.CompletionProvider, thresholds domain.SimilarityThresholds) *agentMemory {
+	return &agentMemory{
+		repo:       repo,
+		embeddings: embeddings,
+		completion: cmpl,
+		thresholds: thresholds,
+	}
+}
+
+// scoredMemory is a memory together with its similarity to a query.
+type scoredMemory struct {
+	memory     domain.Memory
+	similarity float64
+}
+
+// Recall returns the user's memories most similar to the request text.
+func (m *agentMemory) Recall(ctx context.Context, req domain.RecallRequest) (*domain.RecallResult, error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultRecallLimit
+	}
+
+	ranked, err := m.rank(ctx, req.UserID, req.Text)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &domain.RecallResult{}
+	for _, s := range ranked {
+		if s.similarity < m.thresholds.Recall || len(res.Memories) == limit {
+			break
+		}
+		res.Memories = append(res.Memories, s.memory.Text)
+	}
+	return res, nil
+}
+
+// Memorize extracts facts from the turn, stores new ones and applies forget requests.
+func (m *agentMemory) Memorize(ctx context.Context, req domain.MemorizeRequest) (*domain.MemorizeResult, error) {
+	var prompt strings.Builder
+	prompt.WriteString(memoryExtractionInstruction)
+	for _, msg := range req.Messages {
+		fmt.Fprintf(&prompt, "%s: %s\n", msg.Role, msg.Text)
+	}
+
+	extraction, err := m.completion.CreateCompletion(ctx, &domain.CompletionRequest{
+		Prompt: prompt.String(),
+	})
+	if err != nil {
+		slog.Error("Failed to extract memories", "error", err)
+		return nil, fmt.Errorf("failed to extract memories: %w", err)
+	}
+
+	res := &domain.MemorizeResult{}
+	for _, line := range strings.Split(extraction.Text, "\n") {
+		line = strings.TrimSpace(line)
+
+		if fact, ok := strings.CutPrefix(line, forgetPrefix); ok {
+			forgotten, err := m.Forget(ctx, domain.ForgetRequest{UserID: req.UserID, Text: strings.TrimSpace(fact)})
+			if err != nil {
+				return res, err
+			}
+			res.Forgotten = append(res.Forgotten, forgotten.Forgotten...)
+			continue
+		}
+
+		if fact, ok := strings.CutPrefix(line, rememberPrefix); ok {
+			fact = strings.TrimSpace(fact)
+			stored, err := m.remember(ctx, req.UserID, fact)
+			if err != nil {
+				return res, err
+			}
+			if stored {
+				res.Remembered = append(res.Remembered, fact)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// Forget deletes the memory most similar to the request text, if it is similar enough.
+func (m *agentMemory) Forget(ctx context.Context, req domain.ForgetRequest) (*domain.ForgetResult, error) {
+	res := &domain.ForgetResult{}
+	if req.Text == "" {
+		return res, nil
+	}
+
+	ranked, err := m.rank(ctx, req.UserID, req.Text)
+	if err != nil {
+		return nil, err
+	}
+	if len(ranked) == 0 || ranked[0].similarity < m.thresholds.Forget {
+		slog.Debug("No memory to forget", "text", req.Text)
+		return res, nil
+	}
+
+	best := ranked[0].memory
+	if err := m.repo.Delete(ctx, best.ID); err != nil {
+		slog.Error("Failed to delete memory", "error", err, "memoryId", best.ID)
+		return nil, fmt.Errorf("failed to delete memory: %w", err)
+	}
+	slog.Info("Forgot memory", "userId", req.UserID, "memory", best.Text)
+
+	res.Forgotten = append(res.Forgotten, best.Text)
+	return res, nil
+}
+
+// remember stores a fact unless it is already known. It reports whether
+// the fact has been stored.
+func (m *agentMemory) remember(ctx context.Context, userID, fact string) (bool, error) {
+	if fact == "" {
+		return false, nil
+	}
+
+	embedding, err := m.embed(ctx, fact)
+	if err != nil {
+		return false, err
+	}
+
+	memories, err := m.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to find memories", "error", err, "userId", userID)
+		return false, fmt.Errorf("failed to find memories: %w", err)
+	}
+	for _, existing := range memories {
+		if cosineSimilarity(embedding, existing.Embedding) >= m.thresholds.Duplicate {
+			slog.Debug("Memory already known", "memory", fact)
+			return false, nil
+		}
+	}
+
+	_, err = m.repo.Save(ctx, domain.Memory{
+		UserID:    userID,
+		Text:      fact,
+		Embedding: embedding,
+	})
+	if err != nil {
+		slog.Error("Failed to save memory", "error", err, "userId", userID)
+		return false, fmt.Errorf("failed to save memory: %w", err)
+	}
+	slog.Info("Remembered", "userId", userID, "memory", fact)
+
+	return true, nil
+}
+
+// rank returns the user's memories ordered by descending similarity to text.
+func (m *agentMemory) rank(ctx context.Context, userID, text string) ([]scoredMemory, error) {
+	memories, err := m.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to find memories", "error", err, "userId", userID)
+		return nil, fmt.Errorf("failed to find memories: %w", err)
+	}
+	if len(memories) == 0 {
+		return nil, nil
+	}
+
+	query, err := m.embed(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := make([]scoredMemory, 0, len(memories))
+	for _, mem := range memories {
+		ranked = append(ranked, scoredMemory{memory: mem, similarity: cosineSimilarity(query, mem.Embedding)})
+	}
+	slices.SortFunc(ranked, func(a, b scoredMemory) int {
+		switch {
+		case a.similarity > b.similarity:
+			return -1
+		case a.similarity < b.similarity:
+			return 1
+		}
+		return 0
+	})
+
+	return ranked, nil
+}
+
+func (m *agentMemory) embed(ctx context.Context, text string) ([]float64, error) {
+	res, err := m.embeddings.CreateEmbedding(ctx, domain.EmbeddingRequest{Data: text})
+	if err != nil {
+		slog.Error("Failed to create embedding", "error", err)
+		return nil, fmt.Errorf("failed to create embedding: %w", err)
+	}
+	return res.Embedding, nil
+}
+
+// cosineSimilarity returns the cosine of the angle between a and b,
+// or 0 if they differ in length or either is a zero vector.
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
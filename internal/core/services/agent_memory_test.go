@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"go.uber.org/mock/gomock"
+)
+
+// testThresholds are the similarity thresholds the test embeddings are made for.
+var testThresholds = domain.SimilarityThresholds{Recall: 0.78, Duplicate: 0.93, Forget: 0.85, Retrieve: 0.75}
+
+// testEmbeddings maps texts to fixed embedding vectors.
+var testEmbeddings = map[string][]float64{
+	"The user's daughter is called Mia":   {1, 0, 0},
+	"The user likes jazz":                 {0, 1, 0},
+	"The user lives in Berlin":            {0, 0, 1},
+	"What's my daughter's name?":          {0.95, 0.1, 0},
+	"Play some music":                     {0.3, 0.9, 0.1},
+	"The user's daughter is named Mia":    {0.99, 0.01, 0},
+	"The user prefers tea over coffee":    {0.5, 0.5, 0.7},
+	"The user likes jazz music very much": {0.05, 0.99, 0},
+}
+
+func newTestAgentMemory(t *testing.T) (*agentMemory, *ports.MockMemoryRepo, *ports.MockCompletionProvider) {
+	ctrl := gomock.NewController(t)
+	repo := ports.NewMockMemoryRepo(ctrl)
+	embeddings := ports.NewMockEmbeddingProvider(ctrl)
+	cmpl := ports.NewMockCompletionProvider(ctrl)
+
+	embeddings.EXPECT().CreateEmbedding(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, req domain.EmbeddingRequest) (*domain.EmbeddingResult, error) {
+			e, ok := testEmbeddings[req.Data]
+			if !ok {
+				t.Fatalf("unexpected embedding request %q", req.Data)
+			}
+			return &domain.EmbeddingResult{Embedding: e}, nil
+		}).AnyTimes()
+
+	var stored []domain.Memory
+	for i, text := range []string{"The user's daughter is called Mia", "The user likes jazz", "The user lives in Berlin"} {
+		stored = append(stored, domain.Memory{ID: string(rune('a' + i)), UserID: "u1", Text: text, Embedding: testEmbeddings[text]})
+	}
+	repo.EXPECT().FindByUserID(gomock.Any(), "u1").Return(stored, nil).AnyTimes()
+
+	return NewAgentMemory(repo, embeddings, cmpl, testThresholds), repo, cmpl
+}
+
+func TestAgentMemoryRecall(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		limit    int
+		memories []string
+	}{
+		{
+			name:     "most similar memory",
+			text:     "What's my daughter's name?",
+			memories: []string{"The user's daughter is called Mia"},
+		},
+		{
+			name:     "no sufficiently similar memory",
+			text:     "The user prefers tea over coffee",
+			memories: nil,
+		},
+		{
+			name:     "limit",
+			text:     "Play some music",
+			limit:    1,
+			memories: []string{"The user likes jazz"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, _, _ := newTestAgentMemory(t)
+
+			res, err := m.Recall(context.Background(), domain.RecallRequest{UserID: "u1", Text: tc.text, Limit: tc.limit})
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+			if !slices.Equal(res.Memories, tc.memories) {
+				t.Errorf("expected memories %q, got %q", tc.memories, res.Memories)
+			}
+		})
+	}
+}
+
+func TestAgentMemoryMemorize(t *testing.T) {
+	testCases := []struct {
+		name       string
+		extraction string
+		saved      []string
+		deleted    []string
+	}{
+		{
+			name:       "nothing to remember",
+			extraction: "NONE",
+		},
+		{
+			name:       "new fact",
+			extraction: "REMEMBER: The user prefers tea over coffee",
+			saved:      []string{"The user prefers tea over coffee"},
+		},
+		{
+			name:       "known fact is not stored twice",
+			extraction: "REMEMBER: The user likes jazz music very much",
+		},
+		{
+			name:       "forget that",
+			extraction: "FORGET: The user's daughter is named Mia",
+			deleted:    []string{"a"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			m, repo, cmpl := newTestAgentMemory(t)
+
+			cmpl.EXPECT().CreateCompletion(gomock.Any(), gomock.Any()).
+				Return(&domain.CompletionResult{Text: tc.extraction}, nil)
+
+			var saved, deleted []string
+			repo.EXPECT().Save(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, mem domain.Memory) (*domain.Memory, error) {
+					saved = append(saved, mem.Text)
+					return &mem, nil
+				}).AnyTimes()
+			repo.EXPECT().Delete(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, id string) error {
+					deleted = append(deleted, id)
+					return nil
+				}).AnyTimes()
+
+			_, err := m.Memorize(context.Background(), domain.MemorizeRequest{
+				UserID: "u1",
+				Messages: []domain.Message{
+					{Role: domain.MessageRoleUser, Text: "Hello"},
+				},
+			})
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+			if !slices.Equal(saved, tc.saved) {
+				t.Errorf("expected saved %q, got %q", tc.saved, saved)
+			}
+			if !slices.Equal(deleted, tc.deleted) {
+				t.Errorf("expected deleted %q, got %q", tc.deleted, deleted)
+			}
+		})
+	}
+}
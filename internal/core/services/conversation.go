@@ -0,0 +1,211 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+const (
+	// keepRecentMessages is the number of most recent messages that are never
+	// summarized, so the latest turns stay verbatim in the context window.
+	keepRecentMessages = 4
+
+	// summaryPrefix introduces the summary message replacing older turns.
+	summaryPrefix = "Summary of the earlier conversation: "
+)
+
+// conversationService implements ports.ConversationService.
+//
+// A conversation stays active as long as turns follow each other within the
+// idle timeout; afterwards the next turn starts a new conversation.
+//
+// Once the history exceeds the character budget (roughly 4 characters per
+// token), older messages are replaced by a summary created by the summarizer.
+type conversationService struct {
+	repo          ports.ConversationRepo
+	summarizer    ports.SummaryProvider
+	idleTimeout   time.Duration
+	historyBudget int
+	now           func() time.Time
+
+	mu    sync.Mutex
+	locks map[string]*conversationLock
+}
+
+// conversationLock serializes access to the conversation of a device and
+// user. It is kept only while turns hold or wait for it.
+type conversationLock struct {
+	sync.Mutex
+	users int
+}
+
+// NewConversationService creates a conversation service that starts a new
+// conversation once the previous one has been idle for longer than idleTimeout,
+// and compacts histories longer than historyBudget characters using the
+// summarizer. A historyBudget of 0 disables compaction.
+func NewConversationService(repo ports.ConversationRepo, summarizer ports.SummaryProvider, idleTimeout time.Duration, historyBudget int) *conversationService {
+	return &conversationService{
+		repo:          repo,
+		summarizer:    summarizer,
+		idleTimeout:   idleTimeout,
+		historyBudget: historyBudget,
+		now:           time.Now,
+		locks:         make(map[string]*conversationLock),
+	}
+}
+
+// History returns the messages of the active conversation, if any.
+//
+// It waits for a compaction of the same conversation still in progress.
+func (s *conversationService) History(ctx context.Context, deviceID, userID string) ([]domain.Message, error) {
+	unlock := s.lock(deviceID, userID)
+	defer unlock()
+
+	c, err := s.active(ctx, deviceID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, nil
+	}
+	return c.Messages, nil
+}
+
+// Record appends messages to the active conversation or starts a new one,
+// compacting the history if it exceeds the budget.
+func (s *conversationService) Record(ctx context.Context, deviceID, userID string, messages ...domain.Message) error {
+	unlock := s.lock(deviceID, userID)
+	defer unlock()
+
+	now := s.now()
+
+	c, err := s.active(ctx, deviceID, userID)
+	if err != nil {
+		return err
+	}
+	if c == nil {
+		id, err := uuid.NewV7()
+		if err != nil {
+			return fmt.Errorf("failed to generate conversation id: %w", err)
+		}
+		c = &domain.Conversation{
+			ID:        id.String(),
+			DeviceID:  deviceID,
+			UserID:    userID,
+			StartedAt: now,
+		}
+		slog.Debug("Starting new conversation", "conversationId", c.ID, "deviceId", deviceID)
+	}
+
+	c.Messages = append(c.Messages, messages...)
+	c.UpdatedAt = now
+
+	if s.historyBudget > 0 && historyLength(c.Messages) > s.historyBudget {
+		// a failed compaction keeps the full history; it is retried next turn
+		if compacted, err := s.compact(ctx, c.Messages); err != nil {
+			slog.Error("failed to compact conversation", "err", err, "conversationId", c.ID)
+		} else {
+			c.Messages = compacted
+		}
+	}
+
+	if err := s.repo.Save(ctx, *c); err != nil {
+		slog.Error("failed to save conversation", "err", err, "conversationId", c.ID)
+		return fmt.Errorf("failed to save conversation: %w", err)
+	}
+	return nil
+}
+
+// compact replaces all but the most recent messages with a summary.
+// A previous summary is part of the summarized messages, so it rolls over.
+func (s *conversationService) compact(ctx context.Context, messages []domain.Message) ([]domain.Message, error) {
+	if len(messages) <= keepRecentMessages {
+		return messages, nil
+	}
+
+	// tool results are kept together with the assistant message calling
+	// the tools, which models require
+	cut := len(messages) - keepRecentMessages
+	for cut > 0 && messages[cut].Role == domain.MessageRoleTool {
+		cut--
+	}
+	if cut == 0 {
+		return messages, nil
+	}
+	older := messages[:cut]
+	recent := messages[cut:]
+
+	summary, err := s.summarizer.CreateSummary(ctx, &domain.SummaryRequest{Messages: older})
+	if err != nil {
+		return nil, err
+	}
+	slog.Debug("Compacted conversation", "summarized", len(older), "kept", len(recent))
+
+	compacted := make([]domain.Message, 0, len(recent)+1)
+	compacted = append(compacted, domain.Message{Role: domain.MessageRoleSystem, Text: summaryPrefix + summary.Text})
+	return append(compacted, recent...), nil
+}
+
+// active returns the latest conversation unless it has been idle for too
+// long; nil means a new conversation has to be started.
+func (s *conversationService) active(ctx context.Context, deviceID, userID string) (*domain.Conversation, error) {
+	c, err := s.repo.FindLatest(ctx, deviceID, userID)
+	if errors.Is(err, domain.ErrConversationNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		slog.Error("failed to find conversation", "err", err, "deviceId", deviceID)
+		return nil, fmt.Errorf("failed to find conversation: %w", err)
+	}
+
+	if s.now().Sub(c.UpdatedAt) > s.idleTimeout {
+		return nil, nil
+	}
+	return c, nil
+}
+
+// lock serializes access to the conversation of a device and user, so a
+// slow compaction is never overwritten by the next turn. The lock is
+// dropped once no turn holds or waits for it, so devices and users that
+// went quiet take up no memory.
+func (s *conversationService) lock(deviceID, userID string) func() {
+	key := deviceID + "/" + userID
+
+	s.mu.Lock()
+	l, ok := s.locks[key]
+	if !ok {
+		l = &conversationLock{}
+		s.locks[key] = l
+	}
+	l.users++
+	s.mu.Unlock()
+
+	l.Lock()
+	return func() {
+		l.Unlock()
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		l.users--
+		if l.users == 0 {
+			delete(s.locks, key)
+		}
+	}
+}
+
+// historyLength returns the number of characters of all messages.
+func historyLength(messages []domain.Message) int {
+	n := 0
+	for _, m := range messages {
+		n += len(m.Text)
+	}
+	return n
+}
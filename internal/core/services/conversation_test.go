@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"go.uber.org/mock/gomock"
+)
+
+func TestConversationService(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	prior := []domain.Message{
+		{Role: domain.MessageRoleUser, Text: "What's the weather in Berlin?"},
+		{Role: domain.MessageRoleAssistant, Text: "Sunny, 20 degrees."},
+	}
+	turn := []domain.Message{
+		{Role: domain.MessageRoleUser, Text: "And tomorrow?"},
+		{Role: domain.MessageRoleAssistant, Text: "Rainy."},
+	}
+
+	testCases := []struct {
+		name          string
+		latest        *domain.Conversation
+		findErr       error
+		history       int
+		savedMessages int
+		continued     bool
+	}{
+		{
+			name:          "no conversation yet",
+			findErr:       domain.ErrConversationNotFound,
+			history:       0,
+			savedMessages: 2,
+		},
+		{
+			name:          "active conversation",
+			latest:        &domain.Conversation{ID: "c1", DeviceID: "d1", UserID: "u1", Messages: prior, UpdatedAt: now.Add(-time.Minute)},
+			history:       2,
+			savedMessages: 4,
+			continued:     true,
+		},
+		{
+			name:          "idle conversation",
+			latest:        &domain.Conversation{ID: "c1", DeviceID: "d1", UserID: "u1", Messages: prior, UpdatedAt: now.Add(-time.Hour)},
+			history:       0,
+			savedMessages: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := ports.NewMockConversationRepo(ctrl)
+
+			s := NewConversationService(repo, ports.NewMockSummaryProvider(ctrl), 5*time.Minute, 0)
+			s.now = func() time.Time { return now }
+
+			repo.EXPECT().FindLatest(gomock.Any(), "d1", "u1").Return(tc.latest, tc.findErr).Times(2)
+			repo.EXPECT().Save(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, c domain.Conversation) error {
+					if len(c.Messages) != tc.savedMessages {
+						t.Errorf("expected %d saved messages, got %d", tc.savedMessages, len(c.Messages))
+					}
+					if tc.continued != (c.ID == "c1") {
+						t.Errorf("expected continued conversation %v, got id %q", tc.continued, c.ID)
+					}
+					if !c.UpdatedAt.Equal(now) {
+						t.Errorf("expected updated at %v, got %v", now, c.UpdatedAt)
+					}
+					return nil
+				})
+
+			history, err := s.History(context.Background(), "d1", "u1")
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+			if len(history) != tc.history {
+				t.Errorf("expected %d history messages, got %d", tc.history, len(history))
+			}
+
+			if err := s.Record(context.Background(), "d1", "u1", turn...); err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+		})
+	}
+}
+
+func TestConversationServiceLock(t *testing.T) {
+	s := NewConversationService(nil, nil, 5*time.Minute, 0)
+
+	unlock := s.lock("d1", "u1")
+	locked := make(chan func())
+	go func() {
+		locked <- s.lock("d1", "u1")
+	}()
+
+	// the next turn of the same conversation waits for the current one
+	select {
+	case <-locked:
+		t.Fatal("expected the conversation to stay locked")
+	case <-time.After(20 * time.Millisecond):
+	}
+	s.lock("d2", "u1")()
+
+	unlock()
+	select {
+	case unlock = <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("expected the conversation to be unlocked")
+	}
+	unlock()
+
+	if len(s.locks) != 0 {
+		t.Errorf("expected unused locks to be dropped, got %d", len(s.locks))
+	}
+}
+
+func TestConversationServiceCompaction(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	var prior []domain.Message
+	for i := 0; i < 3; i++ {
+		prior = append(prior,
+			domain.Message{Role: domain.MessageRoleUser, Text: "Tell me something about the Raspberry Pi."},
+			domain.Message{Role: domain.MessageRoleAssistant, Text: "The Raspberry Pi is a small single-board computer."},
+		)
+	}
+	turn := []domain.Message{
+		{Role: domain.MessageRoleUser, Text: "And who makes it?"},
+		{Role: domain.MessageRoleAssistant, Text: "The Raspberry Pi Foundation."},
+	}
+
+	testCases := []struct {
+		name       string
+		budget     int
+		summaryErr error
+		summarize  bool
+		saved      int
+		summarized bool
+	}{
+		{
+			name:   "within budget",
+			budget: 10000,
+			saved:  8,
+		},
+		{
+			name:       "over budget",
+			budget:     100,
+			summarize:  true,
+			saved:      keepRecentMessages + 1,
+			summarized: true,
+		},
+		{
+			name:       "summary fails",
+			budget:     100,
+			summarize:  true,
+			summaryErr: errors.New("model unavailable"),
+			saved:      8,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := ports.NewMockConversationRepo(ctrl)
+			summarizer := ports.NewMockSummaryProvider(ctrl)
+
+			s := NewConversationService(repo, summarizer, 5*time.Minute, tc.budget)
+			s.now = func() time.Time { return now }
+
+			repo.EXPECT().FindLatest(gomock.Any(), "d1", "u1").
+				Return(&domain.Conversation{ID: "c1", DeviceID: "d1", UserID: "u1", Messages: prior, UpdatedAt: now}, nil)
+
+			if tc.summarize {
+				summarizer.EXPECT().CreateSummary(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, req *domain.SummaryRequest) (*domain.SummaryResult, error) {
+						if len(req.Messages) != len(prior)+len(turn)-keepRecentMessages {
+							t.Errorf("expected %d summarized messages, got %d", len(prior)+len(turn)-keepRecentMessages, len(req.Messages))
+						}
+						if tc.summaryErr != nil {
+							return nil, tc.summaryErr
+						}
+						return &domain.SummaryResult{Text: "The user asked about the Raspberry Pi."}, nil
+					})
+			}
+
+			repo.EXPECT().Save(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, c domain.Conversation) error {
+					if len(c.Messages) != tc.saved {
+						t.Errorf("expected %d saved messages, got %d", tc.saved, len(c.Messages))
+					}
+					if summarized := c.Messages[0].Role == domain.MessageRoleSystem; summarized != tc.summarized {
+						t.Errorf("expected summarized %v, got first message %+v", tc.summarized, c.Messages[0])
+					}
+					if last := c.Messages[len(c.Messages)-1]; !reflect.DeepEqual(last, turn[1]) {
+						t.Errorf("expected last message %+v, got %+v", turn[1], last)
+					}
+					return nil
+				})
+
+			if err := s.Record(context.Background(), "d1", "u1", turn...); err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+		})
+	}
+}
+
+func TestConversationServiceCompactionKeepsToolResults(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	summarizer := ports.NewMockSummaryProvider(ctrl)
+	s := NewConversationService(ports.NewMockConversationRepo(ctrl), summarizer, 5*time.Minute, 100)
+
+	call := domain.Message{Role: domain.MessageRoleAssistant, ToolCalls: []domain.ToolCall{{ID: "c1"}, {ID: "c2"}, {ID: "c3"}}}
+	messages := []domain.Message{
+		{Role: domain.MessageRoleUser, Text: "Hello"},
+		{Role: domain.MessageRoleAssistant, Text: "Hi!"},
+		{Role: domain.MessageRoleUser, Text: "Turn off all lights."},
+		call,
+		{Role: domain.MessageRoleTool, ToolCallID: "c1"},
+		{Role: domain.MessageRoleTool, ToolCallID: "c2"},
+		{Role: domain.MessageRoleTool, ToolCallID: "c3"},
+		{Role: domain.MessageRoleAssistant, Text: "Done."},
+	}
+
+	summarizer.EXPECT().CreateSummary(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, req *domain.SummaryRequest) (*domain.SummaryResult, error) {
+			if len(req.Messages) != 3 {
+				t.Errorf("expected 3 summarized messages, got %d", len(req.Messages))
+			}
+			return &domain.SummaryResult{Text: "The user greeted."}, nil
+		})
+
+	compacted, err := s.compact(context.Background(), messages)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if !reflect.DeepEqual(compacted[1], call) {
+		t.Errorf("expected the tool calls to be kept with their results, got %+v", compacted[1])
+	}
+}
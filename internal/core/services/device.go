@@ -49,6 +49,7 @@ func (s *deviceService) RegisterDevice(ctx context.Context, reg domain.DeviceReg
 		UserID:           &userID,
 		OTP:              &otp,
 		EnrollmentStatus: domain.DeviceEnrollmentStateCreated,
+		TimeZone:         reg.TimeZone,
 	}
 
 	saved, err := s.deviceRepo.Save(ctx, device)
@@ -104,6 +105,16 @@ func (s *deviceService) EnrollDevice(ctx context.Context, enr domain.DeviceEnrol
 	return &res, nil
 }
 
+func (s *deviceService) GetDevice(ctx context.Context, id string) (*domain.Device, error) {
+	device, err := s.deviceRepo.Find(ctx, id)
+	if err != nil {
+		slog.Error("failed to find device", "deviceID", id)
+		return nil, fmt.Errorf("failed to find device: %w", err)
+	}
+
+	return device, nil
+}
+
 // generatePassword creates a cryptographically secure random password
 // of the given length. It uses only Go's standard library (crypto/rand),
 // so it’s safe for device OTPs, API keys, or temporary credentials.
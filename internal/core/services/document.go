@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+const (
+	// maxChunkLength is the maximum length of a document chunk in bytes.
+	// Chunks of a few paragraphs keep retrieved passages focused while
+	// carrying enough context to answer from.
+	maxChunkLength = 800
+
+	// chunkOverlap is the length of the tail of a chunk repeated at the start
+	// of the next one, so facts spanning a chunk boundary are not lost.
+	chunkOverlap = 120
+
+	// defaultRetrieveLimit is the number of passages retrieved when no limit is given.
+	defaultRetrieveLimit = 3
+)
+
+// documentService implements ports.DocumentService.
+//
+// Like the agent memory, passages are ranked in Go by cosine similarity
+// of their embeddings.
+type documentService struct {
+	repo       ports.DocumentRepo
+	extractor  ports.TextExtractor
+	embeddings ports.EmbeddingProvider
+	// minSimilarity is the cosine similarity a passage needs to be
+	// considered relevant to the prompt.
+	minSimilarity float64
+}
+
+// NewDocumentService creates a document service storing documents in repo,
+// retrieving passages with the similarity threshold suiting the model of
+// the embedding provider.
+func NewDocumentService(repo ports.DocumentRepo, extractor ports.TextExtractor, embeddings ports.EmbeddingProvider, thresholds domain.SimilarityThresholds) *documentService {
+	return &documentService{
+		repo:          repo,
+		extractor:     extractor,
+		embeddings:    embeddings,
+		minSimilarity: thresholds.Retrieve,
+	}
+}
+
+// Upload extracts, chunks, embeds and stores a document.
+func (s *documentService) Upload(ctx context.Context, upload domain.DocumentUpload) (*domain.Document, error) {
+	text, err := s.extractor.ExtractText(ctx, upload.ContentType, upload.Content)
+	if err != nil {
+		slog.Error("Failed to extract document text", "error", err, "contentType", upload.ContentType)
+		return nil, fmt.Errorf("failed to extract document text: %w", err)
+	}
+
+	texts := chunkText(text, maxChunkLength, chunkOverlap)
+	if len(texts) == 0 {
+		return nil, domain.ErrEmptyDocument
+	}
+
+	chunks := make([]domain.DocumentChunk, 0, len(texts))
+	for i, t := range texts {
+		res, err := s.embeddings.CreateEmbedding(ctx, domain.EmbeddingRequest{Data: t})
+		if err != nil {
+			slog.Error("Failed to create embedding", "error", err)
+			return nil, fmt.Errorf("failed to create embedding: %w", err)
+		}
+		chunks = append(chunks, domain.DocumentChunk{
+			UserID:    upload.UserID,
+			Index:     i,
+			Text:      t,
+			Embedding: res.Embedding,
+		})
+	}
+
+	doc, err := s.repo.Save(ctx, domain.Document{
+		UserID:      upload.UserID,
+		Title:       upload.Title,
+		ContentType: upload.ContentType,
+		Chunks:      len(chunks),
+	}, chunks)
+	if err != nil {
+		slog.Error("Failed to save document", "error", err, "userId", upload.UserID)
+		return nil, fmt.Errorf("failed to save document: %w", err)
+	}
+	slog.Info("Document uploaded", "userId", upload.UserID, "documentId", doc.ID, "chunks", len(chunks))
+
+	return doc, nil
+}
+
+// List returns all documents of the user.
+func (s *documentService) List(ctx context.Context, userID string) ([]domain.Document, error) {
+	docs, err := s.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to find documents", "error", err, "userId", userID)
+		return nil, fmt.Errorf("failed to find documents: %w", err)
+	}
+	return docs, nil
+}
+
+// Delete removes a document of the user.
+func (s *documentService) Delete(ctx context.Context, userID, documentID string) error {
+	doc, err := s.repo.Find(ctx, documentID)
+	if err != nil {
+		if errors.Is(err, domain.ErrDocumentNotFound) {
+			return err
+		}
+		slog.Error("Failed to find document", "error", err, "documentId", documentID)
+		return fmt.Errorf("failed to find document: %w", err)
+	}
+	if doc.UserID != userID {
+		return domain.ErrDocumentNotFound
+	}
+
+	if err := s.repo.Delete(ctx, documentID); err != nil {
+		slog.Error("Failed to delete document", "error", err, "documentId", documentID)
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	slog.Info("Document deleted", "userId", userID, "documentId", documentID)
+
+	return nil
+}
+
+// scoredChunk is a document chunk together with its similarity to a query.
+type scoredChunk struct {
+	chunk      domain.DocumentChunk
+	similarity float64
+}
+
+// Retrieve returns the passages of the user's documents most similar to text.
+func (s *documentService) Retrieve(ctx context.Context, userID, text string, limit int) ([]domain.DocumentReference, error) {
+	if limit <= 0 {
+		limit = defaultRetrieveLimit
+	}
+
+	chunks, err := s.repo.FindChunksByUserID(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to find document chunks", "error", err, "userId", userID)
+		return nil, fmt.Errorf("failed to find document chunks: %w", err)
+	}
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+
+	query, err := s.embeddings.CreateEmbedding(ctx, domain.EmbeddingRequest{Data: text})
+	if err != nil {
+		slog.Error("Failed to create embedding", "error", err)
+		return nil, fmt.Errorf("failed to create embedding: %w", err)
+	}
+
+	var ranked []scoredChunk
+	for _, c := range chunks {
+		if sim := cosineSimilarity(query.Embedding, c.Embedding); sim >= s.minSimilarity {
+			ranked = append(ranked, scoredChunk{chunk: c, similarity: sim})
+		}
+	}
+	if len(ranked) == 0 {
+		return nil, nil
+	}
+	slices.SortFunc(ranked, func(a, b scoredChunk) int {
+		switch {
+		case a.similarity > b.similarity:
+			return -1
+		case a.similarity < b.similarity:
+			return 1
+		}
+		return 0
+	})
+	ranked = ranked[:min(limit, len(ranked))]
+
+	docs, err := s.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to find documents", "error", err, "userId", userID)
+		return nil, fmt.Errorf("failed to find documents: %w", err)
+	}
+	titles := make(map[string]string, len(docs))
+	for _, d := range docs {
+		titles[d.ID] = d.Title
+	}
+
+	refs := make([]domain.DocumentReference, 0, len(ranked))
+	for _, r := range ranked {
+		refs = append(refs, domain.DocumentReference{
+			DocumentID: r.chunk.DocumentID,
+			Title:      titles[r.chunk.DocumentID],
+			Text:       r.chunk.Text,
+		})
+	}
+	return refs, nil
+}
+
+// chunkText splits text into chunks of at most maxLen bytes on word
+// boundaries, keeping paragraph breaks. Consecutive chunks share up to
+// overlap bytes.
+func chunkText(text string, maxLen, overlap int) []string {
+	var (
+		chunks  []string
+		current strings.Builder
+		// pending reports whether current holds more than the overlap
+		pending bool
+	)
+
+	flush := func() {
+		chunk := strings.TrimSpace(current.String())
+		current.Reset()
+		pending = false
+		if chunk == "" {
+			return
+		}
+		chunks = append(chunks, chunk)
+		if tail := overlapTail(chunk, overlap); tail != "" {
+			current.WriteString(tail)
+			current.WriteString(" ")
+		}
+	}
+
+	add := func(word string) {
+		if current.Len()+len(word) > maxLen {
+			flush()
+		}
+		current.WriteString(word)
+		pending = true
+	}
+
+	// oversized words (e.g. URLs or tables without spaces) are split hard,
+	// so that they fit into a chunk after the overlap
+	maxWord := max(maxLen-overlap-1, 1)
+	for _, word := range chunkWords(text) {
+		for len(word) > maxWord {
+			cut := runeBoundary(word, maxWord)
+			add(word[:cut])
+			word = word[cut:]
+		}
+		add(word)
+	}
+	if pending {
+		flush()
+	}
+
+	return chunks
+}
+
+// runeBoundary returns the largest index of s up to n, but at least the
+// length of the first rune, which does not cut a UTF-8 sequence.
+func runeBoundary(s string, n int) int {
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	if cut == 0 {
+		_, cut = utf8.DecodeRuneInString(s)
+	}
+	return cut
+}
+
+// chunkWords splits text into words keeping their trailing separator, and
+// turning runs of blank lines into a single paragraph break.
+func chunkWords(text string) []string {
+	var words []string
+	for i, para := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		fields := strings.Fields(para)
+		if len(fields) == 0 {
+			continue
+		}
+		if i > 0 && len(words) > 0 {
+			words[len(words)-1] = strings.TrimRight(words[len(words)-1], " ") + "\n\n"
+		}
+		for _, f := range fields {
+			words = append(words, f+" ")
+		}
+	}
+	return words
+}
+
+// overlapTail returns the trailing whole words of chunk, at most n bytes long.
+func overlapTail(chunk string, n int) string {
+	if n <= 0 || len(chunk) <= n {
+		return ""
+	}
+	tail := chunk[len(chunk)-n:]
+	if i := strings.IndexAny(tail, " \n"); i >= 0 {
+		return strings.TrimSpace(tail[i:])
+	}
+	return ""
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"go.uber.org/mock/gomock"
+)
+
+func TestChunkText(t *testing.T) {
+	testCases := []struct {
+		name    string
+		text    string
+		maxLen  int
+		overlap int
+		chunks  []string
+	}{
+		{
+			name:   "short text",
+			text:   "Descale the coffee machine every two months.",
+			maxLen: 100,
+			chunks: []string{"Descale the coffee machine every two months."},
+		},
+		{
+			name:   "paragraphs are kept",
+			text:   "First paragraph.\n\n\nSecond paragraph.",
+			maxLen: 100,
+			chunks: []string{"First paragraph.\n\nSecond paragraph."},
+		},
+		{
+			name:    "split on words with overlap",
+			text:    "one two three four five six seven",
+			maxLen:  16,
+			overlap: 6,
+			chunks:  []string{"one two three", "three four five", "five six seven"},
+		},
+		{
+			name:   "oversized word",
+			text:   strings.Repeat("x", 25),
+			maxLen: 10,
+			chunks: []string{strings.Repeat("x", 9), strings.Repeat("x", 9), strings.Repeat("x", 7)},
+		},
+		{
+			name:   "oversized word split on runes",
+			text:   strings.Repeat("日本", 5),
+			maxLen: 8,
+			chunks: []string{"日本", "日本", "日本", "日本", "日本"},
+		},
+		{
+			name:   "blank text",
+			text:   " \n\n ",
+			maxLen: 10,
+			chunks: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			chunks := chunkText(tc.text, tc.maxLen, tc.overlap)
+			if !reflect.DeepEqual(chunks, tc.chunks) {
+				t.Errorf("expected chunks %q, got %q", tc.chunks, chunks)
+			}
+		})
+	}
+}
+
+func TestDocumentServiceRetrieve(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := ports.NewMockDocumentRepo(ctrl)
+	embeddings := ports.NewMockEmbeddingProvider(ctrl)
+
+	vectors := map[string][]float64{
+		"How often should I descale?": {1, 0.1, 0},
+		"Descale every two months.":   {0.98, 0.1, 0},
+		"Use filtered water.":         {0.8, 0.6, 0},
+		"The WiFi password is pi.":    {0, 0, 1},
+	}
+	embeddings.EXPECT().CreateEmbedding(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, req domain.EmbeddingRequest) (*domain.EmbeddingResult, error) {
+			return &domain.EmbeddingResult{Embedding: vectors[req.Data]}, nil
+		})
+
+	repo.EXPECT().FindChunksByUserID(gomock.Any(), "u1").Return([]domain.DocumentChunk{
+		{DocumentID: "d1", Text: "Use filtered water.", Embedding: vectors["Use filtered water."]},
+		{DocumentID: "d1", Text: "Descale every two months.", Embedding: vectors["Descale every two months."]},
+		{DocumentID: "d2", Text: "The WiFi password is pi.", Embedding: vectors["The WiFi password is pi."]},
+	}, nil)
+	repo.EXPECT().FindByUserID(gomock.Any(), "u1").Return([]domain.Document{
+		{ID: "d1", Title: "Coffee machine manual"},
+		{ID: "d2", Title: "House rules"},
+	}, nil)
+
+	s := NewDocumentService(repo, ports.NewMockTextExtractor(ctrl), embeddings, testThresholds)
+
+	refs, err := s.Retrieve(context.Background(), "u1", "How often should I descale?", 0)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	expected := []domain.DocumentReference{
+		{DocumentID: "d1", Title: "Coffee machine manual", Text: "Descale every two months."},
+		{DocumentID: "d1", Title: "Coffee machine manual", Text: "Use filtered water."},
+	}
+	if !reflect.DeepEqual(refs, expected) {
+		t.Errorf("expected references %+v, got %+v", expected, refs)
+	}
+}
+
+func TestSentenceCitations(t *testing.T) {
+	refs := []domain.DocumentReference{
+		{DocumentID: "d1", Title: "Coffee machine manual"},
+		{DocumentID: "d2", Title: "House rules"},
+	}
+
+	testCases := []struct {
+		name      string
+		sentence  string
+		text      string
+		citations []domain.Citation
+	}{
+		{
+			name:     "no citation",
+			sentence: "Hello there.",
+			text:     "Hello there.",
+		},
+		{
+			name:      "single citation",
+			sentence:  "Descale it every two months [1].",
+			text:      "Descale it every two months.",
+			citations: []domain.Citation{{DocumentID: "d1", Title: "Coffee machine manual"}},
+		},
+		{
+			name:     "multiple and repeated citations",
+			sentence: "Use filtered water [2, 1] and descale regularly [1].",
+			text:     "Use filtered water and descale regularly.",
+			citations: []domain.Citation{
+				{DocumentID: "d2", Title: "House rules"},
+				{DocumentID: "d1", Title: "Coffee machine manual"},
+			},
+		},
+		{
+			name:     "unknown reference",
+			sentence: "See the manual [7].",
+			text:     "See the manual.",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			citations := sentenceCitations(tc.sentence, refs)
+			if !reflect.DeepEqual(citations, tc.citations) {
+				t.Errorf("expected citations %+v, got %+v", tc.citations, citations)
+			}
+			if text := stripCitations(tc.sentence); text != tc.text {
+				t.Errorf("expected text %q, got %q", tc.text, text)
+			}
+		})
+	}
+}
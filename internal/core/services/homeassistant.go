@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+// homeAssistantService implements ports.HomeAssistantService.
+type homeAssistantService struct {
+	repo   ports.HomeAssistantConfigRepo
+	client ports.HomeAssistantClient
+}
+
+// NewHomeAssistantService creates a Home Assistant service looking up the
+// instance of each user in repo.
+func NewHomeAssistantService(repo ports.HomeAssistantConfigRepo, client ports.HomeAssistantClient) *homeAssistantService {
+	return &homeAssistantService{
+		repo:   repo,
+		client: client,
+	}
+}
+
+// Configure validates and stores the instance of the user.
+func (s *homeAssistantService) Configure(ctx context.Context, config domain.HomeAssistantConfig) error {
+	u, err := url.Parse(config.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("URL %q is not an http(s) URL: %w", config.BaseURL, domain.ErrInvalidHomeAssistantConfig)
+	}
+	if config.Token == "" {
+		return fmt.Errorf("token is required: %w", domain.ErrInvalidHomeAssistantConfig)
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+
+	if err := s.repo.Save(ctx, config); err != nil {
+		slog.Error("Failed to save home assistant config", "error", err, "userId", config.UserID)
+		return fmt.Errorf("failed to save home assistant config: %w", err)
+	}
+	slog.Info("Home assistant configured", "userId", config.UserID, "url", config.BaseURL)
+
+	return nil
+}
+
+// Config returns the instance configured by the user.
+func (s *homeAssistantService) Config(ctx context.Context, userID string) (*domain.HomeAssistantConfig, error) {
+	config, err := s.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrHomeAssistantNotConfigured) {
+			return nil, err
+		}
+		slog.Error("Failed to find home assistant config", "error", err, "userId", userID)
+		return nil, fmt.Errorf("failed to find home assistant config: %w", err)
+	}
+	return config, nil
+}
+
+// Remove deletes the configuration of the user.
+func (s *homeAssistantService) Remove(ctx context.Context, userID string) error {
+	if err := s.repo.Delete(ctx, userID); err != nil {
+		slog.Error("Failed to delete home assistant config", "error", err, "userId", userID)
+		return fmt.Errorf("failed to delete home assistant config: %w", err)
+	}
+	return nil
+}
+
+// Entities returns the entities of the user's instance in entityDomain, or all if empty.
+func (s *homeAssistantService) Entities(ctx context.Context, userID, entityDomain string) ([]domain.HomeAssistantEntity, error) {
+	config, err := s.Config(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	entities, err := s.client.States(ctx, *config)
+	if err != nil {
+		return nil, err
+	}
+	if entityDomain == "" {
+		return entities, nil
+	}
+
+	var filtered []domain.HomeAssistantEntity
+	for _, e := range entities {
+		if strings.HasPrefix(e.EntityID, entityDomain+".") {
+			filtered = append(filtered, e)
+		}
+	}
+	return filtered, nil
+}
+
+// State returns the current state of an entity of the user's instance.
+func (s *homeAssistantService) State(ctx context.Context, userID, entityID string) (*domain.HomeAssistantEntity, error) {
+	config, err := s.Config(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.client.State(ctx, *config, entityID)
+}
+
+// CallService calls a service of the user's instance.
+func (s *homeAssistantService) CallService(ctx context.Context, userID string, call domain.HomeAssistantServiceCall) ([]domain.HomeAssistantEntity, error) {
+	config, err := s.Config(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Calling home assistant service", "userId", userID, "domain", call.Domain, "service", call.Service)
+	return s.client.CallService(ctx, *config, call)
+}
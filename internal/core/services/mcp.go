@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+// mcpAccessService implements ports.MCPAccessService.
+type mcpAccessService struct {
+	repo    ports.MCPAccessRepo
+	servers []string
+}
+
+// NewMCPAccessService creates a service storing the MCP servers and tools
+// enabled by users in repo. Only the given servers may be enabled.
+func NewMCPAccessService(repo ports.MCPAccessRepo, servers []string) *mcpAccessService {
+	return &mcpAccessService{
+		repo:    repo,
+		servers: servers,
+	}
+}
+
+// Servers returns the names of the configured MCP servers.
+func (s *mcpAccessService) Servers() []string {
+	return s.servers
+}
+
+// Grant validates and stores the enabled servers and tools of the user.
+func (s *mcpAccessService) Grant(ctx context.Context, access domain.MCPAccess) error {
+	for _, g := range access.Grants {
+		if !slices.Contains(s.servers, g.Server) {
+			return fmt.Errorf("server %q: %w", g.Server, domain.ErrUnknownMCPServer)
+		}
+	}
+
+	if err := s.repo.Save(ctx, access); err != nil {
+		slog.Error("Failed to save mcp access", "error", err, "userId", access.UserID)
+		return fmt.Errorf("failed to save mcp access: %w", err)
+	}
+	slog.Info("MCP access granted", "userId", access.UserID, "grants", len(access.Grants))
+
+	return nil
+}
+
+// Access returns the enabled servers and tools of the user.
+func (s *mcpAccessService) Access(ctx context.Context, userID string) (*domain.MCPAccess, error) {
+	access, err := s.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to find mcp access", "error", err, "userId", userID)
+		return nil, fmt.Errorf("failed to find mcp access: %w", err)
+	}
+	return access, nil
+}
+
+// Allowed reports whether the user has enabled the tool; it is not if the
+// access cannot be looked up.
+func (s *mcpAccessService) Allowed(ctx context.Context, userID, server, tool string) bool {
+	access, err := s.Access(ctx, userID)
+	if err != nil {
+		return false
+	}
+	return access.Allows(server, tool)
+}
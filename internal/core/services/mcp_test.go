@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMCPAccessServiceGrant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := ports.NewMockMCPAccessRepo(ctrl)
+	s := NewMCPAccessService(repo, []string{"search", "weather"})
+
+	access := domain.MCPAccess{UserID: "u1", Grants: []domain.MCPGrant{{Server: "calendar"}}}
+	if err := s.Grant(context.Background(), access); !errors.Is(err, domain.ErrUnknownMCPServer) {
+		t.Errorf("expected unknown server, got %v", err)
+	}
+
+	access.Grants = []domain.MCPGrant{{Server: "weather"}, {Server: "search", Tools: []string{"web_search"}}}
+	repo.EXPECT().Save(gomock.Any(), access).Return(nil)
+	if err := s.Grant(context.Background(), access); err != nil {
+		t.Errorf("expected error to be nil got %v", err)
+	}
+}
+
+func TestMCPAccessServiceAllowed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := ports.NewMockMCPAccessRepo(ctrl)
+	s := NewMCPAccessService(repo, []string{"search", "weather"})
+
+	repo.EXPECT().FindByUserID(gomock.Any(), "u1").Return(&domain.MCPAccess{
+		UserID: "u1",
+		Grants: []domain.MCPGrant{{Server: "weather"}, {Server: "search", Tools: []string{"web_search"}}},
+	}, nil).AnyTimes()
+	repo.EXPECT().FindByUserID(gomock.Any(), "u2").Return(nil, errors.New("db down"))
+
+	testCases := []struct {
+		userID, server, tool string
+		allowed              bool
+	}{
+		{"u1", "weather", "get_forecast", true},
+		{"u1", "search", "web_search", true},
+		{"u1", "search", "image_search", false},
+		{"u1", "calendar", "list_events", false},
+		{"u2", "weather", "get_forecast", false},
+	}
+
+	for _, tc := range testCases {
+		if allowed := s.Allowed(context.Background(), tc.userID, tc.server, tc.tool); allowed != tc.allowed {
+			t.Errorf("expected %s/%s allowed for %s to be %v", tc.server, tc.tool, tc.userID, tc.allowed)
+		}
+	}
+}
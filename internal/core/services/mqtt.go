@@ -0,0 +1,163 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+const (
+	// mqttResubscribeInterval is the delay before subscribing again after
+	// the connection to the broker was lost.
+	mqttResubscribeInterval = 15 * time.Second
+
+	// maxAnnouncementLength bounds the spoken text of a message, in characters.
+	maxAnnouncementLength = 300
+
+	// announcementQueueSize bounds the messages waiting to be announced;
+	// further messages are dropped until the queue drains.
+	announcementQueueSize = 16
+)
+
+// mqttAnnouncer speaks the messages of MQTT topics on devices, e.g. "someone
+// is at the door" on the kitchen device when the doorbell topic is published.
+type mqttAnnouncer struct {
+	client        ports.MQTTClient
+	notifier      ports.DeviceNotifier
+	speech        ports.SpeechProvider
+	announcements []domain.MQTTAnnouncement
+}
+
+// NewMQTTAnnouncer creates an announcer notifying the devices of the
+// announcements of messages on their topics, spoken by the speech provider.
+func NewMQTTAnnouncer(client ports.MQTTClient, notifier ports.DeviceNotifier, speech ports.SpeechProvider, announcements []domain.MQTTAnnouncement) *mqttAnnouncer {
+	return &mqttAnnouncer{
+		client:        client,
+		notifier:      notifier,
+		speech:        speech,
+		announcements: announcements,
+	}
+}
+
+// Run subscribes to the announced topics and announces their messages until
+// the context is canceled, subscribing again whenever the connection is lost.
+func (a *mqttAnnouncer) Run(ctx context.Context) error {
+	if len(a.announcements) == 0 {
+		return nil
+	}
+
+	filters := make([]string, 0, len(a.announcements))
+	for _, an := range a.announcements {
+		filters = append(filters, an.TopicFilter)
+	}
+
+	queue := make(chan domain.MQTTMessage, announcementQueueSize)
+	go func() {
+		for msg := range queue {
+			a.announce(ctx, msg)
+		}
+	}()
+	defer close(queue)
+
+	enqueue := func(msg domain.MQTTMessage) {
+		select {
+		case queue <- msg:
+		default:
+			slog.Warn("Announcements queue full, dropping MQTT message", "topic", msg.Topic)
+		}
+	}
+
+	for {
+		err := a.client.Subscribe(ctx, filters, enqueue)
+		if ctx.Err() != nil {
+			return nil
+		}
+		slog.Warn("MQTT subscription ended, subscribing again", "error", err, "retryIn", mqttResubscribeInterval)
+
+		t := time.NewTimer(mqttResubscribeInterval)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil
+		case <-t.C:
+		}
+	}
+}
+
+// announce notifies the devices of all announcements matching the message.
+func (a *mqttAnnouncer) announce(ctx context.Context, msg domain.MQTTMessage) {
+	// retained messages are old news
+	if msg.Retain {
+		return
+	}
+
+	text := announcementText(msg.Payload)
+	if text == "" {
+		return
+	}
+
+	var deviceIDs []string
+	for _, an := range a.announcements {
+		if domain.MatchMQTTTopic(an.TopicFilter, msg.Topic) {
+			deviceIDs = append(deviceIDs, an.DeviceIDs...)
+		}
+	}
+	if len(deviceIDs) == 0 {
+		return
+	}
+	slog.Info("Announcing MQTT message", "topic", msg.Topic, "text", text, "devices", deviceIDs)
+
+	audio, format, err := synthesize(ctx, a.speech, text)
+	if err != nil {
+		slog.Error("Failed to produce announcement speech", "error", err)
+	}
+
+	notified := make(map[string]bool, len(deviceIDs))
+	for _, deviceID := range deviceIDs {
+		if notified[deviceID] {
+			continue
+		}
+		notified[deviceID] = true
+
+		notification := domain.DeviceNotification{Kind: domain.NotificationKindAnnouncement, Text: text}
+		if audio != nil {
+			notification.Audio = bytes.NewReader(audio)
+			notification.Format = format
+		}
+		if err := a.notifier.Notify(ctx, deviceID, notification); err != nil {
+			slog.Warn("Failed to deliver announcement", "error", err, "deviceId", deviceID)
+		}
+	}
+}
+
+// announcementText returns the text to speak for a message payload: the
+// "text" or "message" field of a JSON object, or else the payload itself.
+func announcementText(payload []byte) string {
+	var fields struct {
+		Text    string `json:"text"`
+		Message string `json:"message"`
+	}
+	text := string(payload)
+	if json.Unmarshal(payload, &fields) == nil {
+		text = fields.Text
+		if text == "" {
+			text = fields.Message
+		}
+	}
+
+	text = strings.TrimSpace(text)
+	if !utf8.ValidString(text) {
+		return ""
+	}
+	if r := []rune(text); len(r) > maxAnnouncementLength {
+		text = string(r[:maxAnnouncementLength])
+	}
+	return text
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"go.uber.org/mock/gomock"
+)
+
+func TestMQTTAnnouncerAnnounce(t *testing.T) {
+	announcements := []domain.MQTTAnnouncement{
+		{TopicFilter: "home/+/doorbell", DeviceIDs: []string{"kitchen", "office"}},
+		{TopicFilter: "home/#", DeviceIDs: []string{"kitchen"}},
+	}
+
+	testCases := []struct {
+		name     string
+		msg      domain.MQTTMessage
+		devices  []string
+		expected string
+	}{
+		{
+			name:     "plain text",
+			msg:      domain.MQTTMessage{Topic: "home/front/doorbell", Payload: []byte("Someone is at the door")},
+			devices:  []string{"kitchen", "office"},
+			expected: "Someone is at the door",
+		},
+		{
+			name:     "JSON message",
+			msg:      domain.MQTTMessage{Topic: "home/washer", Payload: []byte(`{"message":"The washer is done","code":3}`)},
+			devices:  []string{"kitchen"},
+			expected: "The washer is done",
+		},
+		{
+			name: "JSON without text",
+			msg:  domain.MQTTMessage{Topic: "home/washer", Payload: []byte(`{"code":3}`)},
+		},
+		{
+			name: "retained",
+			msg:  domain.MQTTMessage{Topic: "home/front/doorbell", Payload: []byte("ring"), Retain: true},
+		},
+		{
+			name: "not announced",
+			msg:  domain.MQTTMessage{Topic: "garden/sprinkler", Payload: []byte("on")},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			notifier := ports.NewMockDeviceNotifier(ctrl)
+			speech := ports.NewMockSpeechProvider(ctrl)
+
+			if len(tc.devices) > 0 {
+				speech.EXPECT().ProduceSpeechAudio(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, req *domain.SpeechRequest) (<-chan *domain.SpeechResult, error) {
+						ch := make(chan *domain.SpeechResult, 1)
+						ch <- &domain.SpeechResult{Audio: bytes.NewReader([]byte(req.Text))}
+						close(ch)
+						return ch, nil
+					})
+			}
+			for _, deviceID := range tc.devices {
+				notifier.EXPECT().Notify(gomock.Any(), deviceID, gomock.Any()).
+					DoAndReturn(func(ctx context.Context, deviceID string, n domain.DeviceNotification) error {
+						audio, _ := io.ReadAll(n.Audio)
+						if n.Kind != domain.NotificationKindAnnouncement || n.Text != tc.expected || string(audio) != tc.expected {
+							t.Errorf("unexpected %s notification %q with audio %q", n.Kind, n.Text, audio)
+						}
+						return nil
+					})
+			}
+
+			a := NewMQTTAnnouncer(ports.NewMockMQTTClient(ctrl), notifier, speech, announcements)
+			a.announce(context.Background(), tc.msg)
+		})
+	}
+}
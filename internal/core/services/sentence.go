@@ -0,0 +1,96 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// sentenceTerminators end a sentence when followed by whitespace.
+	sentenceTerminators = ".!?…"
+
+	// sentenceClosers may trail a terminator (e.g. a closing quote) and
+	// still belong to the same sentence.
+	sentenceClosers = `"')]»”’`
+
+	// minSentenceLength is the minimal length (in bytes) of a chunk. Shorter
+	// fragments such as "Hi!" or "Dr." are merged into the following sentence,
+	// which avoids choppy speech and a TTS request per word.
+	minSentenceLength = 12
+)
+
+// sentenceChunker splits streamed completion text into sentences, so each
+// sentence can be synthesized as soon as the model has finished it.
+type sentenceChunker struct {
+	buf       string
+	minLength int
+}
+
+// newSentenceChunker creates a chunker using minSentenceLength.
+func newSentenceChunker() *sentenceChunker {
+	return &sentenceChunker{minLength: minSentenceLength}
+}
+
+// Push appends streamed text and returns all sentences completed by it.
+func (c *sentenceChunker) Push(text string) []string {
+	c.buf += text
+
+	var sentences []string
+	for {
+		end := sentenceEnd(c.buf, c.minLength)
+		if end < 0 {
+			return sentences
+		}
+
+		if s := strings.TrimSpace(c.buf[:end]); s != "" {
+			sentences = append(sentences, s)
+		}
+		c.buf = strings.TrimLeftFunc(c.buf[end:], unicode.IsSpace)
+	}
+}
+
+// Flush returns the remaining buffered text once the stream has ended.
+func (c *sentenceChunker) Flush() string {
+	s := strings.TrimSpace(c.buf)
+	c.buf = ""
+	return s
+}
+
+// sentenceEnd returns the byte offset just after the first sentence boundary
+// at or beyond minLength, or -1 if text contains no complete sentence yet.
+//
+// A boundary is a line break, or a terminator (plus optional closers) that
+// is followed by whitespace. Requiring the whitespace means "3.14" or a
+// terminator at the very end of the buffer never split prematurely.
+func sentenceEnd(text string, minLength int) int {
+	for i, r := range text {
+		if i+utf8.RuneLen(r) < minLength {
+			continue
+		}
+
+		if r == '\n' {
+			return i + 1
+		}
+		if !strings.ContainsRune(sentenceTerminators, r) {
+			continue
+		}
+
+		j := i + utf8.RuneLen(r)
+		for j < len(text) {
+			next, size := utf8.DecodeRuneInString(text[j:])
+			if !strings.ContainsRune(sentenceTerminators+sentenceClosers, next) {
+				break
+			}
+			j += size
+		}
+		if j >= len(text) {
+			return -1
+		}
+
+		if next, _ := utf8.DecodeRuneInString(text[j:]); unicode.IsSpace(next) {
+			return j
+		}
+	}
+	return -1
+}
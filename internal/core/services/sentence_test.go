@@ -0,0 +1,62 @@
+package services
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSentenceChunker(t *testing.T) {
+	testCases := []struct {
+		name      string
+		deltas    []string
+		sentences []string
+		rest      string
+	}{
+		{
+			name:      "sentences split across deltas",
+			deltas:    []string{"The weather is", " sunny today. It will", " rain tomorrow! Take an umbrella."},
+			sentences: []string{"The weather is sunny today.", "It will rain tomorrow!"},
+			rest:      "Take an umbrella.",
+		},
+		{
+			name:      "short fragments are merged",
+			deltas:    []string{"Hi! Sure. ", "Here is the answer. "},
+			sentences: []string{"Hi! Sure. Here is the answer."},
+		},
+		{
+			name:      "decimal numbers do not split",
+			deltas:    []string{"Pi is roughly 3.14 and that is enough", "."},
+			sentences: nil,
+			rest:      "Pi is roughly 3.14 and that is enough.",
+		},
+		{
+			name:      "closing quotes and line breaks",
+			deltas:    []string{`He said "see you later." `, "The first item\nSecond item"},
+			sentences: []string{`He said "see you later."`, "The first item"},
+			rest:      "Second item",
+		},
+		{
+			name:      "ellipsis and repeated terminators",
+			deltas:    []string{"Well, let me think… ", "Really?! Yes, really. "},
+			sentences: []string{"Well, let me think…", "Really?! Yes, really."},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := newSentenceChunker()
+
+			var sentences []string
+			for _, d := range tc.deltas {
+				sentences = append(sentences, c.Push(d)...)
+			}
+
+			if !slices.Equal(sentences, tc.sentences) {
+				t.Errorf("expected sentences %q, got %q", tc.sentences, sentences)
+			}
+			if rest := c.Flush(); rest != tc.rest {
+				t.Errorf("expected rest %q, got %q", tc.rest, rest)
+			}
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+// synthesize speaks the text into a single audio buffer, e.g. for
+// notifications pushed to a device as a whole, and returns its format.
+func synthesize(ctx context.Context, speech ports.SpeechProvider, text string) ([]byte, domain.AudioFormat, error) {
+	speechCh, err := speech.ProduceSpeechAudio(ctx, &domain.SpeechRequest{Text: text})
+	if err != nil {
+		return nil, domain.AudioFormat{}, err
+	}
+
+	var (
+		buf    bytes.Buffer
+		format domain.AudioFormat
+	)
+	for res := range speechCh {
+		format = res.Format
+		if _, err := io.Copy(&buf, res.Audio); err != nil {
+			return nil, domain.AudioFormat{}, err
+		}
+	}
+	return buf.Bytes(), format, nil
+}
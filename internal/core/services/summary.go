@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+// summaryInstruction asks the model for a compact summary that can replace
+// the summarized messages in the context window.
+const summaryInstruction = "Summarize the following conversation between a user and a voice assistant " +
+	"in a few sentences. Keep names, facts, preferences, decisions and open questions " +
+	"that may matter for the rest of the conversation. Answer with the summary only.\n\n"
+
+// completionSummarizer implements ports.SummaryProvider on top of any
+// ports.CompletionProvider (e.g. OpenAI or a local model).
+type completionSummarizer struct {
+	completion ports.CompletionProvider
+}
+
+// NewCompletionSummarizer creates a summarizer that uses the given completion provider.
+func NewCompletionSummarizer(cmpl ports.CompletionProvider) *completionSummarizer {
+	return &completionSummarizer{
+		completion: cmpl,
+	}
+}
+
+// CreateSummary summarizes the given messages in a single completion request.
+func (s *completionSummarizer) CreateSummary(ctx context.Context, req *domain.SummaryRequest) (*domain.SummaryResult, error) {
+	var prompt strings.Builder
+	prompt.WriteString(summaryInstruction)
+	for _, m := range req.Messages {
+		fmt.Fprintf(&prompt, "%s: %s\n", m.Role, m.Text)
+	}
+
+	res, err := s.completion.CreateCompletion(ctx, &domain.CompletionRequest{
+		Prompt: prompt.String(),
+	})
+	if err != nil {
+		slog.Error("Failed to create summary", "error", err)
+		return nil, fmt.Errorf("failed to create summary: %w", err)
+	}
+
+	return &domain.SummaryResult{
+		Text: strings.TrimSpace(res.Text),
+	}, nil
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+const (
+	// maxTimerWait bounds how long the scheduler sleeps, as a safety net in
+	// case a change of the timers was not signaled.
+	maxTimerWait = time.Minute
+
+	// timerRetryInterval is the delay before notifying a device again after
+	// a fired timer could not be delivered.
+	timerRetryInterval = 15 * time.Second
+
+	// missedTimerGrace is how long delivery of a fired timer is retried, e.g.
+	// while the device reconnects. Older timers are dropped.
+	missedTimerGrace = 10 * time.Minute
+)
+
+// timerService implements ports.TimerService.
+//
+// Timers are persisted in the repository, so they survive restarts; Run
+// fires them in order, waking up early whenever timers are set or canceled.
+//
+// Due timers are not claimed before they fire, so only one backend
+// instance may run the timers, the one holding the voice sessions of the
+// devices; further instances would ring the devices again.
+type timerService struct {
+	repo     ports.TimerRepo
+	notifier ports.DeviceNotifier
+	speech   ports.SpeechProvider
+
+	// wake signals Run that the next timer may have changed
+	wake chan struct{}
+
+	now func() time.Time
+}
+
+// NewTimerService creates a timer service storing timers in repo and
+// notifying devices of fired timers, with the label spoken by the speech provider.
+func NewTimerService(repo ports.TimerRepo, notifier ports.DeviceNotifier, speech ports.SpeechProvider) *timerService {
+	return &timerService{
+		repo:     repo,
+		notifier: notifier,
+		speech:   speech,
+		wake:     make(chan struct{}, 1),
+		now:      time.Now,
+	}
+}
+
+// Set creates a countdown timer or an alarm. The device must be reachable,
+// as the timer would not ring otherwise.
+func (s *timerService) Set(ctx context.Context, req domain.TimerRequest) (*domain.Timer, error) {
+	if !s.notifier.Reachable(req.DeviceID) {
+		return nil, fmt.Errorf("the device cannot ring timers as it keeps no voice session open to the backend: %w", domain.ErrDeviceNotConnected)
+	}
+	now := s.now()
+
+	timer := domain.Timer{
+		DeviceID:  req.DeviceID,
+		UserID:    req.UserID,
+		Label:     req.Label,
+		CreatedAt: now,
+		TimeZone:  req.TimeZone,
+	}
+	switch {
+	case req.Duration > 0:
+		timer.Kind = domain.TimerKindTimer
+		timer.FiresAt = now.Add(req.Duration)
+	case req.At.After(now):
+		timer.Kind = domain.TimerKindAlarm
+		timer.FiresAt = req.At
+	default:
+		return nil, fmt.Errorf("timer needs a duration or a time in the future: %w", domain.ErrInvalidTimer)
+	}
+
+	saved, err := s.repo.Save(ctx, timer)
+	if err != nil {
+		slog.Error("Failed to save timer", "error", err, "userId", req.UserID)
+		return nil, fmt.Errorf("failed to save timer: %w", err)
+	}
+	slog.Info("Timer set", "timerId", saved.ID, "kind", saved.Kind, "firesAt", saved.FiresAt)
+
+	s.reschedule()
+	return saved, nil
+}
+
+// List returns the pending timers of the user.
+func (s *timerService) List(ctx context.Context, userID string) ([]domain.Timer, error) {
+	timers, err := s.repo.FindByUserID(ctx, userID)
+	if err != nil {
+		slog.Error("Failed to find timers", "error", err, "userId", userID)
+		return nil, fmt.Errorf("failed to find timers: %w", err)
+	}
+	return timers, nil
+}
+
+// Cancel deletes a pending timer of the user.
+func (s *timerService) Cancel(ctx context.Context, userID, timerID string) error {
+	timers, err := s.List(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range timers {
+		if t.ID != timerID {
+			continue
+		}
+		if err := s.repo.Delete(ctx, timerID); err != nil {
+			slog.Error("Failed to delete timer", "error", err, "timerId", timerID)
+			return fmt.Errorf("failed to delete timer: %w", err)
+		}
+		slog.Info("Timer canceled", "timerId", timerID)
+
+		s.reschedule()
+		return nil
+	}
+
+	return domain.ErrTimerNotFound
+}
+
+// Run fires due timers until the context is canceled.
+func (s *timerService) Run(ctx context.Context) error {
+	for {
+		wait := s.fireDue(ctx)
+
+		next, err := s.repo.FindNext(ctx)
+		switch {
+		case err == nil:
+			if d := next.FiresAt.Sub(s.now()); d > 0 && d < wait {
+				wait = d
+			}
+		case !errors.Is(err, domain.ErrTimerNotFound):
+			slog.Error("Failed to find next timer", "error", err)
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return nil
+		case <-s.wake:
+			t.Stop()
+		case <-t.C:
+		}
+	}
+}
+
+// fireDue notifies the devices of all due timers and returns how long to
+// wait at most before checking again.
+func (s *timerService) fireDue(ctx context.Context) time.Duration {
+	now := s.now()
+
+	due, err := s.repo.FindDue(ctx, now)
+	if err != nil {
+		slog.Error("Failed to find due timers", "error", err)
+		return timerRetryInterval
+	}
+
+	wait := maxTimerWait
+	for _, t := range due {
+		err := s.fire(ctx, t)
+		if err != nil && now.Sub(t.FiresAt) < missedTimerGrace {
+			slog.Warn("Failed to deliver timer, retrying", "error", err, "timerId", t.ID, "deviceId", t.DeviceID)
+			wait = timerRetryInterval
+			continue
+		}
+		if err != nil {
+			slog.Error("Failed to deliver timer, dropping it", "error", err, "timerId", t.ID, "deviceId", t.DeviceID)
+		}
+
+		if err := s.repo.Delete(ctx, t.ID); err != nil {
+			slog.Error("Failed to delete fired timer", "error", err, "timerId", t.ID)
+		}
+	}
+
+	return wait
+}
+
+// fire notifies the device of a due timer.
+func (s *timerService) fire(ctx context.Context, t domain.Timer) error {
+	text := timerMessage(t)
+	slog.Info("Timer fired", "timerId", t.ID, "deviceId", t.DeviceID, "text", text)
+
+	notification := domain.DeviceNotification{
+		Kind: string(t.Kind),
+		Text: text,
+	}
+	// the device still plays its alert sound if the label cannot be spoken
+	if audio, format, err := synthesize(ctx, s.speech, text); err != nil {
+		slog.Error("Failed to produce timer speech", "error", err)
+	} else {
+		notification.Audio = bytes.NewReader(audio)
+		notification.Format = format
+	}
+
+	return s.notifier.Notify(ctx, t.DeviceID, notification)
+}
+
+// reschedule wakes up Run to reconsider the next timer.
+func (s *timerService) reschedule() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// timerMessage returns the sentence announcing a fired timer.
+func timerMessage(t domain.Timer) string {
+	if t.Kind == domain.TimerKindAlarm {
+		at := t.FiresAt.In(t.Location()).Format("15:04")
+		if t.Label != "" {
+			return fmt.Sprintf("It's %s. %s.", at, t.Label)
+		}
+		return fmt.Sprintf("It's %s, this is your alarm.", at)
+	}
+
+	if t.Label != "" {
+		return fmt.Sprintf("Your %s timer is done.", t.Label)
+	}
+	return fmt.Sprintf("Your timer for %s is done.", formatDuration(t.FiresAt.Sub(t.CreatedAt)))
+}
+
+// formatDuration formats a duration the way it is spoken,
+// e.g. "1 hour 5 minutes" or "30 seconds".
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < time.Second {
+		return "0 seconds"
+	}
+
+	var parts []string
+	for _, unit := range []struct {
+		d    time.Duration
+		name string
+	}{
+		{time.Hour, "hour"},
+		{time.Minute, "minute"},
+		{time.Second, "second"},
+	} {
+		n := int(d / unit.d)
+		d -= time.Duration(n) * unit.d
+		switch {
+		case n == 1:
+			parts = append(parts, "1 "+unit.name)
+		case n > 1:
+			parts = append(parts, fmt.Sprintf("%d %ss", n, unit.name))
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"go.uber.org/mock/gomock"
+)
+
+func TestTimerServiceSet(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name        string
+		req         domain.TimerRequest
+		unreachable bool
+		kind        domain.TimerKind
+		firesAt     time.Time
+		err         error
+	}{
+		{
+			name:    "countdown timer",
+			req:     domain.TimerRequest{Duration: 10 * time.Minute, Label: "pasta"},
+			kind:    domain.TimerKindTimer,
+			firesAt: now.Add(10 * time.Minute),
+		},
+		{
+			name:    "alarm",
+			req:     domain.TimerRequest{At: now.Add(8 * time.Hour)},
+			kind:    domain.TimerKindAlarm,
+			firesAt: now.Add(8 * time.Hour),
+		},
+		{
+			name: "alarm in the past",
+			req:  domain.TimerRequest{At: now.Add(-time.Minute)},
+			err:  domain.ErrInvalidTimer,
+		},
+		{
+			name: "neither duration nor time",
+			req:  domain.TimerRequest{},
+			err:  domain.ErrInvalidTimer,
+		},
+		{
+			name:        "device without voice session",
+			req:         domain.TimerRequest{Duration: 10 * time.Minute},
+			unreachable: true,
+			err:         domain.ErrDeviceNotConnected,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := ports.NewMockTimerRepo(ctrl)
+			if tc.err == nil {
+				repo.EXPECT().Save(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, timer domain.Timer) (*domain.Timer, error) {
+						timer.ID = "t1"
+						return &timer, nil
+					})
+			}
+
+			notifier := ports.NewMockDeviceNotifier(ctrl)
+			notifier.EXPECT().Reachable(gomock.Any()).Return(!tc.unreachable)
+
+			s := NewTimerService(repo, notifier, ports.NewMockSpeechProvider(ctrl))
+			s.now = func() time.Time { return now }
+
+			timer, err := s.Set(context.Background(), tc.req)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("expected error %v, got %v", tc.err, err)
+			}
+			if tc.err != nil {
+				return
+			}
+			if timer.Kind != tc.kind || !timer.FiresAt.Equal(tc.firesAt) {
+				t.Errorf("expected %s firing at %v, got %s firing at %v", tc.kind, tc.firesAt, timer.Kind, timer.FiresAt)
+			}
+		})
+	}
+}
+
+func TestTimerServiceFireDue(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name      string
+		firesAt   time.Time
+		notifyErr error
+		deleted   bool
+		wait      time.Duration
+	}{
+		{
+			name:    "delivered",
+			firesAt: now,
+			deleted: true,
+			wait:    maxTimerWait,
+		},
+		{
+			name:      "device offline, retried",
+			firesAt:   now.Add(-time.Minute),
+			notifyErr: domain.ErrDeviceNotConnected,
+			wait:      timerRetryInterval,
+		},
+		{
+			name:      "device offline too long, dropped",
+			firesAt:   now.Add(-missedTimerGrace),
+			notifyErr: domain.ErrDeviceNotConnected,
+			deleted:   true,
+			wait:      maxTimerWait,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			repo := ports.NewMockTimerRepo(ctrl)
+			notifier := ports.NewMockDeviceNotifier(ctrl)
+			speech := ports.NewMockSpeechProvider(ctrl)
+
+			timer := domain.Timer{ID: "t1", DeviceID: "d1", Kind: domain.TimerKindTimer, Label: "pasta", FiresAt: tc.firesAt}
+			repo.EXPECT().FindDue(gomock.Any(), now).Return([]domain.Timer{timer}, nil)
+			speech.EXPECT().ProduceSpeechAudio(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, req *domain.SpeechRequest) (<-chan *domain.SpeechResult, error) {
+					ch := make(chan *domain.SpeechResult, 1)
+					ch <- &domain.SpeechResult{Audio: bytes.NewReader([]byte(req.Text))}
+					close(ch)
+					return ch, nil
+				})
+			notifier.EXPECT().Notify(gomock.Any(), "d1", gomock.Any()).
+				DoAndReturn(func(ctx context.Context, deviceID string, n domain.DeviceNotification) error {
+					audio, _ := io.ReadAll(n.Audio)
+					if n.Text != "Your pasta timer is done." || string(audio) != n.Text {
+						t.Errorf("unexpected notification %q with audio %q", n.Text, audio)
+					}
+					return tc.notifyErr
+				})
+			if tc.deleted {
+				repo.EXPECT().Delete(gomock.Any(), "t1").Return(nil)
+			}
+
+			s := NewTimerService(repo, notifier, speech)
+			s.now = func() time.Time { return now }
+
+			if wait := s.fireDue(context.Background()); wait != tc.wait {
+				t.Errorf("expected wait %v, got %v", tc.wait, wait)
+			}
+		})
+	}
+}
+
+func TestTimerMessageTimeZone(t *testing.T) {
+	firesAt := time.Date(2026, 10, 17, 5, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		timeZone string
+		text     string
+	}{
+		{timeZone: "Europe/Berlin", text: "It's 07:00, this is your alarm."},
+		{timeZone: "UTC", text: "It's 05:00, this is your alarm."},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.timeZone, func(t *testing.T) {
+			text := timerMessage(domain.Timer{Kind: domain.TimerKindAlarm, FiresAt: firesAt, TimeZone: tc.timeZone})
+			if text != tc.text {
+				t.Errorf("expected %q, got %q", tc.text, text)
+			}
+		})
+	}
+}
@@ -2,8 +2,15 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/ownerofglory/raspi-agent/internal/core/domain"
 	"github.com/ownerofglory/raspi-agent/internal/core/ports"
@@ -22,6 +29,10 @@ type voiceAssistant struct {
 	transcription ports.TranscriptionProvider
 	speech        ports.SpeechProvider
 	completion    ports.CompletionProvider
+	conversations ports.ConversationService
+	memory        ports.AgentMemory
+	documents     ports.DocumentService
+	tools         ports.ToolProvider
 }
 
 // NewVoiceAssistant constructs a new voiceAssistant instance.
@@ -30,34 +41,55 @@ type voiceAssistant struct {
 //   - transcription: converts user audio to text (STT engine)
 //   - completion: generates responses based on the text (LLM engine)
 //   - speech: converts response text back into speech (TTS engine)
+//   - conversations: keeps prior turns so follow-up questions have context
+//   - memory: recalls and learns long-term facts about the user
+//   - documents: retrieves passages of the user's documents to ground answers in
+//   - tools: the tools the model may call to act on behalf of the user
 //
 // This composition allows modular configuration — for example, combining
 // Whisper STT with GPT-based completion and Piper or OpenAI TTS.
-func NewVoiceAssistant(stt ports.TranscriptionProvider, tts ports.SpeechProvider, cmpl ports.CompletionProvider) *voiceAssistant {
+func NewVoiceAssistant(stt ports.TranscriptionProvider, tts ports.SpeechProvider, cmpl ports.CompletionProvider, conversations ports.ConversationService, memory ports.AgentMemory, documents ports.DocumentService, tools ports.ToolProvider) *voiceAssistant {
 	return &voiceAssistant{
 		transcription: stt,
 		speech:        tts,
 		completion:    cmpl,
+		conversations: conversations,
+		memory:        memory,
+		documents:     documents,
+		tools:         tools,
 	}
 }
 
+// speechPrefetch is the number of sentences whose speech synthesis may be
+// requested ahead of the one currently being streamed, hiding TTS latency
+// between sentences.
+const speechPrefetch = 2
+
 // Assist executes a full voice interaction flow.
 //
-// It performs the following steps sequentially:
+// It performs the following steps:
 //  1. Transcribes the input audio using the STT provider.
-//  2. Sends the transcribed text to the LLM for completion.
-//  3. Streams the synthesized speech of the LLM response.
+//  2. Streams the LLM response to the transcribed text, in the context of
+//     the ongoing conversation of the requesting device and user.
+//  3. Splits the response into sentences as they are generated and
+//     synthesizes each sentence, in order, while the model keeps generating.
+//  4. Runs the tools the model calls, in parallel, speaking their user
+//     messages meanwhile, and continues the completion with their results.
+//
+// Returns a *receive-only* channel (<-chan *VoiceAssistantResult) that first
+// yields the transcript, followed by the text of each sentence and the
+// progressively generated audio chunks, so playback of the first sentence
+// starts while the rest of the answer is still being generated.
 //
-// Returns a *receive-only* channel (<-chan *VoiceAssistantResult) that yields
-// progressively generated audio chunks, allowing immediate playback while
-// speech synthesis continues in the background.
+// Once the answer is complete, the turn is recorded in the conversation.
 //
 // The context controls cancellation and timeout across all stages.
 // If any stage fails, the function logs the error, cleans up, and closes
 // the result channel gracefully.
 func (v *voiceAssistant) Assist(ctx context.Context, req *domain.VoiceAssistantRequest) (<-chan *domain.VoiceAssistantResult, error) {
 	tr := domain.TranscribeRequest{
-		Audio: req.Audio,
+		Audio:    req.Audio,
+		Language: req.Voice.Language,
 	}
 	transcribe, err := v.transcription.Transcribe(ctx, tr)
 	if err != nil {
@@ -65,46 +97,402 @@ func (v *voiceAssistant) Assist(ctx context.Context, req *domain.VoiceAssistantR
 		return nil, fmt.Errorf("failed to transcribe: %w", err)
 	}
 
-	cr := domain.CompletionRequest{
-		Prompt: transcribe.Text,
-	}
-	completion, err := v.completion.CreateCompletion(ctx, &cr)
+	history, err := v.conversations.History(ctx, req.DeviceID, req.UserID)
 	if err != nil {
-		slog.Error("Failed to create completion", "error", err)
-		return nil, fmt.Errorf("failed to create completion: %w", err)
+		slog.Error("Failed to get conversation history", "error", err)
+		return nil, fmt.Errorf("failed to get conversation history: %w", err)
+	}
+
+	// memories and documents belong to a user, so a device no one owns
+	// answers from general knowledge only
+	var memories []string
+	var references []domain.DocumentReference
+	if req.UserID != "" {
+		// memories are a nice-to-have; answer without them if recall fails
+		recalled, err := v.memory.Recall(ctx, domain.RecallRequest{UserID: req.UserID, Text: transcribe.Text})
+		if err != nil {
+			slog.Error("Failed to recall memories", "error", err)
+		} else {
+			memories = recalled.Memories
+		}
+
+		// likewise, answer from general knowledge if retrieval fails
+		references, err = v.documents.Retrieve(ctx, req.UserID, transcribe.Text, 0)
+		if err != nil {
+			slog.Error("Failed to retrieve documents", "error", err)
+		}
 	}
 
-	sr := domain.SpeechRequest{
-		Text: completion.Text,
+	// tools act for the requesting user and device
+	caller := domain.ToolCaller{DeviceID: req.DeviceID, UserID: req.UserID, TimeZone: req.TimeZone}
+	toolCtx := domain.WithToolCaller(ctx, caller)
+
+	tools := v.tools.Tools(toolCtx)
+	cr := domain.CompletionRequest{
+		Prompt:    transcribe.Text,
+		History:   history,
+		Memories:  memories,
+		Documents: references,
+		Tools:     tools,
+		// the device listens for the reply of the user if the answer awaits one
+		MarkReplies: true,
 	}
-	speechCh, err := v.speech.ProduceSpeechAudio(ctx, &sr)
+	deltas, err := v.completion.StreamCompletion(ctx, &cr)
 	if err != nil {
-		slog.Error("Failed to produce speech", "error", err)
-		return nil, fmt.Errorf("failed to produce speech: %w", err)
+		slog.Error("Failed to create completion", "error", err)
+		return nil, fmt.Errorf("failed to create completion: %w", err)
 	}
 
 	resCh := make(chan *domain.VoiceAssistantResult)
+	speechQueue := make(chan (<-chan *domain.SpeechResult), speechPrefetch)
 
+	// sentence routine: chunk the completion and request speech per sentence,
+	// calling tools as long as the model asks for them
 	go func() {
-		defer close(resCh)
-		for {
-			select {
-			case <-ctx.Done():
+		defer close(speechQueue)
+
+		if !send(ctx, resCh, &domain.VoiceAssistantResult{Type: domain.VoiceAssistantEventTranscript, Text: transcribe.Text}) {
+			return
+		}
+
+		var (
+			answer strings.Builder
+			// roundStart is where the answer of the current round starts, as
+			// the answers of earlier rounds are kept with their tool calls
+			roundStart int
+			// markedReply reports whether the model marked the answer of the
+			// last round as awaiting a reply
+			markedReply bool
+		)
+		chunker := newSentenceChunker()
+		// failed reports whether the model could not complete its answer
+		failed := false
+		for round := 1; ; round++ {
+			roundStart = answer.Len()
+			var calls []domain.ToolCall
+			calls, markedReply, err = v.streamAnswer(ctx, deltas, chunker, &answer, references, req.Voice, resCh, speechQueue)
+			if ctx.Err() != nil {
 				return
-			case msg, ok := <-speechCh:
-				if !ok {
-					slog.Warn("Speech channel closed")
+			}
+			if err != nil {
+				failed = true
+				break
+			}
+			if len(calls) == 0 {
+				break
+			}
+
+			// speak what the model said before calling the tools first
+			if last := chunker.Flush(); last != "" {
+				if !v.speakSentence(ctx, last, references, req.Voice, resCh, speechQueue) {
 					return
 				}
+			}
 
-				r := domain.VoiceAssistantResult{
-					Audio: msg.Audio,
+			results, ok := v.callTools(toolCtx, calls, tools, req.Voice, resCh, speechQueue)
+			if !ok {
+				return
+			}
+			cr.ToolMessages = append(cr.ToolMessages, domain.Message{
+				Role:      domain.MessageRoleAssistant,
+				Text:      answer.String()[roundStart:],
+				ToolCalls: calls,
+			})
+			cr.ToolMessages = append(cr.ToolMessages, results...)
+
+			// make the model answer with what it has got after the last round
+			if round == maxToolRounds {
+				cr.Tools = nil
+			}
+			deltas, err = v.completion.StreamCompletion(ctx, &cr)
+			if err != nil {
+				slog.Error("Failed to create completion", "error", err)
+				roundStart = answer.Len()
+				failed = true
+				break
+			}
+		}
+
+		if failed {
+			markedReply = false
+			if !send(ctx, resCh, &domain.VoiceAssistantResult{Type: domain.VoiceAssistantEventError, Text: "failed to complete the answer"}) {
+				return
+			}
+			// the tools have acted already, so tell the user and keep the
+			// turn, without the unfinished answer
+			if len(cr.ToolMessages) > 0 {
+				chunker = newSentenceChunker()
+				roundStart = answer.Len()
+				answer.WriteString(toolFallbackAnswer)
+				if !v.speakSentence(ctx, toolFallbackAnswer, nil, req.Voice, resCh, speechQueue) {
+					return
 				}
+			}
+		}
+
+		if last := chunker.Flush(); last != "" {
+			v.speakSentence(ctx, last, references, req.Voice, resCh, speechQueue)
+		}
+		// models which do not mark their answers may still ask a question
+		if markedReply || (!failed && expectsReply(answer.String())) {
+			send(ctx, resCh, &domain.VoiceAssistantResult{Type: domain.VoiceAssistantEventExpectsReply})
+		}
+
+		// a turn the model failed to answer at all is not worth remembering
+		final := stripCitations(answer.String()[roundStart:])
+		if final == "" && len(cr.ToolMessages) == 0 {
+			return
+		}
+		// recording may compact the history; don't hold up the reply
+		go v.recordTurn(context.WithoutCancel(ctx), req, history, transcribe.Text, cr.ToolMessages, final)
+	}()
 
-				resCh <- &r
+	// speech routine: stream the audio of each sentence in order
+	go func() {
+		defer close(resCh)
+
+		for speechCh := range speechQueue {
+			for msg := range speechCh {
+				r := domain.VoiceAssistantResult{
+					Type:   domain.VoiceAssistantEventAudio,
+					Audio:  msg.Audio,
+					Format: msg.Format,
+				}
+				if !send(ctx, resCh, &r) {
+					drainSpeech(speechCh, speechQueue)
+					return
+				}
 			}
 		}
 	}()
 
 	return resCh, nil
 }
+
+// streamAnswer speaks the completion deltas sentence by sentence until the
+// stream ends, and returns the tool calls the model asks for, if any, and
+// whether the model marked the answer as awaiting a reply of the user.
+// It returns an error if the stream failed partway or the context has been
+// canceled; the answer then holds what was streamed so far.
+func (v *voiceAssistant) streamAnswer(ctx context.Context, deltas <-chan domain.CompletionDelta, chunker *sentenceChunker, answer *strings.Builder, references []domain.DocumentReference, voice domain.VoiceOptions, resCh chan<- *domain.VoiceAssistantResult, speechQueue chan<- (<-chan *domain.SpeechResult)) ([]domain.ToolCall, bool, error) {
+	var (
+		calls        []domain.ToolCall
+		expectsReply bool
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case delta, ok := <-deltas:
+			if !ok {
+				return calls, expectsReply, nil
+			}
+			if delta.Err != nil {
+				slog.Error("Failed to stream completion", "error", delta.Err)
+				return nil, false, fmt.Errorf("failed to stream completion: %w", delta.Err)
+			}
+
+			calls = append(calls, delta.ToolCalls...)
+			expectsReply = expectsReply || delta.ExpectsReply
+			answer.WriteString(delta.Text)
+			for _, sentence := range chunker.Push(delta.Text) {
+				if !v.speakSentence(ctx, sentence, references, voice, resCh, speechQueue) {
+					return nil, false, ctx.Err()
+				}
+			}
+		}
+	}
+}
+
+const (
+	// maxToolRounds is the number of completion rounds in which the model may
+	// call tools before it has to answer with the results it has got.
+	maxToolRounds = 5
+
+	// toolTimeout bounds the execution of a single tool call.
+	toolTimeout = 30 * time.Second
+
+	// toolFallbackAnswer is spoken if the model cannot answer after tools
+	// have been called, so the user knows the request was not ignored.
+	toolFallbackAnswer = "Sorry, I did that but couldn't finish my answer."
+)
+
+// callTools reports and runs the tool calls of a completion round in parallel,
+// speaking the tools' user messages meanwhile. The context carries the
+// domain.ToolCaller the tools act for. It returns the tool results in
+// the order of the calls, or false once the context has been canceled.
+func (v *voiceAssistant) callTools(ctx context.Context, calls []domain.ToolCall, tools []domain.ToolDefinition, voice domain.VoiceOptions, resCh chan<- *domain.VoiceAssistantResult, speechQueue chan<- (<-chan *domain.SpeechResult)) ([]domain.Message, bool) {
+	results := make([]domain.Message, len(calls))
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = v.callTool(ctx, call)
+		}()
+	}
+
+	var spoken []string
+	for _, call := range calls {
+		if !send(ctx, resCh, &domain.VoiceAssistantResult{Type: domain.VoiceAssistantEventToolCall, Text: call.Name}) {
+			break
+		}
+
+		i := slices.IndexFunc(tools, func(t domain.ToolDefinition) bool { return t.Name == call.Name })
+		if i < 0 || tools[i].UserMessage == "" || slices.Contains(spoken, tools[i].UserMessage) {
+			continue
+		}
+		spoken = append(spoken, tools[i].UserMessage)
+		if !v.speakSentence(ctx, tools[i].UserMessage, nil, voice, resCh, speechQueue) {
+			break
+		}
+	}
+
+	wg.Wait()
+	return results, ctx.Err() == nil
+}
+
+// callTool runs a single tool call and returns its result as a tool message.
+// Failures are reported to the model, which can explain them to the user.
+func (v *voiceAssistant) callTool(ctx context.Context, call domain.ToolCall) domain.Message {
+	ctx, cancel := context.WithTimeout(ctx, toolTimeout)
+	defer cancel()
+
+	msg := domain.Message{Role: domain.MessageRoleTool, ToolCallID: call.ID}
+
+	slog.Info("Calling tool", "tool", call.Name, "args", call.Arguments)
+	res, err := v.tools.ExecuteTool(ctx, call.Name, call.Arguments)
+	if err != nil {
+		slog.Error("Tool call failed", "tool", call.Name, "error", err)
+		res = map[string]string{"error": err.Error()}
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		slog.Error("Failed to marshal tool result", "tool", call.Name, "error", err)
+		data, _ = json.Marshal(map[string]string{"error": "invalid tool result"})
+	}
+	msg.Text = string(data)
+
+	return msg
+}
+
+// memoryContextMessages is the number of earlier messages passed along with a
+// turn to the agent memory, so references like "forget that" can be resolved.
+const memoryContextMessages = 2
+
+// recordTurn stores the user's prompt, the tool calls and results of the
+// turn, if any, and the assistant's final answer in the conversation and
+// lets the agent memory of the device's owner, if any, learn from the prompt
+// and answer.
+func (v *voiceAssistant) recordTurn(ctx context.Context, req *domain.VoiceAssistantRequest, history []domain.Message, prompt string, toolMessages []domain.Message, answer string) {
+	turn := []domain.Message{
+		{Role: domain.MessageRoleUser, Text: prompt},
+		{Role: domain.MessageRoleAssistant, Text: answer},
+	}
+
+	recorded := append([]domain.Message{turn[0]}, toolMessages...)
+	recorded = append(recorded, turn[1])
+	if err := v.conversations.Record(ctx, req.DeviceID, req.UserID, recorded...); err != nil {
+		slog.Error("Failed to record conversation turn", "error", err)
+	}
+
+	if req.UserID == "" {
+		return
+	}
+
+	recent := history[max(0, len(history)-memoryContextMessages):]
+	_, err := v.memory.Memorize(ctx, domain.MemorizeRequest{
+		UserID:   req.UserID,
+		Messages: append(slices.Clone(recent), turn...),
+	})
+	if err != nil {
+		slog.Error("Failed to memorize conversation turn", "error", err)
+	}
+}
+
+// speakSentence emits the sentence text along with the documents it cites
+// and queues its speech synthesis in the voice, without the citation markers.
+// It returns false once the context has been canceled.
+func (v *voiceAssistant) speakSentence(ctx context.Context, sentence string, references []domain.DocumentReference, voice domain.VoiceOptions, resCh chan<- *domain.VoiceAssistantResult, speechQueue chan<- (<-chan *domain.SpeechResult)) bool {
+	citations := sentenceCitations(sentence, references)
+	sentence = stripCitations(sentence)
+	if sentence == "" {
+		return true
+	}
+
+	r := domain.VoiceAssistantResult{Type: domain.VoiceAssistantEventText, Text: sentence, Citations: citations}
+	if !send(ctx, resCh, &r) {
+		return false
+	}
+
+	speechCh, err := v.speech.ProduceSpeechAudio(ctx, &domain.SpeechRequest{Text: sentence, Voice: voice})
+	if err != nil {
+		// skip the sentence rather than aborting the whole answer
+		slog.Error("Failed to produce speech", "error", err)
+		return ctx.Err() == nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case speechQueue <- speechCh:
+		return true
+	}
+}
+
+// citationPattern matches citation markers like [1] or [1, 3] in an answer.
+var citationPattern = regexp.MustCompile(`\s*\[(\d+(?:\s*,\s*\d+)*)\]`)
+
+// sentenceCitations returns the documents cited in the sentence, in order
+// of first citation and without duplicates.
+func sentenceCitations(sentence string, references []domain.DocumentReference) []domain.Citation {
+	var citations []domain.Citation
+	for _, m := range citationPattern.FindAllStringSubmatch(sentence, -1) {
+		for _, n := range strings.Split(m[1], ",") {
+			i, err := strconv.Atoi(strings.TrimSpace(n))
+			if err != nil || i < 1 || i > len(references) {
+				continue
+			}
+			c := domain.Citation{DocumentID: references[i-1].DocumentID, Title: references[i-1].Title}
+			if !slices.Contains(citations, c) {
+				citations = append(citations, c)
+			}
+		}
+	}
+	return citations
+}
+
+// expectsReply reports whether the answer asks the user something, i.e.
+// ends with a question, so the device may listen for the reply. It is the
+// fallback for answers the model did not mark, see
+// domain.CompletionRequest.MarkReplies.
+func expectsReply(answer string) bool {
+	answer = strings.TrimRight(stripCitations(answer), ` "'”»)`)
+	return strings.HasSuffix(answer, "?") || strings.HasSuffix(answer, "？")
+}
+
+// stripCitations removes citation markers, which are not meant to be spoken.
+func stripCitations(text string) string {
+	return strings.TrimSpace(citationPattern.ReplaceAllString(text, ""))
+}
+
+// send delivers a result unless the context is canceled first.
+func send(ctx context.Context, resCh chan<- *domain.VoiceAssistantResult, r *domain.VoiceAssistantResult) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case resCh <- r:
+		return true
+	}
+}
+
+// drainSpeech discards pending speech so that producers can terminate.
+func drainSpeech(current <-chan *domain.SpeechResult, queue <-chan (<-chan *domain.SpeechResult)) {
+	for range current {
+	}
+	for speechCh := range queue {
+		for range speechCh {
+		}
+	}
+}
@@ -0,0 +1,388 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"go.uber.org/mock/gomock"
+)
+
+func TestVoiceAssistantToolCalls(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	stt := ports.NewMockTranscriptionProvider(ctrl)
+	tts := ports.NewMockSpeechProvider(ctrl)
+	cmpl := ports.NewMockCompletionProvider(ctrl)
+	conversations := ports.NewMockConversationService(ctrl)
+	memory := ports.NewMockAgentMemory(ctrl)
+	documents := ports.NewMockDocumentService(ctrl)
+	tools := ports.NewMockToolProvider(ctrl)
+
+	stt.EXPECT().Transcribe(gomock.Any(), gomock.Any()).
+		Return(&domain.TranscribeResult{Text: "What's the weather and the time in Berlin?"}, nil)
+	conversations.EXPECT().History(gomock.Any(), "d1", "u1").Return(nil, nil)
+	memory.EXPECT().Recall(gomock.Any(), gomock.Any()).Return(&domain.RecallResult{}, nil)
+	documents.EXPECT().Retrieve(gomock.Any(), "u1", gomock.Any(), 0).Return(nil, nil)
+
+	recorded := make(chan []domain.Message, 1)
+	conversations.EXPECT().Record(gomock.Any(), "d1", "u1", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, deviceID, userID string, msgs ...domain.Message) error {
+			recorded <- msgs
+			return nil
+		})
+	memory.EXPECT().Memorize(gomock.Any(), gomock.Any()).Return(&domain.MemorizeResult{}, nil).AnyTimes()
+
+	tools.EXPECT().Tools(gomock.Any()).Return([]domain.ToolDefinition{
+		{Name: "get_weather", UserMessage: "Let me check the weather."},
+		{Name: "get_time", UserMessage: "Let me check the time."},
+		{Name: "get_forecast"},
+	})
+	tools.EXPECT().ExecuteTool(gomock.Any(), "get_weather", `{"city":"Berlin"}`).Return(map[string]string{"weather": "sunny"}, nil)
+	tools.EXPECT().ExecuteTool(gomock.Any(), "get_time", `{"city":"Berlin"}`).Return(nil, errors.New("clock unavailable"))
+
+	calls := []domain.ToolCall{
+		{ID: "c1", Name: "get_weather", Arguments: `{"city":"Berlin"}`},
+		{ID: "c2", Name: "get_time", Arguments: `{"city":"Berlin"}`},
+	}
+	gomock.InOrder(
+		cmpl.EXPECT().StreamCompletion(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, req *domain.CompletionRequest) (<-chan domain.CompletionDelta, error) {
+				if len(req.Tools) != 3 {
+					t.Errorf("expected 3 tools, got %d", len(req.Tools))
+				}
+				return deltaStream(domain.CompletionDelta{ToolCalls: calls}), nil
+			}),
+		cmpl.EXPECT().StreamCompletion(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, req *domain.CompletionRequest) (<-chan domain.CompletionDelta, error) {
+				expected := []domain.Message{
+					{Role: domain.MessageRoleAssistant, ToolCalls: calls},
+					{Role: domain.MessageRoleTool, ToolCallID: "c1", Text: `{"weather":"sunny"}`},
+					{Role: domain.MessageRoleTool, ToolCallID: "c2", Text: `{"error":"clock unavailable"}`},
+				}
+				if !slices.EqualFunc(req.ToolMessages, expected, func(a, b domain.Message) bool {
+					return a.Role == b.Role && a.Text == b.Text && a.ToolCallID == b.ToolCallID && len(a.ToolCalls) == len(b.ToolCalls)
+				}) {
+					t.Errorf("expected tool messages %+v, got %+v", expected, req.ToolMessages)
+				}
+				return deltaStream(domain.CompletionDelta{Text: "It is sunny in Berlin, "}, domain.CompletionDelta{Text: "but I can't tell the time."}), nil
+			}),
+	)
+
+	tts.EXPECT().ProduceSpeechAudio(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, req *domain.SpeechRequest) (<-chan *domain.SpeechResult, error) {
+			ch := make(chan *domain.SpeechResult, 1)
+			ch <- &domain.SpeechResult{Audio: bytes.NewReader([]byte(req.Text))}
+			close(ch)
+			return ch, nil
+		}).Times(3)
+
+	va := NewVoiceAssistant(stt, tts, cmpl, conversations, memory, documents, tools)
+	resCh, err := va.Assist(context.Background(), &domain.VoiceAssistantRequest{Audio: strings.NewReader("audio"), DeviceID: "d1", UserID: "u1"})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	var toolEvents, texts []string
+	for res := range resCh {
+		switch res.Type {
+		case domain.VoiceAssistantEventToolCall:
+			toolEvents = append(toolEvents, res.Text)
+		case domain.VoiceAssistantEventText:
+			texts = append(texts, res.Text)
+		}
+	}
+
+	if expected := []string{"get_weather", "get_time"}; !slices.Equal(toolEvents, expected) {
+		t.Errorf("expected tool call events %q, got %q", expected, toolEvents)
+	}
+	if expected := []string{"Let me check the weather.", "Let me check the time.", "It is sunny in Berlin, but I can't tell the time."}; !slices.Equal(texts, expected) {
+		t.Errorf("expected texts %q, got %q", expected, texts)
+	}
+
+	select {
+	case msgs := <-recorded:
+		roles := []domain.MessageRole{domain.MessageRoleUser, domain.MessageRoleAssistant, domain.MessageRoleTool, domain.MessageRoleTool, domain.MessageRoleAssistant}
+		if got := messageRoles(msgs); !slices.Equal(got, roles) {
+			t.Fatalf("expected recorded roles %v, got %v", roles, got)
+		}
+		if answer := msgs[4].Text; answer != "It is sunny in Berlin, but I can't tell the time." {
+			t.Errorf("expected recorded answer of the last round, got %q", answer)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the turn to be recorded")
+	}
+}
+
+func TestVoiceAssistantToolCallsCompletionFails(t *testing.T) {
+	testCases := []struct {
+		name     string
+		followUp func() (<-chan domain.CompletionDelta, error)
+	}{
+		{
+			name: "completion fails",
+			followUp: func() (<-chan domain.CompletionDelta, error) {
+				return nil, errors.New("rate limited")
+			},
+		},
+		{
+			name: "stream fails partway",
+			followUp: func() (<-chan domain.CompletionDelta, error) {
+				return deltaStream(domain.CompletionDelta{Text: "The lights"}, domain.CompletionDelta{Err: errors.New("connection reset")}), nil
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			stt := ports.NewMockTranscriptionProvider(ctrl)
+			tts := ports.NewMockSpeechProvider(ctrl)
+			cmpl := ports.NewMockCompletionProvider(ctrl)
+			conversations := ports.NewMockConversationService(ctrl)
+			memory := ports.NewMockAgentMemory(ctrl)
+			documents := ports.NewMockDocumentService(ctrl)
+			tools := ports.NewMockToolProvider(ctrl)
+
+			stt.EXPECT().Transcribe(gomock.Any(), gomock.Any()).Return(&domain.TranscribeResult{Text: "Turn off the lights."}, nil)
+			conversations.EXPECT().History(gomock.Any(), "d1", "u1").Return(nil, nil)
+			memory.EXPECT().Recall(gomock.Any(), gomock.Any()).Return(&domain.RecallResult{}, nil)
+			memory.EXPECT().Memorize(gomock.Any(), gomock.Any()).Return(&domain.MemorizeResult{}, nil).AnyTimes()
+			documents.EXPECT().Retrieve(gomock.Any(), "u1", gomock.Any(), 0).Return(nil, nil)
+
+			recorded := make(chan []domain.Message, 1)
+			conversations.EXPECT().Record(gomock.Any(), "d1", "u1", gomock.Any()).
+				DoAndReturn(func(ctx context.Context, deviceID, userID string, msgs ...domain.Message) error {
+					recorded <- msgs
+					return nil
+				})
+
+			tools.EXPECT().Tools(gomock.Any()).Return([]domain.ToolDefinition{{Name: "turn_off"}})
+			tools.EXPECT().ExecuteTool(gomock.Any(), "turn_off", `{}`).Return(map[string]string{"state": "off"}, nil)
+
+			gomock.InOrder(
+				cmpl.EXPECT().StreamCompletion(gomock.Any(), gomock.Any()).
+					Return(deltaStream(domain.CompletionDelta{ToolCalls: []domain.ToolCall{{ID: "c1", Name: "turn_off", Arguments: `{}`}}}), nil),
+				cmpl.EXPECT().StreamCompletion(gomock.Any(), gomock.Any()).Return(tc.followUp()),
+			)
+
+			tts.EXPECT().ProduceSpeechAudio(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, req *domain.SpeechRequest) (<-chan *domain.SpeechResult, error) {
+					if req.Text != toolFallbackAnswer {
+						t.Errorf("expected the fallback answer to be spoken, got %q", req.Text)
+					}
+					ch := make(chan *domain.SpeechResult, 1)
+					ch <- &domain.SpeechResult{Audio: bytes.NewReader([]byte(req.Text))}
+					close(ch)
+					return ch, nil
+				})
+
+			va := NewVoiceAssistant(stt, tts, cmpl, conversations, memory, documents, tools)
+			resCh, err := va.Assist(context.Background(), &domain.VoiceAssistantRequest{Audio: strings.NewReader("audio"), DeviceID: "d1", UserID: "u1"})
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+
+			var types []domain.VoiceAssistantEventType
+			for res := range resCh {
+				types = append(types, res.Type)
+			}
+			expected := []domain.VoiceAssistantEventType{
+				domain.VoiceAssistantEventTranscript,
+				domain.VoiceAssistantEventToolCall,
+				domain.VoiceAssistantEventError,
+				domain.VoiceAssistantEventText,
+				domain.VoiceAssistantEventAudio,
+			}
+			if !slices.Equal(types, expected) {
+				t.Errorf("expected events %v, got %v", expected, types)
+			}
+
+			select {
+			case msgs := <-recorded:
+				roles := []domain.MessageRole{domain.MessageRoleUser, domain.MessageRoleAssistant, domain.MessageRoleTool, domain.MessageRoleAssistant}
+				if got := messageRoles(msgs); !slices.Equal(got, roles) {
+					t.Fatalf("expected recorded roles %v, got %v", roles, got)
+				}
+				if msgs[3].Text != toolFallbackAnswer {
+					t.Errorf("expected the fallback answer to be recorded, got %q", msgs[3].Text)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("expected the turn to be recorded")
+			}
+		})
+	}
+}
+
+func TestVoiceAssistantUnownedDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	stt := ports.NewMockTranscriptionProvider(ctrl)
+	tts := ports.NewMockSpeechProvider(ctrl)
+	cmpl := ports.NewMockCompletionProvider(ctrl)
+	conversations := ports.NewMockConversationService(ctrl)
+	memory := ports.NewMockAgentMemory(ctrl)
+	documents := ports.NewMockDocumentService(ctrl)
+	tools := ports.NewMockToolProvider(ctrl)
+
+	// no recall, retrieval or memorizing is expected without an owner
+	stt.EXPECT().Transcribe(gomock.Any(), gomock.Any()).Return(&domain.TranscribeResult{Text: "What's the time?"}, nil)
+	conversations.EXPECT().History(gomock.Any(), "d1", "").Return(nil, nil)
+	tools.EXPECT().Tools(gomock.Any()).Return(nil)
+	cmpl.EXPECT().StreamCompletion(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, req *domain.CompletionRequest) (<-chan domain.CompletionDelta, error) {
+			if len(req.Memories) != 0 || len(req.Documents) != 0 {
+				t.Errorf("expected no memories or documents, got %v and %v", req.Memories, req.Documents)
+			}
+			return deltaStream(domain.CompletionDelta{Text: "It is noon."}), nil
+		})
+	tts.EXPECT().ProduceSpeechAudio(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, req *domain.SpeechRequest) (<-chan *domain.SpeechResult, error) {
+			ch := make(chan *domain.SpeechResult, 1)
+			ch <- &domain.SpeechResult{Audio: bytes.NewReader([]byte(req.Text))}
+			close(ch)
+			return ch, nil
+		})
+
+	recorded := make(chan []domain.Message, 1)
+	conversations.EXPECT().Record(gomock.Any(), "d1", "", gomock.Any()).
+		DoAndReturn(func(ctx context.Context, deviceID, userID string, msgs ...domain.Message) error {
+			recorded <- msgs
+			return nil
+		})
+
+	va := NewVoiceAssistant(stt, tts, cmpl, conversations, memory, documents, tools)
+	resCh, err := va.Assist(context.Background(), &domain.VoiceAssistantRequest{Audio: strings.NewReader("audio"), DeviceID: "d1"})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	for range resCh {
+	}
+
+	select {
+	case msgs := <-recorded:
+		if len(msgs) != 2 || msgs[1].Text != "It is noon." {
+			t.Errorf("expected the turn to be recorded, got %v", msgs)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the turn to be recorded")
+	}
+}
+
+func TestVoiceAssistantExpectsReply(t *testing.T) {
+	testCases := []struct {
+		name     string
+		deltas   []domain.CompletionDelta
+		expected bool
+	}{
+		{
+			name:     "marked by the model",
+			deltas:   []domain.CompletionDelta{{Text: "Tell me which room."}, {ExpectsReply: true}},
+			expected: true,
+		},
+		{
+			name:     "question not marked",
+			deltas:   []domain.CompletionDelta{{Text: "Which room do you mean?"}},
+			expected: true,
+		},
+		{
+			name:     "statement",
+			deltas:   []domain.CompletionDelta{{Text: "The lights are off."}},
+			expected: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			stt := ports.NewMockTranscriptionProvider(ctrl)
+			tts := ports.NewMockSpeechProvider(ctrl)
+			cmpl := ports.NewMockCompletionProvider(ctrl)
+			conversations := ports.NewMockConversationService(ctrl)
+			memory := ports.NewMockAgentMemory(ctrl)
+			documents := ports.NewMockDocumentService(ctrl)
+			tools := ports.NewMockToolProvider(ctrl)
+
+			stt.EXPECT().Transcribe(gomock.Any(), gomock.Any()).Return(&domain.TranscribeResult{Text: "Turn on the light."}, nil)
+			conversations.EXPECT().History(gomock.Any(), "d1", "u1").Return(nil, nil)
+			conversations.EXPECT().Record(gomock.Any(), "d1", "u1", gomock.Any()).Return(nil).AnyTimes()
+			memory.EXPECT().Recall(gomock.Any(), gomock.Any()).Return(&domain.RecallResult{}, nil)
+			memory.EXPECT().Memorize(gomock.Any(), gomock.Any()).Return(&domain.MemorizeResult{}, nil).AnyTimes()
+			documents.EXPECT().Retrieve(gomock.Any(), "u1", gomock.Any(), 0).Return(nil, nil)
+			tools.EXPECT().Tools(gomock.Any()).Return(nil)
+
+			cmpl.EXPECT().StreamCompletion(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, req *domain.CompletionRequest) (<-chan domain.CompletionDelta, error) {
+					if !req.MarkReplies {
+						t.Error("expected the model to be asked to mark answers awaiting a reply")
+					}
+					return deltaStream(tc.deltas...), nil
+				})
+			tts.EXPECT().ProduceSpeechAudio(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, req *domain.SpeechRequest) (<-chan *domain.SpeechResult, error) {
+					ch := make(chan *domain.SpeechResult, 1)
+					ch <- &domain.SpeechResult{Audio: bytes.NewReader([]byte(req.Text))}
+					close(ch)
+					return ch, nil
+				})
+
+			va := NewVoiceAssistant(stt, tts, cmpl, conversations, memory, documents, tools)
+			resCh, err := va.Assist(context.Background(), &domain.VoiceAssistantRequest{Audio: strings.NewReader("audio"), DeviceID: "d1", UserID: "u1"})
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+
+			got := false
+			for res := range resCh {
+				got = got || res.Type == domain.VoiceAssistantEventExpectsReply
+			}
+			if got != tc.expected {
+				t.Errorf("expected the reply to be expected to be %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+// messageRoles returns the roles of the messages.
+func messageRoles(msgs []domain.Message) []domain.MessageRole {
+	roles := make([]domain.MessageRole, 0, len(msgs))
+	for _, m := range msgs {
+		roles = append(roles, m.Role)
+	}
+	return roles
+}
+
+// deltaStream returns a closed channel holding the given deltas.
+func deltaStream(deltas ...domain.CompletionDelta) <-chan domain.CompletionDelta {
+	ch := make(chan domain.CompletionDelta, len(deltas))
+	for _, d := range deltas {
+		ch <- d
+	}
+	close(ch)
+	return ch
+}
+
+func TestExpectsReply(t *testing.T) {
+	testCases := []struct {
+		answer   string
+		expected bool
+	}{
+		{answer: "It is sunny in Berlin.", expected: false},
+		{answer: "Which room do you mean?", expected: true},
+		{answer: "Shall I descale it now? [1]", expected: true},
+		{answer: `Did you say "kitchen?"`, expected: true},
+		{answer: "Why? Because it is late.", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.answer, func(t *testing.T) {
+			if got := expectsReply(tc.answer); got != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
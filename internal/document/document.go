@@ -0,0 +1,68 @@
+package document
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// maxDocumentSize bounds the size of an uploaded document.
+const maxDocumentSize = 20 << 20
+
+// textExtractor implements ports.TextExtractor for plain text, Markdown
+// and text-based PDF documents.
+type textExtractor struct{}
+
+// NewTextExtractor creates a new text extractor.
+func NewTextExtractor() *textExtractor {
+	return &textExtractor{}
+}
+
+// ExtractText returns the text of the document. Markdown is kept as is,
+// since language models read it well.
+func (e *textExtractor) ExtractText(ctx context.Context, contentType string, content io.Reader) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("invalid content type %q: %w", contentType, domain.ErrUnsupportedDocumentType)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(content, maxDocumentSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read document: %w", err)
+	}
+	if len(data) > maxDocumentSize {
+		return "", fmt.Errorf("document exceeds %d bytes", maxDocumentSize)
+	}
+
+	switch mediaType {
+	case domain.DocumentTypeText, domain.DocumentTypeMarkdown, "text/x-markdown":
+		if !utf8.Valid(data) {
+			return strings.ToValidUTF8(string(data), ""), nil
+		}
+		return string(data), nil
+	case domain.DocumentTypePDF:
+		return extractPDFText(data), nil
+	default:
+		return "", fmt.Errorf("%s: %w", mediaType, domain.ErrUnsupportedDocumentType)
+	}
+}
+
+// ContentTypeByExtension returns the content type of a document file based
+// on its extension, or an empty string if it is not a supported document.
+func ContentTypeByExtension(filename string) string {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".txt", ".text":
+		return domain.DocumentTypeText
+	case ".md", ".markdown":
+		return domain.DocumentTypeMarkdown
+	case ".pdf":
+		return domain.DocumentTypePDF
+	}
+	return ""
+}
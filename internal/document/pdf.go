@@ -0,0 +1,257 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxStreamSize bounds the size of a single decompressed PDF stream.
+const maxStreamSize = 32 << 20
+
+var (
+	// streamPattern matches the dictionary of a PDF object followed by its stream.
+	streamPattern = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+
+	// textObjectPattern matches a BT ... ET text object in a content stream.
+	textObjectPattern = regexp.MustCompile(`(?s)\bBT\b(.*?)\bET\b`)
+)
+
+// extractPDFText extracts the text shown by the content streams of a PDF.
+//
+// It is a best-effort extractor without external dependencies: it inflates
+// FlateDecode (and unfiltered) streams and collects the strings of the text
+// showing operators (Tj, TJ, ' and "). Scanned PDFs and fonts with custom
+// (e.g. Identity-H) encodings yield no usable text.
+func extractPDFText(data []byte) string {
+	var out strings.Builder
+
+	for _, loc := range streamPattern.FindAllSubmatchIndex(data, -1) {
+		dict := string(data[loc[2]:loc[3]])
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			continue
+		}
+		raw := data[start : start+end]
+
+		var content []byte
+		switch {
+		case strings.Contains(dict, "/FlateDecode"):
+			zr, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				continue
+			}
+			// truncated streams still yield the text decoded so far
+			content, _ = io.ReadAll(io.LimitReader(zr, maxStreamSize))
+			zr.Close()
+		case strings.Contains(dict, "/Filter"):
+			// images and other encodings carry no text
+			continue
+		default:
+			content = raw
+		}
+
+		for _, m := range textObjectPattern.FindAllSubmatch(content, -1) {
+			if text := textObjectText(m[1]); text != "" {
+				out.WriteString(text)
+				out.WriteString("\n")
+			}
+		}
+	}
+
+	return strings.TrimSpace(out.String())
+}
+
+// textObjectText returns the text shown within a single text object.
+func textObjectText(obj []byte) string {
+	var (
+		text     strings.Builder
+		operands []string
+	)
+
+	newline := func() {
+		if text.Len() > 0 && !strings.HasSuffix(text.String(), "\n") {
+			text.WriteString("\n")
+		}
+	}
+
+	for i := 0; i < len(obj); {
+		c := obj[i]
+		switch {
+		case c == '(':
+			s, n := readLiteralString(obj[i:])
+			operands = append(operands, s)
+			i += n
+		case c == '<' && i+1 < len(obj) && obj[i+1] != '<':
+			s, n := readHexString(obj[i:])
+			operands = append(operands, s)
+			i += n
+		case c == '[':
+			// TJ array: strings interleaved with kerning adjustments
+			end := bytes.IndexByte(obj[i:], ']')
+			if end < 0 {
+				end = len(obj) - i
+			}
+			operands = append(operands, arrayText(obj[i+1:i+end]))
+			i += end + 1
+		case isPDFDelimiter(c) || unicode.IsSpace(rune(c)):
+			i++
+		default:
+			start := i
+			for i < len(obj) && !isPDFDelimiter(obj[i]) && !unicode.IsSpace(rune(obj[i])) {
+				i++
+			}
+			switch string(obj[start:i]) {
+			case "Tj", "TJ":
+				text.WriteString(strings.Join(operands, ""))
+			case "'", `"`:
+				newline()
+				text.WriteString(strings.Join(operands, ""))
+			case "T*", "Td", "TD", "Tm":
+				newline()
+			}
+			if isPDFOperator(obj[start:i]) {
+				operands = operands[:0]
+			}
+		}
+	}
+
+	return strings.TrimSpace(text.String())
+}
+
+// arrayText joins the strings of a TJ array, turning large negative
+// kerning adjustments into spaces.
+func arrayText(arr []byte) string {
+	var text strings.Builder
+	for i := 0; i < len(arr); {
+		switch c := arr[i]; {
+		case c == '(':
+			s, n := readLiteralString(arr[i:])
+			text.WriteString(s)
+			i += n
+		case c == '<':
+			s, n := readHexString(arr[i:])
+			text.WriteString(s)
+			i += n
+		case c == '-' || c == '.' || (c >= '0' && c <= '9'):
+			start := i
+			i++
+			for i < len(arr) && (arr[i] == '.' || (arr[i] >= '0' && arr[i] <= '9')) {
+				i++
+			}
+			if f, err := strconv.ParseFloat(string(arr[start:i]), 64); err == nil && f < -200 {
+				text.WriteString(" ")
+			}
+		default:
+			i++
+		}
+	}
+	return text.String()
+}
+
+// readLiteralString reads a (...) string with escapes and nested parentheses.
+// It returns the decoded string and the number of bytes consumed.
+func readLiteralString(b []byte) (string, int) {
+	var s strings.Builder
+	depth := 0
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch c {
+		case '(':
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return s.String(), i + 1
+			}
+		case '\\':
+			i++
+			if i >= len(b) {
+				return s.String(), i
+			}
+			switch e := b[i]; e {
+			case 'n':
+				s.WriteByte('\n')
+			case 'r', 't', 'b', 'f':
+				s.WriteByte(' ')
+			case '\r', '\n':
+				// line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					j := i
+					for j < len(b) && j < i+3 && b[j] >= '0' && b[j] <= '7' {
+						j++
+					}
+					v, _ := strconv.ParseUint(string(b[i:j]), 8, 8)
+					s.WriteString(latin1(byte(v)))
+					i = j - 1
+				} else {
+					s.WriteByte(e)
+				}
+			}
+			continue
+		}
+		s.WriteString(latin1(c))
+	}
+	return s.String(), len(b)
+}
+
+// readHexString reads a <...> string. It returns the decoded string and
+// the number of bytes consumed.
+func readHexString(b []byte) (string, int) {
+	end := bytes.IndexByte(b, '>')
+	if end < 0 {
+		return "", len(b)
+	}
+
+	hex := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, string(b[1:end]))
+	if len(hex)%2 == 1 {
+		hex += "0"
+	}
+
+	var s strings.Builder
+	for i := 0; i+1 < len(hex); i += 2 {
+		v, err := strconv.ParseUint(hex[i:i+2], 16, 8)
+		if err != nil {
+			break
+		}
+		s.WriteString(latin1(byte(v)))
+	}
+	return s.String(), end + 1
+}
+
+// latin1 maps a single byte of a simple font string to text, dropping
+// control characters.
+func latin1(b byte) string {
+	if b < 0x20 && b != '\n' {
+		return ""
+	}
+	return string(rune(b))
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// isPDFOperator reports whether a token is an operator (rather than a number
+// or name operand), which consumes the operands collected so far.
+func isPDFOperator(tok []byte) bool {
+	if len(tok) == 0 {
+		return false
+	}
+	c := tok[0]
+	return c != '-' && c != '+' && c != '.' && (c < '0' || c > '9')
+}
@@ -0,0 +1,70 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"testing"
+)
+
+// testPDF builds a minimal PDF with a single content stream.
+func testPDF(content string, compress bool) []byte {
+	stream := []byte(content)
+	filter := ""
+	if compress {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		_, _ = zw.Write(stream)
+		_ = zw.Close()
+		stream = buf.Bytes()
+		filter = " /Filter /FlateDecode"
+	}
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	pdf.WriteString("1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n")
+	fmt.Fprintf(&pdf, "4 0 obj << /Length %d%s >>\nstream\n", len(stream), filter)
+	pdf.Write(stream)
+	pdf.WriteString("\nendstream\nendobj\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestExtractPDFText(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		compress bool
+		text     string
+	}{
+		{
+			name:    "Tj operators",
+			content: "BT /F1 12 Tf 72 712 Td (Descale the coffee machine) Tj 0 -14 Td (every two months.) Tj ET",
+			text:    "Descale the coffee machine\nevery two months.",
+		},
+		{
+			name:     "compressed TJ array with kerning",
+			content:  "BT /F1 12 Tf [(Wi) 20 (Fi) -250 (password:) -300 (raspberry)] TJ ET",
+			compress: true,
+			text:     "WiFi password: raspberry",
+		},
+		{
+			name:    "escapes and hex strings",
+			content: `BT (Caf\351 \(open\)) Tj T* <4D6F6E> Tj ET`,
+			text:    "Café (open)\nMon",
+		},
+		{
+			name:    "no text objects",
+			content: "q 1 0 0 1 0 0 cm Q",
+			text:    "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			text := extractPDFText(testPDF(tc.content, tc.compress))
+			if text != tc.text {
+				t.Errorf("expected text %q, got %q", tc.text, text)
+			}
+		})
+	}
+}
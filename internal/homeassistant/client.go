@@ -0,0 +1,143 @@
+// Package homeassistant implements a client of the Home Assistant REST API,
+// see https://developers.home-assistant.io/docs/api/rest/.
+package homeassistant
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// requestTimeout bounds a single request, so that an unreachable instance
+// does not stall the voice assistant.
+const requestTimeout = 10 * time.Second
+
+// client implements ports.HomeAssistantClient.
+type client struct {
+	httpClient *http.Client
+}
+
+// NewClient creates a Home Assistant REST API client.
+func NewClient() *client {
+	return &client{
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// entityState is the JSON representation of an entity state.
+type entityState struct {
+	EntityID    string         `json:"entity_id"`
+	State       string         `json:"state"`
+	Attributes  map[string]any `json:"attributes"`
+	LastChanged time.Time      `json:"last_changed"`
+}
+
+// States returns the states of all entities.
+//
+// Endpoint: GET /api/states
+func (c *client) States(ctx context.Context, config domain.HomeAssistantConfig) ([]domain.HomeAssistantEntity, error) {
+	var states []entityState
+	if err := c.do(ctx, config, http.MethodGet, "/api/states", nil, &states); err != nil {
+		return nil, err
+	}
+	return toDomainEntities(states), nil
+}
+
+// State returns the state of a single entity.
+//
+// Endpoint: GET /api/states/{entityId}
+func (c *client) State(ctx context.Context, config domain.HomeAssistantConfig, entityID string) (*domain.HomeAssistantEntity, error) {
+	var state entityState
+	if err := c.do(ctx, config, http.MethodGet, "/api/states/"+url.PathEscape(entityID), nil, &state); err != nil {
+		return nil, err
+	}
+	entity := toDomainEntity(state)
+	return &entity, nil
+}
+
+// CallService calls a service and returns the entities it changed.
+//
+// Endpoint: POST /api/services/{domain}/{service}
+func (c *client) CallService(ctx context.Context, config domain.HomeAssistantConfig, call domain.HomeAssistantServiceCall) ([]domain.HomeAssistantEntity, error) {
+	data := call.Data
+	if data == nil {
+		data = map[string]any{}
+	}
+
+	var states []entityState
+	path := "/api/services/" + url.PathEscape(call.Domain) + "/" + url.PathEscape(call.Service)
+	if err := c.do(ctx, config, http.MethodPost, path, data, &states); err != nil {
+		return nil, err
+	}
+	return toDomainEntities(states), nil
+}
+
+// do sends an authenticated request with body encoded as JSON, if not nil,
+// and decodes the JSON response into resp.
+func (c *client) do(ctx context.Context, config domain.HomeAssistantConfig, method, path string, body, resp any) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal home assistant request: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, config.BaseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create home assistant request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+config.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		slog.Error("Home assistant request failed", "err", err, "path", path)
+		return fmt.Errorf("home assistant request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return domain.ErrHomeAssistantEntityNotFound
+	case res.StatusCode == http.StatusUnauthorized:
+		return fmt.Errorf("home assistant rejected the access token")
+	case res.StatusCode < 200 || res.StatusCode >= 300:
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		slog.Error("Home assistant error", "status", res.Status, "body", string(b), "path", path)
+		return fmt.Errorf("home assistant error: %s", res.Status)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(resp); err != nil {
+		return fmt.Errorf("failed to decode home assistant response: %w", err)
+	}
+	return nil
+}
+
+func toDomainEntities(states []entityState) []domain.HomeAssistantEntity {
+	entities := make([]domain.HomeAssistantEntity, 0, len(states))
+	for _, s := range states {
+		entities = append(entities, toDomainEntity(s))
+	}
+	return entities
+}
+
+func toDomainEntity(s entityState) domain.HomeAssistantEntity {
+	return domain.HomeAssistantEntity{
+		EntityID:    s.EntityID,
+		State:       s.State,
+		Attributes:  s.Attributes,
+		LastChanged: s.LastChanged,
+	}
+}
@@ -0,0 +1,109 @@
+package homeassistant
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// newStub returns a Home Assistant stub with a kitchen light and a
+// temperature sensor, accepting the token "secret".
+func newStub(t *testing.T) *httptest.Server {
+	states := map[string]map[string]any{
+		"light.kitchen": {
+			"entity_id":  "light.kitchen",
+			"state":      "off",
+			"attributes": map[string]any{"friendly_name": "Kitchen"},
+		},
+		"sensor.outside_temperature": {
+			"entity_id":  "sensor.outside_temperature",
+			"state":      "21.5",
+			"attributes": map[string]any{"unit_of_measurement": "°C"},
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/states", func(rw http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(rw).Encode([]any{states["light.kitchen"], states["sensor.outside_temperature"]})
+	})
+	mux.HandleFunc("GET /api/states/{entityId}", func(rw http.ResponseWriter, r *http.Request) {
+		state, ok := states[r.PathValue("entityId")]
+		if !ok {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(rw).Encode(state)
+	})
+	mux.HandleFunc("POST /api/services/light/turn_on", func(rw http.ResponseWriter, r *http.Request) {
+		var data map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&data); err != nil || data["entity_id"] != "light.kitchen" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		states["light.kitchen"]["state"] = "on"
+		_ = json.NewEncoder(rw).Encode([]any{states["light.kitchen"]})
+	})
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(rw, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	srv := newStub(t)
+	config := domain.HomeAssistantConfig{BaseURL: srv.URL, Token: "secret"}
+	c := NewClient()
+	ctx := context.Background()
+
+	entities, err := c.States(ctx, config)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	var names []string
+	for _, e := range entities {
+		names = append(names, e.Name())
+	}
+	if expected := []string{"Kitchen", "sensor.outside_temperature"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected entities %q, got %q", expected, names)
+	}
+
+	changed, err := c.CallService(ctx, config, domain.HomeAssistantServiceCall{
+		Domain:  "light",
+		Service: "turn_on",
+		Data:    map[string]any{"entity_id": "light.kitchen"},
+	})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if len(changed) != 1 || changed[0].State != "on" {
+		t.Errorf("expected the kitchen light to be turned on, got %+v", changed)
+	}
+
+	state, err := c.State(ctx, config, "sensor.outside_temperature")
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if state.State != "21.5" || state.Attributes["unit_of_measurement"] != "°C" {
+		t.Errorf("unexpected sensor state %+v", state)
+	}
+
+	if _, err := c.State(ctx, config, "light.garage"); !errors.Is(err, domain.ErrHomeAssistantEntityNotFound) {
+		t.Errorf("expected entity not found, got %v", err)
+	}
+
+	if _, err := c.States(ctx, domain.HomeAssistantConfig{BaseURL: srv.URL, Token: "wrong"}); err == nil {
+		t.Error("expected an error for an invalid token")
+	}
+}
@@ -1,3 +1,26 @@
 package client
 
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
 const backendBasePath = "/raspi-agent/api"
+
+// voiceQuery returns the query parameters selecting the voice and the
+// language of the replies, if configured.
+func voiceQuery(voice domain.VoiceOptions) url.Values {
+	query := url.Values{}
+	if voice.Voice != "" {
+		query.Set("voice", voice.Voice)
+	}
+	if voice.Rate > 0 {
+		query.Set("rate", strconv.FormatFloat(voice.Rate, 'f', -1, 64))
+	}
+	if voice.Language != "" {
+		query.Set("language", voice.Language)
+	}
+	return query
+}
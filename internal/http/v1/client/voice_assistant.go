@@ -5,60 +5,58 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
-	"mime/multipart"
 	"net/http"
-	"os"
+	"sync/atomic"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
 )
 
 const PostReceiveAssistanceURL string = backendBasePath + "/v1/voice-assistance"
 
+// expectsReplyTrailer is the trailer the backend sets to "true" if the
+// assistant expects the user to reply.
+const expectsReplyTrailer = "X-Expects-Reply"
+
 type voiceAssistant struct {
 	client  *http.Client
 	baseURL string
+	voice   domain.VoiceOptions
 }
 
-func NewVoiceAssistant(baseURL string) *voiceAssistant {
+// NewVoiceAssistant creates a client of the backend at baseURL asking for
+// replies spoken in the voice.
+func NewVoiceAssistant(baseURL string, voice domain.VoiceOptions) *voiceAssistant {
 	return &voiceAssistant{
 		baseURL: baseURL,
+		voice:   voice,
 		client: &http.Client{
 			Timeout: 0,
 		},
 	}
 }
 
-func (v *voiceAssistant) ReceiveVoiceAssistance(ctx context.Context, filePath string) (<-chan []byte, error) {
+// ReceiveVoiceAssistance streams the user's audio to the backend and returns
+// the assistant's spoken reply as a stream of audio chunks, in the format
+// advertised by the Content-Type of the response. Whether the assistant
+// expects a reply is told by the trailer of the response. A reply without
+// audio (204 No Content) is returned without chunks.
+//
+// The audio reader is sent as a raw `audio/wav` body using chunked transfer
+// encoding, so it can be fed directly from the microphone while the user is
+// still speaking. The request completes once the reader returns io.EOF.
+//
+// The reply is spoken in the voice, completed by the voice of the client.
+func (v *voiceAssistant) ReceiveVoiceAssistance(ctx context.Context, audio io.Reader, voice domain.VoiceOptions) (*domain.AudioStream, error) {
 	url := fmt.Sprintf("%s%s", v.baseURL, PostReceiveAssistanceURL)
-
-	file, err := os.Open(filePath)
-	if err != nil {
-		slog.Error("Error opening file", "err", err)
-		return nil, fmt.Errorf("failed to open file: %w", err)
+	if query := voiceQuery(voice.WithDefaults(v.voice)); len(query) > 0 {
+		url += "?" + query.Encode()
 	}
 
-	// prepare multipart/form-data request
-	bodyReader, bodyWriter := io.Pipe()
-	multipartWriter := multipart.NewWriter(bodyWriter)
-
-	go func() {
-		defer bodyWriter.Close()
-		defer file.Close()
-		part, err := multipartWriter.CreateFormFile("audio", filePath)
-		if err != nil {
-			slog.Error("failed to create multipart form", "err", err)
-			return
-		}
-		if _, err := io.Copy(part, file); err != nil {
-			slog.Error("failed to copy file to multipart", "err", err)
-			return
-		}
-		multipartWriter.Close()
-	}()
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bodyReader)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, audio)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
-	req.Header.Set("Content-Type", multipartWriter.FormDataContentType())
+	req.Header.Set("Content-Type", "audio/wav")
 
 	// send request
 	resp, err := v.client.Do(req)
@@ -67,13 +65,24 @@ func (v *voiceAssistant) ReceiveVoiceAssistance(ctx context.Context, filePath st
 		return nil, fmt.Errorf("failed to send audio: %w", err)
 	}
 
+	if resp.StatusCode == http.StatusNoContent {
+		resp.Body.Close()
+		slog.Info("Reply without audio")
+		expectsReply := resp.Header.Get(expectsReplyTrailer) == "true"
+		return &domain.AudioStream{ExpectsReply: func() bool { return expectsReply }}, nil
+	}
 	if resp.StatusCode != http.StatusOK {
 		slog.Error("failed to send request to voice assistant", "status", resp.Status)
 		defer resp.Body.Close()
 		return nil, fmt.Errorf("backend returned status: %s", resp.Status)
 	}
 
+	format := domain.ParseAudioFormat(resp.Header.Get("Content-Type"))
+	slog.Info("Receiving audio stream", "contentType", format)
+
 	ch := make(chan []byte)
+	// the trailer is set before ch is closed
+	var expectsReply atomic.Bool
 
 	// read streaming audio from response
 	go func() {
@@ -91,11 +100,16 @@ func (v *voiceAssistant) ReceiveVoiceAssistance(ctx context.Context, filePath st
 					// copy the slice to avoid overwriting by next read
 					copyBuf := make([]byte, n)
 					copy(copyBuf, audioBuf[:n])
-					ch <- copyBuf
+					select {
+					case <-ctx.Done():
+						return
+					case ch <- copyBuf:
+					}
 				}
 				if err != nil {
 					if err == io.EOF {
 						slog.Info("Audio stream finished")
+						expectsReply.Store(resp.Trailer.Get(expectsReplyTrailer) == "true")
 						return
 					}
 					slog.Error("failed to read audio stream", "err", err)
@@ -105,5 +119,5 @@ func (v *voiceAssistant) ReceiveVoiceAssistance(ctx context.Context, filePath st
 		}
 	}()
 
-	return ch, nil
+	return &domain.AudioStream{Format: format, Chunks: ch, ExpectsReply: expectsReply.Load}, nil
 }
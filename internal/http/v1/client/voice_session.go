@@ -0,0 +1,457 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/websocket"
+)
+
+const GetVoiceSessionURL string = backendBasePath + "/v1/voice-session"
+
+const (
+	// sessionAudioChunkSize is the size of the binary frames used to upload audio
+	// (128ms of 16kHz mono 16-bit PCM).
+	sessionAudioChunkSize = 4096
+
+	// sessionPingInterval keeps idle sessions alive through proxies.
+	sessionPingInterval = 30 * time.Second
+
+	// sessionEventBuffer is the number of backend messages of the current
+	// turn buffered for the turn. Once it is full, the session stops reading
+	// until the turn consumes them, slowing the backend down to the pace of
+	// the playback, or until the turn is over.
+	sessionEventBuffer = 64
+
+	// sessionNotificationBuffer is the number of notifications buffered
+	// while the device is busy, e.g. playing a reply.
+	sessionNotificationBuffer = 8
+
+	// sessionReconnectInterval is the delay before reopening a lost session
+	// kept open to receive notifications.
+	sessionReconnectInterval = 5 * time.Second
+)
+
+// Voice session message types, see the backend's voice session handler.
+const (
+	sessionMessageStart        = "start"
+	sessionMessageAudioEnd     = "audio_end"
+	sessionMessageCancel       = "cancel"
+	sessionMessageTranscript   = "transcript"
+	sessionMessageText         = "text"
+	sessionMessageToolCall     = "tool_call"
+	sessionMessageExpectsReply = "expects_reply"
+	sessionMessageEndOfTurn    = "end_of_turn"
+	sessionMessageError        = "error"
+	sessionMessageAudioFormat  = "audio_format"
+	sessionMessageNotification = "notification"
+)
+
+type voiceSessionMessage struct {
+	Type        string               `json:"type"`
+	Turn        uint64               `json:"turn,omitempty"`
+	Voice       *domain.VoiceOptions `json:"voice,omitempty"`
+	Kind        string               `json:"kind,omitempty"`
+	Text        string               `json:"text,omitempty"`
+	Citations   []sessionCitation    `json:"citations,omitempty"`
+	Audio       []byte               `json:"audio,omitempty"`
+	ContentType string               `json:"contentType,omitempty"`
+	Error       string               `json:"error,omitempty"`
+}
+
+// sessionCitation identifies a document a text event is based on.
+type sessionCitation struct {
+	DocumentID string `json:"documentId"`
+	Title      string `json:"title"`
+}
+
+// sessionEvent is a single message received from the backend.
+type sessionEvent struct {
+	msgType websocket.MessageType
+	data    []byte
+}
+
+// sessionTurn receives the backend messages of a turn.
+type sessionTurn struct {
+	// id is sent in the start message and echoed by the backend on the
+	// messages of the turn
+	id     uint64
+	events chan sessionEvent
+	// done is closed once the turn is over, i.e. takes no more events
+	done chan struct{}
+}
+
+// voiceSession is a ports.VoiceAssistantClient that keeps one long-lived
+// WebSocket session to the backend and runs every turn over it.
+//
+// The connection is opened lazily on the first turn and re-established
+// on the next turn after it has been lost, unless Notifications keeps it open.
+type voiceSession struct {
+	sessionURL string
+
+	mu   sync.Mutex
+	conn *websocket.Conn
+	// closed is closed once conn is lost
+	closed chan struct{}
+	// turn is the current turn, if any, turns counts the turns started
+	turn  *sessionTurn
+	turns uint64
+
+	notifications chan domain.DeviceNotification
+}
+
+// NewVoiceSession creates a WebSocket voice session client for the backend
+// at baseURL (http(s):// or ws(s)://), asking for replies spoken in the voice.
+func NewVoiceSession(baseURL string, voice domain.VoiceOptions) (*voiceSession, error) {
+	u, err := url.Parse(baseURL + GetVoiceSessionURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backend URL: %w", err)
+	}
+
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	}
+	u.RawQuery = voiceQuery(voice).Encode()
+
+	return &voiceSession{
+		sessionURL:    u.String(),
+		notifications: make(chan domain.DeviceNotification, sessionNotificationBuffer),
+	}, nil
+}
+
+// Notifications keeps the voice session open until ctx is canceled and
+// returns the notifications pushed by the backend outside of turns,
+// e.g. fired timers. Notifications arriving while the buffer is full are
+// dropped. It must be called at most once.
+func (v *voiceSession) Notifications(ctx context.Context) <-chan domain.DeviceNotification {
+	go func() {
+		for {
+			if _, closed, err := v.connect(ctx); err == nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-closed:
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(sessionReconnectInterval):
+			}
+		}
+	}()
+
+	return v.notifications
+}
+
+// ReceiveVoiceAssistance runs a single turn over the voice session.
+//
+// The audio is uploaded in binary frames as it is read; once the reader
+// returns io.EOF the end of the audio is signaled. It returns as soon as the
+// backend announces the format of the reply audio, whose chunks the returned
+// stream yields until the backend reports the end of the turn.
+// Transcript, text and tool call events are logged as they arrive; the
+// expects_reply event is reported by the stream once it is over. Messages
+// of earlier turns, e.g. of a reply interrupted by barge-in, are dropped.
+//
+// The non-zero fields of voice override the voice of the session for the
+// turn.
+//
+// If reading the audio fails (e.g. domain.ErrNoSpeechDetected), the turn is
+// canceled on the backend and the error is returned.
+func (v *voiceSession) ReceiveVoiceAssistance(ctx context.Context, audio io.Reader, voice domain.VoiceOptions) (*domain.AudioStream, error) {
+	conn, closed, err := v.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	turn := v.startTurn()
+	started := false
+	defer func() {
+		if !started {
+			v.endTurn(turn)
+		}
+	}()
+
+	start := voiceSessionMessage{Type: sessionMessageStart, Turn: turn.id}
+	if voice != (domain.VoiceOptions{}) {
+		start.Voice = &voice
+	}
+	if err := v.send(conn, start); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, sessionAudioChunkSize)
+	for {
+		n, err := audio.Read(buf)
+		if n > 0 {
+			if err := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); err != nil {
+				v.reset(conn)
+				return nil, fmt.Errorf("failed to send audio: %w", err)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			_ = v.send(conn, voiceSessionMessage{Type: sessionMessageCancel})
+			return nil, fmt.Errorf("failed to read audio: %w", err)
+		}
+	}
+
+	if err := v.send(conn, voiceSessionMessage{Type: sessionMessageAudioEnd}); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan []byte)
+	// the format of the reply audio, nil if the reply has none
+	formatCh := make(chan *domain.AudioFormat, 1)
+	// set before ch is closed
+	var expectsReply atomic.Bool
+
+	started = true
+	go func() {
+		defer close(ch)
+		defer v.endTurn(turn)
+
+		// the format is unknown if it is not announced, e.g. by an older
+		// backend; a turn ending before any audio has none
+		announced := false
+		announce := func(format *domain.AudioFormat) {
+			if !announced {
+				announced = true
+				formatCh <- format
+			}
+		}
+		defer announce(nil)
+
+		for {
+			select {
+			case <-ctx.Done():
+				_ = v.send(conn, voiceSessionMessage{Type: sessionMessageCancel})
+				return
+			case <-closed:
+				slog.Error("Voice session closed during turn")
+				return
+			case ev := <-turn.events:
+
+				if ev.msgType == websocket.BinaryMessage {
+					announce(&domain.AudioFormat{})
+					select {
+					case <-ctx.Done():
+						return
+					case ch <- ev.data:
+					}
+					continue
+				}
+
+				var msg voiceSessionMessage
+				if err := json.Unmarshal(ev.data, &msg); err != nil {
+					slog.Warn("Invalid voice session message", "err", err)
+					continue
+				}
+
+				switch msg.Type {
+				case sessionMessageAudioFormat:
+					format := domain.ParseAudioFormat(msg.ContentType)
+					announce(&format)
+				case sessionMessageTranscript:
+					slog.Info("Transcript", "text", msg.Text)
+				case sessionMessageText:
+					slog.Info("Assistant response", "text", msg.Text)
+					for _, c := range msg.Citations {
+						slog.Info("Assistant cites document", "title", c.Title, "documentId", c.DocumentID)
+					}
+				case sessionMessageToolCall:
+					slog.Info("Assistant calls tool", "tool", msg.Text)
+				case sessionMessageExpectsReply:
+					expectsReply.Store(true)
+				case sessionMessageError:
+					slog.Error("Voice session turn failed", "error", msg.Error)
+				case sessionMessageEndOfTurn:
+					slog.Info("Audio stream finished")
+					return
+				default:
+					slog.Debug("Unknown voice session message", "type", msg.Type)
+				}
+			}
+		}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case format := <-formatCh:
+		if format == nil {
+			slog.Info("Reply without audio")
+			return &domain.AudioStream{ExpectsReply: expectsReply.Load}, nil
+		}
+		return &domain.AudioStream{Format: *format, Chunks: ch, ExpectsReply: expectsReply.Load}, nil
+	}
+}
+
+// startTurn makes a new turn the current one, whose events the session
+// forwards from now on.
+func (v *voiceSession) startTurn() *sessionTurn {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.turns++
+	v.turn = &sessionTurn{
+		id:     v.turns,
+		events: make(chan sessionEvent, sessionEventBuffer),
+		done:   make(chan struct{}),
+	}
+	return v.turn
+}
+
+// endTurn ends the turn, dropping its events from now on.
+func (v *voiceSession) endTurn(turn *sessionTurn) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.turn == turn {
+		v.turn = nil
+	}
+	close(turn.done)
+}
+
+// connect returns the current session connection, dialing a new one if
+// needed, and the channel closed once it is lost.
+func (v *voiceSession) connect(ctx context.Context) (*websocket.Conn, <-chan struct{}, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.conn != nil {
+		return v.conn, v.closed, nil
+	}
+
+	conn, err := websocket.Dial(ctx, v.sessionURL, nil)
+	if err != nil {
+		slog.Error("failed to open voice session", "err", err)
+		return nil, nil, fmt.Errorf("failed to open voice session: %w", err)
+	}
+	slog.Info("Voice session opened", "url", v.sessionURL)
+
+	closed := make(chan struct{})
+	go v.readLoop(conn, closed)
+
+	v.conn = conn
+	v.closed = closed
+	return conn, closed, nil
+}
+
+// readLoop forwards backend messages to the current turn, or the
+// notifications, and keeps the connection alive with pings until it is
+// closed.
+func (v *voiceSession) readLoop(conn *websocket.Conn, closed chan<- struct{}) {
+	defer close(closed)
+	defer v.reset(conn)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(sessionPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if err := conn.Ping(); err != nil {
+					slog.Warn("Voice session ping failed", "err", err)
+					return
+				}
+			}
+		}
+	}()
+
+	// audio frames belong to the turn of the preceding message
+	var turnID uint64
+	for {
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			slog.Info("Voice session closed", "err", err)
+			return
+		}
+
+		if msgType == websocket.TextMessage {
+			var msg voiceSessionMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				slog.Warn("Invalid voice session message", "err", err)
+				continue
+			}
+			if msg.Type == sessionMessageNotification {
+				v.notify(msg)
+				continue
+			}
+			turnID = msg.Turn
+		}
+
+		v.mu.Lock()
+		turn := v.turn
+		v.mu.Unlock()
+		if turn == nil || turn.id != turnID {
+			slog.Debug("Dropping voice session message of an earlier turn", "turn", turnID)
+			continue
+		}
+		select {
+		case turn.events <- sessionEvent{msgType: msgType, data: data}:
+		case <-turn.done:
+		}
+	}
+}
+
+// notify forwards the notification message to the notifications.
+func (v *voiceSession) notify(msg voiceSessionMessage) {
+	notification := domain.DeviceNotification{Kind: msg.Kind, Text: msg.Text}
+	if len(msg.Audio) > 0 {
+		notification.Audio = bytes.NewReader(msg.Audio)
+		notification.Format = domain.ParseAudioFormat(msg.ContentType)
+	}
+
+	select {
+	case v.notifications <- notification:
+		slog.Info("Notification received", "kind", msg.Kind, "text", msg.Text)
+	default:
+		slog.Warn("Notification dropped", "kind", msg.Kind, "text", msg.Text)
+	}
+}
+
+// reset drops conn so that the next turn reconnects.
+func (v *voiceSession) reset(conn *websocket.Conn) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.conn == conn {
+		conn.Close()
+		v.conn = nil
+		v.closed = nil
+	}
+}
+
+func (v *voiceSession) send(conn *websocket.Conn, msg voiceSessionMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+		v.reset(conn)
+		return fmt.Errorf("failed to send session message: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/websocket"
+)
+
+// newSessionStub returns a backend replying to every turn with the audio
+// of its turn ID, after replaying the reply of the previous turn as a late
+// reply of a turn canceled by barge-in.
+func newSessionStub(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(rw, r)
+		if err != nil {
+			t.Errorf("expected error to be nil got %v", err)
+			return
+		}
+		defer conn.Close()
+
+		reply := func(turn uint64, audio string) {
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"type":"audio_format","turn":%d,"contentType":"audio/wav"}`, turn)))
+			_ = conn.WriteMessage(websocket.BinaryMessage, []byte(audio))
+			_ = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf(`{"type":"end_of_turn","turn":%d}`, turn)))
+		}
+
+		var turn uint64
+		for {
+			msgType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if msgType != websocket.TextMessage {
+				continue
+			}
+			var msg voiceSessionMessage
+			_ = json.Unmarshal(data, &msg)
+			switch msg.Type {
+			case sessionMessageStart:
+				turn = msg.Turn
+			case sessionMessageAudioEnd:
+				if turn > 1 {
+					reply(turn-1, "late")
+				}
+				reply(turn, fmt.Sprintf("turn %d", turn))
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVoiceSessionDropsEarlierTurns(t *testing.T) {
+	srv := newSessionStub(t)
+	v, err := NewVoiceSession(srv.URL, domain.VoiceOptions{})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, expected := range []string{"turn 1", "turn 2"} {
+		stream, err := v.ReceiveVoiceAssistance(ctx, strings.NewReader("audio"), domain.VoiceOptions{})
+		if err != nil {
+			t.Fatalf("expected error to be nil got %v", err)
+		}
+
+		var audio []string
+		for chunk := range stream.Chunks {
+			audio = append(audio, string(chunk))
+		}
+		if len(audio) != 1 || audio[0] != expected {
+			t.Errorf("expected audio %q, got %q", expected, audio)
+		}
+	}
+}
+
+func TestVoiceSessionReplyWithoutAudio(t *testing.T) {
+	// the backend ends the turn without audio, e.g. as its reply failed
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(rw, r)
+		if err != nil {
+			t.Errorf("expected error to be nil got %v", err)
+			return
+		}
+		defer conn.Close()
+
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg voiceSessionMessage
+			_ = json.Unmarshal(data, &msg)
+			if msg.Type == sessionMessageAudioEnd {
+				_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"expects_reply","turn":1}`))
+				_ = conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"end_of_turn","turn":1}`))
+			}
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	v, err := NewVoiceSession(srv.URL, domain.VoiceOptions{})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stream, err := v.ReceiveVoiceAssistance(ctx, strings.NewReader("audio"), domain.VoiceOptions{})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if stream.Chunks != nil {
+		t.Errorf("expected a reply without audio")
+	}
+	if !stream.ExpectsReply() {
+		t.Errorf("expected the assistant to expect a reply")
+	}
+}
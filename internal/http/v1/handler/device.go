@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/ownerofglory/raspi-agent/internal/core/domain"
 	"github.com/ownerofglory/raspi-agent/internal/core/ports"
@@ -23,6 +24,10 @@ const (
 type deviceRegistrationReq struct {
 	// Name is the human-readable name for the device (e.g., “Raspberry Pi Living Room”).
 	Name string `json:"name"`
+
+	// TimeZone is the IANA time zone of the device (e.g., "Europe/Berlin"),
+	// in which alarms are set and announced. Empty uses the time zone of the backend.
+	TimeZone string `json:"timeZone"`
 }
 
 // deviceRegisterResp defines the JSON response returned after a device is registered.
@@ -67,7 +72,8 @@ func NewDeviceHandler(service ports.DeviceService) *deviceHandler {
 // Expected JSON body:
 //
 //	{
-//	  "name": "Raspberry Pi 5"
+//	  "name": "Raspberry Pi 5",
+//	  "timeZone": "Europe/Berlin"
 //	}
 //
 // Response 200 OK:
@@ -94,10 +100,17 @@ func (d *deviceHandler) HandlePostRegisterDevice(rw http.ResponseWriter, r *http
 		rw.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	if req.TimeZone != "" {
+		if _, err := time.LoadLocation(req.TimeZone); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
 
 	device, err := d.service.RegisterDevice(r.Context(), domain.DeviceRegistration{
-		UserID: userID,
-		Name:   req.Name,
+		UserID:   userID,
+		Name:     req.Name,
+		TimeZone: req.TimeZone,
 	})
 	if err != nil {
 		rw.WriteHeader(http.StatusInternalServerError)
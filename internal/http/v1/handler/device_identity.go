@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	appAuth "github.com/ownerofglory/raspi-agent/internal/auth"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+// requestDevice returns the identity of the device authenticated by its
+// certificate as a request of the device: the ID of the device, the ID of
+// the user owning it and the time zone of the device.
+//
+// The identity is empty if the request was not authenticated by a device
+// certificate.
+func requestDevice(ctx context.Context, devices ports.DeviceService) (domain.VoiceAssistantRequest, error) {
+	deviceID, ok := ctx.Value(appAuth.DeviceKey).(string)
+	if !ok || deviceID == "" {
+		return domain.VoiceAssistantRequest{}, nil
+	}
+
+	device, err := devices.GetDevice(ctx, deviceID)
+	if err != nil {
+		return domain.VoiceAssistantRequest{}, fmt.Errorf("unable to find device %s: %w", deviceID, err)
+	}
+
+	identity := domain.VoiceAssistantRequest{DeviceID: deviceID, TimeZone: device.TimeZone}
+	if device.UserID != nil {
+		identity.UserID = *device.UserID
+	}
+	return identity, nil
+}
@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"github.com/ownerofglory/raspi-agent/internal/document"
+)
+
+const (
+	// DocumentsPath is the backend API path for uploading (POST) and
+	// listing (GET) the documents of a user.
+	DocumentsPath = baseManagementPath + "/v1/users/{userId}/documents"
+
+	// DeleteDocumentPath is the backend API path for deleting a document of a user.
+	DeleteDocumentPath = baseManagementPath + "/v1/users/{userId}/documents/{documentId}"
+)
+
+// maxUploadMemory is the part of a multipart upload kept in memory;
+// the rest is buffered in temporary files.
+const maxUploadMemory = 8 << 20
+
+// documentResp defines the JSON representation of a document.
+type documentResp struct {
+	DocumentID  string    `json:"documentId"`
+	Title       string    `json:"title"`
+	ContentType string    `json:"contentType"`
+	Chunks      int       `json:"chunks"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// documentHandler handles the document management HTTP requests.
+type documentHandler struct {
+	service ports.DocumentService
+}
+
+// NewDocumentHandler returns a new instance of documentHandler.
+func NewDocumentHandler(service ports.DocumentService) *documentHandler {
+	return &documentHandler{service: service}
+}
+
+// HandlePostDocument uploads a document for a user.
+//
+// Endpoint: POST /v1/users/{userId}/documents
+//
+// The document is either sent as a multipart form with a "file" part and an
+// optional "title" field, or as the raw request body with its Content-Type
+// and the title in the "title" query parameter. Supported types are
+// text/plain, text/markdown and application/pdf.
+//
+// Response 201 Created:
+//
+//	{
+//	  "documentId": "1234-abcd",
+//	  "title": "Coffee machine manual",
+//	  "contentType": "application/pdf",
+//	  "chunks": 12,
+//	  "createdAt": "2025-01-01T12:00:00Z"
+//	}
+func (h *documentHandler) HandlePostDocument(rw http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userId")
+	defer r.Body.Close()
+
+	upload := domain.DocumentUpload{
+		UserID:      userID,
+		Title:       r.URL.Query().Get("title"),
+		ContentType: r.Header.Get("Content-Type"),
+		Content:     r.Body,
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(upload.ContentType); mediaType == "multipart/form-data" {
+		if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		upload.Content = file
+		upload.ContentType = header.Header.Get("Content-Type")
+		if upload.ContentType == "" || upload.ContentType == "application/octet-stream" {
+			upload.ContentType = document.ContentTypeByExtension(header.Filename)
+		}
+		upload.Title = r.FormValue("title")
+		if upload.Title == "" {
+			upload.Title = header.Filename
+		}
+	}
+
+	doc, err := h.service.Upload(r.Context(), upload)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrUnsupportedDocumentType):
+			rw.WriteHeader(http.StatusUnsupportedMediaType)
+		case errors.Is(err, domain.ErrEmptyDocument):
+			rw.WriteHeader(http.StatusUnprocessableEntity)
+		default:
+			rw.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	respBody, err := json.Marshal(toDocumentResp(*doc))
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusCreated)
+	_, _ = rw.Write(respBody)
+}
+
+// HandleGetDocuments lists the documents of a user.
+//
+// Endpoint: GET /v1/users/{userId}/documents
+//
+// Response 200 OK: a JSON array of documents as returned on upload.
+func (h *documentHandler) HandleGetDocuments(rw http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userId")
+
+	docs, err := h.service.List(r.Context(), userID)
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp := make([]documentResp, 0, len(docs))
+	for _, d := range docs {
+		resp = append(resp, toDocumentResp(d))
+	}
+	respBody, err := json.Marshal(resp)
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	_, _ = rw.Write(respBody)
+}
+
+// HandleDeleteDocument deletes a document of a user.
+//
+// Endpoint: DELETE /v1/users/{userId}/documents/{documentId}
+//
+// Response 204 No Content, or 404 Not Found if the user has no such document.
+func (h *documentHandler) HandleDeleteDocument(rw http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userId")
+	documentID := r.PathValue("documentId")
+
+	if err := h.service.Delete(r.Context(), userID, documentID); err != nil {
+		if errors.Is(err, domain.ErrDocumentNotFound) {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func toDocumentResp(d domain.Document) documentResp {
+	return documentResp{
+		DocumentID:  d.ID,
+		Title:       d.Title,
+		ContentType: d.ContentType,
+		Chunks:      d.Chunks,
+		CreatedAt:   d.CreatedAt,
+	}
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+// HomeAssistantPath is the backend API path for setting (PUT), reading (GET)
+// and removing (DELETE) the Home Assistant instance of a user.
+const HomeAssistantPath = baseManagementPath + "/v1/users/{userId}/home-assistant"
+
+// homeAssistantReq defines the JSON payload configuring a Home Assistant instance.
+type homeAssistantReq struct {
+	BaseURL string `json:"baseUrl"`
+	Token   string `json:"token"`
+}
+
+// homeAssistantResp defines the JSON representation of a configured instance.
+// The token is never returned.
+type homeAssistantResp struct {
+	BaseURL string `json:"baseUrl"`
+}
+
+// homeAssistantHandler handles the Home Assistant configuration HTTP requests.
+type homeAssistantHandler struct {
+	service ports.HomeAssistantService
+}
+
+// NewHomeAssistantHandler returns a new instance of homeAssistantHandler.
+func NewHomeAssistantHandler(service ports.HomeAssistantService) *homeAssistantHandler {
+	return &homeAssistantHandler{service: service}
+}
+
+// HandlePutHomeAssistant configures the Home Assistant instance of a user.
+//
+// Endpoint: PUT /v1/users/{userId}/home-assistant
+//
+// Request body:
+//
+//	{
+//	  "baseUrl": "http://homeassistant.local:8123",
+//	  "token": "<long-lived access token>"
+//	}
+//
+// Response 204 No Content, or 400 Bad Request for an invalid URL or missing token.
+func (h *homeAssistantHandler) HandlePutHomeAssistant(rw http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userId")
+	defer r.Body.Close()
+
+	var req homeAssistantReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	err := h.service.Configure(r.Context(), domain.HomeAssistantConfig{
+		UserID:  userID,
+		BaseURL: req.BaseURL,
+		Token:   req.Token,
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidHomeAssistantConfig) {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetHomeAssistant returns the Home Assistant instance of a user.
+//
+// Endpoint: GET /v1/users/{userId}/home-assistant
+//
+// Response 200 OK with {"baseUrl": "..."}, or 404 Not Found if none is configured.
+func (h *homeAssistantHandler) HandleGetHomeAssistant(rw http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userId")
+
+	config, err := h.service.Config(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, domain.ErrHomeAssistantNotConfigured) {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	respBody, err := json.Marshal(homeAssistantResp{BaseURL: config.BaseURL})
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	_, _ = rw.Write(respBody)
+}
+
+// HandleDeleteHomeAssistant removes the Home Assistant instance of a user.
+//
+// Endpoint: DELETE /v1/users/{userId}/home-assistant
+//
+// Response 204 No Content.
+func (h *homeAssistantHandler) HandleDeleteHomeAssistant(rw http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userId")
+
+	if err := h.service.Remove(r.Context(), userID); err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+// MCPAccessPath is the backend API path for setting (PUT) and reading (GET)
+// the MCP servers and tools enabled by a user.
+const MCPAccessPath = baseManagementPath + "/v1/users/{userId}/mcp-access"
+
+// mcpGrant defines the JSON representation of the enabled tools of a server.
+// No tools enable all tools of the server.
+type mcpGrant struct {
+	Server string   `json:"server"`
+	Tools  []string `json:"tools,omitempty"`
+}
+
+// mcpAccessReq defines the JSON payload enabling MCP servers and tools.
+type mcpAccessReq struct {
+	Grants []mcpGrant `json:"grants"`
+}
+
+// mcpAccessResp defines the JSON representation of the enabled MCP servers
+// and tools, along with the servers that can be enabled.
+type mcpAccessResp struct {
+	Servers []string   `json:"servers"`
+	Grants  []mcpGrant `json:"grants"`
+}
+
+// mcpAccessHandler handles the MCP access HTTP requests.
+type mcpAccessHandler struct {
+	service ports.MCPAccessService
+}
+
+// NewMCPAccessHandler returns a new instance of mcpAccessHandler.
+func NewMCPAccessHandler(service ports.MCPAccessService) *mcpAccessHandler {
+	return &mcpAccessHandler{service: service}
+}
+
+// HandlePutMCPAccess replaces the MCP servers and tools enabled by a user.
+//
+// Endpoint: PUT /v1/users/{userId}/mcp-access
+//
+// Request body:
+//
+//	{
+//	  "grants": [
+//	    {"server": "weather"},
+//	    {"server": "search", "tools": ["web_search"]}
+//	  ]
+//	}
+//
+// Response 204 No Content, or 400 Bad Request for servers that are not configured.
+func (h *mcpAccessHandler) HandlePutMCPAccess(rw http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userId")
+	defer r.Body.Close()
+
+	var req mcpAccessReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	access := domain.MCPAccess{UserID: userID}
+	for _, g := range req.Grants {
+		access.Grants = append(access.Grants, domain.MCPGrant{Server: g.Server, Tools: g.Tools})
+	}
+
+	if err := h.service.Grant(r.Context(), access); err != nil {
+		if errors.Is(err, domain.ErrUnknownMCPServer) {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// HandleGetMCPAccess returns the MCP servers and tools enabled by a user.
+//
+// Endpoint: GET /v1/users/{userId}/mcp-access
+//
+// Response 200 OK with {"servers": ["search", "weather"], "grants": [...]}.
+func (h *mcpAccessHandler) HandleGetMCPAccess(rw http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userId")
+
+	access, err := h.service.Access(r.Context(), userID)
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp := mcpAccessResp{Servers: h.service.Servers(), Grants: []mcpGrant{}}
+	for _, g := range access.Grants {
+		resp.Grants = append(resp.Grants, mcpGrant{Server: g.Server, Tools: g.Tools})
+	}
+	respBody, err := json.Marshal(resp)
+	if err != nil {
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	_, _ = rw.Write(respBody)
+}
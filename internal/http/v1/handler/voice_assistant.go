@@ -5,16 +5,28 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
 	"net/http"
-	"os"
-	"time"
+	"strconv"
 
 	"github.com/ownerofglory/raspi-agent/internal/core/domain"
 	"github.com/ownerofglory/raspi-agent/internal/core/ports"
 )
 
 const (
-	filePathPattern = "/tmp/voice%s.wav"
+	// audioFormField is the multipart form field carrying the audio upload.
+	audioFormField = "audio"
+
+	// voiceQueryParam and rateQueryParam select the voice of the reply,
+	// languageQueryParam the language spoken by the user.
+	voiceQueryParam    = "voice"
+	rateQueryParam     = "rate"
+	languageQueryParam = "language"
+
+	// expectsReplyTrailer is the trailer set to "true" once the audio is
+	// streamed if the assistant expects the user to reply, or the header
+	// of a reply without audio.
+	expectsReplyTrailer = "X-Expects-Reply"
 
 	PostReceiveVoiceAssistance = basePath + "/v1/voice-assistance"
 )
@@ -22,80 +34,84 @@ const (
 // voiceAssistantHandler handles HTTP requests for voice assistant operations.
 //
 // It bridges the HTTP layer with the core domain layer by translating incoming
-// multipart audio uploads into domain.VoiceAssistantRequest objects and
+// audio uploads into domain.VoiceAssistantRequest objects and
 // streaming the resulting synthesized audio directly to the client.
 type voiceAssistantHandler struct {
 	assistant ports.VoiceAssistant
+	devices   ports.DeviceService
 }
 
 // NewVoiceAssistantHandler constructs a new HTTP handler for voice assistant requests.
 //
 // The handler requires a concrete implementation of the ports.VoiceAssistant interface,
-// which orchestrates transcription, completion, and TTS in the backend.
-func NewVoiceAssistantHandler(va ports.VoiceAssistant) *voiceAssistantHandler {
+// which orchestrates transcription, completion, and TTS in the backend, and
+// a ports.DeviceService to look up the user owning the requesting device.
+func NewVoiceAssistantHandler(va ports.VoiceAssistant, devices ports.DeviceService) *voiceAssistantHandler {
 	return &voiceAssistantHandler{
 		assistant: va,
+		devices:   devices,
 	}
 }
 
-// HandleAssist processes an uploaded audio file, runs it through the voice assistant pipeline,
+// HandleAssist consumes uploaded audio, runs it through the voice assistant pipeline,
 // and streams the resulting synthesized audio back to the client.
 //
+// The audio is never buffered to disk: it is handed to the assistant as a
+// stream, so transcription can start while the device is still uploading.
+//
 // Request:
 //   - Method: POST
-//   - Content-Type: multipart/form-data
-//   - Form field: "audio" (audio file)
+//   - Content-Type: audio/wav (raw, typically chunked transfer encoding), or
+//   - Content-Type: multipart/form-data with form field "audio" (audio file)
+//   - Query: optional "voice" (voice name of the speech provider) and "rate"
+//     (speaking rate, e.g. 1.25), as configured on the device, and "language"
+//     (spoken language as ISO 639-1 code, e.g. "de"), e.g. of the wake word's persona
 //
 // Response:
-//   - Content-Type: audio/mpeg
+//   - Content-Type: the format of the synthesized speech, e.g. audio/mpeg
+//     for OpenAI or audio/wav for Piper
 //   - Transfer-Encoding: chunked
 //   - The connection is kept alive to stream generated audio progressively.
+//   - Trailer X-Expects-Reply: "true" if the assistant expects the user to
+//     reply, e.g. to a question, so the device may listen without the wake word.
+//   - 204 No Content if the reply has no audio, e.g. if it failed, with
+//     X-Expects-Reply as a header.
 //
 // Flow:
-//  1. The uploaded audio file is saved temporarily.
-//  2. It’s passed into the assistant pipeline for processing.
-//  3. The handler streams the resulting audio chunks as they become available.
+//  0. The device (and its owner) is identified from the client certificate,
+//     which keys the conversation context.
+//  1. The request body (or the "audio" part) is passed into the assistant pipeline.
+//  2. The handler streams the resulting audio chunks as they become available.
 //
 // Example client usage (curl):
 //
+//	curl -X POST -H "Content-Type: audio/wav" -T sample.wav http://<host>/v1/voice-assistance --output reply.mp3
 //	curl -X POST -F "audio=@sample.wav" http://<host>/v1/voice-assistance --output reply.mp3
 func (v *voiceAssistantHandler) HandleAssist(rw http.ResponseWriter, r *http.Request) {
-	audioFile, fh, err := r.FormFile("audio")
-	if err != nil {
-		slog.Error("Unable to get form data file", "err", err)
-		rw.WriteHeader(http.StatusBadRequest)
-		return
-	}
-	defer audioFile.Close()
 	defer r.Body.Close()
 
-	filePath := fmt.Sprintf(filePathPattern, time.Now().Format("20060102150405"))
-	tmpFile, err := os.Create(filePath)
+	identity, err := requestDevice(r.Context(), v.devices)
 	if err != nil {
-		slog.Error("Unable to create temporary file", "err", err)
-		rw.WriteHeader(http.StatusInternalServerError)
+		slog.Error("Unable to identify device", "err", err)
+		rw.WriteHeader(http.StatusForbidden)
 		return
 	}
-	defer tmpFile.Close()
-	defer os.Remove(filePath)
 
-	written, err := io.Copy(tmpFile, audioFile)
+	audio, err := requestAudio(r)
 	if err != nil {
-		slog.Error("Unable to copy audio to file", "err", err)
-		rw.WriteHeader(http.StatusInternalServerError)
+		slog.Error("Unable to get request audio", "err", err)
+		rw.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
-	if written != fh.Size {
-		slog.Warn("Audio file copy size missmatch", "size", fh.Size, "written", written)
-	}
-	// Reopen saved file for reading
-	tmpFile.Seek(0, io.SeekStart)
-
 	ctx, cancel := context.WithCancel(r.Context())
 	defer cancel()
 	resCh, err := v.assistant.Assist(ctx, &domain.VoiceAssistantRequest{
-		Audio: tmpFile,
+		Audio:    audio,
+		DeviceID: identity.DeviceID,
+		UserID:   identity.UserID,
+		TimeZone: identity.TimeZone,
+		Voice:    requestVoice(r),
 	})
 	if err != nil {
 		slog.Error("Unable to assist audio", "err", err)
@@ -103,19 +119,31 @@ func (v *voiceAssistantHandler) HandleAssist(rw http.ResponseWriter, r *http.Req
 		return
 	}
 
-	rw.Header().Set("Content-Type", "audio/mpeg")
-	rw.Header().Set("Cache-Control", "no-cache")
-	rw.Header().Set("Connection", "keep-alive")
-	rw.Header().Set("Transfer-Encoding", "chunked")
-
 	flusher, ok := rw.(http.Flusher)
 	if !ok {
 		http.Error(rw, "streaming not supported", http.StatusInternalServerError)
 		return
 	}
 
-	slog.Info("Streaming audio response to client...")
+	// the headers are sent with the first audio, advertising its format
+	started := false
+	start := func(format domain.AudioFormat) {
+		if started {
+			return
+		}
+		started = true
+
+		contentType := audioContentType(format)
+		rw.Header().Set("Content-Type", contentType)
+		rw.Header().Set("Cache-Control", "no-cache")
+		rw.Header().Set("Connection", "keep-alive")
+		rw.Header().Set("Transfer-Encoding", "chunked")
+		rw.Header().Set("Trailer", expectsReplyTrailer)
+		rw.WriteHeader(http.StatusOK)
+		slog.Info("Streaming audio response to client...", "contentType", contentType)
+	}
 
+	expectsReply := false
 	for {
 		select {
 		case <-ctx.Done():
@@ -125,9 +153,26 @@ func (v *voiceAssistantHandler) HandleAssist(rw http.ResponseWriter, r *http.Req
 		case res, ok := <-resCh:
 			if !ok {
 				slog.Info("Assistant stream completed")
+				if expectsReply {
+					rw.Header().Set(expectsReplyTrailer, "true")
+				}
+				if !started {
+					slog.Info("Assistant replied without audio")
+					rw.WriteHeader(http.StatusNoContent)
+				}
 				return
 			}
 
+			// Only audio is sent over plain HTTP, other events are dropped
+			// except for the trailer
+			if res.Type == domain.VoiceAssistantEventExpectsReply {
+				expectsReply = true
+			}
+			if res.Audio == nil {
+				continue
+			}
+			start(res.Format)
+
 			// Stream the raw audio bytes directly to the client
 			_, err := io.Copy(rw, res.Audio)
 			if err != nil {
@@ -138,3 +183,56 @@ func (v *voiceAssistantHandler) HandleAssist(rw http.ResponseWriter, r *http.Req
 		}
 	}
 }
+
+// audioContentType returns the content type advertised for audio of the
+// format, audio/mpeg if it is unknown.
+func audioContentType(format domain.AudioFormat) string {
+	if contentType := format.String(); contentType != "" {
+		return contentType
+	}
+	return domain.AudioContentTypeMPEG
+}
+
+// requestAudio returns a reader over the uploaded audio without buffering it.
+//
+// Multipart uploads are read part by part until the "audio" field is found;
+// any other content type is treated as a raw audio body.
+func requestAudio(r *http.Request) (io.Reader, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid content type: %w", err)
+	}
+
+	if mediaType != "multipart/form-data" {
+		return r.Body, nil
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read multipart body: %w", err)
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			return nil, fmt.Errorf("missing form field %q", audioFormField)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("unable to read multipart part: %w", err)
+		}
+		if part.FormName() == audioFormField {
+			return part, nil
+		}
+	}
+}
+
+// requestVoice returns the voice the device asks the reply to be spoken in.
+// An invalid rate is ignored.
+func requestVoice(r *http.Request) domain.VoiceOptions {
+	query := r.URL.Query()
+	voice := domain.VoiceOptions{Voice: query.Get(voiceQueryParam), Language: query.Get(languageQueryParam)}
+	if rate, err := strconv.ParseFloat(query.Get(rateQueryParam), 64); err == nil && rate > 0 {
+		voice.Rate = rate
+	}
+	return voice
+}
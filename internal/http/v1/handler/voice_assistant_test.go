@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandleAssist(t *testing.T) {
+	audio := []byte("RIFF-fake-wav-audio")
+	reply := []byte("ID3-fake-mpeg-reply")
+
+	multipartBody := func(field string) (*bytes.Buffer, string) {
+		body := &bytes.Buffer{}
+		w := multipart.NewWriter(body)
+		part, _ := w.CreateFormFile(field, "voice.wav")
+		_, _ = part.Write(audio)
+		_ = w.Close()
+		return body, w.FormDataContentType()
+	}
+
+	testCases := []struct {
+		name        string
+		body        func() (*bytes.Buffer, string)
+		query       string
+		voice       domain.VoiceOptions
+		format      domain.AudioFormat
+		statusCode  int
+		expectAudio bool
+		contentType string
+		expectReply bool
+	}{
+		{
+			name: "raw audio body",
+			body: func() (*bytes.Buffer, string) {
+				return bytes.NewBuffer(audio), "audio/wav"
+			},
+			format:      domain.AudioFormat{ContentType: domain.AudioContentTypeMPEG},
+			statusCode:  http.StatusOK,
+			expectAudio: true,
+			contentType: "audio/mpeg",
+		},
+		{
+			name: "wav reply",
+			body: func() (*bytes.Buffer, string) {
+				return bytes.NewBuffer(audio), "audio/wav"
+			},
+			format:      domain.AudioFormat{ContentType: domain.AudioContentTypeWAV},
+			statusCode:  http.StatusOK,
+			expectAudio: true,
+			contentType: "audio/wav",
+		},
+		{
+			name: "raw pcm reply",
+			body: func() (*bytes.Buffer, string) {
+				return bytes.NewBuffer(audio), "audio/wav"
+			},
+			format:      domain.AudioFormat{ContentType: domain.AudioContentTypePCM, SampleRate: 22050, Channels: 1},
+			statusCode:  http.StatusOK,
+			expectAudio: true,
+			contentType: "audio/pcm;rate=22050;channels=1",
+		},
+		{
+			name: "voice of the device",
+			body: func() (*bytes.Buffer, string) {
+				return bytes.NewBuffer(audio), "audio/wav"
+			},
+			query:       "?voice=en_GB-alan-low&rate=1.25&language=en",
+			voice:       domain.VoiceOptions{Voice: "en_GB-alan-low", Rate: 1.25, Language: "en"},
+			statusCode:  http.StatusOK,
+			expectAudio: true,
+			contentType: "audio/mpeg",
+		},
+		{
+			name: "question expecting a reply",
+			body: func() (*bytes.Buffer, string) {
+				return bytes.NewBuffer(audio), "audio/wav"
+			},
+			statusCode:  http.StatusOK,
+			expectAudio: true,
+			contentType: "audio/mpeg",
+			expectReply: true,
+		},
+		{
+			name: "multipart audio field",
+			body: func() (*bytes.Buffer, string) {
+				return multipartBody(audioFormField)
+			},
+			statusCode:  http.StatusOK,
+			expectAudio: true,
+			contentType: "audio/mpeg",
+		},
+		{
+			name: "multipart without audio field",
+			body: func() (*bytes.Buffer, string) {
+				return multipartBody("other")
+			},
+			statusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockAssistant := ports.NewMockVoiceAssistant(ctrl)
+			h := NewVoiceAssistantHandler(mockAssistant, ports.NewMockDeviceService(ctrl))
+
+			if tc.expectAudio {
+				mockAssistant.EXPECT().Assist(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, req *domain.VoiceAssistantRequest) (<-chan *domain.VoiceAssistantResult, error) {
+						got, err := io.ReadAll(req.Audio)
+						if err != nil {
+							t.Errorf("expected error to be nil got %v", err)
+						}
+						if !bytes.Equal(got, audio) {
+							t.Errorf("expected audio %q, got %q", audio, got)
+						}
+						if req.Voice != tc.voice {
+							t.Errorf("expected voice %+v, got %+v", tc.voice, req.Voice)
+						}
+
+						ch := make(chan *domain.VoiceAssistantResult, 2)
+						ch <- &domain.VoiceAssistantResult{Audio: bytes.NewReader(reply), Format: tc.format}
+						if tc.expectReply {
+							ch <- &domain.VoiceAssistantResult{Type: domain.VoiceAssistantEventExpectsReply}
+						}
+						close(ch)
+						return ch, nil
+					})
+			}
+
+			body, contentType := tc.body()
+			req := httptest.NewRequest(http.MethodPost, PostReceiveVoiceAssistance+tc.query, body)
+			req.Header.Set("Content-Type", contentType)
+			rec := httptest.NewRecorder()
+
+			h.HandleAssist(rec, req)
+
+			res := rec.Result()
+			if res.StatusCode != tc.statusCode {
+				t.Fatalf("expected status %d, got %d", tc.statusCode, res.StatusCode)
+			}
+
+			if tc.statusCode == http.StatusOK {
+				defer res.Body.Close()
+
+				if got := res.Header.Get("Content-Type"); got != tc.contentType {
+					t.Errorf("expected content type %q, got %q", tc.contentType, got)
+				}
+
+				resData, err := io.ReadAll(res.Body)
+				if err != nil {
+					t.Errorf("expected error to be nil got %v", err)
+				}
+				if !bytes.Equal(resData, reply) {
+					t.Errorf("expected reply %q, got %q", reply, resData)
+				}
+				if got := res.Trailer.Get(expectsReplyTrailer) == "true"; got != tc.expectReply {
+					t.Errorf("expected the reply to be expected %v, got %v", tc.expectReply, got)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleAssistWithoutAudio(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockAssistant := ports.NewMockVoiceAssistant(ctrl)
+	h := NewVoiceAssistantHandler(mockAssistant, ports.NewMockDeviceService(ctrl))
+
+	// e.g. a failed reply, whose question is still awaiting an answer
+	mockAssistant.EXPECT().Assist(gomock.Any(), gomock.Any()).Return(resultStream(
+		&domain.VoiceAssistantResult{Type: domain.VoiceAssistantEventText, Text: "Which room?"},
+		&domain.VoiceAssistantResult{Type: domain.VoiceAssistantEventExpectsReply},
+	), nil)
+
+	req := httptest.NewRequest(http.MethodPost, PostReceiveVoiceAssistance, bytes.NewBufferString("RIFF-fake-wav-audio"))
+	req.Header.Set("Content-Type", "audio/wav")
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.HandleAssist(rec, req)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected the request to complete")
+	}
+
+	res := rec.Result()
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, res.StatusCode)
+	}
+	if got := res.Header.Get(expectsReplyTrailer); got != "true" {
+		t.Errorf("expected the reply to be expected, got %q", got)
+	}
+}
+
+// resultStream returns a closed stream of the results.
+func resultStream(results ...*domain.VoiceAssistantResult) <-chan *domain.VoiceAssistantResult {
+	ch := make(chan *domain.VoiceAssistantResult, len(results))
+	for _, res := range results {
+		ch <- res
+	}
+	close(ch)
+	return ch
+}
@@ -0,0 +1,432 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"github.com/ownerofglory/raspi-agent/internal/websocket"
+)
+
+const GetVoiceSessionPath = basePath + "/v1/voice-session"
+
+// PostDeviceListenPath is the backend API path asking a device of the user
+// to start listening, as if its wake word was spoken.
+const PostDeviceListenPath = baseManagementPath + "/v1/users/{userId}/devices/{deviceId}/listen"
+
+// Voice session message types sent by the device.
+const (
+	// sessionMessageStart begins a new turn, canceling any reply still in progress.
+	sessionMessageStart = "start"
+
+	// sessionMessageAudioEnd marks the end of the user's audio for the current turn.
+	sessionMessageAudioEnd = "audio_end"
+
+	// sessionMessageCancel aborts the current turn.
+	sessionMessageCancel = "cancel"
+)
+
+// Voice session message types sent by the backend, in addition to the
+// domain.VoiceAssistantEventType values for transcript, text and tool calls.
+const (
+	// sessionMessageEndOfTurn is sent once the reply of a turn is complete.
+	sessionMessageEndOfTurn = "end_of_turn"
+
+	// sessionMessageError reports a failure of the current turn.
+	sessionMessageError = "error"
+
+	// sessionMessageAudioFormat announces the content type of the reply
+	// audio before its first binary frame.
+	sessionMessageAudioFormat = "audio_format"
+
+	// sessionMessageNotification pushes a domain.DeviceNotification to the
+	// device outside of a turn.
+	sessionMessageNotification = "notification"
+)
+
+// voiceSessionMessage is the JSON control/event message exchanged over
+// the voice session text frames. Turn identifies the turn a message belongs
+// to, chosen by the device in its start message and echoed on every
+// message of the turn.
+type voiceSessionMessage struct {
+	Type        string               `json:"type"`
+	Turn        uint64               `json:"turn,omitempty"`
+	Voice       *domain.VoiceOptions `json:"voice,omitempty"`
+	Kind        string               `json:"kind,omitempty"`
+	Text        string               `json:"text,omitempty"`
+	Citations   []sessionCitation    `json:"citations,omitempty"`
+	Audio       []byte               `json:"audio,omitempty"`
+	ContentType string               `json:"contentType,omitempty"`
+	Error       string               `json:"error,omitempty"`
+}
+
+// sessionCitation identifies a document a text event is based on.
+type sessionCitation struct {
+	DocumentID string `json:"documentId"`
+	Title      string `json:"title"`
+}
+
+// voiceSessionHandler serves long-lived, full-duplex voice sessions over WebSocket.
+//
+// It also implements ports.DeviceNotifier for the devices holding a session.
+type voiceSessionHandler struct {
+	assistant ports.VoiceAssistant
+	devices   ports.DeviceService
+
+	mu sync.Mutex
+	// sessions holds the open session connection of each device
+	sessions map[string]*websocket.Conn
+}
+
+// NewVoiceSessionHandler constructs a new WebSocket handler for voice sessions
+// backed by the given voice assistant pipeline. The device service is used
+// to look up the user owning the connected device.
+func NewVoiceSessionHandler(va ports.VoiceAssistant, devices ports.DeviceService) *voiceSessionHandler {
+	return &voiceSessionHandler{
+		assistant: va,
+		devices:   devices,
+		sessions:  make(map[string]*websocket.Conn),
+	}
+}
+
+// sessionTurn tracks a single request/reply exchange within a session.
+type sessionTurn struct {
+	audio       *io.PipeWriter
+	audioClosed bool
+	cancel      context.CancelFunc
+}
+
+// turnWriter writes the messages of a turn to the device, tagged with the
+// ID of the turn. The writes of all turns of a session are serialized and
+// those of a canceled turn are dropped, so that nothing of a turn is
+// written once the next one has started.
+type turnWriter struct {
+	conn *websocket.Conn
+	// mu is shared by the turns of the session
+	mu  *sync.Mutex
+	ctx context.Context
+	id  uint64
+}
+
+// writeMessage writes a message of the turn.
+func (w *turnWriter) writeMessage(msg voiceSessionMessage) error {
+	msg.Turn = w.id
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return w.write(websocket.TextMessage, data)
+}
+
+// writeAudio writes a chunk of the reply audio of the turn, which belongs
+// to the turn of the preceding messages.
+func (w *turnWriter) writeAudio(chunk []byte) error {
+	return w.write(websocket.BinaryMessage, chunk)
+}
+
+func (w *turnWriter) write(msgType websocket.MessageType, data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.ctx.Err(); err != nil {
+		return err
+	}
+	return w.conn.WriteMessage(msgType, data)
+}
+
+// HandleSession upgrades the connection to a WebSocket and runs a voice
+// session until the device disconnects.
+//
+// Query parameters "voice", "rate" and "language" optionally select the
+// voice of the replies, see HandleAssist. The voice of a turn may be
+// overridden by its start message.
+//
+// Device → backend:
+//   - Binary frames: user audio (WAV stream) of the current turn.
+//     The first binary frame implicitly starts a turn.
+//   - Text frames: {"type":"start","turn":<turn ID>,"voice":{"voice":...,"rate":...,"language":...}}
+//     (voice optional), {"type":"audio_end"}, {"type":"cancel"}
+//
+// Backend → device:
+//   - Binary frames: synthesized reply audio of the turn of the preceding
+//     text frame, preceded by {"type":"audio_format","contentType":...},
+//     e.g. "audio/mpeg" or "audio/wav"
+//   - Text frames: {"type":"transcript","text":...},
+//     {"type":"text","text":...,"citations":[{"documentId":...,"title":...}]},
+//     {"type":"tool_call","text":<tool name>}, {"type":"error","error":...},
+//     {"type":"expects_reply"} if the assistant expects the user to reply
+//     and {"type":"end_of_turn"} once the reply is complete. All of them
+//     carry the "turn" of the start message, so the device can drop the
+//     messages of a turn it has abandoned.
+//   - Text frames outside of turns: {"type":"notification","kind":...,"text":...,
+//     "audio":<base64 audio>,"contentType":...}, see Notify.
+//
+// Transcription starts while the audio is still being received; a new
+// turn cancels any reply still in progress.
+func (v *voiceSessionHandler) HandleSession(rw http.ResponseWriter, r *http.Request) {
+	identity, err := requestDevice(r.Context(), v.devices)
+	if err != nil {
+		slog.Error("Unable to identify device", "err", err)
+		rw.WriteHeader(http.StatusForbidden)
+		return
+	}
+	identity.Voice = requestVoice(r)
+
+	conn, err := websocket.Upgrade(rw, r)
+	if err != nil {
+		slog.Error("Unable to upgrade voice session", "err", err)
+		return
+	}
+	defer conn.Close()
+
+	v.register(identity.DeviceID, conn)
+	defer v.unregister(identity.DeviceID, conn)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	slog.Info("Voice session started", "remote", conn.RemoteAddr())
+
+	// serializes the writes of the turns
+	var writes sync.Mutex
+	var turn *sessionTurn
+	endTurn := func() {
+		if turn != nil {
+			turn.cancel()
+			turn.audio.Close()
+			turn = nil
+		}
+	}
+	defer endTurn()
+
+	for {
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			slog.Info("Voice session ended", "err", err)
+			return
+		}
+
+		if msgType == websocket.BinaryMessage {
+			if turn == nil || turn.audioClosed {
+				endTurn()
+				turn = v.startTurn(ctx, &turnWriter{conn: conn, mu: &writes}, identity)
+			}
+			// fails only once the turn is over; late audio is dropped
+			_, _ = turn.audio.Write(data)
+			continue
+		}
+
+		var msg voiceSessionMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			slog.Warn("Invalid voice session message", "err", err)
+			continue
+		}
+
+		switch msg.Type {
+		case sessionMessageStart:
+			endTurn()
+			turnIdentity := identity
+			if msg.Voice != nil {
+				turnIdentity.Voice = msg.Voice.WithDefaults(identity.Voice)
+			}
+			turn = v.startTurn(ctx, &turnWriter{conn: conn, mu: &writes, id: msg.Turn}, turnIdentity)
+		case sessionMessageAudioEnd:
+			if turn != nil {
+				turn.audio.Close()
+				turn.audioClosed = true
+			}
+		case sessionMessageCancel:
+			endTurn()
+		default:
+			slog.Warn("Unknown voice session message", "type", msg.Type)
+		}
+	}
+}
+
+// startTurn runs the assistant pipeline for a new turn in the background,
+// fed by the returned turn's audio pipe, writing the reply with w.
+func (v *voiceSessionHandler) startTurn(ctx context.Context, w *turnWriter, identity domain.VoiceAssistantRequest) *sessionTurn {
+	turnCtx, cancel := context.WithCancel(ctx)
+	w.ctx = turnCtx
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer cancel()
+		// unblock the session loop if the pipeline stops reading early
+		defer pr.Close()
+
+		if err := v.replyTurn(turnCtx, w, identity, pr); err != nil && turnCtx.Err() == nil {
+			slog.Error("Voice session turn failed", "err", err)
+			_ = w.writeMessage(voiceSessionMessage{Type: sessionMessageError, Error: err.Error()})
+		}
+		_ = w.writeMessage(voiceSessionMessage{Type: sessionMessageEndOfTurn})
+	}()
+
+	return &sessionTurn{audio: pw, cancel: cancel}
+}
+
+// replyTurn streams the assistant's results for one turn to the device.
+func (v *voiceSessionHandler) replyTurn(ctx context.Context, w *turnWriter, identity domain.VoiceAssistantRequest, audio io.Reader) error {
+	resCh, err := v.assistant.Assist(ctx, &domain.VoiceAssistantRequest{
+		Audio:    audio,
+		DeviceID: identity.DeviceID,
+		UserID:   identity.UserID,
+		TimeZone: identity.TimeZone,
+		Voice:    identity.Voice,
+	})
+	if err != nil {
+		return err
+	}
+
+	formatSent := false
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case res, ok := <-resCh:
+			if !ok {
+				return nil
+			}
+
+			if res.Audio != nil {
+				chunk, err := io.ReadAll(res.Audio)
+				if err != nil {
+					return err
+				}
+				if !formatSent {
+					formatSent = true
+					msg := voiceSessionMessage{Type: sessionMessageAudioFormat, ContentType: audioContentType(res.Format)}
+					if err := w.writeMessage(msg); err != nil {
+						return err
+					}
+				}
+				if err := w.writeAudio(chunk); err != nil {
+					return err
+				}
+				continue
+			}
+
+			msg := voiceSessionMessage{Type: string(res.Type), Text: res.Text}
+			if res.Type == domain.VoiceAssistantEventError {
+				msg = voiceSessionMessage{Type: sessionMessageError, Error: res.Text}
+			}
+			for _, c := range res.Citations {
+				msg.Citations = append(msg.Citations, sessionCitation{DocumentID: c.DocumentID, Title: c.Title})
+			}
+			if err := w.writeMessage(msg); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Notify sends the notification to the device over its open voice session.
+func (v *voiceSessionHandler) Notify(ctx context.Context, deviceID string, notification domain.DeviceNotification) error {
+	v.mu.Lock()
+	conn, ok := v.sessions[deviceID]
+	v.mu.Unlock()
+	if !ok {
+		return domain.ErrDeviceNotConnected
+	}
+
+	msg := voiceSessionMessage{
+		Type: sessionMessageNotification,
+		Kind: notification.Kind,
+		Text: notification.Text,
+	}
+	if notification.Audio != nil {
+		audio, err := io.ReadAll(notification.Audio)
+		if err != nil {
+			return fmt.Errorf("failed to read notification audio: %w", err)
+		}
+		msg.Audio = audio
+		msg.ContentType = audioContentType(notification.Format)
+	}
+
+	if err := writeSessionMessage(conn, msg); err != nil {
+		slog.Error("Failed to send notification", "err", err, "deviceId", deviceID)
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	return nil
+}
+
+// Reachable reports whether the device holds an open voice session.
+func (v *voiceSessionHandler) Reachable(deviceID string) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	_, ok := v.sessions[deviceID]
+	return ok
+}
+
+// HandleListen asks a device of the user to start listening, e.g. from an
+// app instead of saying the wake word, by notifying it over its open voice
+// session.
+//
+// Endpoint: POST /v1/users/{userId}/devices/{deviceId}/listen
+//
+// Response 202 Accepted, 404 Not Found if the user owns no such device, or
+// 409 Conflict if the device has no open voice session.
+func (v *voiceSessionHandler) HandleListen(rw http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userId")
+	deviceID := r.PathValue("deviceId")
+
+	device, err := v.devices.GetDevice(r.Context(), deviceID)
+	if err != nil {
+		if errors.Is(err, domain.ErrDeviceNotFound) {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if device.UserID == nil || *device.UserID != userID {
+		rw.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	err = v.Notify(r.Context(), deviceID, domain.DeviceNotification{Kind: domain.NotificationKindListen})
+	if err != nil {
+		if errors.Is(err, domain.ErrDeviceNotConnected) {
+			rw.WriteHeader(http.StatusConflict)
+			return
+		}
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.WriteHeader(http.StatusAccepted)
+}
+
+// register makes conn the session of the device, replacing an older one.
+func (v *voiceSessionHandler) register(deviceID string, conn *websocket.Conn) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.sessions[deviceID] = conn
+}
+
+// unregister removes conn unless the device has opened a newer session since.
+func (v *voiceSessionHandler) unregister(deviceID string, conn *websocket.Conn) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.sessions[deviceID] == conn {
+		delete(v.sessions, deviceID)
+	}
+}
+
+func writeSessionMessage(conn *websocket.Conn, msg voiceSessionMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
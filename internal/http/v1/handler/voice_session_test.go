@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"github.com/ownerofglory/raspi-agent/internal/websocket"
+	"go.uber.org/mock/gomock"
+)
+
+func TestHandleSession(t *testing.T) {
+	audio := []byte("RIFF-fake-wav-audio")
+	reply := []byte("ID3-fake-mpeg-reply")
+
+	ctrl := gomock.NewController(t)
+	mockAssistant := ports.NewMockVoiceAssistant(ctrl)
+	h := NewVoiceSessionHandler(mockAssistant, ports.NewMockDeviceService(ctrl))
+
+	mockAssistant.EXPECT().Assist(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, req *domain.VoiceAssistantRequest) (<-chan *domain.VoiceAssistantResult, error) {
+			got, err := io.ReadAll(req.Audio)
+			if err != nil {
+				t.Errorf("expected error to be nil got %v", err)
+			}
+			if !bytes.Equal(got, audio) {
+				t.Errorf("expected audio %q, got %q", audio, got)
+			}
+			// the voice of the turn completed by the voice of the session
+			if want := (domain.VoiceOptions{Voice: "onyx", Rate: 1.5, Language: "de"}); req.Voice != want {
+				t.Errorf("expected voice %+v, got %+v", want, req.Voice)
+			}
+
+			ch := make(chan *domain.VoiceAssistantResult, 3)
+			ch <- &domain.VoiceAssistantResult{Type: domain.VoiceAssistantEventTranscript, Text: "hello"}
+			ch <- &domain.VoiceAssistantResult{
+				Type:      domain.VoiceAssistantEventText,
+				Text:      "Descale it every two months.",
+				Citations: []domain.Citation{{DocumentID: "doc1", Title: "Coffee machine manual"}},
+			}
+			ch <- &domain.VoiceAssistantResult{
+				Type:   domain.VoiceAssistantEventAudio,
+				Audio:  bytes.NewReader(reply),
+				Format: domain.AudioFormat{ContentType: domain.AudioContentTypeWAV},
+			}
+			close(ch)
+			return ch, nil
+		})
+
+	srv := httptest.NewServer(http.HandlerFunc(h.HandleSession))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http")+"?voice=alloy&rate=1.5", nil)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	defer conn.Close()
+
+	send := func(msgType websocket.MessageType, data []byte) {
+		if err := conn.WriteMessage(msgType, data); err != nil {
+			t.Fatalf("expected error to be nil got %v", err)
+		}
+	}
+	send(websocket.TextMessage, []byte(`{"type":"start","turn":7,"voice":{"voice":"onyx","language":"de"}}`))
+	send(websocket.BinaryMessage, audio[:4])
+	send(websocket.BinaryMessage, audio[4:])
+	send(websocket.TextMessage, []byte(`{"type":"audio_end"}`))
+
+	expected := []struct {
+		msgType websocket.MessageType
+		data    string
+	}{
+		{msgType: websocket.TextMessage, data: `{"type":"transcript","turn":7,"text":"hello"}`},
+		{msgType: websocket.TextMessage, data: `{"type":"text","turn":7,"text":"Descale it every two months.","citations":[{"documentId":"doc1","title":"Coffee machine manual"}]}`},
+		{msgType: websocket.TextMessage, data: `{"type":"audio_format","turn":7,"contentType":"audio/wav"}`},
+		{msgType: websocket.BinaryMessage, data: string(reply)},
+		{msgType: websocket.TextMessage, data: `{"type":"end_of_turn","turn":7}`},
+	}
+
+	for _, e := range expected {
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("expected error to be nil got %v", err)
+		}
+		if msgType != e.msgType {
+			t.Fatalf("expected message type %d, got %d (%q)", e.msgType, msgType, data)
+		}
+
+		if msgType == websocket.TextMessage {
+			var got, want voiceSessionMessage
+			_ = json.Unmarshal(data, &got)
+			_ = json.Unmarshal([]byte(e.data), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("expected message %+v, got %+v", want, got)
+			}
+			continue
+		}
+		if string(data) != e.data {
+			t.Errorf("expected audio %q, got %q", e.data, data)
+		}
+	}
+}
+
+func TestHandleListen(t *testing.T) {
+	owner := "user1"
+	testCases := []struct {
+		name       string
+		device     *domain.Device
+		err        error
+		statusCode int
+	}{
+		{
+			name:       "device not connected",
+			device:     &domain.Device{UserID: &owner},
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "device of another user",
+			device:     &domain.Device{},
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "unknown device",
+			err:        domain.ErrDeviceNotFound,
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockDevices := ports.NewMockDeviceService(ctrl)
+			mockDevices.EXPECT().GetDevice(gomock.Any(), "device1").Return(tc.device, tc.err)
+			h := NewVoiceSessionHandler(ports.NewMockVoiceAssistant(ctrl), mockDevices)
+
+			req := httptest.NewRequest(http.MethodPost, "/listen", nil)
+			req.SetPathValue("userId", owner)
+			req.SetPathValue("deviceId", "device1")
+			rec := httptest.NewRecorder()
+			h.HandleListen(rec, req)
+
+			if rec.Code != tc.statusCode {
+				t.Errorf("expected status %d, got %d", tc.statusCode, rec.Code)
+			}
+		})
+	}
+}
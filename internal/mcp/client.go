@@ -0,0 +1,296 @@
+// Package mcp implements a client of Model Context Protocol servers, see
+// https://modelcontextprotocol.io/specification/2025-06-18.
+//
+// Servers are reached either as a subprocess speaking over stdio or over the
+// streamable HTTP transport. Only the tools of a server are used.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// protocolVersion is the MCP revision requested by the client.
+const protocolVersion = "2025-06-18"
+
+// clientName is announced to the servers on initialization.
+const clientName = "raspi-agent"
+
+// ListToolsTimeout bounds connecting to a server and listing its tools on
+// startup, e.g. so that a stuck subprocess does not block the startup.
+const ListToolsTimeout = 30 * time.Second
+
+// JSON-RPC error codes answered to requests of the server.
+const rpcMethodNotFound = -32601
+
+// errSessionLost is returned by transports once the session cannot be used
+// anymore, e.g. because the subprocess exited; the client then starts a new one.
+var errSessionLost = errors.New("mcp session lost")
+
+// rpcMessage is a JSON-RPC 2.0 request, notification or response.
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  any             `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// isResponse reports whether the message answers a request.
+func (m *rpcMessage) isResponse() bool {
+	return m.ID != nil && m.Method == ""
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("mcp error %d: %s", e.Code, e.Message)
+}
+
+// transport exchanges JSON-RPC messages with a server.
+type transport interface {
+	// request sends the request and returns the response to it.
+	request(ctx context.Context, msg *rpcMessage) (*rpcMessage, error)
+
+	// notify sends a notification.
+	notify(ctx context.Context, msg *rpcMessage) error
+
+	// close ends the session.
+	close() error
+}
+
+// client implements ports.MCPClient.
+//
+// It connects on first use and reconnects whenever the session is lost,
+// e.g. after the server restarted.
+type client struct {
+	config domain.MCPServerConfig
+
+	mu sync.Mutex
+	// session is nil until connected
+	session transport
+
+	nextID atomic.Int64
+}
+
+// NewClient creates a client of the server. It does not connect until used.
+func NewClient(config domain.MCPServerConfig) (*client, error) {
+	if (config.Command == "") == (config.URL == "") {
+		return nil, fmt.Errorf("mcp server %q needs either a command or a URL", config.Name)
+	}
+	return &client{config: config}, nil
+}
+
+// mcpTool is the JSON representation of a tool.
+type mcpTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// ListTools returns all tools of the server, following the pagination.
+//
+// Method: tools/list
+func (c *client) ListTools(ctx context.Context) ([]domain.MCPTool, error) {
+	var tools []domain.MCPTool
+
+	cursor := ""
+	for {
+		params := map[string]any{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+
+		var res struct {
+			Tools      []mcpTool `json:"tools"`
+			NextCursor string    `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &res); err != nil {
+			return nil, err
+		}
+		for _, t := range res.Tools {
+			tools = append(tools, domain.MCPTool{
+				Name:        t.Name,
+				Description: t.Description,
+				InputSchema: domain.ToolSchema(t.InputSchema),
+			})
+		}
+
+		if res.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = res.NextCursor
+	}
+}
+
+// contentBlock is the JSON representation of an item of a tool result.
+type contentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// CallTool calls a tool of the server.
+//
+// Method: tools/call
+func (c *client) CallTool(ctx context.Context, name, args string) (*domain.MCPToolResult, error) {
+	arguments := json.RawMessage("{}")
+	if strings.TrimSpace(args) != "" {
+		arguments = json.RawMessage(args)
+	}
+	if !json.Valid(arguments) {
+		return nil, errors.New("tool arguments are not valid JSON")
+	}
+
+	var res struct {
+		Content           []contentBlock `json:"content"`
+		StructuredContent any            `json:"structuredContent"`
+		IsError           bool           `json:"isError"`
+	}
+	params := map[string]any{"name": name, "arguments": arguments}
+	if err := c.call(ctx, "tools/call", params, &res); err != nil {
+		return nil, err
+	}
+
+	// only text can be passed on to the model; other content is named
+	var text []string
+	for _, b := range res.Content {
+		if b.Type == "text" {
+			text = append(text, b.Text)
+		} else {
+			text = append(text, "["+b.Type+"]")
+		}
+	}
+
+	return &domain.MCPToolResult{
+		Text:       strings.Join(text, "\n"),
+		Structured: res.StructuredContent,
+		IsError:    res.IsError,
+	}, nil
+}
+
+// Close ends the session, if any.
+func (c *client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session == nil {
+		return nil
+	}
+	err := c.session.close()
+	c.session = nil
+	return err
+}
+
+// call sends a request and decodes the result into res, starting a new
+// session and retrying once if the session was lost.
+func (c *client) call(ctx context.Context, method string, params, res any) error {
+	var resp *rpcMessage
+	for attempt := 0; ; attempt++ {
+		session, err := c.connect(ctx)
+		if err != nil {
+			return err
+		}
+
+		resp, err = session.request(ctx, c.newRequest(method, params))
+		if errors.Is(err, errSessionLost) && attempt == 0 {
+			slog.Warn("MCP session lost, reconnecting", "server", c.config.Name, "err", err)
+			c.drop(session)
+			continue
+		}
+		if err != nil {
+			slog.Error("MCP request failed", "server", c.config.Name, "method", method, "err", err)
+			return fmt.Errorf("mcp request %s failed: %w", method, err)
+		}
+		break
+	}
+
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if err := json.Unmarshal(resp.Result, res); err != nil {
+		return fmt.Errorf("failed to decode mcp %s result: %w", method, err)
+	}
+	return nil
+}
+
+// connect returns the current session, starting and initializing a new one
+// if there is none.
+func (c *client) connect(ctx context.Context) (transport, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session != nil {
+		return c.session, nil
+	}
+
+	var (
+		session transport
+		err     error
+	)
+	if c.config.Command != "" {
+		session, err = startStdio(c.config)
+	} else {
+		session = newHTTPTransport(c.config)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.initialize(ctx, session); err != nil {
+		_ = session.close()
+		return nil, err
+	}
+	slog.Info("Connected to MCP server", "server", c.config.Name)
+
+	c.session = session
+	return session, nil
+}
+
+// initialize performs the initialization handshake of a new session.
+//
+// Method: initialize, followed by notifications/initialized
+func (c *client) initialize(ctx context.Context, session transport) error {
+	resp, err := session.request(ctx, c.newRequest("initialize", map[string]any{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]any{"name": clientName, "version": "1.0.0"},
+	}))
+	if err != nil {
+		slog.Error("Unable to connect to MCP server", "server", c.config.Name, "err", err)
+		return fmt.Errorf("unable to connect to mcp server %q: %w", c.config.Name, err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("mcp server %q refused initialization: %w", c.config.Name, resp.Error)
+	}
+
+	return session.notify(ctx, &rpcMessage{JSONRPC: "2.0", Method: "notifications/initialized"})
+}
+
+// drop forgets the session unless another request has replaced it already.
+func (c *client) drop(session transport) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.session == session {
+		_ = session.close()
+		c.session = nil
+	}
+}
+
+func (c *client) newRequest(method string, params any) *rpcMessage {
+	id := c.nextID.Add(1)
+	return &rpcMessage{JSONRPC: "2.0", ID: &id, Method: method, Params: params}
+}
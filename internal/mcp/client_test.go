@@ -0,0 +1,204 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// stubServer answers MCP requests with two tools, "echo" and "fail", the
+// tools being listed on two pages.
+func stubServer(req rpcMessage) *rpcMessage {
+	if req.ID == nil {
+		return nil
+	}
+	resp := &rpcMessage{JSONRPC: "2.0", ID: req.ID}
+	params, _ := req.Params.(map[string]any)
+
+	var result any
+	switch req.Method {
+	case "initialize":
+		result = map[string]any{
+			"protocolVersion": protocolVersion,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "stub", "version": "1.0.0"},
+		}
+	case "tools/list":
+		schema := map[string]any{"type": "object", "properties": map[string]any{"text": map[string]any{"type": "string"}}}
+		if params["cursor"] == "2" {
+			result = map[string]any{"tools": []any{map[string]any{"name": "fail", "inputSchema": schema}}}
+		} else {
+			result = map[string]any{
+				"tools":      []any{map[string]any{"name": "echo", "description": "Echo the text.", "inputSchema": schema}},
+				"nextCursor": "2",
+			}
+		}
+	case "tools/call":
+		args, _ := params["arguments"].(map[string]any)
+		result = map[string]any{
+			"content": []any{map[string]any{"type": "text", "text": fmt.Sprint(args["text"])}},
+			"isError": params["name"] == "fail",
+		}
+	default:
+		resp.Error = &rpcError{Code: rpcMethodNotFound, Message: "unknown method"}
+		return resp
+	}
+
+	resp.Result, _ = json.Marshal(result)
+	return resp
+}
+
+// TestHelperProcess is run as the stdio MCP server subprocess.
+func TestHelperProcess(t *testing.T) {
+	if os.Getenv("MCP_HELPER_PROCESS") != "1" {
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req rpcMessage
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			os.Exit(2)
+		}
+		if resp := stubServer(req); resp != nil {
+			data, _ := json.Marshal(resp)
+			fmt.Println(string(data))
+		}
+	}
+	os.Exit(0)
+}
+
+func newHTTPStub(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req rpcMessage
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.Method != "initialize" && r.Header.Get(headerSessionID) != "s1" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		resp := stubServer(req)
+		if resp == nil {
+			rw.WriteHeader(http.StatusAccepted)
+			return
+		}
+		data, _ := json.Marshal(resp)
+
+		rw.Header().Set(headerSessionID, "s1")
+		// tool calls are answered as event stream, preceded by a notification
+		if req.Method == "tools/call" {
+			rw.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(rw, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\n")
+			fmt.Fprintf(rw, "event: message\ndata: %s\n\n", data)
+			return
+		}
+		rw.Header().Set("Content-Type", "application/json")
+		_, _ = rw.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestClient(t *testing.T) {
+	srv := newHTTPStub(t)
+
+	testCases := []struct {
+		name   string
+		config domain.MCPServerConfig
+	}{
+		{
+			name: "stdio",
+			config: domain.MCPServerConfig{
+				Name:    "stub",
+				Command: os.Args[0],
+				Args:    []string{"-test.run=TestHelperProcess"},
+				Env:     map[string]string{"MCP_HELPER_PROCESS": "1"},
+			},
+		},
+		{
+			name: "streamable HTTP",
+			config: domain.MCPServerConfig{
+				Name:    "stub",
+				URL:     srv.URL,
+				Headers: map[string]string{"Authorization": "Bearer secret"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, err := NewClient(tc.config)
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+			defer c.Close()
+			ctx := context.Background()
+
+			tools, err := c.ListTools(ctx)
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+			if len(tools) != 2 || tools[0].Name != "echo" || tools[1].Name != "fail" {
+				t.Fatalf("expected tools echo and fail, got %+v", tools)
+			}
+			if tools[0].Description != "Echo the text." || tools[0].InputSchema["type"] != "object" {
+				t.Errorf("unexpected tool %+v", tools[0])
+			}
+
+			res, err := c.CallTool(ctx, "echo", `{"text":"hello"}`)
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+			if res.Text != "hello" || res.IsError {
+				t.Errorf("expected echoed hello, got %+v", res)
+			}
+
+			res, err = c.CallTool(ctx, "fail", `{"text":"broken"}`)
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+			if !res.IsError {
+				t.Errorf("expected a tool error, got %+v", res)
+			}
+		})
+	}
+}
+
+func TestClientReconnect(t *testing.T) {
+	c, _ := NewClient(domain.MCPServerConfig{
+		Name:    "stub",
+		Command: os.Args[0],
+		Args:    []string{"-test.run=TestHelperProcess"},
+		Env:     map[string]string{"MCP_HELPER_PROCESS": "1"},
+	})
+	defer c.Close()
+	ctx := context.Background()
+
+	if _, err := c.ListTools(ctx); err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	// the server exits, e.g. after a crash
+	session, _ := c.connect(ctx)
+	_ = session.(*stdioTransport).cmd.Process.Kill()
+	<-session.(*stdioTransport).exited
+
+	if _, err := c.CallTool(ctx, "echo", `{"text":"again"}`); err != nil {
+		t.Errorf("expected the client to reconnect, got %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// serversFile is the JSON file configuring the MCP servers, in the format
+// common to MCP hosts:
+//
+//	{
+//	  "mcpServers": {
+//	    "weather": {"command": "uvx", "args": ["mcp-weather"], "env": {"API_KEY": "..."}},
+//	    "search":  {"url": "https://mcp.example.com/mcp", "headers": {"Authorization": "Bearer ..."}}
+//	  }
+//	}
+type serversFile struct {
+	MCPServers map[string]struct {
+		Command string            `json:"command"`
+		Args    []string          `json:"args"`
+		Env     map[string]string `json:"env"`
+		URL     string            `json:"url"`
+		Headers map[string]string `json:"headers"`
+	} `json:"mcpServers"`
+}
+
+// LoadServers reads the MCP servers configured in the JSON file at path,
+// ordered by name.
+func LoadServers(path string) ([]domain.MCPServerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mcp servers: %w", err)
+	}
+
+	var f serversFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse mcp servers: %w", err)
+	}
+
+	servers := make([]domain.MCPServerConfig, 0, len(f.MCPServers))
+	for name, s := range f.MCPServers {
+		if (s.Command == "") == (s.URL == "") {
+			return nil, fmt.Errorf("mcp server %q needs either a command or a url", name)
+		}
+		servers = append(servers, domain.MCPServerConfig{
+			Name:    name,
+			Command: s.Command,
+			Args:    s.Args,
+			Env:     s.Env,
+			URL:     s.URL,
+			Headers: s.Headers,
+		})
+	}
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].Name < servers[j].Name
+	})
+
+	return servers, nil
+}
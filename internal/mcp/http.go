@@ -0,0 +1,168 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/tmaxmax/go-sse"
+)
+
+// requestTimeout bounds a single HTTP request; tool calls are bounded by
+// the caller's context as well.
+const requestTimeout = 60 * time.Second
+
+// Headers of the streamable HTTP transport.
+const (
+	headerSessionID       = "Mcp-Session-Id"
+	headerProtocolVersion = "MCP-Protocol-Version"
+)
+
+// httpTransport talks to a server over the streamable HTTP transport: each
+// message is POSTed to the endpoint, which answers with a JSON response or
+// a stream of server-sent events ending with the response.
+type httpTransport struct {
+	url        string
+	headers    map[string]string
+	httpClient *http.Client
+
+	mu sync.Mutex
+	// sessionID is assigned by the server on initialization, if it keeps sessions
+	sessionID string
+}
+
+func newHTTPTransport(config domain.MCPServerConfig) *httpTransport {
+	return &httpTransport{
+		url:        config.URL,
+		headers:    config.Headers,
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+func (t *httpTransport) request(ctx context.Context, msg *rpcMessage) (*rpcMessage, error) {
+	res, err := t.post(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if id := res.Header.Get(headerSessionID); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		var resp rpcMessage
+		if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+			return nil, fmt.Errorf("failed to decode mcp response: %w", err)
+		}
+		return &resp, nil
+	}
+
+	// the stream may carry notifications and requests before the response
+	for ev, err := range sse.Read(res.Body, nil) {
+		if err != nil {
+			return nil, fmt.Errorf("failed to read mcp event stream: %w", err)
+		}
+
+		var resp rpcMessage
+		if err := json.Unmarshal([]byte(ev.Data), &resp); err != nil {
+			slog.Warn("Invalid mcp event", "err", err)
+			continue
+		}
+		if resp.isResponse() && *resp.ID == *msg.ID {
+			return &resp, nil
+		}
+	}
+	return nil, fmt.Errorf("mcp event stream ended without a response")
+}
+
+func (t *httpTransport) notify(ctx context.Context, msg *rpcMessage) error {
+	res, err := t.post(ctx, msg)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+// post sends the message and checks the status of the response.
+func (t *httpTransport) post(ctx context.Context, msg *rpcMessage) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create mcp request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID != "" {
+		req.Header.Set(headerSessionID, sessionID)
+		req.Header.Set(headerProtocolVersion, protocolVersion)
+	}
+
+	res, err := t.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotFound && sessionID != "":
+		res.Body.Close()
+		return nil, fmt.Errorf("%w: session expired", errSessionLost)
+	case res.StatusCode < 200 || res.StatusCode >= 300:
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		res.Body.Close()
+		return nil, fmt.Errorf("mcp server error: %s: %s", res.Status, b)
+	}
+	return res, nil
+}
+
+// close terminates the session on the server, if it keeps one.
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set(headerSessionID, sessionID)
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+
+	res, err := t.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
@@ -0,0 +1,186 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+const (
+	// maxStdioMessageSize bounds a single message read from a subprocess.
+	maxStdioMessageSize = 16 << 20
+
+	// stopTimeout is how long a subprocess may take to exit after its
+	// stdin was closed before it is killed.
+	stopTimeout = 5 * time.Second
+)
+
+// stdioTransport talks to a server running as a subprocess, exchanging
+// newline-delimited JSON-RPC messages over its stdin and stdout.
+type stdioTransport struct {
+	name  string
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	// wmu serializes writes to stdin
+	wmu sync.Mutex
+
+	mu sync.Mutex
+	// pending holds the requests waiting for their response, by ID
+	pending map[int64]chan *rpcMessage
+	// exited is closed once stdout is closed, i.e. the server has stopped
+	exited chan struct{}
+}
+
+// startStdio starts the server subprocess.
+func startStdio(config domain.MCPServerConfig) (*stdioTransport, error) {
+	cmd := exec.Command(config.Command, config.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range config.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stderr = &serverLog{name: config.Name}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		slog.Error("Unable to start MCP server", "server", config.Name, "err", err)
+		return nil, fmt.Errorf("unable to start mcp server %q: %w", config.Name, err)
+	}
+
+	t := &stdioTransport{
+		name:    config.Name,
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int64]chan *rpcMessage),
+		exited:  make(chan struct{}),
+	}
+	go t.readLoop(stdout)
+
+	return t, nil
+}
+
+// readLoop dispatches the messages of the server until it exits.
+func (t *stdioTransport) readLoop(stdout io.Reader) {
+	defer close(t.exited)
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxStdioMessageSize)
+	for scanner.Scan() {
+		var msg rpcMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			slog.Warn("Invalid message from MCP server", "server", t.name, "err", err)
+			continue
+		}
+
+		switch {
+		case msg.isResponse():
+			t.mu.Lock()
+			ch, ok := t.pending[*msg.ID]
+			delete(t.pending, *msg.ID)
+			t.mu.Unlock()
+			if ok {
+				ch <- &msg
+			}
+		case msg.ID != nil:
+			t.answer(&msg)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		slog.Error("Failed to read from MCP server", "server", t.name, "err", err)
+	}
+}
+
+// answer responds to a request of the server: pings are answered, as
+// required, all other methods are not supported.
+func (t *stdioTransport) answer(req *rpcMessage) {
+	resp := &rpcMessage{JSONRPC: "2.0", ID: req.ID}
+	if req.Method == "ping" {
+		resp.Result = json.RawMessage("{}")
+	} else {
+		resp.Error = &rpcError{Code: rpcMethodNotFound, Message: "method not supported: " + req.Method}
+	}
+	if err := t.write(resp); err != nil {
+		slog.Warn("Failed to answer MCP server", "server", t.name, "method", req.Method, "err", err)
+	}
+}
+
+func (t *stdioTransport) request(ctx context.Context, msg *rpcMessage) (*rpcMessage, error) {
+	ch := make(chan *rpcMessage, 1)
+	t.mu.Lock()
+	t.pending[*msg.ID] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, *msg.ID)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(msg); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSessionLost, err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-t.exited:
+		return nil, fmt.Errorf("%w: server exited", errSessionLost)
+	case resp := <-ch:
+		return resp, nil
+	}
+}
+
+func (t *stdioTransport) notify(ctx context.Context, msg *rpcMessage) error {
+	return t.write(msg)
+}
+
+func (t *stdioTransport) write(msg *rpcMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	t.wmu.Lock()
+	defer t.wmu.Unlock()
+	_, err = t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+// close closes stdin, so the server exits, and kills it if it does not.
+func (t *stdioTransport) close() error {
+	_ = t.stdin.Close()
+
+	select {
+	case <-t.exited:
+	case <-time.After(stopTimeout):
+		slog.Warn("MCP server did not exit, killing it", "server", t.name)
+		_ = t.cmd.Process.Kill()
+	}
+	return t.cmd.Wait()
+}
+
+// serverLog forwards the stderr output of a server to the log.
+type serverLog struct {
+	name string
+}
+
+func (l *serverLog) Write(p []byte) (int, error) {
+	slog.Debug("MCP server output", "server", l.name, "output", string(p))
+	return len(p), nil
+}
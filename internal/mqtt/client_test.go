@@ -0,0 +1,274 @@
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// stubBroker is a local broker accepting the user "user" with the password
+// "secret". It keeps retained messages and forwards messages to subscribers.
+type stubBroker struct {
+	ln net.Listener
+
+	mu          sync.Mutex
+	retained    map[string][]byte
+	subscribers map[*brokerConn][]string
+}
+
+func newStubBroker(t *testing.T) *stubBroker {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	b := &stubBroker{
+		ln:          ln,
+		retained:    map[string][]byte{},
+		subscribers: map[*brokerConn][]string{},
+	}
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(&brokerConn{Conn: c, r: bufio.NewReader(c)})
+		}
+	}()
+	return b
+}
+
+func (b *stubBroker) url(credentials string) string {
+	return "mqtt://" + credentials + "@" + b.ln.Addr().String()
+}
+
+func (b *stubBroker) serve(c *brokerConn) {
+	defer c.Close()
+	defer func() {
+		b.mu.Lock()
+		delete(b.subscribers, c)
+		b.mu.Unlock()
+	}()
+
+	for {
+		header, body, err := c.readPacket()
+		if err != nil {
+			return
+		}
+
+		switch header & packetTypeMask {
+		case packetConnect:
+			code := byte(5)
+			if connectCredentials(body) == "user:secret" {
+				code = 0
+			}
+			_ = c.writePacket(packetConnack, []byte{0, code})
+		case packetPublish:
+			msg, packetID, _ := parsePublish(header, body)
+			if msg.QoS > 0 {
+				_ = c.writePacket(packetPuback, binary.BigEndian.AppendUint16(nil, packetID))
+			}
+			b.publish(*msg)
+		case packetSubscribe:
+			b.subscribe(c, body)
+		case packetPingreq:
+			_ = c.writePacket(packetPingresp, nil)
+		case packetDisconnect:
+			return
+		}
+	}
+}
+
+func (b *stubBroker) publish(msg domain.MQTTMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if msg.Retain {
+		b.retained[msg.Topic] = msg.Payload
+	}
+	msg.Retain = false
+	for c, filters := range b.subscribers {
+		for _, f := range filters {
+			if domain.MatchMQTTTopic(f, msg.Topic) {
+				_ = c.publish(domain.MQTTMessage{Topic: msg.Topic, Payload: msg.Payload}, 0)
+				break
+			}
+		}
+	}
+}
+
+func (b *stubBroker) subscribe(c *brokerConn, body []byte) {
+	packetID, rest := body[:2], body[2:]
+	var filters []string
+	codes := []byte{}
+	for len(rest) > 0 {
+		f, r, _ := readString(rest)
+		filters = append(filters, f)
+		rest = r[1:]
+		if f == "forbidden" {
+			codes = append(codes, subackFailure)
+		} else {
+			codes = append(codes, 0)
+		}
+	}
+	_ = c.writePacket(packetSuback, append(packetID, codes...))
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[c] = filters
+	for topic, payload := range b.retained {
+		for _, f := range filters {
+			if domain.MatchMQTTTopic(f, topic) {
+				_ = c.publish(domain.MQTTMessage{Topic: topic, Payload: payload, Retain: true}, 0)
+			}
+		}
+	}
+}
+
+// connectCredentials returns "username:password" of a CONNECT packet.
+func connectCredentials(body []byte) string {
+	_, rest, _ := readString(body)
+	flags := rest[1]
+	_, rest, _ = readString(rest[4:]) // skip level, flags, keep alive and client ID
+
+	var username, password string
+	if flags&connectUsername != 0 {
+		username, rest, _ = readString(rest)
+	}
+	if flags&connectPassword != 0 {
+		password, _, _ = readString(rest)
+	}
+	return username + ":" + password
+}
+
+func TestClientRetained(t *testing.T) {
+	b := newStubBroker(t)
+	c, err := NewClient(b.url("user:secret"), "test")
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	ctx := context.Background()
+
+	err = c.Publish(ctx, domain.MQTTMessage{Topic: "home/temperature", Payload: []byte("21.5"), QoS: 1, Retain: true})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	msg, err := c.Retained(ctx, "home/temperature")
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if string(msg.Payload) != "21.5" || !msg.Retain {
+		t.Errorf("expected retained payload 21.5, got %+v", msg)
+	}
+
+	if _, err := c.Retained(ctx, "home/humidity"); !errors.Is(err, domain.ErrNoRetainedMQTTMessage) {
+		t.Errorf("expected no retained message, got %v", err)
+	}
+
+	// live messages published meanwhile are no retained value
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				_ = c.Publish(ctx, domain.MQTTMessage{Topic: "home/humidity", Payload: []byte("40"), QoS: 1})
+			}
+		}
+	}()
+	_, err = c.Retained(ctx, "home/humidity")
+	close(done)
+	if !errors.Is(err, domain.ErrNoRetainedMQTTMessage) {
+		t.Errorf("expected no retained message despite live messages, got %v", err)
+	}
+
+	if _, err := c.Retained(ctx, "home/+"); !errors.Is(err, domain.ErrInvalidMQTTTopic) {
+		t.Errorf("expected invalid topic, got %v", err)
+	}
+	if err := c.Publish(ctx, domain.MQTTMessage{Topic: "home/#"}); !errors.Is(err, domain.ErrInvalidMQTTTopic) {
+		t.Errorf("expected invalid topic, got %v", err)
+	}
+}
+
+func TestClientSubscribe(t *testing.T) {
+	b := newStubBroker(t)
+	c, _ := NewClient(b.url("user:secret"), "test")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan domain.MQTTMessage, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Subscribe(ctx, []string{"home/+/doorbell"}, func(msg domain.MQTTMessage) {
+			received <- msg
+		})
+	}()
+
+	// publish until the subscription is in place
+	deadline := time.After(5 * time.Second)
+	for msg := (domain.MQTTMessage{}); msg.Topic == ""; {
+		if err := c.Publish(ctx, domain.MQTTMessage{Topic: "home/front/doorbell", Payload: []byte("ring")}); err != nil {
+			t.Fatalf("expected error to be nil got %v", err)
+		}
+		select {
+		case msg = <-received:
+			if string(msg.Payload) != "ring" {
+				t.Errorf("expected payload ring, got %q", msg.Payload)
+			}
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("no message received")
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("expected nil after cancellation, got %v", err)
+	}
+
+	if err := c.Subscribe(context.Background(), []string{"forbidden"}, func(domain.MQTTMessage) {}); err == nil {
+		t.Error("expected an error for a rejected subscription")
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	b := newStubBroker(t)
+	c, _ := NewClient(b.url("user:wrong"), "test")
+
+	if err := c.Publish(context.Background(), domain.MQTTMessage{Topic: "home/light", Payload: []byte("on")}); err == nil {
+		t.Error("expected an error for invalid credentials")
+	}
+}
+
+func TestMatchMQTTTopic(t *testing.T) {
+	testCases := []struct {
+		filter, topic string
+		match         bool
+	}{
+		{"home/doorbell", "home/doorbell", true},
+		{"home/doorbell", "home/door", false},
+		{"home/+/doorbell", "home/front/doorbell", true},
+		{"home/+", "home/front/doorbell", false},
+		{"home/#", "home/front/doorbell", true},
+		{"home/#", "home", true},
+		{"#", "alerts", true},
+		{"alerts/#", "home/doorbell", false},
+	}
+
+	for _, tc := range testCases {
+		if match := domain.MatchMQTTTopic(tc.filter, tc.topic); match != tc.match {
+			t.Errorf("expected %q matching %q to be %v", tc.filter, tc.topic, tc.match)
+		}
+	}
+}
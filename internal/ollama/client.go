@@ -0,0 +1,74 @@
+// Package ollama implements completion and embedding providers on top of
+// the native API of an Ollama server, see
+// https://github.com/ollama/ollama/blob/main/docs/api.md.
+//
+// Ollama runs models locally, e.g. on a home server, so the voice assistant
+// does not depend on cloud calls.
+package ollama
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// DefaultURL is the address an Ollama server listens on by default.
+const DefaultURL = "http://localhost:11434"
+
+// client sends requests to the native API of an Ollama server.
+type client struct {
+	baseURL string
+	// httpClient has no timeout: loading a model may take a while, requests
+	// are bounded by their context instead
+	httpClient *http.Client
+}
+
+func newClient(baseURL string) client {
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	return client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{},
+	}
+}
+
+// post sends body encoded as JSON and returns the response once its status
+// was checked. The caller must close the body of the response.
+func (c *client) post(ctx context.Context, path string, body any) (*http.Response, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal ollama request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ollama request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		slog.Error("Ollama request failed", "err", err, "path", path)
+		return nil, fmt.Errorf("ollama request failed: %w", err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		defer res.Body.Close()
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		b, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		if json.Unmarshal(b, &apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = string(b)
+		}
+		slog.Error("Ollama error", "status", res.Status, "error", apiErr.Error, "path", path)
+		return nil, fmt.Errorf("ollama error: %s: %s", res.Status, apiErr.Error)
+	}
+	return res, nil
+}
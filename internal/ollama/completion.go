@@ -0,0 +1,257 @@
+package ollama
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// DefaultModel is the chat model used unless another is configured.
+const DefaultModel = "llama3.2"
+
+// maxChunkSize bounds a single line of a streamed response.
+const maxChunkSize = 1 << 20
+
+// completionClient implements the CompletionProvider interface using the
+// chat endpoint of an Ollama server.
+type completionClient struct {
+	client
+	options domain.CompletionOptions
+}
+
+// NewCompletionClient creates a completion provider for the Ollama server at
+// baseURL, DefaultURL if empty. The model defaults to DefaultModel and the
+// system prompt to domain.DefaultSystemPrompt.
+func NewCompletionClient(baseURL string, options domain.CompletionOptions) *completionClient {
+	if options.Model == "" {
+		options.Model = DefaultModel
+	}
+	if options.SystemPrompt == "" {
+		options.SystemPrompt = domain.DefaultSystemPrompt
+	}
+	return &completionClient{
+		client:  newClient(baseURL),
+		options: options,
+	}
+}
+
+// chatRequest is the JSON body of a chat request.
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+	Tools    []chatTool    `json:"tools,omitempty"`
+	Stream   bool          `json:"stream"`
+	Options  modelOptions  `json:"options,omitzero"`
+}
+
+// modelOptions holds the model parameters of a request.
+type modelOptions struct {
+	Temperature *float64 `json:"temperature,omitempty"`
+	NumPredict  int      `json:"num_predict,omitempty"`
+}
+
+type chatMessage struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	ToolCalls []toolCall `json:"tool_calls,omitempty"`
+	// ToolName names the tool a tool message is the result of
+	ToolName string `json:"tool_name,omitempty"`
+}
+
+type toolCall struct {
+	ID       string `json:"id,omitempty"`
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type chatTool struct {
+	Type     string `json:"type"`
+	Function struct {
+		Name        string            `json:"name"`
+		Description string            `json:"description"`
+		Parameters  domain.ToolSchema `json:"parameters"`
+	} `json:"function"`
+}
+
+// chatResponse is a JSON response to a chat request, or a chunk of it when
+// streamed.
+type chatResponse struct {
+	Message chatMessage `json:"message"`
+	Done    bool        `json:"done"`
+	Error   string      `json:"error"`
+}
+
+// CreateCompletion generates a text completion for the given prompt.
+//
+// Endpoint: POST /api/chat
+func (c *completionClient) CreateCompletion(ctx context.Context, req *domain.CompletionRequest) (*domain.CompletionResult, error) {
+	res, err := c.post(ctx, "/api/chat", c.chatRequest(req, false))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var chat chatResponse
+	if err := json.NewDecoder(res.Body).Decode(&chat); err != nil {
+		return nil, fmt.Errorf("failed to decode ollama chat response: %w", err)
+	}
+	if chat.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", chat.Error)
+	}
+
+	text, expectsReply := domain.CutExpectsReplyMarker(chat.Message.Content)
+	return &domain.CompletionResult{
+		Text:         text,
+		ExpectsReply: expectsReply,
+	}, nil
+}
+
+// StreamCompletion generates a completion for the given prompt and streams
+// the content deltas as they are produced. The response is streamed as
+// newline-delimited JSON chunks.
+//
+// Endpoint: POST /api/chat
+func (c *completionClient) StreamCompletion(ctx context.Context, req *domain.CompletionRequest) (<-chan domain.CompletionDelta, error) {
+	chatReq := c.chatRequest(req, true)
+	chatReq.Tools = chatTools(req.Tools)
+	res, err := c.post(ctx, "/api/chat", chatReq)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan domain.CompletionDelta)
+
+	go func() {
+		defer res.Body.Close()
+		defer close(ch)
+
+		send := func(delta domain.CompletionDelta) bool {
+			select {
+			case <-ctx.Done():
+				slog.Warn("Completion stream canceled by context")
+				return false
+			case ch <- delta:
+				return true
+			}
+		}
+
+		// tool calls arrive complete, but possibly spread over several chunks
+		var toolCalls []domain.ToolCall
+		var replies domain.ExpectsReplyFilter
+
+		scanner := bufio.NewScanner(res.Body)
+		scanner.Buffer(make([]byte, 64*1024), maxChunkSize)
+		for scanner.Scan() {
+			var chunk chatResponse
+			if err := json.Unmarshal(scanner.Bytes(), &chunk); err != nil {
+				slog.Warn("Invalid ollama chat chunk", "err", err)
+				continue
+			}
+			if chunk.Error != "" {
+				slog.Error("completion stream read error", "err", chunk.Error)
+				send(domain.CompletionDelta{Err: fmt.Errorf("completion stream read error: %s", chunk.Error)})
+				return
+			}
+
+			for _, tc := range chunk.Message.ToolCalls {
+				id := tc.ID
+				if id == "" {
+					id = fmt.Sprintf("call_%d", len(toolCalls))
+				}
+				toolCalls = append(toolCalls, domain.ToolCall{
+					ID:        id,
+					Name:      tc.Function.Name,
+					Arguments: string(tc.Function.Arguments),
+				})
+			}
+
+			if text := replies.Push(chunk.Message.Content); text != "" && !send(domain.CompletionDelta{Text: text}) {
+				return
+			}
+			if chunk.Done {
+				break
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			slog.Error("completion stream read error", "err", err)
+			send(domain.CompletionDelta{Err: fmt.Errorf("completion stream read error: %w", err)})
+			return
+		}
+
+		rest, expectsReply := replies.Flush()
+		if rest != "" || len(toolCalls) > 0 || expectsReply {
+			send(domain.CompletionDelta{Text: rest, ToolCalls: toolCalls, ExpectsReply: expectsReply})
+		}
+	}()
+
+	return ch, nil
+}
+
+// chatRequest builds a chat request for the completion request, applying
+// the configured options.
+func (c *completionClient) chatRequest(req *domain.CompletionRequest, stream bool) *chatRequest {
+	return &chatRequest{
+		Model:    c.options.Model,
+		Messages: chatMessages(req.Messages(c.options.SystemPrompt)),
+		Stream:   stream,
+		Options: modelOptions{
+			Temperature: c.options.Temperature,
+			NumPredict:  c.options.MaxTokens,
+		},
+	}
+}
+
+// chatMessages converts the domain messages to chat messages. Tool results
+// are identified by the name of the tool rather than the ID of the call.
+func chatMessages(messages []domain.Message) []chatMessage {
+	toolNames := make(map[string]string)
+
+	params := make([]chatMessage, 0, len(messages))
+	for _, m := range messages {
+		msg := chatMessage{Role: string(m.Role), Content: m.Text}
+		for _, tc := range m.ToolCalls {
+			toolNames[tc.ID] = tc.Name
+
+			call := toolCall{ID: tc.ID}
+			call.Function.Name = tc.Name
+			call.Function.Arguments = json.RawMessage("{}")
+			if json.Valid([]byte(tc.Arguments)) {
+				call.Function.Arguments = json.RawMessage(tc.Arguments)
+			}
+			msg.ToolCalls = append(msg.ToolCalls, call)
+		}
+		if m.Role == domain.MessageRoleTool {
+			msg.ToolName = toolNames[m.ToolCallID]
+		}
+		params = append(params, msg)
+	}
+	return params
+}
+
+// chatTools converts the tool definitions to function tools. Tools without
+// a schema take no arguments.
+func chatTools(tools []domain.ToolDefinition) []chatTool {
+	params := make([]chatTool, 0, len(tools))
+	for _, t := range tools {
+		tool := chatTool{Type: "function"}
+		tool.Function.Name = t.Name
+		tool.Function.Description = t.Description
+		tool.Function.Parameters = t.Schema
+		if len(tool.Function.Parameters) == 0 {
+			tool.Function.Parameters = domain.ToolSchema{"type": "object", "properties": map[string]any{}}
+		}
+		params = append(params, tool)
+	}
+	return params
+}
@@ -0,0 +1,174 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// newStub returns an Ollama stub that calls the "get_time" tool unless the
+// conversation carries its result, and answers with the result otherwise.
+func newStub(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/chat", func(rw http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model != "llama3.2" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.Messages[0].Role != "system" || req.Messages[0].Content != "Be brief." {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.Options.Temperature == nil || *req.Options.Temperature != 0.2 || req.Options.NumPredict != 64 {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		last := req.Messages[len(req.Messages)-1]
+		if !req.Stream {
+			_ = json.NewEncoder(rw).Encode(map[string]any{"message": map[string]any{"role": "assistant", "content": "Hi!"}, "done": true})
+			return
+		}
+		if last.Role == "tool" {
+			if last.ToolName != "get_time" {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintln(rw, `{"message":{"role":"assistant","content":"It is "},"done":false}`)
+			fmt.Fprintf(rw, `{"message":{"role":"assistant","content":%q},"done":false}`+"\n", last.Content)
+			fmt.Fprintln(rw, `{"message":{"role":"assistant","content":""},"done":true}`)
+			return
+		}
+		if len(req.Tools) != 1 || req.Tools[0].Function.Name != "get_time" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintln(rw, `{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"get_time","arguments":{"zone":"UTC"}}}]},"done":false}`)
+		fmt.Fprintln(rw, `{"message":{"role":"assistant","content":""},"done":true}`)
+	})
+	mux.HandleFunc("POST /api/embed", func(rw http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(rw).Encode(map[string]any{"model": "nomic-embed-text", "embeddings": [][]float64{{0.1, 0.2}}})
+	})
+	mux.HandleFunc("/", func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+		_, _ = rw.Write([]byte(`{"error":"model not found"}`))
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func collect(t *testing.T, ch <-chan domain.CompletionDelta) (string, []domain.ToolCall) {
+	var text string
+	var toolCalls []domain.ToolCall
+	for d := range ch {
+		if d.Err != nil {
+			t.Fatalf("expected error to be nil got %v", d.Err)
+		}
+		text += d.Text
+		toolCalls = append(toolCalls, d.ToolCalls...)
+	}
+	return text, toolCalls
+}
+
+func TestCompletionClient(t *testing.T) {
+	srv := newStub(t)
+	temperature := 0.2
+	c := NewCompletionClient(srv.URL, domain.CompletionOptions{Temperature: &temperature, MaxTokens: 64, SystemPrompt: "Be brief."})
+	ctx := context.Background()
+
+	res, err := c.CreateCompletion(ctx, &domain.CompletionRequest{Prompt: "Hello"})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if res.Text != "Hi!" {
+		t.Errorf("expected Hi!, got %q", res.Text)
+	}
+
+	req := &domain.CompletionRequest{
+		Prompt: "What time is it?",
+		Tools:  []domain.ToolDefinition{{Name: "get_time", Description: "Returns the time."}},
+	}
+	ch, err := c.StreamCompletion(ctx, req)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	_, toolCalls := collect(t, ch)
+	if len(toolCalls) != 1 || toolCalls[0].Name != "get_time" || toolCalls[0].Arguments != `{"zone":"UTC"}` || toolCalls[0].ID == "" {
+		t.Fatalf("expected a get_time call, got %+v", toolCalls)
+	}
+
+	// the result is passed back by the name of the tool
+	req.ToolMessages = []domain.Message{
+		{Role: domain.MessageRoleAssistant, ToolCalls: toolCalls},
+		{Role: domain.MessageRoleTool, ToolCallID: toolCalls[0].ID, Text: "noon"},
+	}
+	ch, err = c.StreamCompletion(ctx, req)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	text, toolCalls := collect(t, ch)
+	if text != "It is noon" || len(toolCalls) != 0 {
+		t.Errorf("expected the answer 'It is noon', got %q and %+v", text, toolCalls)
+	}
+}
+
+func TestCompletionClientExpectsReply(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || !strings.Contains(req.Messages[0].Content, domain.ExpectsReplyMarker) {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// the marker is split over chunks
+		for _, content := range []string{"Which room", " do you mean? <await", "-reply>"} {
+			fmt.Fprintf(rw, `{"message":{"role":"assistant","content":%q},"done":false}`+"\n", content)
+		}
+		fmt.Fprintln(rw, `{"message":{"role":"assistant","content":""},"done":true}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	ch, err := NewCompletionClient(srv.URL, domain.CompletionOptions{}).
+		StreamCompletion(context.Background(), &domain.CompletionRequest{Prompt: "Turn on the light", MarkReplies: true})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	var text string
+	expectsReply := false
+	for d := range ch {
+		if d.Err != nil {
+			t.Fatalf("expected error to be nil got %v", d.Err)
+		}
+		text += d.Text
+		expectsReply = expectsReply || d.ExpectsReply
+	}
+	if text != "Which room do you mean? " || !expectsReply {
+		t.Errorf("expected the answer 'Which room do you mean? ' awaiting a reply, got %q, %v", text, expectsReply)
+	}
+}
+
+func TestEmbeddingClient(t *testing.T) {
+	srv := newStub(t)
+
+	res, err := NewEmbeddingClient(srv.URL, "").CreateEmbedding(context.Background(), domain.EmbeddingRequest{Data: "hello"})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if len(res.Embedding) != 2 || res.Model != DefaultEmbeddingModel {
+		t.Errorf("unexpected embedding %+v", res)
+	}
+
+	_, err = NewEmbeddingClient(srv.URL+"/missing", "").CreateEmbedding(context.Background(), domain.EmbeddingRequest{Data: "hello"})
+	if err == nil {
+		t.Error("expected an error for an unknown endpoint")
+	}
+}
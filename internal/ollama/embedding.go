@@ -0,0 +1,72 @@
+package ollama
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// DefaultEmbeddingModel is the embedding model used unless another is configured.
+const DefaultEmbeddingModel = "nomic-embed-text"
+
+// SimilarityThresholds are tuned to DefaultEmbeddingModel, whose
+// similarities spread much wider than those of OpenAI's models: unrelated
+// texts score around 0.4, related ones from 0.6. Other models may need
+// other thresholds.
+var SimilarityThresholds = domain.SimilarityThresholds{
+	Recall:    0.6,
+	Duplicate: 0.9,
+	Forget:    0.7,
+	Retrieve:  0.55,
+}
+
+// embeddingClient implements the EmbeddingProvider interface using the
+// embed endpoint of an Ollama server.
+type embeddingClient struct {
+	client
+	model string
+}
+
+// NewEmbeddingClient creates an embedding provider for the Ollama server at
+// baseURL, DefaultURL if empty, using the model, DefaultEmbeddingModel if empty.
+func NewEmbeddingClient(baseURL, model string) *embeddingClient {
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+	return &embeddingClient{
+		client: newClient(baseURL),
+		model:  model,
+	}
+}
+
+// CreateEmbedding generates the embedding of the request data.
+//
+// Endpoint: POST /api/embed
+func (e *embeddingClient) CreateEmbedding(ctx context.Context, req domain.EmbeddingRequest) (*domain.EmbeddingResult, error) {
+	res, err := e.post(ctx, "/api/embed", map[string]any{
+		"model": e.model,
+		"input": req.Data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create embedding failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	var embed struct {
+		Model      string      `json:"model"`
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&embed); err != nil {
+		return nil, fmt.Errorf("failed to decode ollama embed response: %w", err)
+	}
+	if len(embed.Embeddings) == 0 {
+		return nil, fmt.Errorf("create embedding failed: no embedding returned")
+	}
+
+	return &domain.EmbeddingResult{
+		Embedding: embed.Embeddings[0],
+		Model:     embed.Model,
+	}, nil
+}
@@ -13,47 +13,199 @@ import (
 // OpenAI API as a backend.
 //
 // It wraps an initialized *openai.Client and exposes a high-level method
-// to create chat-style completions based on a user prompt.
+// to create chat-style completions based on a user prompt. Any server
+// implementing the Chat Completions API (e.g. a local model server) can be
+// used by configuring the base URL of the client.
 type completionClient struct {
-	client *openai.Client
+	client  *openai.Client
+	options domain.CompletionOptions
 }
 
 // NewCompletionClient creates a new instance of completionClient.
 // The provided OpenAI client must be pre-configured with a valid API key.
-func NewCompletionClient(client *openai.Client) *completionClient {
+// The model defaults to GPT-4o-mini and the system prompt to
+// domain.DefaultSystemPrompt.
+func NewCompletionClient(client *openai.Client, options domain.CompletionOptions) *completionClient {
+	if options.Model == "" {
+		options.Model = openai.ChatModelGPT4oMini
+	}
+	if options.SystemPrompt == "" {
+		options.SystemPrompt = domain.DefaultSystemPrompt
+	}
 	return &completionClient{
-		client: client,
+		client:  client,
+		options: options,
 	}
 }
 
 // CreateCompletion generates a text completion for the given prompt using
 // the OpenAI Chat Completions API.
-//
-// It currently uses the GPT-4o-mini model for efficiency but can be
-// parameterized or made configurable in future versions.
 func (c *completionClient) CreateCompletion(ctx context.Context, req *domain.CompletionRequest) (*domain.CompletionResult, error) {
-	messages := make([]openai.ChatCompletionMessageParamUnion, 0)
-
-	systemMessage := openai.SystemMessage("You are an AI agent named Vicky")
-	messages = append(messages, systemMessage)
-
-	userContent := openai.TextContentPart(req.Prompt)
-	userMessage := openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{
-		userContent,
-	})
-
-	messages = append(messages, userMessage)
-	completion, err := c.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
-		Messages: messages,
-		Model:    openai.ChatModelGPT4oMini,
-	})
+	completion, err := c.client.Chat.Completions.New(ctx, c.params(req))
 	if err != nil {
 		slog.Error("completion create completion request fail", "err", err)
 		return nil, fmt.Errorf("completion create completion request fail: %w", err)
 	}
 
 	m := completion.Choices[0].Message
+	text, expectsReply := domain.CutExpectsReplyMarker(m.Content)
 	return &domain.CompletionResult{
-		Text: m.Content,
+		Text:         text,
+		ExpectsReply: expectsReply,
 	}, nil
 }
+
+// StreamCompletion generates a completion for the given prompt and streams
+// the content deltas as they are produced by the OpenAI Chat Completions API.
+func (c *completionClient) StreamCompletion(ctx context.Context, req *domain.CompletionRequest) (<-chan domain.CompletionDelta, error) {
+	params := c.params(req)
+	params.Tools = chatTools(req.Tools)
+	stream := c.client.Chat.Completions.NewStreaming(ctx, params)
+	if err := stream.Err(); err != nil {
+		slog.Error("completion stream request fail", "err", err)
+		return nil, fmt.Errorf("completion stream request fail: %w", err)
+	}
+
+	ch := make(chan domain.CompletionDelta)
+
+	go func() {
+		defer stream.Close()
+		defer close(ch)
+
+		// tool calls are streamed in fragments, keyed by their index
+		var toolCalls []domain.ToolCall
+		var replies domain.ExpectsReplyFilter
+
+		for stream.Next() {
+			chunk := stream.Current()
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+			delta := chunk.Choices[0].Delta
+
+			for _, tc := range delta.ToolCalls {
+				for int(tc.Index) >= len(toolCalls) {
+					toolCalls = append(toolCalls, domain.ToolCall{})
+				}
+				call := &toolCalls[tc.Index]
+				if tc.ID != "" {
+					call.ID = tc.ID
+				}
+				call.Name += tc.Function.Name
+				call.Arguments += tc.Function.Arguments
+			}
+
+			text := replies.Push(delta.Content)
+			if text == "" {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				slog.Warn("Completion stream canceled by context")
+				return
+			case ch <- domain.CompletionDelta{Text: text}:
+			}
+		}
+
+		if err := stream.Err(); err != nil {
+			slog.Error("completion stream read error", "err", err)
+			select {
+			case <-ctx.Done():
+			case ch <- domain.CompletionDelta{Err: fmt.Errorf("completion stream read error: %w", err)}:
+			}
+			return
+		}
+
+		rest, expectsReply := replies.Flush()
+		if rest != "" || len(toolCalls) > 0 || expectsReply {
+			select {
+			case <-ctx.Done():
+			case ch <- domain.CompletionDelta{Text: rest, ToolCalls: toolCalls, ExpectsReply: expectsReply}:
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// params builds the parameters of a chat completion for the request,
+// applying the configured options.
+func (c *completionClient) params(req *domain.CompletionRequest) openai.ChatCompletionNewParams {
+	params := openai.ChatCompletionNewParams{
+		Messages: chatMessages(req.Messages(c.options.SystemPrompt)),
+		Model:    c.options.Model,
+	}
+	if c.options.Temperature != nil {
+		params.Temperature = openai.Float(*c.options.Temperature)
+	}
+	if c.options.MaxTokens > 0 {
+		// max_tokens rather than max_completion_tokens, which compatible servers may not know
+		params.MaxTokens = openai.Int(int64(c.options.MaxTokens))
+	}
+	return params
+}
+
+// chatMessages converts the domain messages to chat messages.
+func chatMessages(messages []domain.Message) []openai.ChatCompletionMessageParamUnion {
+	params := make([]openai.ChatCompletionMessageParamUnion, 0, len(messages))
+	for _, m := range messages {
+		params = append(params, chatMessage(m))
+	}
+	return params
+}
+
+// chatMessage converts a single domain message to a chat message.
+func chatMessage(m domain.Message) openai.ChatCompletionMessageParamUnion {
+	switch m.Role {
+	case domain.MessageRoleSystem:
+		return openai.SystemMessage(m.Text)
+	case domain.MessageRoleTool:
+		return openai.ToolMessage(m.Text, m.ToolCallID)
+	case domain.MessageRoleAssistant:
+		if len(m.ToolCalls) == 0 {
+			return openai.AssistantMessage(m.Text)
+		}
+
+		assistant := openai.ChatCompletionAssistantMessageParam{}
+		if m.Text != "" {
+			assistant.Content.OfString = openai.String(m.Text)
+		}
+		for _, tc := range m.ToolCalls {
+			assistant.ToolCalls = append(assistant.ToolCalls, openai.ChatCompletionMessageToolCallUnionParam{
+				OfFunction: &openai.ChatCompletionMessageFunctionToolCallParam{
+					ID: tc.ID,
+					Function: openai.ChatCompletionMessageFunctionToolCallFunctionParam{
+						Name:      tc.Name,
+						Arguments: tc.Arguments,
+					},
+				},
+			})
+		}
+		return openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant}
+	default:
+		return openai.UserMessage(m.Text)
+	}
+}
+
+// chatTools converts the tool definitions to function tools of the Chat
+// Completions API. Tools without a schema take no arguments.
+func chatTools(tools []domain.ToolDefinition) []openai.ChatCompletionToolUnionParam {
+	if len(tools) == 0 {
+		return nil
+	}
+
+	params := make([]openai.ChatCompletionToolUnionParam, 0, len(tools))
+	for _, t := range tools {
+		parameters := openai.FunctionParameters(t.Schema)
+		if len(parameters) == 0 {
+			parameters = openai.FunctionParameters{"type": "object", "properties": map[string]any{}}
+		}
+		params = append(params, openai.ChatCompletionFunctionTool(openai.FunctionDefinitionParam{
+			Name:        t.Name,
+			Description: openai.String(t.Description),
+			Parameters:  parameters,
+		}))
+	}
+	return params
+}
@@ -8,6 +8,15 @@ import (
 	"github.com/ownerofglory/raspi-agent/internal/core/domain"
 )
 
+// SimilarityThresholds are tuned to text-embedding-ada-002, whose
+// similarities of unrelated texts rarely fall below 0.7.
+var SimilarityThresholds = domain.SimilarityThresholds{
+	Recall:    0.78,
+	Duplicate: 0.93,
+	Forget:    0.85,
+	Retrieve:  0.75,
+}
+
 type embeddingClient struct {
 	client *openai.Client
 }
@@ -17,10 +17,22 @@ func NewSpeechToTextClient(client *openai.Client) *speechToText {
 	return &speechToText{client: client}
 }
 
+// defaultAudioFilename is used for streamed audio that has no file name,
+// since the API infers the audio format from the file extension.
+const defaultAudioFilename = "speech.wav"
+
 func (s *speechToText) Transcribe(ctx context.Context, req domain.TranscribeRequest) (*domain.TranscribeResult, error) {
+	audio := req.Audio
+	if _, named := audio.(interface{ Name() string }); !named {
+		audio = openai.File(audio, defaultAudioFilename, "audio/wav")
+	}
+
 	params := openai.AudioTranscriptionNewParams{
 		Model: openai.AudioModelGPT4oMiniTranscribe,
-		File:  req.Audio,
+		File:  audio,
+	}
+	if req.Language != "" {
+		params.Language = openai.String(req.Language)
 	}
 	res, err := s.client.Audio.Transcriptions.New(ctx, params)
 	if err != nil {
@@ -34,13 +34,7 @@ type textToSpeechEvent struct {
 }
 
 func (c *textToSpeech) ProduceSpeechSSE(ctx context.Context, req *domain.SpeechRequest) (<-chan *domain.SpeechResult, error) {
-	params := openai.AudioSpeechNewParams{
-		Input:        req.Text,
-		Model:        openai.SpeechModelGPT4oMiniTTS,
-		Voice:        openai.AudioSpeechNewParamsVoiceShimmer,
-		StreamFormat: openai.AudioSpeechNewParamsStreamFormatSSE,
-	}
-	response, err := c.client.Audio.Speech.New(ctx, params)
+	response, err := c.client.Audio.Speech.New(ctx, speechParams(req, openai.AudioSpeechNewParamsStreamFormatSSE))
 	if err != nil {
 		slog.Error("Failed to Text to Speech", "err", err)
 		return nil, fmt.Errorf("failed to Text to Speech: %w", err)
@@ -81,7 +75,8 @@ func (c *textToSpeech) ProduceSpeechSSE(ctx context.Context, req *domain.SpeechR
 				}
 
 				ch <- &domain.SpeechResult{
-					Audio: bytes.NewReader(audioData),
+					Audio:  bytes.NewReader(audioData),
+					Format: speechFormat,
 				}
 			}
 		}
@@ -91,13 +86,7 @@ func (c *textToSpeech) ProduceSpeechSSE(ctx context.Context, req *domain.SpeechR
 }
 
 func (c *textToSpeech) ProduceSpeechAudio(ctx context.Context, req *domain.SpeechRequest) (<-chan *domain.SpeechResult, error) {
-	params := openai.AudioSpeechNewParams{
-		Input:        req.Text,
-		Model:        openai.SpeechModelGPT4oMiniTTS,
-		Voice:        openai.AudioSpeechNewParamsVoiceShimmer,
-		StreamFormat: openai.AudioSpeechNewParamsStreamFormatAudio,
-	}
-	response, err := c.client.Audio.Speech.New(ctx, params)
+	response, err := c.client.Audio.Speech.New(ctx, speechParams(req, openai.AudioSpeechNewParamsStreamFormatAudio))
 	if err != nil {
 		slog.Error("Failed to Text to Speech", "err", err)
 		return nil, fmt.Errorf("failed to Text to Speech: %w", err)
@@ -127,9 +116,12 @@ func (c *textToSpeech) ProduceSpeechAudio(ctx context.Context, req *domain.Speec
 				copy(chunk, buf[:n])
 
 				select {
-				case ch <- &domain.SpeechResult{Audio: io.NopCloser(io.NewSectionReader(
-					bytes.NewReader(chunk), 0, int64(len(chunk)),
-				))}:
+				case ch <- &domain.SpeechResult{
+					Audio: io.NopCloser(io.NewSectionReader(
+						bytes.NewReader(chunk), 0, int64(len(chunk)),
+					)),
+					Format: speechFormat,
+				}:
 					// successfully sent
 				case <-ctx.Done():
 					slog.Warn("TTS stream canceled by context")
@@ -141,3 +133,24 @@ func (c *textToSpeech) ProduceSpeechAudio(ctx context.Context, req *domain.Speec
 
 	return ch, nil
 }
+
+// speechFormat is the format of the synthesized speech, the default MP3.
+var speechFormat = domain.AudioFormat{ContentType: domain.AudioContentTypeMPEG}
+
+// speechParams builds the speech parameters for the request. The voice
+// defaults to shimmer; the rate is passed as speed.
+func speechParams(req *domain.SpeechRequest, format openai.AudioSpeechNewParamsStreamFormat) openai.AudioSpeechNewParams {
+	params := openai.AudioSpeechNewParams{
+		Input:        req.Text,
+		Model:        openai.SpeechModelGPT4oMiniTTS,
+		Voice:        openai.AudioSpeechNewParamsVoiceShimmer,
+		StreamFormat: format,
+	}
+	if req.Voice.Voice != "" {
+		params.Voice = openai.AudioSpeechNewParamsVoice(req.Voice.Voice)
+	}
+	if req.Voice.Rate > 0 {
+		params.Speed = openai.Float(req.Voice.Rate)
+	}
+	return params
+}
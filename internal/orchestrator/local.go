@@ -0,0 +1,105 @@
+package orchestrator
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+// localAssistant is the assistance strategy of a device running the
+// assistant itself: it streams the results of the local pipeline as the
+// reply a backend would send.
+type localAssistant struct {
+	assistant ports.VoiceAssistant
+	voice     domain.VoiceOptions
+	userID    string
+}
+
+// NewLocalAssistant creates a client of the assistant running on the
+// device, answering in the voice of the device unless overridden per
+// request, e.g. by the persona of the wake word. The requests are made on
+// behalf of the local user, whose memories, documents and tool
+// configuration the assistant uses.
+func NewLocalAssistant(assistant ports.VoiceAssistant, voice domain.VoiceOptions, userID string) *localAssistant {
+	return &localAssistant{
+		assistant: assistant,
+		voice:     voice,
+		userID:    userID,
+	}
+}
+
+// ReceiveVoiceAssistance asks the assistant about the audio and returns the
+// reply once its first audio is available, in the format of that audio, or
+// a reply without chunks once the assistant ends without audio.
+func (l *localAssistant) ReceiveVoiceAssistance(ctx context.Context, audio io.Reader, voice domain.VoiceOptions) (*domain.AudioStream, error) {
+	req := domain.VoiceAssistantRequest{
+		Audio:  audio,
+		UserID: l.userID,
+		Voice:  voice.WithDefaults(l.voice),
+	}
+	results, err := l.assistant.Assist(ctx, &req)
+	if err != nil {
+		return nil, err
+	}
+
+	var expectsReply atomic.Bool
+
+	// playback starts with the first audio, in its format
+	var first *domain.VoiceAssistantResult
+	for first == nil {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case res, ok := <-results:
+			if !ok {
+				return &domain.AudioStream{ExpectsReply: expectsReply.Load}, nil
+			}
+			if res.Type == domain.VoiceAssistantEventExpectsReply {
+				expectsReply.Store(true)
+			}
+			if res.Audio != nil {
+				first = res
+			}
+		}
+	}
+	chunks := make(chan []byte)
+	stream := &domain.AudioStream{Format: first.Format, Chunks: chunks, ExpectsReply: expectsReply.Load}
+
+	go func() {
+		defer close(chunks)
+
+		for res := first; ; {
+			if res.Type == domain.VoiceAssistantEventExpectsReply {
+				expectsReply.Store(true)
+			}
+			if res.Audio != nil {
+				data, err := io.ReadAll(res.Audio)
+				if err != nil {
+					slog.Error("Unable to read audio", "error", err)
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case chunks <- data:
+				}
+			}
+
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case res, ok = <-results:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+
+	return stream, nil
+}
@@ -0,0 +1,335 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/audio/wav"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+// listenRetryInterval is how long to wait before listening for the wake
+// word again after listening failed, e.g. while a wake word server restarts.
+var listenRetryInterval = 2 * time.Second
+
+// orchestrator runs the assistant of a device as a state machine: it
+// listens for the wake word, records the user's utterance, waits for the
+// reply of the assistant and plays it.
+//
+// The assistant is a strategy: a client of the backend for devices
+// offloading the assistance, or the local pipeline for devices running the
+// assistant themselves, see NewLocalAssistant.
+type orchestrator struct {
+	listener  ports.WakeListener
+	recorder  ports.Recorder
+	player    ports.Player
+	assistant ports.VoiceAssistantClient
+	utterance domain.UtteranceOptions
+	followUp  domain.FollowUpOptions
+	bargeIn   bool
+
+	mu        sync.Mutex
+	state     domain.AssistantState
+	observers []func(domain.AssistantTransition)
+	// turn identifies the current turn, 0 if there is none, turns counts
+	// the turns started so far
+	turn  uint64
+	turns uint64
+	// cancelTurn cancels the current turn, done is closed once it is over
+	cancelTurn context.CancelFunc
+	done       chan struct{}
+}
+
+// NewOrchestrator creates the orchestrator of a device asking the assistant
+// for replies. After a reply, it listens for a follow-up as configured by
+// followUp. With bargeIn, the wake word is listened for while the assistant
+// is talking and interrupts the reply.
+func NewOrchestrator(listener ports.WakeListener, recorder ports.Recorder, player ports.Player, assistant ports.VoiceAssistantClient, utterance domain.UtteranceOptions, followUp domain.FollowUpOptions, bargeIn bool) *orchestrator {
+	return &orchestrator{
+		listener:  listener,
+		recorder:  recorder,
+		player:    player,
+		assistant: assistant,
+		utterance: utterance,
+		followUp:  followUp,
+		bargeIn:   bargeIn,
+		state:     domain.AssistantStateIdle,
+	}
+}
+
+// State returns the current state of the assistant.
+func (o *orchestrator) State() domain.AssistantState {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return o.state
+}
+
+// Observe calls observer on every transition of the state, in order, e.g.
+// to show the state on LEDs. Observers are called synchronously and must
+// neither block nor call the orchestrator.
+func (o *orchestrator) Observe(observer func(domain.AssistantTransition)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.observers = append(o.observers, observer)
+}
+
+// Run listens for wake words and converses with the user until the context
+// is canceled or the wake word input ends, e.g. the file of a scripted run.
+// A failed turn, e.g. as the backend is unreachable, only ends the turn;
+// listening for the wake word is retried after failures.
+func (o *orchestrator) Run(ctx context.Context) error {
+	var turns sync.WaitGroup
+	defer func() {
+		turns.Wait()
+		o.setState(0, domain.AssistantStateIdle, nil)
+	}()
+
+	o.setState(0, domain.AssistantStateListening, nil)
+	for {
+		if !o.bargeIn {
+			o.waitTurn(ctx)
+		}
+
+		wake, err := o.listener.Listen(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, io.EOF) {
+			slog.Info("Wake word input ended, stopping")
+			return nil
+		}
+		if err != nil {
+			slog.Error("Failed to listen for a wake word", "error", err)
+			o.setState(0, domain.AssistantStateError, err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(listenRetryInterval):
+			}
+			o.setState(0, domain.AssistantStateListening, nil)
+			continue
+		}
+		slog.Debug("Wake word detected", "name", wake.Word.Name, "confidence", wake.Confidence)
+
+		if wake.Word.Action == domain.WakeActionStop {
+			o.stopTurn()
+			continue
+		}
+
+		// the wake word interrupts the reply of the previous turn
+		turn, turnCtx, end := o.startTurn(ctx)
+		captured := make(chan struct{})
+		turns.Add(1)
+		go func() {
+			defer turns.Done()
+			defer end()
+			o.converse(turnCtx, turn, wake.Word.Voice, captured)
+		}()
+
+		// the next wake word is only listened for after the utterance
+		<-captured
+	}
+}
+
+// converse replies to the user's utterance, spoken in the voice, and then,
+// within the follow-up window, to the user's follow-ups in the same
+// conversation, until the user does not continue or the turn is canceled
+// or fails. captured is closed once the first utterance has been captured.
+func (o *orchestrator) converse(ctx context.Context, turn uint64, voice domain.VoiceOptions, captured chan<- struct{}) {
+	utterance := o.utterance
+	for {
+		expectsReply, err := o.reply(ctx, turn, utterance, voice, captured)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("Turn failed", "error", err)
+				o.setState(turn, domain.AssistantStateError, err)
+			}
+			return
+		}
+		if !o.followUp.Listen(expectsReply) || ctx.Err() != nil {
+			return
+		}
+
+		slog.Info("Listening for a follow-up", "window", o.followUp.Window)
+		utterance = o.followUp.Utterance(o.utterance)
+		captured = nil
+	}
+}
+
+// reply records an utterance of the user, which is sent to the assistant
+// while it is being recorded, and plays the reply until it is complete or
+// the turn is canceled, e.g. by barge-in. It reports whether the assistant
+// expects the user to reply. No speech is not a failure, but nothing to
+// reply to. captured, if not nil, is closed once the utterance has been
+// captured.
+func (o *orchestrator) reply(ctx context.Context, turn uint64, utterance domain.UtteranceOptions, voice domain.VoiceOptions, captured chan<- struct{}) (bool, error) {
+	o.setState(turn, domain.AssistantStateRecording, nil)
+	frames, err := o.recorder.StreamAudio(ctx, utterance)
+	if err != nil {
+		if captured != nil {
+			close(captured)
+		}
+		return false, fmt.Errorf("failed to record audio input: %w", err)
+	}
+
+	frames, noSpeech := o.capture(turn, frames, captured)
+	audio := wav.NewStreamReader(frames)
+	// unblocks the capture if the assistant stopped reading
+	defer audio.Close()
+
+	assistance, err := o.assistant.ReceiveVoiceAssistance(ctx, audio, voice)
+	if errors.Is(err, domain.ErrNoSpeechDetected) || (err != nil && noSpeech()) {
+		slog.Debug("No speech detected, listening for the wake word again")
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to receive voice: %w", err)
+	}
+
+	// there is nothing to play if the reply has no audio
+	if assistance.Chunks != nil {
+		o.setState(turn, domain.AssistantStateSpeaking, nil)
+		if err := o.player.PlaybackStream(ctx, assistance.Format, assistance.Chunks); err != nil {
+			return false, fmt.Errorf("unable to playback: %w", err)
+		}
+	}
+	return assistance.ExpectsReply != nil && assistance.ExpectsReply(), nil
+}
+
+// capture forwards the frames of the utterance and moves the turn on to
+// thinking once it has been captured, closing captured if not nil. The
+// returned function reports whether no speech was detected.
+func (o *orchestrator) capture(turn uint64, frames <-chan domain.AudioFrame, captured chan<- struct{}) (<-chan domain.AudioFrame, func() bool) {
+	forwarded := make(chan domain.AudioFrame)
+	var noSpeech, failed atomic.Bool
+
+	go func() {
+		defer func() {
+			if captured != nil {
+				close(captured)
+			}
+		}()
+		defer close(forwarded)
+
+		for frame := range frames {
+			if frame.Err != nil {
+				failed.Store(true)
+				noSpeech.Store(errors.Is(frame.Err, domain.ErrNoSpeechDetected))
+			}
+			forwarded <- frame
+		}
+
+		if !failed.Load() {
+			o.mu.Lock()
+			if o.turn == turn && o.state == domain.AssistantStateRecording {
+				o.transition(domain.AssistantStateThinking, nil)
+			}
+			o.mu.Unlock()
+		}
+	}()
+
+	return forwarded, noSpeech.Load
+}
+
+// setState moves the assistant to the state on behalf of the turn, or of
+// the device if turn is 0, unless the turn is no longer the current one
+// or the device is busy with a turn.
+func (o *orchestrator) setState(turn uint64, state domain.AssistantState, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.turn != turn {
+		return
+	}
+	o.transition(state, err)
+}
+
+// transition moves the assistant to the state and notifies the observers.
+// It must be called with mu held.
+func (o *orchestrator) transition(state domain.AssistantState, err error) {
+	if o.state == state && err == nil {
+		return
+	}
+
+	t := domain.AssistantTransition{From: o.state, To: state, Err: err, Time: time.Now()}
+	o.state = state
+	slog.Debug("Assistant state changed", "from", t.From, "to", t.To)
+	for _, observer := range o.observers {
+		observer(t)
+	}
+}
+
+// startTurn starts a turn, interrupting the current one if any, and
+// returns its identifier, its context and the function ending it.
+func (o *orchestrator) startTurn(ctx context.Context) (uint64, context.Context, func()) {
+	turnCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	o.mu.Lock()
+	if o.cancelTurn != nil {
+		select {
+		case <-o.done:
+		default:
+			slog.Info("Barge-in, interrupting the reply")
+		}
+		o.cancelTurn()
+	}
+	o.turns++
+	turn := o.turns
+	o.turn = turn
+	o.cancelTurn = cancel
+	o.done = done
+	o.mu.Unlock()
+
+	return turn, turnCtx, func() {
+		cancel()
+
+		o.mu.Lock()
+		if o.turn == turn {
+			o.turn = 0
+			o.transition(domain.AssistantStateListening, nil)
+		}
+		o.mu.Unlock()
+
+		close(done)
+	}
+}
+
+// stopTurn interrupts the current turn, if any, without starting another.
+func (o *orchestrator) stopTurn() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.cancelTurn != nil {
+		select {
+		case <-o.done:
+		default:
+			slog.Info("Stopping the reply")
+		}
+		o.cancelTurn()
+	}
+}
+
+// waitTurn blocks until the current turn, if any, is over.
+func (o *orchestrator) waitTurn(ctx context.Context) {
+	o.mu.Lock()
+	done := o.done
+	o.mu.Unlock()
+
+	if done == nil {
+		return
+	}
+	select {
+	case <-ctx.Done():
+	case <-done:
+	}
+}
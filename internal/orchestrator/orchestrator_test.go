@@ -0,0 +1,358 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"github.com/ownerofglory/raspi-agent/internal/core/services"
+	"github.com/ownerofglory/raspi-agent/internal/wakeword"
+	"go.uber.org/mock/gomock"
+)
+
+// utterance returns the frames of a captured utterance, ending with err if
+// not nil.
+func utterance(err error) <-chan domain.AudioFrame {
+	frames := make(chan domain.AudioFrame, 2)
+	frames <- domain.AudioFrame{Samples: make([]int16, 160), SampleRate: 16000, Channels: 1}
+	if err != nil {
+		frames <- domain.AudioFrame{Err: err}
+	}
+	close(frames)
+	return frames
+}
+
+// reply reads the uploaded audio like a backend and replies with a chunk,
+// or fails with err.
+func reply(err error) func(context.Context, io.Reader, domain.VoiceOptions) (*domain.AudioStream, error) {
+	return func(_ context.Context, audio io.Reader, _ domain.VoiceOptions) (*domain.AudioStream, error) {
+		if _, readErr := io.ReadAll(audio); readErr != nil {
+			return nil, fmt.Errorf("failed to transcribe: %w", readErr)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		chunks := make(chan []byte, 1)
+		chunks <- []byte{1, 2, 3, 4}
+		close(chunks)
+		return &domain.AudioStream{Chunks: chunks}, nil
+	}
+}
+
+func TestOrchestratorRun(t *testing.T) {
+	listenRetryInterval = time.Millisecond
+	wake := &domain.WakeEvent{Word: domain.WakeWord{Name: "jarvis"}}
+
+	testCases := []struct {
+		name    string
+		listen  []error
+		speech  []error
+		replies []error
+		states  []domain.AssistantState
+	}{
+		{
+			name:    "recovers from a failed turn",
+			listen:  []error{nil, nil},
+			speech:  []error{nil, nil},
+			replies: []error{errors.New("backend unavailable"), nil},
+			states: []domain.AssistantState{
+				domain.AssistantStateListening,
+				domain.AssistantStateRecording,
+				domain.AssistantStateThinking,
+				domain.AssistantStateError,
+				domain.AssistantStateListening,
+				domain.AssistantStateRecording,
+				domain.AssistantStateThinking,
+				domain.AssistantStateSpeaking,
+				domain.AssistantStateListening,
+				domain.AssistantStateIdle,
+			},
+		},
+		{
+			name:    "recovers from a failure to listen",
+			listen:  []error{errors.New("connection lost"), nil},
+			speech:  []error{nil},
+			replies: []error{nil},
+			states: []domain.AssistantState{
+				domain.AssistantStateListening,
+				domain.AssistantStateError,
+				domain.AssistantStateListening,
+				domain.AssistantStateRecording,
+				domain.AssistantStateThinking,
+				domain.AssistantStateSpeaking,
+				domain.AssistantStateListening,
+				domain.AssistantStateIdle,
+			},
+		},
+		{
+			name:    "no speech",
+			listen:  []error{nil},
+			speech:  []error{domain.ErrNoSpeechDetected},
+			replies: []error{nil},
+			states: []domain.AssistantState{
+				domain.AssistantStateListening,
+				domain.AssistantStateRecording,
+				domain.AssistantStateListening,
+				domain.AssistantStateIdle,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			listener := ports.NewMockWakeListener(ctrl)
+			recorder := ports.NewMockRecorder(ctrl)
+			player := ports.NewMockPlayer(ctrl)
+			assistant := ports.NewMockVoiceAssistantClient(ctrl)
+
+			var listens []any
+			for _, err := range tc.listen {
+				if err != nil {
+					listens = append(listens, listener.EXPECT().Listen(gomock.Any()).Return(nil, err))
+					continue
+				}
+				listens = append(listens, listener.EXPECT().Listen(gomock.Any()).Return(wake, nil))
+			}
+			listens = append(listens, listener.EXPECT().Listen(gomock.Any()).Return(nil, io.EOF))
+			gomock.InOrder(listens...)
+
+			for i, err := range tc.speech {
+				recorder.EXPECT().StreamAudio(gomock.Any(), gomock.Any()).Return(utterance(err), nil)
+				assistant.EXPECT().ReceiveVoiceAssistance(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(reply(tc.replies[i]))
+			}
+			player.EXPECT().PlaybackStream(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ domain.AudioFormat, chunks <-chan []byte) error {
+					for range chunks {
+					}
+					return nil
+				}).AnyTimes()
+
+			o := NewOrchestrator(listener, recorder, player, assistant, domain.UtteranceOptions{}, domain.FollowUpOptions{}, false)
+			var states []domain.AssistantState
+			o.Observe(func(t domain.AssistantTransition) {
+				states = append(states, t.To)
+			})
+
+			if err := o.Run(context.Background()); err != nil {
+				t.Errorf("expected the run to end with the wake word input, got %v", err)
+			}
+			if !reflect.DeepEqual(states, tc.states) {
+				t.Errorf("expected states %v, got %v", tc.states, states)
+			}
+		})
+	}
+}
+
+func TestOrchestratorRunWithoutAudio(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	listener := ports.NewMockWakeListener(ctrl)
+	recorder := ports.NewMockRecorder(ctrl)
+	player := ports.NewMockPlayer(ctrl)
+	assistant := ports.NewMockVoiceAssistantClient(ctrl)
+
+	gomock.InOrder(
+		listener.EXPECT().Listen(gomock.Any()).Return(&domain.WakeEvent{}, nil),
+		listener.EXPECT().Listen(gomock.Any()).Return(nil, io.EOF),
+	)
+	recorder.EXPECT().StreamAudio(gomock.Any(), gomock.Any()).Return(utterance(nil), nil)
+	// e.g. a reply of tools only, there is nothing to play
+	assistant.EXPECT().ReceiveVoiceAssistance(gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, audio io.Reader, _ domain.VoiceOptions) (*domain.AudioStream, error) {
+			_, _ = io.ReadAll(audio)
+			return &domain.AudioStream{}, nil
+		})
+
+	o := NewOrchestrator(listener, recorder, player, assistant, domain.UtteranceOptions{}, domain.FollowUpOptions{}, false)
+	var states []domain.AssistantState
+	o.Observe(func(t domain.AssistantTransition) {
+		states = append(states, t.To)
+	})
+
+	if err := o.Run(context.Background()); err != nil {
+		t.Errorf("expected the run to end with the wake word input, got %v", err)
+	}
+	expected := []domain.AssistantState{
+		domain.AssistantStateListening,
+		domain.AssistantStateRecording,
+		domain.AssistantStateThinking,
+		domain.AssistantStateListening,
+		domain.AssistantStateIdle,
+	}
+	if !reflect.DeepEqual(states, expected) {
+		t.Errorf("expected states %v, got %v", expected, states)
+	}
+}
+
+func TestOrchestratorRunFileEngine(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	recorder := ports.NewMockRecorder(ctrl)
+	player := ports.NewMockPlayer(ctrl)
+	assistant := ports.NewMockVoiceAssistantClient(ctrl)
+
+	// the stop word needs no turn, after it the file ends
+	wakeWords := filepath.Join(t.TempDir(), "wakewords.json")
+	if err := os.WriteFile(wakeWords, []byte(`[{"name": "stop", "action": "stop"}]`), 0o644); err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	wakeFile := filepath.Join(t.TempDir(), "wake.txt")
+	if err := os.WriteFile(wakeFile, []byte("stop\n"), 0o644); err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	listener, _, err := wakeword.FromConfig(ctx, nil, nil, wakeword.Config{Engine: wakeword.EngineFile, WakeWords: wakeWords, WakeFile: wakeFile})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	o := NewOrchestrator(listener, recorder, player, assistant, domain.UtteranceOptions{}, domain.FollowUpOptions{}, true)
+	done := make(chan error, 1)
+	go func() {
+		done <- o.Run(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected the run to end with the wake file, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the orchestrator to end with the wake file")
+	}
+}
+
+func TestLocalAssistant(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	va := ports.NewMockVoiceAssistant(ctrl)
+	format := domain.AudioFormat{ContentType: domain.AudioContentTypeMPEG}
+
+	va.EXPECT().Assist(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, req *domain.VoiceAssistantRequest) (<-chan *domain.VoiceAssistantResult, error) {
+			expected := domain.VoiceOptions{Voice: "onyx", Rate: 1.2}
+			if req.Voice != expected {
+				t.Errorf("expected voice %+v, got %+v", expected, req.Voice)
+			}
+
+			results := make(chan *domain.VoiceAssistantResult, 4)
+			results <- &domain.VoiceAssistantResult{Type: domain.VoiceAssistantEventTranscript, Text: "hello"}
+			results <- &domain.VoiceAssistantResult{Type: domain.VoiceAssistantEventAudio, Audio: bytes.NewReader([]byte{1, 2}), Format: format}
+			results <- &domain.VoiceAssistantResult{Type: domain.VoiceAssistantEventAudio, Audio: bytes.NewReader([]byte{3}), Format: format}
+			results <- &domain.VoiceAssistantResult{Type: domain.VoiceAssistantEventExpectsReply}
+			close(results)
+			return results, nil
+		})
+
+	l := NewLocalAssistant(va, domain.VoiceOptions{Voice: "shimmer", Rate: 1.2}, "local")
+	stream, err := l.ReceiveVoiceAssistance(context.Background(), bytes.NewReader(nil), domain.VoiceOptions{Voice: "onyx"})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if stream.Format != format {
+		t.Errorf("expected format %+v, got %+v", format, stream.Format)
+	}
+
+	var audio []byte
+	for chunk := range stream.Chunks {
+		audio = append(audio, chunk...)
+	}
+	if !bytes.Equal(audio, []byte{1, 2, 3}) {
+		t.Errorf("expected audio %v, got %v", []byte{1, 2, 3}, audio)
+	}
+	if !stream.ExpectsReply() {
+		t.Errorf("expected the assistant to expect a reply")
+	}
+}
+
+func TestLocalAssistantWithoutAudio(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	va := ports.NewMockVoiceAssistant(ctrl)
+
+	results := make(chan *domain.VoiceAssistantResult, 2)
+	results <- &domain.VoiceAssistantResult{Type: domain.VoiceAssistantEventError, Text: "completion failed"}
+	results <- &domain.VoiceAssistantResult{Type: domain.VoiceAssistantEventExpectsReply}
+	close(results)
+	va.EXPECT().Assist(gomock.Any(), gomock.Any()).Return(results, nil)
+
+	l := NewLocalAssistant(va, domain.VoiceOptions{}, "local")
+	stream, err := l.ReceiveVoiceAssistance(context.Background(), bytes.NewReader(nil), domain.VoiceOptions{})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if stream.Chunks != nil {
+		t.Errorf("expected a reply without audio")
+	}
+	if !stream.ExpectsReply() {
+		t.Errorf("expected the assistant to expect a reply")
+	}
+}
+
+func TestLocalAssistantUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	stt := ports.NewMockTranscriptionProvider(ctrl)
+	tts := ports.NewMockSpeechProvider(ctrl)
+	cmpl := ports.NewMockCompletionProvider(ctrl)
+	conversations := ports.NewMockConversationService(ctrl)
+	memory := ports.NewMockAgentMemory(ctrl)
+	documents := ports.NewMockDocumentService(ctrl)
+	tools := ports.NewMockToolProvider(ctrl)
+
+	// the local user's memories and documents ground the answer
+	stt.EXPECT().Transcribe(gomock.Any(), gomock.Any()).Return(&domain.TranscribeResult{Text: "Where are my keys?"}, nil)
+	conversations.EXPECT().History(gomock.Any(), "", "local").Return(nil, nil)
+	memory.EXPECT().Recall(gomock.Any(), domain.RecallRequest{UserID: "local", Text: "Where are my keys?"}).
+		Return(&domain.RecallResult{Memories: []string{"The keys are in the hallway."}}, nil)
+	documents.EXPECT().Retrieve(gomock.Any(), "local", "Where are my keys?", 0).Return(nil, nil)
+	tools.EXPECT().Tools(gomock.Any()).Return(nil)
+	cmpl.EXPECT().StreamCompletion(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, req *domain.CompletionRequest) (<-chan domain.CompletionDelta, error) {
+			deltas := make(chan domain.CompletionDelta, 1)
+			deltas <- domain.CompletionDelta{Text: "In the hallway."}
+			close(deltas)
+			return deltas, nil
+		})
+	tts.EXPECT().ProduceSpeechAudio(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, req *domain.SpeechRequest) (<-chan *domain.SpeechResult, error) {
+			speech := make(chan *domain.SpeechResult, 1)
+			speech <- &domain.SpeechResult{Audio: bytes.NewReader([]byte(req.Text))}
+			close(speech)
+			return speech, nil
+		})
+
+	memorized := make(chan struct{})
+	conversations.EXPECT().Record(gomock.Any(), "", "local", gomock.Any()).Return(nil)
+	memory.EXPECT().Memorize(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, req domain.MemorizeRequest) (*domain.MemorizeResult, error) {
+			if req.UserID != "local" {
+				t.Errorf("expected memories of the local user, got %q", req.UserID)
+			}
+			close(memorized)
+			return &domain.MemorizeResult{}, nil
+		})
+
+	assistant := services.NewVoiceAssistant(stt, tts, cmpl, conversations, memory, documents, tools)
+	l := NewLocalAssistant(assistant, domain.VoiceOptions{}, "local")
+	stream, err := l.ReceiveVoiceAssistance(context.Background(), bytes.NewReader(nil), domain.VoiceOptions{})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	for range stream.Chunks {
+	}
+
+	select {
+	case <-memorized:
+	case <-time.After(time.Second):
+		t.Fatal("expected the turn to be memorized")
+	}
+}
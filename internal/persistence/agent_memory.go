@@ -0,0 +1,84 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/persistence/entity"
+	"gorm.io/gorm"
+)
+
+// memoryRepo is a GORM-based implementation of ports.MemoryRepo.
+//
+// Embeddings are stored as plain float8[] columns; similarity search is
+// done by the agent memory service.
+type memoryRepo struct {
+	db *gorm.DB
+}
+
+// NewMemoryRepo creates a new GORM-backed agent memory repository.
+func NewMemoryRepo(db *gorm.DB) *memoryRepo {
+	return &memoryRepo{db: db}
+}
+
+// Save inserts a new memory.
+func (r *memoryRepo) Save(ctx context.Context, memory domain.Memory) (*domain.Memory, error) {
+	userID, err := uuid.Parse(memory.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	e := entity.Memory{
+		UserID:    userID,
+		Text:      memory.Text,
+		Embedding: entity.Vector(memory.Embedding),
+	}
+	if err := r.db.WithContext(ctx).Create(&e).Error; err != nil {
+		slog.Error("failed to save memory", "err", err, "user_id", memory.UserID)
+		return nil, fmt.Errorf("save memory: %w", err)
+	}
+
+	return toDomainMemory(&e), nil
+}
+
+// FindByUserID retrieves all memories of a user, oldest first.
+func (r *memoryRepo) FindByUserID(ctx context.Context, userID string) ([]domain.Memory, error) {
+	var entities []entity.Memory
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Find(&entities).Error; err != nil {
+
+		slog.Error("failed to find memories by user id", "err", err, "user_id", userID)
+		return nil, fmt.Errorf("find memories by user id: %w", err)
+	}
+
+	memories := make([]domain.Memory, 0, len(entities))
+	for _, e := range entities {
+		memories = append(memories, *toDomainMemory(&e))
+	}
+	return memories, nil
+}
+
+// Delete removes a memory by ID.
+func (r *memoryRepo) Delete(ctx context.Context, id string) error {
+	if err := r.db.WithContext(ctx).Delete(&entity.Memory{}, "id = ?", id).Error; err != nil {
+		slog.Error("failed to delete memory", "err", err, "id", id)
+		return fmt.Errorf("delete memory: %w", err)
+	}
+	return nil
+}
+
+// toDomainMemory converts a persistence entity.Memory to a domain.Memory.
+func toDomainMemory(e *entity.Memory) *domain.Memory {
+	return &domain.Memory{
+		ID:        e.ID.String(),
+		UserID:    e.UserID.String(),
+		Text:      e.Text,
+		Embedding: []float64(e.Embedding),
+		CreatedAt: e.CreatedAt,
+	}
+}
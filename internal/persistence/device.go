@@ -127,6 +127,7 @@ func toDeviceEntity(d domain.Device) (entity.Device, error) {
 		e.OTP = *d.OTP
 	}
 	e.EnrollmentStatus = string(d.EnrollmentStatus)
+	e.TimeZone = d.TimeZone
 
 	// Attach user if present
 	if d.UserID != nil {
@@ -158,5 +159,6 @@ func toDomainDevice(e *entity.Device) *domain.Device {
 		OTP:              &otp,
 		Name:             e.Name,
 		EnrollmentStatus: domain.DeviceEnrollmentState(e.EnrollmentStatus),
+		TimeZone:         e.TimeZone,
 	}
 }
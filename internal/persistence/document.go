@@ -0,0 +1,149 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/persistence/entity"
+	"gorm.io/gorm"
+)
+
+// documentRepo is a GORM-based implementation of ports.DocumentRepo.
+//
+// Chunk embeddings are stored as plain float8[] columns; similarity search
+// is done by the document service.
+type documentRepo struct {
+	db *gorm.DB
+}
+
+// NewDocumentRepo creates a new GORM-backed document repository.
+func NewDocumentRepo(db *gorm.DB) *documentRepo {
+	return &documentRepo{db: db}
+}
+
+// Save inserts a new document together with its chunks in one transaction.
+func (r *documentRepo) Save(ctx context.Context, document domain.Document, chunks []domain.DocumentChunk) (*domain.Document, error) {
+	userID, err := uuid.Parse(document.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	e := entity.Document{
+		UserID:      userID,
+		Title:       document.Title,
+		ContentType: document.ContentType,
+	}
+	for _, c := range chunks {
+		e.Chunks = append(e.Chunks, entity.DocumentChunk{
+			UserID:    userID,
+			Index:     c.Index,
+			Text:      c.Text,
+			Embedding: entity.Vector(c.Embedding),
+		})
+	}
+
+	if err := r.db.WithContext(ctx).Create(&e).Error; err != nil {
+		slog.Error("failed to save document", "err", err, "user_id", document.UserID)
+		return nil, fmt.Errorf("save document: %w", err)
+	}
+
+	return toDomainDocument(&e, len(e.Chunks)), nil
+}
+
+// Find retrieves a single document by its ID.
+func (r *documentRepo) Find(ctx context.Context, id string) (*domain.Document, error) {
+	var e entity.Document
+	if err := r.db.WithContext(ctx).First(&e, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("document %s not found: %w", id, domain.ErrDocumentNotFound)
+		}
+
+		slog.Error("failed to find document", "err", err, "id", id)
+		return nil, err
+	}
+
+	var chunks int64
+	if err := r.db.WithContext(ctx).Model(&entity.DocumentChunk{}).
+		Where("document_id = ?", id).
+		Count(&chunks).Error; err != nil {
+
+		slog.Error("failed to count document chunks", "err", err, "id", id)
+		return nil, fmt.Errorf("count document chunks: %w", err)
+	}
+
+	return toDomainDocument(&e, int(chunks)), nil
+}
+
+// FindByUserID retrieves all documents of a user, oldest first.
+func (r *documentRepo) FindByUserID(ctx context.Context, userID string) ([]domain.Document, error) {
+	var rows []struct {
+		entity.Document
+		ChunkCount int
+	}
+	if err := r.db.WithContext(ctx).Model(&entity.Document{}).
+		Select("documents.*, (SELECT COUNT(*) FROM document_chunks WHERE document_chunks.document_id = documents.id) AS chunk_count").
+		Where("user_id = ?", userID).
+		Order("created_at").
+		Scan(&rows).Error; err != nil {
+
+		slog.Error("failed to find documents by user id", "err", err, "user_id", userID)
+		return nil, fmt.Errorf("find documents by user id: %w", err)
+	}
+
+	documents := make([]domain.Document, 0, len(rows))
+	for _, row := range rows {
+		documents = append(documents, *toDomainDocument(&row.Document, row.ChunkCount))
+	}
+	return documents, nil
+}
+
+// FindChunksByUserID retrieves the chunks of all documents of a user.
+func (r *documentRepo) FindChunksByUserID(ctx context.Context, userID string) ([]domain.DocumentChunk, error) {
+	var entities []entity.DocumentChunk
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("document_id, index").
+		Find(&entities).Error; err != nil {
+
+		slog.Error("failed to find document chunks by user id", "err", err, "user_id", userID)
+		return nil, fmt.Errorf("find document chunks by user id: %w", err)
+	}
+
+	chunks := make([]domain.DocumentChunk, 0, len(entities))
+	for _, e := range entities {
+		chunks = append(chunks, domain.DocumentChunk{
+			ID:         e.ID.String(),
+			DocumentID: e.DocumentID.String(),
+			UserID:     e.UserID.String(),
+			Index:      e.Index,
+			Text:       e.Text,
+			Embedding:  []float64(e.Embedding),
+		})
+	}
+	return chunks, nil
+}
+
+// Delete removes a document by ID; its chunks are removed by cascade.
+func (r *documentRepo) Delete(ctx context.Context, id string) error {
+	if err := r.db.WithContext(ctx).Delete(&entity.Document{}, "id = ?", id).Error; err != nil {
+		slog.Error("failed to delete document", "err", err, "id", id)
+		return fmt.Errorf("delete document: %w", err)
+	}
+	return nil
+}
+
+// toDomainDocument converts a persistence entity.Document to a domain.Document.
+func toDomainDocument(e *entity.Document, chunks int) *domain.Document {
+	return &domain.Document{
+		ID:          e.ID.String(),
+		UserID:      e.UserID.String(),
+		Title:       e.Title,
+		ContentType: e.ContentType,
+		Chunks:      chunks,
+		CreatedAt:   e.CreatedAt,
+	}
+}
@@ -11,6 +11,7 @@ type Device struct {
 	Name             string    `gorm:"type:varchar(256);default:''"`
 	OTP              string    `gorm:"type:varchar(256);default:''"`
 	EnrollmentStatus string    `gorm:"type:varchar(256);default:''"`
+	TimeZone         string    `gorm:"type:varchar(64);default:''"`
 	UserID           uuid.UUID `gorm:"type:uuid;"`
 	User             *User     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 }
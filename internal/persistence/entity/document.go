@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Document represents a row in the `documents` table
+type Document struct {
+	ID          uuid.UUID       `gorm:"type:uuid;not null;primaryKey"`
+	UserID      uuid.UUID       `gorm:"type:uuid;not null;index"`
+	User        *User           `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Title       string          `gorm:"type:varchar(256);default:''"`
+	ContentType string          `gorm:"type:varchar(256);default:''"`
+	Chunks      []DocumentChunk `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	CreatedAt   time.Time
+}
+
+// BeforeCreate hook to auto-generate UUIDs
+func (d *Document) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.ID == uuid.Nil {
+		d.ID, err = uuid.NewV7()
+		return
+	}
+	return
+}
+
+// DocumentChunk represents a row in the `document_chunks` table
+type DocumentChunk struct {
+	ID         uuid.UUID `gorm:"type:uuid;not null;primaryKey"`
+	DocumentID uuid.UUID `gorm:"type:uuid;not null;index"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	Index      int       `gorm:"not null"`
+	Text       string    `gorm:"type:text;not null"`
+	Embedding  Vector    `gorm:"type:float8[]"`
+}
+
+// BeforeCreate hook to auto-generate UUIDs
+func (c *DocumentChunk) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID, err = uuid.NewV7()
+		return
+	}
+	return
+}
@@ -0,0 +1,16 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// HomeAssistantConfig represents a row in the `home_assistant_configs` table
+type HomeAssistantConfig struct {
+	UserID    uuid.UUID `gorm:"type:uuid;not null;primaryKey"`
+	User      *User     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	BaseURL   string    `gorm:"type:varchar(512);not null"`
+	Token     string    `gorm:"type:text;not null"`
+	UpdatedAt time.Time
+}
@@ -0,0 +1,17 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MCPGrant represents a row in the `mcp_grants` table: an MCP tool enabled
+// by a user, or all tools of the server if Tool is empty.
+type MCPGrant struct {
+	UserID    uuid.UUID `gorm:"type:uuid;not null;primaryKey"`
+	User      *User     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Server    string    `gorm:"type:varchar(128);not null;primaryKey"`
+	Tool      string    `gorm:"type:varchar(128);not null;primaryKey"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,27 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Memory represents a row in the `memories` table
+type Memory struct {
+	ID        uuid.UUID `gorm:"type:uuid;not null;primaryKey"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	User      *User     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Text      string    `gorm:"type:text;not null"`
+	Embedding Vector    `gorm:"type:float8[]"`
+	CreatedAt time.Time
+}
+
+// BeforeCreate hook to auto-generate UUIDs
+func (m *Memory) BeforeCreate(tx *gorm.DB) (err error) {
+	if m.ID == uuid.Nil {
+		m.ID, err = uuid.NewV7()
+		return
+	}
+	return
+}
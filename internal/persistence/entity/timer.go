@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Timer represents a row in the `timers` table
+type Timer struct {
+	ID        uuid.UUID `gorm:"type:uuid;not null;primaryKey"`
+	DeviceID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	Device    *Device   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	User      *User     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Kind      string    `gorm:"type:varchar(32);not null"`
+	Label     string    `gorm:"type:varchar(256);default:''"`
+	FiresAt   time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+	TimeZone  string `gorm:"type:varchar(64);default:''"`
+}
+
+// BeforeCreate hook to auto-generate UUIDs
+func (t *Timer) BeforeCreate(tx *gorm.DB) (err error) {
+	if t.ID == uuid.Nil {
+		t.ID, err = uuid.NewV7()
+		return
+	}
+	return
+}
@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Vector is an embedding stored as a Postgres float8[] column.
+//
+// It implements driver.Valuer and sql.Scanner using the Postgres array
+// text representation, e.g. "{0.1,-0.2,0.3}".
+type Vector []float64
+
+// Value encodes the vector as a Postgres array literal.
+func (v Vector) Value() (driver.Value, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, f := range v {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	b.WriteByte('}')
+	return b.String(), nil
+}
+
+// Scan decodes a Postgres array literal into the vector.
+func (v *Vector) Scan(src any) error {
+	var s string
+	switch t := src.(type) {
+	case nil:
+		*v = nil
+		return nil
+	case string:
+		s = t
+	case []byte:
+		s = string(t)
+	default:
+		return fmt.Errorf("unsupported vector source type %T", src)
+	}
+
+	s = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(s), "{"), "}")
+	if s == "" {
+		*v = Vector{}
+		return nil
+	}
+
+	parts := strings.Split(s, ",")
+	vec := make(Vector, len(parts))
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return fmt.Errorf("invalid vector element %q: %w", p, err)
+		}
+		vec[i] = f
+	}
+	*v = vec
+	return nil
+}
@@ -0,0 +1,77 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/persistence/entity"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// homeAssistantConfigRepo is a GORM-based implementation of ports.HomeAssistantConfigRepo.
+type homeAssistantConfigRepo struct {
+	db *gorm.DB
+}
+
+// NewHomeAssistantConfigRepo creates a new GORM-backed Home Assistant configuration repository.
+func NewHomeAssistantConfigRepo(db *gorm.DB) *homeAssistantConfigRepo {
+	return &homeAssistantConfigRepo{db: db}
+}
+
+// Save creates or replaces the configuration of a user.
+func (r *homeAssistantConfigRepo) Save(ctx context.Context, config domain.HomeAssistantConfig) error {
+	userID, err := uuid.Parse(config.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	e := entity.HomeAssistantConfig{
+		UserID:  userID,
+		BaseURL: config.BaseURL,
+		Token:   config.Token,
+	}
+	if err := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&e).Error; err != nil {
+
+		slog.Error("failed to save home assistant config", "err", err, "user_id", config.UserID)
+		return fmt.Errorf("save home assistant config: %w", err)
+	}
+	return nil
+}
+
+// FindByUserID retrieves the configuration of a user.
+func (r *homeAssistantConfigRepo) FindByUserID(ctx context.Context, userID string) (*domain.HomeAssistantConfig, error) {
+	var e entity.HomeAssistantConfig
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		First(&e).Error; err != nil {
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrHomeAssistantNotConfigured
+		}
+
+		slog.Error("failed to find home assistant config", "err", err, "user_id", userID)
+		return nil, fmt.Errorf("find home assistant config: %w", err)
+	}
+
+	return &domain.HomeAssistantConfig{
+		UserID:  e.UserID.String(),
+		BaseURL: e.BaseURL,
+		Token:   e.Token,
+	}, nil
+}
+
+// Delete removes the configuration of a user.
+func (r *homeAssistantConfigRepo) Delete(ctx context.Context, userID string) error {
+	if err := r.db.WithContext(ctx).Delete(&entity.HomeAssistantConfig{}, "user_id = ?", userID).Error; err != nil {
+		slog.Error("failed to delete home assistant config", "err", err, "user_id", userID)
+		return fmt.Errorf("delete home assistant config: %w", err)
+	}
+	return nil
+}
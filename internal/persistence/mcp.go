@@ -0,0 +1,82 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/google/uuid"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/persistence/entity"
+	"gorm.io/gorm"
+)
+
+// mcpAccessRepo is a GORM-based implementation of ports.MCPAccessRepo.
+type mcpAccessRepo struct {
+	db *gorm.DB
+}
+
+// NewMCPAccessRepo creates a new GORM-backed MCP access repository.
+func NewMCPAccessRepo(db *gorm.DB) *mcpAccessRepo {
+	return &mcpAccessRepo{db: db}
+}
+
+// Save replaces the grants of a user.
+func (r *mcpAccessRepo) Save(ctx context.Context, access domain.MCPAccess) error {
+	userID, err := uuid.Parse(access.UserID)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	var entities []entity.MCPGrant
+	for _, g := range access.Grants {
+		if len(g.Tools) == 0 {
+			entities = append(entities, entity.MCPGrant{UserID: userID, Server: g.Server})
+		}
+		for _, tool := range g.Tools {
+			entities = append(entities, entity.MCPGrant{UserID: userID, Server: g.Server, Tool: tool})
+		}
+	}
+
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&entity.MCPGrant{}, "user_id = ?", userID).Error; err != nil {
+			return err
+		}
+		if len(entities) == 0 {
+			return nil
+		}
+		return tx.Create(&entities).Error
+	})
+	if err != nil {
+		slog.Error("failed to save mcp grants", "err", err, "user_id", access.UserID)
+		return fmt.Errorf("save mcp grants: %w", err)
+	}
+	return nil
+}
+
+// FindByUserID retrieves the grants of a user.
+func (r *mcpAccessRepo) FindByUserID(ctx context.Context, userID string) (*domain.MCPAccess, error) {
+	var entities []entity.MCPGrant
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("server, tool").
+		Find(&entities).Error; err != nil {
+
+		slog.Error("failed to find mcp grants", "err", err, "user_id", userID)
+		return nil, fmt.Errorf("find mcp grants: %w", err)
+	}
+
+	access := &domain.MCPAccess{UserID: userID}
+	for _, e := range entities {
+		n := len(access.Grants)
+		if n == 0 || access.Grants[n-1].Server != e.Server {
+			access.Grants = append(access.Grants, domain.MCPGrant{Server: e.Server})
+			n++
+		}
+		// an empty tool grants the whole server
+		if e.Tool != "" {
+			access.Grants[n-1].Tools = append(access.Grants[n-1].Tools, e.Tool)
+		}
+	}
+	return access, nil
+}
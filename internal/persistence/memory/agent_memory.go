@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// memoryRepo is an in-memory implementation of ports.MemoryRepo, used where
+// no database is available (e.g. the standalone onboard agent).
+type memoryRepo struct {
+	mu       sync.Mutex
+	memories []domain.Memory
+}
+
+// NewMemoryRepo creates a new in-memory agent memory repository.
+func NewMemoryRepo() *memoryRepo {
+	return &memoryRepo{}
+}
+
+// Save stores a new memory.
+func (r *memoryRepo) Save(ctx context.Context, memory domain.Memory) (*domain.Memory, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	memory.ID = id.String()
+	memory.CreatedAt = time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.memories = append(r.memories, memory)
+
+	return &memory, nil
+}
+
+// FindByUserID returns all memories of the user, oldest first.
+func (r *memoryRepo) FindByUserID(ctx context.Context, userID string) ([]domain.Memory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var memories []domain.Memory
+	for _, m := range r.memories {
+		if m.UserID == userID {
+			memories = append(memories, m)
+		}
+	}
+	return memories, nil
+}
+
+// Delete removes the memory with the given ID.
+func (r *memoryRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.memories = slices.DeleteFunc(r.memories, func(m domain.Memory) bool {
+		return m.ID == id
+	})
+	return nil
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// conversationKey identifies the conversation of a user on a device.
+type conversationKey struct {
+	deviceID string
+	userID   string
+}
+
+// conversationRepo is an in-memory implementation of ports.ConversationRepo.
+//
+// Conversations are short-lived (they end after a few minutes of idling), so
+// only the latest conversation per device and user is kept, and it is not
+// expected to survive a restart.
+type conversationRepo struct {
+	mu            sync.Mutex
+	conversations map[conversationKey]domain.Conversation
+}
+
+// NewConversationRepo creates a new in-memory conversation repository.
+func NewConversationRepo() *conversationRepo {
+	return &conversationRepo{
+		conversations: make(map[conversationKey]domain.Conversation),
+	}
+}
+
+// FindLatest returns a copy of the latest conversation of the device and user.
+func (r *conversationRepo) FindLatest(ctx context.Context, deviceID, userID string) (*domain.Conversation, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.conversations[conversationKey{deviceID: deviceID, userID: userID}]
+	if !ok {
+		return nil, domain.ErrConversationNotFound
+	}
+
+	c.Messages = slices.Clone(c.Messages)
+	return &c, nil
+}
+
+// Save stores the conversation, replacing any previous one of the device and user.
+func (r *conversationRepo) Save(ctx context.Context, conversation domain.Conversation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conversation.Messages = slices.Clone(conversation.Messages)
+	r.conversations[conversationKey{deviceID: conversation.DeviceID, userID: conversation.UserID}] = conversation
+	return nil
+}
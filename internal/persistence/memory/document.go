@@ -0,0 +1,107 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// documentRepo is an in-memory implementation of ports.DocumentRepo.
+type documentRepo struct {
+	mu        sync.Mutex
+	documents []domain.Document
+	chunks    []domain.DocumentChunk
+}
+
+// NewDocumentRepo creates a new in-memory document repository.
+func NewDocumentRepo() *documentRepo {
+	return &documentRepo{}
+}
+
+// Save stores a new document with its chunks.
+func (r *documentRepo) Save(ctx context.Context, document domain.Document, chunks []domain.DocumentChunk) (*domain.Document, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	document.ID = id.String()
+	document.Chunks = len(chunks)
+	document.CreatedAt = time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.documents = append(r.documents, document)
+	for _, c := range chunks {
+		chunkID, err := uuid.NewV7()
+		if err != nil {
+			return nil, err
+		}
+		c.ID = chunkID.String()
+		c.DocumentID = document.ID
+		c.UserID = document.UserID
+		r.chunks = append(r.chunks, c)
+	}
+
+	return &document, nil
+}
+
+// Find returns the document with the given ID.
+func (r *documentRepo) Find(ctx context.Context, id string) (*domain.Document, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, d := range r.documents {
+		if d.ID == id {
+			return &d, nil
+		}
+	}
+	return nil, fmt.Errorf("document %s not found: %w", id, domain.ErrDocumentNotFound)
+}
+
+// FindByUserID returns all documents of the user, oldest first.
+func (r *documentRepo) FindByUserID(ctx context.Context, userID string) ([]domain.Document, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var documents []domain.Document
+	for _, d := range r.documents {
+		if d.UserID == userID {
+			documents = append(documents, d)
+		}
+	}
+	return documents, nil
+}
+
+// FindChunksByUserID returns the chunks of all documents of the user.
+func (r *documentRepo) FindChunksByUserID(ctx context.Context, userID string) ([]domain.DocumentChunk, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var chunks []domain.DocumentChunk
+	for _, c := range r.chunks {
+		if c.UserID == userID {
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks, nil
+}
+
+// Delete removes the document with the given ID and its chunks.
+func (r *documentRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.documents = slices.DeleteFunc(r.documents, func(d domain.Document) bool {
+		return d.ID == id
+	})
+	r.chunks = slices.DeleteFunc(r.chunks, func(c domain.DocumentChunk) bool {
+		return c.DocumentID == id
+	})
+	return nil
+}
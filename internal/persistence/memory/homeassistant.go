@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// homeAssistantConfigRepo is an in-memory implementation of ports.HomeAssistantConfigRepo.
+type homeAssistantConfigRepo struct {
+	mu      sync.Mutex
+	configs map[string]domain.HomeAssistantConfig
+}
+
+// NewHomeAssistantConfigRepo creates a new in-memory Home Assistant configuration repository.
+func NewHomeAssistantConfigRepo() *homeAssistantConfigRepo {
+	return &homeAssistantConfigRepo{configs: make(map[string]domain.HomeAssistantConfig)}
+}
+
+// Save stores the configuration of a user.
+func (r *homeAssistantConfigRepo) Save(ctx context.Context, config domain.HomeAssistantConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.configs[config.UserID] = config
+	return nil
+}
+
+// FindByUserID returns the configuration of a user.
+func (r *homeAssistantConfigRepo) FindByUserID(ctx context.Context, userID string) (*domain.HomeAssistantConfig, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	config, ok := r.configs[userID]
+	if !ok {
+		return nil, domain.ErrHomeAssistantNotConfigured
+	}
+	return &config, nil
+}
+
+// Delete removes the configuration of a user.
+func (r *homeAssistantConfigRepo) Delete(ctx context.Context, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.configs, userID)
+	return nil
+}
@@ -0,0 +1,40 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// mcpAccessRepo is an in-memory implementation of ports.MCPAccessRepo.
+type mcpAccessRepo struct {
+	mu       sync.Mutex
+	accesses map[string]domain.MCPAccess
+}
+
+// NewMCPAccessRepo creates a new in-memory MCP access repository.
+func NewMCPAccessRepo() *mcpAccessRepo {
+	return &mcpAccessRepo{accesses: make(map[string]domain.MCPAccess)}
+}
+
+// Save stores the grants of a user.
+func (r *mcpAccessRepo) Save(ctx context.Context, access domain.MCPAccess) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.accesses[access.UserID] = access
+	return nil
+}
+
+// FindByUserID returns the grants of a user.
+func (r *mcpAccessRepo) FindByUserID(ctx context.Context, userID string) (*domain.MCPAccess, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	access, ok := r.accesses[userID]
+	if !ok {
+		return &domain.MCPAccess{UserID: userID}, nil
+	}
+	return &access, nil
+}
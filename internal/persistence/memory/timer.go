@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// timerRepo is an in-memory implementation of ports.TimerRepo.
+// Timers are lost on restart.
+type timerRepo struct {
+	mu     sync.Mutex
+	timers []domain.Timer
+}
+
+// NewTimerRepo creates a new in-memory timer repository.
+func NewTimerRepo() *timerRepo {
+	return &timerRepo{}
+}
+
+// Save stores a new timer.
+func (r *timerRepo) Save(ctx context.Context, timer domain.Timer) (*domain.Timer, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+	timer.ID = id.String()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.timers = append(r.timers, timer)
+	slices.SortStableFunc(r.timers, func(a, b domain.Timer) int {
+		return a.FiresAt.Compare(b.FiresAt)
+	})
+
+	return &timer, nil
+}
+
+// FindByUserID returns the timers of the user, soonest first.
+func (r *timerRepo) FindByUserID(ctx context.Context, userID string) ([]domain.Timer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var timers []domain.Timer
+	for _, t := range r.timers {
+		if t.UserID == userID {
+			timers = append(timers, t)
+		}
+	}
+	return timers, nil
+}
+
+// FindDue returns all timers firing at or before until, soonest first.
+func (r *timerRepo) FindDue(ctx context.Context, until time.Time) ([]domain.Timer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var timers []domain.Timer
+	for _, t := range r.timers {
+		if !t.FiresAt.After(until) {
+			timers = append(timers, t)
+		}
+	}
+	return timers, nil
+}
+
+// FindNext returns the timer firing next.
+func (r *timerRepo) FindNext(ctx context.Context) (*domain.Timer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.timers) == 0 {
+		return nil, domain.ErrTimerNotFound
+	}
+	next := r.timers[0]
+	return &next, nil
+}
+
+// Delete removes the timer with the given ID.
+func (r *timerRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.timers = slices.DeleteFunc(r.timers, func(t domain.Timer) bool {
+		return t.ID == id
+	})
+	return nil
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"github.com/ownerofglory/raspi-agent/internal/persistence/entity"
+	"gorm.io/gorm"
+)
+
+func Documents(db *gorm.DB) error {
+	return gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{
+		{
+			ID: "202610161200",
+			Migrate: func(tx *gorm.DB) error {
+				type User struct {
+					ID uuid.UUID `gorm:"type:uuid;not null;primaryKey"`
+				}
+
+				type DocumentChunk struct {
+					ID         uuid.UUID     `gorm:"type:uuid;not null;primaryKey"`
+					DocumentID uuid.UUID     `gorm:"type:uuid;not null;index"`
+					UserID     uuid.UUID     `gorm:"type:uuid;not null;index"`
+					Index      int           `gorm:"not null"`
+					Text       string        `gorm:"type:text;not null"`
+					Embedding  entity.Vector `gorm:"type:float8[]"`
+				}
+
+				type Document struct {
+					ID          uuid.UUID       `gorm:"type:uuid;not null;primaryKey"`
+					UserID      uuid.UUID       `gorm:"type:uuid;not null;index"`
+					User        *User           `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+					Title       string          `gorm:"type:varchar(256);default:''"`
+					ContentType string          `gorm:"type:varchar(256);default:''"`
+					Chunks      []DocumentChunk `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+					CreatedAt   time.Time
+				}
+
+				return tx.AutoMigrate(&Document{}, &DocumentChunk{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("document_chunks", "documents")
+			},
+		},
+	}).Migrate()
+}
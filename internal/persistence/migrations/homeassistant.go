@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func HomeAssistant(db *gorm.DB) error {
+	return gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{
+		{
+			ID: "202610161500",
+			Migrate: func(tx *gorm.DB) error {
+				type User struct {
+					ID uuid.UUID `gorm:"type:uuid;not null;primaryKey"`
+				}
+
+				type HomeAssistantConfig struct {
+					UserID    uuid.UUID `gorm:"type:uuid;not null;primaryKey"`
+					User      *User     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+					BaseURL   string    `gorm:"type:varchar(512);not null"`
+					Token     string    `gorm:"type:text;not null"`
+					UpdatedAt time.Time
+				}
+
+				return tx.AutoMigrate(&HomeAssistantConfig{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("home_assistant_configs")
+			},
+		},
+	}).Migrate()
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func MCPGrants(db *gorm.DB) error {
+	return gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{
+		{
+			ID: "202610161600",
+			Migrate: func(tx *gorm.DB) error {
+				type User struct {
+					ID uuid.UUID `gorm:"type:uuid;not null;primaryKey"`
+				}
+
+				type MCPGrant struct {
+					UserID    uuid.UUID `gorm:"type:uuid;not null;primaryKey"`
+					User      *User     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+					Server    string    `gorm:"type:varchar(128);not null;primaryKey"`
+					Tool      string    `gorm:"type:varchar(128);not null;primaryKey"`
+					CreatedAt time.Time
+				}
+
+				return tx.AutoMigrate(&MCPGrant{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("mcp_grants")
+			},
+		},
+	}).Migrate()
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"github.com/ownerofglory/raspi-agent/internal/persistence/entity"
+	"gorm.io/gorm"
+)
+
+func AgentMemory(db *gorm.DB) error {
+	return gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{
+		{
+			ID: "202610161000",
+			Migrate: func(tx *gorm.DB) error {
+				type User struct {
+					ID uuid.UUID `gorm:"type:uuid;not null;primaryKey"`
+				}
+
+				type Memory struct {
+					ID        uuid.UUID     `gorm:"type:uuid;not null;primaryKey"`
+					UserID    uuid.UUID     `gorm:"type:uuid;not null;index"`
+					User      *User         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+					Text      string        `gorm:"type:text;not null"`
+					Embedding entity.Vector `gorm:"type:float8[]"`
+					CreatedAt time.Time
+				}
+
+				return tx.AutoMigrate(&Memory{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("memories")
+			},
+		},
+	}).Migrate()
+}
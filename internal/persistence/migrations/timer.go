@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func Timers(db *gorm.DB) error {
+	return gormigrate.New(db, gormigrate.DefaultOptions, []*gormigrate.Migration{
+		{
+			ID: "202610161400",
+			Migrate: func(tx *gorm.DB) error {
+				type User struct {
+					ID uuid.UUID `gorm:"type:uuid;not null;primaryKey"`
+				}
+
+				type Device struct {
+					ID uuid.UUID `gorm:"type:uuid;not null;primaryKey"`
+				}
+
+				type Timer struct {
+					ID        uuid.UUID `gorm:"type:uuid;not null;primaryKey"`
+					DeviceID  uuid.UUID `gorm:"type:uuid;not null;index"`
+					Device    *Device   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+					UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+					User      *User     `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+					Kind      string    `gorm:"type:varchar(32);not null"`
+					Label     string    `gorm:"type:varchar(256);default:''"`
+					FiresAt   time.Time `gorm:"not null;index"`
+					CreatedAt time.Time
+				}
+
+				return tx.AutoMigrate(&Timer{})
+			},
+			Rollback: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("timers")
+			},
+		},
+		{
+			ID: "202610161801",
+			Migrate: func(tx *gorm.DB) error {
+				type Timer struct {
+					TimeZone string `gorm:"type:varchar(64);default:''"`
+				}
+
+				return tx.Migrator().AddColumn(&Timer{}, "TimeZone")
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type Timer struct {
+					TimeZone string
+				}
+
+				return tx.Migrator().DropColumn(&Timer{}, "TimeZone")
+			},
+		},
+	}).Migrate()
+}
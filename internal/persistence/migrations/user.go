@@ -35,5 +35,22 @@ func UserWithDevice(db *gorm.DB) error {
 				return tx.Migrator().DropTable("devices", "users")
 			},
 		},
+		{
+			ID: "202610161800",
+			Migrate: func(tx *gorm.DB) error {
+				type Device struct {
+					TimeZone string `gorm:"type:varchar(64);default:''"`
+				}
+
+				return tx.Migrator().AddColumn(&Device{}, "TimeZone")
+			},
+			Rollback: func(tx *gorm.DB) error {
+				type Device struct {
+					TimeZone string
+				}
+
+				return tx.Migrator().DropColumn(&Device{}, "TimeZone")
+			},
+		},
 	}).Migrate()
 }
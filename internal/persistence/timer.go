@@ -0,0 +1,131 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/persistence/entity"
+	"gorm.io/gorm"
+)
+
+// timerRepo is a GORM-based implementation of ports.TimerRepo.
+type timerRepo struct {
+	db *gorm.DB
+}
+
+// NewTimerRepo creates a new GORM-backed timer repository.
+func NewTimerRepo(db *gorm.DB) *timerRepo {
+	return &timerRepo{db: db}
+}
+
+// Save inserts a new timer.
+func (r *timerRepo) Save(ctx context.Context, timer domain.Timer) (*domain.Timer, error) {
+	deviceID, err := uuid.Parse(timer.DeviceID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid device ID: %w", err)
+	}
+	userID, err := uuid.Parse(timer.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	e := entity.Timer{
+		DeviceID:  deviceID,
+		UserID:    userID,
+		Kind:      string(timer.Kind),
+		Label:     timer.Label,
+		FiresAt:   timer.FiresAt,
+		CreatedAt: timer.CreatedAt,
+		TimeZone:  timer.TimeZone,
+	}
+	if err := r.db.WithContext(ctx).Create(&e).Error; err != nil {
+		slog.Error("failed to save timer", "err", err, "user_id", timer.UserID)
+		return nil, fmt.Errorf("save timer: %w", err)
+	}
+
+	return toDomainTimer(&e), nil
+}
+
+// FindByUserID retrieves the timers of a user, soonest first.
+func (r *timerRepo) FindByUserID(ctx context.Context, userID string) ([]domain.Timer, error) {
+	var entities []entity.Timer
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("fires_at").
+		Find(&entities).Error; err != nil {
+
+		slog.Error("failed to find timers by user id", "err", err, "user_id", userID)
+		return nil, fmt.Errorf("find timers by user id: %w", err)
+	}
+
+	return toDomainTimers(entities), nil
+}
+
+// FindDue retrieves all timers firing at or before until, soonest first.
+func (r *timerRepo) FindDue(ctx context.Context, until time.Time) ([]domain.Timer, error) {
+	var entities []entity.Timer
+	if err := r.db.WithContext(ctx).
+		Where("fires_at <= ?", until).
+		Order("fires_at").
+		Find(&entities).Error; err != nil {
+
+		slog.Error("failed to find due timers", "err", err)
+		return nil, fmt.Errorf("find due timers: %w", err)
+	}
+
+	return toDomainTimers(entities), nil
+}
+
+// FindNext retrieves the timer firing next.
+func (r *timerRepo) FindNext(ctx context.Context) (*domain.Timer, error) {
+	var e entity.Timer
+	if err := r.db.WithContext(ctx).
+		Order("fires_at").
+		First(&e).Error; err != nil {
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, domain.ErrTimerNotFound
+		}
+
+		slog.Error("failed to find next timer", "err", err)
+		return nil, fmt.Errorf("find next timer: %w", err)
+	}
+
+	return toDomainTimer(&e), nil
+}
+
+// Delete removes a timer by ID.
+func (r *timerRepo) Delete(ctx context.Context, id string) error {
+	if err := r.db.WithContext(ctx).Delete(&entity.Timer{}, "id = ?", id).Error; err != nil {
+		slog.Error("failed to delete timer", "err", err, "id", id)
+		return fmt.Errorf("delete timer: %w", err)
+	}
+	return nil
+}
+
+func toDomainTimers(entities []entity.Timer) []domain.Timer {
+	timers := make([]domain.Timer, 0, len(entities))
+	for _, e := range entities {
+		timers = append(timers, *toDomainTimer(&e))
+	}
+	return timers
+}
+
+// toDomainTimer converts a persistence entity.Timer to a domain.Timer.
+func toDomainTimer(e *entity.Timer) *domain.Timer {
+	return &domain.Timer{
+		ID:        e.ID.String(),
+		DeviceID:  e.DeviceID.String(),
+		UserID:    e.UserID.String(),
+		Kind:      domain.TimerKind(e.Kind),
+		Label:     e.Label,
+		FiresAt:   e.FiresAt,
+		CreatedAt: e.CreatedAt,
+		TimeZone:  e.TimeZone,
+	}
+}
@@ -0,0 +1,143 @@
+package piper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ownerofglory/raspi-agent/internal/audio/wav"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// DefaultBinary is the name of the Piper command line tool.
+const DefaultBinary = "piper"
+
+// defaultSampleRate is the sample rate of most Piper voices, used if the
+// voice configuration cannot be read.
+const defaultSampleRate = 22050
+
+// cliClient implements ports.SpeechProvider by running Piper for each
+// request, streaming the raw audio it writes to stdout.
+type cliClient struct {
+	binary    string
+	modelPath string
+	rate      float64
+
+	mu sync.Mutex
+	// sampleRates caches the sample rate of each voice model
+	sampleRates map[string]int
+}
+
+// NewCLIClient creates a speech provider running binary, DefaultBinary if
+// empty, with the voice model at modelPath, e.g.
+// "voices/en_US-lessac-medium.onnx", speaking at the rate, 0 for normal speed.
+//
+// Voices requested by name are looked up next to the model.
+func NewCLIClient(binary, modelPath string, rate float64) *cliClient {
+	if binary == "" {
+		binary = DefaultBinary
+	}
+	return &cliClient{
+		binary:      binary,
+		modelPath:   modelPath,
+		rate:        rate,
+		sampleRates: make(map[string]int),
+	}
+}
+
+// ProduceSpeechSSE produces the same audio stream as ProduceSpeechAudio,
+// as Piper has no event stream.
+func (c *cliClient) ProduceSpeechSSE(ctx context.Context, req *domain.SpeechRequest) (<-chan *domain.SpeechResult, error) {
+	return c.ProduceSpeechAudio(ctx, req)
+}
+
+// ProduceSpeechAudio synthesizes the text and streams it as WAV while Piper
+// is still speaking.
+func (c *cliClient) ProduceSpeechAudio(ctx context.Context, req *domain.SpeechRequest) (<-chan *domain.SpeechResult, error) {
+	model := c.model(req.Voice.Voice)
+	rate := c.rate
+	if req.Voice.Rate > 0 {
+		rate = req.Voice.Rate
+	}
+
+	args := []string{"--model", model, "--output-raw"}
+	if scale := lengthScale(rate); scale > 0 {
+		args = append(args, "--length_scale", strconv.FormatFloat(scale, 'f', 3, 64))
+	}
+	cmd := exec.CommandContext(ctx, c.binary, args...)
+	cmd.Stdin = strings.NewReader(strings.ReplaceAll(req.Text, "\n", " ") + "\n")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		slog.Error("Failed to Text to Speech", "err", err)
+		return nil, fmt.Errorf("failed to Text to Speech: %w", err)
+	}
+
+	// the raw output is 16-bit mono PCM at the sample rate of the voice
+	var header bytes.Buffer
+	_ = wav.WriteHeader(&header, c.sampleRate(model), 1, wav.UnknownLength)
+
+	ch := make(chan *domain.SpeechResult)
+	go func() {
+		streamAudio(ctx, header.Bytes(), stdout, ch)
+		if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+			slog.Error("Piper failed", "err", err, "output", stderr.String())
+		}
+	}()
+
+	return ch, nil
+}
+
+// model returns the path of the voice model, the configured one unless
+// another voice is requested.
+func (c *cliClient) model(voice string) string {
+	if voice == "" {
+		return c.modelPath
+	}
+	// voices are names, not paths
+	name := strings.TrimSuffix(filepath.Base(voice), ".onnx")
+	return filepath.Join(filepath.Dir(c.modelPath), name+".onnx")
+}
+
+// sampleRate reads the sample rate of the model from its configuration,
+// stored next to it, e.g. "en_US-lessac-medium.onnx.json".
+func (c *cliClient) sampleRate(model string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if rate, ok := c.sampleRates[model]; ok {
+		return rate
+	}
+
+	rate := defaultSampleRate
+	var config struct {
+		Audio struct {
+			SampleRate int `json:"sample_rate"`
+		} `json:"audio"`
+	}
+	data, err := os.ReadFile(model + ".json")
+	if err == nil {
+		err = json.Unmarshal(data, &config)
+	}
+	if err != nil || config.Audio.SampleRate == 0 {
+		slog.Warn("Unable to read the sample rate of the voice, assuming the default", "model", model, "err", err)
+	} else {
+		rate = config.Audio.SampleRate
+	}
+
+	c.sampleRates[model] = rate
+	return rate
+}
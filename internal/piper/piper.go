@@ -0,0 +1,72 @@
+// Package piper implements text-to-speech on top of Piper, see
+// https://github.com/OHF-Voice/piper1-gpl, either by running it as a
+// subprocess or by calling its HTTP server, so speech is synthesized
+// locally without internet.
+//
+// Speech is streamed as WAV: one stream per request, which the player
+// joins into continuous playback.
+package piper
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// chunkSize is the size of the audio chunks streamed to the caller.
+const chunkSize = 4096
+
+// wavFormat is the format of the streamed speech.
+var wavFormat = domain.AudioFormat{ContentType: domain.AudioContentTypeWAV}
+
+// lengthScale converts a speaking rate to the phoneme length scale of
+// Piper, where smaller is faster. Zero keeps the default of the voice.
+func lengthScale(rate float64) float64 {
+	if rate <= 0 {
+		return 0
+	}
+	return 1 / rate
+}
+
+// streamAudio sends the audio read from r in chunks until EOF, the context
+// is canceled or reading fails, and closes the channel. The header, if
+// any, is sent first.
+func streamAudio(ctx context.Context, header []byte, r io.Reader, ch chan<- *domain.SpeechResult) {
+	defer close(ch)
+
+	send := func(chunk []byte) bool {
+		select {
+		case <-ctx.Done():
+			slog.Warn("TTS stream canceled by context")
+			return false
+		case ch <- &domain.SpeechResult{Audio: bytes.NewReader(chunk), Format: wavFormat}:
+			return true
+		}
+	}
+
+	if len(header) > 0 && !send(header) {
+		return
+	}
+
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			// copy the slice to avoid overwriting by next read
+			chunk := make([]byte, n)
+			copy(chunk, buf[:n])
+			if !send(chunk) {
+				return
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				slog.Error("TTS stream read error", "err", err)
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package piper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ownerofglory/raspi-agent/internal/audio/wav"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// collect reads all chunks of the speech stream.
+func collect(t *testing.T, ch <-chan *domain.SpeechResult) []byte {
+	var audio []byte
+	for res := range ch {
+		b, err := io.ReadAll(res.Audio)
+		if err != nil {
+			t.Fatalf("expected error to be nil got %v", err)
+		}
+		audio = append(audio, b...)
+	}
+	return audio
+}
+
+func TestCLIClient(t *testing.T) {
+	dir := t.TempDir()
+	// the fake piper writes its arguments as "raw audio", followed by the text
+	binary := filepath.Join(dir, "piper")
+	script := "#!/bin/sh\nprintf '%s ' \"$@\"\ncat\n"
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	config := `{"audio":{"sample_rate":16000}}`
+	if err := os.WriteFile(filepath.Join(dir, "de_DE-thorsten-low.onnx.json"), []byte(config), 0o644); err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		req        *domain.SpeechRequest
+		sampleRate int
+		output     string
+	}{
+		{
+			name:       "configured voice",
+			req:        &domain.SpeechRequest{Text: "Hello there."},
+			sampleRate: defaultSampleRate,
+			output:     "--model " + filepath.Join(dir, "en_US-lessac-medium.onnx") + " --output-raw --length_scale 0.800 Hello there.\n",
+		},
+		{
+			name:       "voice of the device",
+			req:        &domain.SpeechRequest{Text: "Hallo.", Voice: domain.VoiceOptions{Voice: "../de_DE-thorsten-low", Rate: 0.5}},
+			sampleRate: 16000,
+			output:     "--model " + filepath.Join(dir, "de_DE-thorsten-low.onnx") + " --output-raw --length_scale 2.000 Hallo.\n",
+		},
+	}
+
+	c := NewCLIClient(binary, filepath.Join(dir, "en_US-lessac-medium.onnx"), 1.25)
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ch, err := c.ProduceSpeechAudio(context.Background(), tc.req)
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+
+			sampleRate, channels, pcm, err := wav.NewPCMReader(bytes.NewReader(collect(t, ch)))
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+			if sampleRate != tc.sampleRate || channels != 1 {
+				t.Errorf("expected %d Hz mono, got %d Hz and %d channels", tc.sampleRate, sampleRate, channels)
+			}
+			if output, _ := io.ReadAll(pcm); string(output) != tc.output {
+				t.Errorf("expected output %q, got %q", tc.output, output)
+			}
+		})
+	}
+}
+
+func TestServerClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["text"] != "Hello." {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if body["voice"] != "en_GB-alan-low" || body["length_scale"] != 0.5 {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rw.Header().Set("Content-Type", "audio/wav")
+		_ = wav.Encode(rw, 16000, 1, []int16{1, 2, 3})
+	}))
+	defer srv.Close()
+
+	c := NewServerClient(srv.URL, "en_US-lessac-medium", 0)
+	ch, err := c.ProduceSpeechAudio(context.Background(), &domain.SpeechRequest{
+		Text:  "Hello.",
+		Voice: domain.VoiceOptions{Voice: "en_GB-alan-low", Rate: 2},
+	})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	_, _, samples, err := wav.Decode(bytes.NewReader(collect(t, ch)))
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if len(samples) != 3 {
+		t.Errorf("expected 3 samples, got %d", len(samples))
+	}
+
+	if _, err := c.ProduceSpeechAudio(context.Background(), &domain.SpeechRequest{Text: "Hello."}); err == nil {
+		t.Error("expected an error for a rejected request")
+	}
+}
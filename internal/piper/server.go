@@ -0,0 +1,92 @@
+package piper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// serverClient implements ports.SpeechProvider using the HTTP server of
+// Piper, which answers with a WAV file per request.
+type serverClient struct {
+	url        string
+	voice      string
+	rate       float64
+	httpClient *http.Client
+}
+
+// NewServerClient creates a speech provider for the Piper server at url,
+// e.g. "http://localhost:5000", speaking with the voice, empty for the
+// default voice of the server, at the rate, 0 for normal speed.
+func NewServerClient(url, voice string, rate float64) *serverClient {
+	return &serverClient{
+		url:        url,
+		voice:      voice,
+		rate:       rate,
+		httpClient: &http.Client{},
+	}
+}
+
+// ProduceSpeechSSE produces the same audio stream as ProduceSpeechAudio,
+// as Piper has no event stream.
+func (s *serverClient) ProduceSpeechSSE(ctx context.Context, req *domain.SpeechRequest) (<-chan *domain.SpeechResult, error) {
+	return s.ProduceSpeechAudio(ctx, req)
+}
+
+// ProduceSpeechAudio synthesizes the text and streams the WAV response as
+// it is received.
+//
+// Endpoint: POST / with {"text": ..., "voice": ..., "length_scale": ...}
+func (s *serverClient) ProduceSpeechAudio(ctx context.Context, req *domain.SpeechRequest) (<-chan *domain.SpeechResult, error) {
+	body := map[string]any{"text": req.Text}
+	voice := s.voice
+	if req.Voice.Voice != "" {
+		voice = req.Voice.Voice
+	}
+	if voice != "" {
+		body["voice"] = voice
+	}
+	rate := s.rate
+	if req.Voice.Rate > 0 {
+		rate = req.Voice.Rate
+	}
+	if scale := lengthScale(rate); scale > 0 {
+		body["length_scale"] = scale
+	}
+
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create piper request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	res, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		slog.Error("Failed to Text to Speech", "err", err)
+		return nil, fmt.Errorf("failed to Text to Speech: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		defer res.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		slog.Error("Piper server error", "status", res.Status, "body", string(msg))
+		return nil, fmt.Errorf("failed to Text to Speech: piper server error: %s", res.Status)
+	}
+
+	ch := make(chan *domain.SpeechResult)
+	go func() {
+		defer res.Body.Close()
+		streamAudio(ctx, nil, res.Body, ch)
+	}()
+
+	return ch, nil
+}
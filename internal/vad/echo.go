@@ -0,0 +1,83 @@
+package vad
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// echoTail is how long the echo of played audio may still be picked up
+	// by the microphone after playback, e.g. through room reverberation.
+	echoTail = 300 * time.Millisecond
+
+	// echoDecay is the factor the tracked playback energy decays by per
+	// played frame, so the level follows the loudness of the speech.
+	echoDecay = 0.95
+)
+
+// EchoGate tells the user's speech apart from the echo of the device's own
+// playback, so the assistant's voice does not trigger the wake word while
+// it is talking (barge-in).
+//
+// The player reports the frames it plays with Played; a wake word listener
+// asks Allow whether the energy of the microphone input around a detection
+// is loud enough to be the user rather than the echo. This is a coarse
+// gate, not echo cancellation; an echo-canceling audio source (e.g. the
+// PipeWire echo-cancel module) works best together with it.
+//
+// A nil *EchoGate allows everything.
+type EchoGate struct {
+	// ratio is the minimum energy of the microphone input relative to the
+	// energy of the played audio
+	ratio float64
+
+	mu       sync.Mutex
+	playback float64
+	playedAt time.Time
+	now      func() time.Time
+}
+
+// NewEchoGate creates a gate accepting microphone input with at least ratio
+// times the energy of the audio played at the same time, e.g. 0.1 for input
+// 10 dB below the playback. The ratio depends on the speaker volume and the
+// distance between speaker and microphone.
+func NewEchoGate(ratio float64) *EchoGate {
+	return &EchoGate{
+		ratio: ratio,
+		now:   time.Now,
+	}
+}
+
+// Played records a frame of 16-bit PCM audio sent to the speaker.
+func (g *EchoGate) Played(frame []int16) {
+	if g == nil {
+		return
+	}
+	energy := Energy(frame)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.now().Sub(g.playedAt) > echoTail {
+		g.playback = 0
+	}
+	g.playback = max(energy, g.playback*echoDecay)
+	g.playedAt = g.now()
+}
+
+// Allow reports whether microphone input of the given mean-square energy
+// may be the user speaking: always if nothing is playing, otherwise only
+// if it is loud enough compared with the playback.
+func (g *EchoGate) Allow(energy float64) bool {
+	if g == nil {
+		return true
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.now().Sub(g.playedAt) > echoTail {
+		return true
+	}
+	return energy >= g.ratio*g.playback
+}
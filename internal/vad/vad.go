@@ -0,0 +1,208 @@
+package vad
+
+import (
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// Classifier decides whether a single frame of 16-bit PCM audio contains speech.
+//
+// Implementations are expected to be stateful (e.g. to track the background
+// noise level) and are fed consecutive frames of the same stream.
+type Classifier interface {
+	// IsSpeech reports whether the given frame is classified as speech.
+	IsSpeech(frame []int16) bool
+}
+
+// Energy classifier tuning constants.
+const (
+	// minSpeechEnergy is the absolute mean-square energy below which a frame
+	// is never considered speech (roughly -50 dBFS), regardless of how quiet
+	// the background is.
+	minSpeechEnergy = 10.0
+
+	// speechToNoiseRatio is how many times louder than the tracked noise
+	// floor a frame must be to count as speech (~10 dB).
+	speechToNoiseRatio = 10.0
+
+	// maxSpeechZCR is the zero-crossing rate (crossings per sample) above which
+	// a frame is treated as broadband noise (hiss, fans, clicks) rather than voice.
+	maxSpeechZCR = 0.35
+
+	// noiseFloorAdaptation is the smoothing factor used to follow the
+	// background noise level on non-speech frames.
+	noiseFloorAdaptation = 0.05
+
+	// noiseFloorDrift is the much slower relative growth applied per speech
+	// frame, so a constant loud background (e.g. a running fan) is eventually
+	// absorbed into the noise floor instead of being treated as endless speech.
+	noiseFloorDrift = 0.002
+)
+
+// energyClassifier is a lightweight VAD frame classifier based on short-term
+// energy and zero-crossing rate, with an adaptive noise floor.
+//
+// It is cheap enough to run on every captured frame on a Raspberry Pi and
+// needs no model files.
+type energyClassifier struct {
+	noiseFloor float64
+}
+
+// NewEnergyClassifier creates a new energy/zero-crossing based classifier.
+func NewEnergyClassifier() *energyClassifier {
+	return &energyClassifier{
+		noiseFloor: minSpeechEnergy / speechToNoiseRatio,
+	}
+}
+
+// IsSpeech classifies a frame and updates the noise floor estimate.
+func (c *energyClassifier) IsSpeech(frame []int16) bool {
+	if len(frame) == 0 {
+		return false
+	}
+
+	energy := Energy(frame)
+	speech := energy > minSpeechEnergy &&
+		energy > c.noiseFloor*speechToNoiseRatio &&
+		ZeroCrossingRate(frame) < maxSpeechZCR
+
+	switch {
+	case energy < c.noiseFloor:
+		// follow drops in background level quickly
+		c.noiseFloor += 0.5 * (energy - c.noiseFloor)
+	case !speech:
+		c.noiseFloor += noiseFloorAdaptation * (energy - c.noiseFloor)
+	default:
+		c.noiseFloor *= 1 + noiseFloorDrift
+	}
+
+	return speech
+}
+
+// Energy returns the mean-square energy of a frame.
+func Energy(frame []int16) float64 {
+	if len(frame) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, s := range frame {
+		v := float64(s)
+		sum += v * v
+	}
+	return sum / float64(len(frame))
+}
+
+// ZeroCrossingRate returns the fraction of adjacent sample pairs whose sign differs.
+func ZeroCrossingRate(frame []int16) float64 {
+	if len(frame) < 2 {
+		return 0
+	}
+
+	crossings := 0
+	for i := 1; i < len(frame); i++ {
+		if (frame[i-1] >= 0) != (frame[i] >= 0) {
+			crossings++
+		}
+	}
+	return float64(crossings) / float64(len(frame)-1)
+}
+
+// State describes where an Endpointer is within an utterance.
+type State int
+
+const (
+	// StateWaiting means no speech has been detected yet.
+	StateWaiting State = iota
+
+	// StateSpeaking means speech has started and the utterance is ongoing.
+	StateSpeaking
+
+	// StateDone means the utterance has ended (trailing silence or max duration).
+	StateDone
+
+	// StateNoSpeech means no speech started before the no-speech timeout.
+	StateNoSpeech
+)
+
+// speechOnset is how much consecutive speech is required before an
+// utterance is considered started. It filters out single clicks and taps.
+const speechOnset = 60 * time.Millisecond
+
+// Endpointer turns per-frame speech decisions into utterance boundaries.
+//
+// Frames are pushed in capture order; after each frame the endpointer reports
+// whether the caller should keep recording (StateWaiting, StateSpeaking) or
+// stop (StateDone, StateNoSpeech).
+type Endpointer struct {
+	classifier Classifier
+	opts       domain.UtteranceOptions
+	sampleRate int
+
+	state   State
+	elapsed time.Duration
+	voiced  time.Duration
+	silence time.Duration
+}
+
+// NewEndpointer creates an Endpointer for a mono stream with the given sample rate.
+func NewEndpointer(sampleRate int, opts domain.UtteranceOptions, classifier Classifier) *Endpointer {
+	return &Endpointer{
+		classifier: classifier,
+		opts:       opts,
+		sampleRate: sampleRate,
+		state:      StateWaiting,
+	}
+}
+
+// Push feeds the next frame of audio and returns the resulting state.
+// Once StateDone or StateNoSpeech is returned, further frames are ignored.
+func (e *Endpointer) Push(frame []int16) State {
+	if e.state == StateDone || e.state == StateNoSpeech {
+		return e.state
+	}
+
+	frameDuration := time.Duration(len(frame)) * time.Second / time.Duration(e.sampleRate)
+	e.elapsed += frameDuration
+	speech := e.classifier.IsSpeech(frame)
+
+	switch e.state {
+	case StateWaiting:
+		if speech {
+			e.voiced += frameDuration
+		} else {
+			e.voiced = 0
+		}
+		if e.voiced >= speechOnset {
+			e.state = StateSpeaking
+		} else if e.opts.NoSpeechTimeout > 0 && e.elapsed >= e.opts.NoSpeechTimeout {
+			e.state = StateNoSpeech
+			return e.state
+		}
+	case StateSpeaking:
+		if speech {
+			e.silence = 0
+		} else {
+			e.silence += frameDuration
+		}
+		if e.silence >= e.opts.SilenceTimeout && e.elapsed >= e.opts.MinDuration {
+			e.state = StateDone
+		}
+	}
+
+	if e.opts.MaxDuration > 0 && e.elapsed >= e.opts.MaxDuration {
+		if e.state == StateWaiting {
+			e.state = StateNoSpeech
+		} else {
+			e.state = StateDone
+		}
+	}
+
+	return e.state
+}
+
+// Elapsed returns the total duration of audio pushed so far.
+func (e *Endpointer) Elapsed() time.Duration {
+	return e.elapsed
+}
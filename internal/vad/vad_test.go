@@ -0,0 +1,206 @@
+package vad
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+const (
+	testSampleRate = 16000
+	testFrameLen   = testSampleRate / 50 // 20ms
+)
+
+func silenceFrame(r *rand.Rand) []int16 {
+	frame := make([]int16, testFrameLen)
+	for i := range frame {
+		frame[i] = int16(r.Intn(5) - 2)
+	}
+	return frame
+}
+
+func voiceFrame(offset int) []int16 {
+	frame := make([]int16, testFrameLen)
+	for i := range frame {
+		t := float64(offset+i) / testSampleRate
+		frame[i] = int16(6000 * math.Sin(2*math.Pi*220*t))
+	}
+	return frame
+}
+
+func noiseFrame(r *rand.Rand) []int16 {
+	frame := make([]int16, testFrameLen)
+	for i := range frame {
+		frame[i] = int16(r.Intn(16000) - 8000)
+	}
+	return frame
+}
+
+func TestEnergyClassifier(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	testCases := []struct {
+		name   string
+		frame  func(i int) []int16
+		speech bool
+	}{
+		{
+			name:   "silence",
+			frame:  func(int) []int16 { return silenceFrame(r) },
+			speech: false,
+		},
+		{
+			name:   "voiced tone",
+			frame:  func(i int) []int16 { return voiceFrame(i * testFrameLen) },
+			speech: true,
+		},
+		{
+			name:   "broadband noise",
+			frame:  func(int) []int16 { return noiseFrame(r) },
+			speech: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewEnergyClassifier()
+			// prime the noise floor with background silence
+			for i := 0; i < 10; i++ {
+				c.IsSpeech(silenceFrame(r))
+			}
+
+			got := c.IsSpeech(tc.frame(0))
+			if got != tc.speech {
+				t.Fatalf("expected speech=%v, got %v", tc.speech, got)
+			}
+		})
+	}
+}
+
+// segment describes a run of frames of the same kind for endpointer tests.
+type segment struct {
+	voiced bool
+	dur    time.Duration
+}
+
+func TestEndpointer(t *testing.T) {
+	opts := domain.UtteranceOptions{
+		SilenceTimeout:  400 * time.Millisecond,
+		MinDuration:     300 * time.Millisecond,
+		MaxDuration:     3 * time.Second,
+		NoSpeechTimeout: time.Second,
+	}
+
+	testCases := []struct {
+		name     string
+		segments []segment
+		state    State
+		elapsed  time.Duration
+	}{
+		{
+			name: "ends after trailing silence",
+			segments: []segment{
+				{voiced: false, dur: 200 * time.Millisecond},
+				{voiced: true, dur: 600 * time.Millisecond},
+				{voiced: false, dur: 2 * time.Second},
+			},
+			state:   StateDone,
+			elapsed: 1200 * time.Millisecond,
+		},
+		{
+			name: "short pause does not end utterance",
+			segments: []segment{
+				{voiced: true, dur: 400 * time.Millisecond},
+				{voiced: false, dur: 200 * time.Millisecond},
+				{voiced: true, dur: 400 * time.Millisecond},
+				{voiced: false, dur: 2 * time.Second},
+			},
+			state:   StateDone,
+			elapsed: 1400 * time.Millisecond,
+		},
+		{
+			name: "no speech",
+			segments: []segment{
+				{voiced: false, dur: 3 * time.Second},
+			},
+			state:   StateNoSpeech,
+			elapsed: time.Second,
+		},
+		{
+			name: "max duration",
+			segments: []segment{
+				{voiced: true, dur: 5 * time.Second},
+			},
+			state:   StateDone,
+			elapsed: 3 * time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(1))
+			e := NewEndpointer(testSampleRate, opts, NewEnergyClassifier())
+
+			state := StateWaiting
+			offset := 0
+		loop:
+			for _, seg := range tc.segments {
+				frames := int(seg.dur / (20 * time.Millisecond))
+				for i := 0; i < frames; i++ {
+					frame := silenceFrame(r)
+					if seg.voiced {
+						frame = voiceFrame(offset)
+					}
+					offset += testFrameLen
+
+					state = e.Push(frame)
+					if state == StateDone || state == StateNoSpeech {
+						break loop
+					}
+				}
+			}
+
+			if state != tc.state {
+				t.Fatalf("expected state %v, got %v", tc.state, state)
+			}
+			if e.Elapsed() != tc.elapsed {
+				t.Errorf("expected elapsed %v, got %v", tc.elapsed, e.Elapsed())
+			}
+		})
+	}
+}
+
+func TestEchoGate(t *testing.T) {
+	now := time.Unix(0, 0)
+	g := NewEchoGate(0.1)
+	g.now = func() time.Time { return now }
+
+	echo := Energy(voiceFrame(0)) * 0.01
+	speech := Energy(voiceFrame(0)) * 0.5
+
+	if !g.Allow(echo) {
+		t.Errorf("expected input to be allowed before playback")
+	}
+
+	g.Played(voiceFrame(0))
+	if g.Allow(echo) {
+		t.Errorf("expected the echo of the playback to be gated")
+	}
+	if !g.Allow(speech) {
+		t.Errorf("expected speech louder than the echo to be allowed")
+	}
+
+	now = now.Add(echoTail + time.Millisecond)
+	if !g.Allow(echo) {
+		t.Errorf("expected input to be allowed after playback")
+	}
+
+	var disabled *EchoGate
+	disabled.Played(voiceFrame(0))
+	if !disabled.Allow(0) {
+		t.Errorf("expected a nil gate to allow everything")
+	}
+}
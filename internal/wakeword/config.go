@@ -0,0 +1,144 @@
+package wakeword
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"github.com/ownerofglory/raspi-agent/internal/vad"
+)
+
+// LoadWakeWords reads the wake words of a device from a JSON file holding
+// an array of domain.WakeWord, named after their model unless named, e.g.
+//
+//	[
+//	  {"name": "jarvis", "model": "resources/jarvis.ppn", "sensitivity": 0.6, "voice": {"voice": "onyx"}},
+//	  {"name": "hallo", "model": "resources/hallo.ppn", "voice": {"voice": "de_DE-thorsten-low", "language": "de"}},
+//	  {"name": "stop", "model": "resources/stop.ppn", "action": "stop"}
+//	]
+func LoadWakeWords(path string) ([]domain.WakeWord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read wake words: %w", err)
+	}
+
+	var words []domain.WakeWord
+	if err := json.Unmarshal(data, &words); err != nil {
+		return nil, fmt.Errorf("invalid wake words: %w", err)
+	}
+	for i, w := range words {
+		if w.Name == "" && w.Model == "" {
+			return nil, fmt.Errorf("invalid wake words: a wake word has neither name nor model")
+		}
+		if w.Name == "" {
+			words[i].Name = w.Model
+		}
+		if w.Sensitivity < 0 || w.Sensitivity > 1 {
+			return nil, fmt.Errorf("invalid wake words: sensitivity of %q not in [0, 1]", w.Name)
+		}
+		switch w.Action {
+		case "", domain.WakeActionAssist, domain.WakeActionStop:
+		default:
+			return nil, fmt.Errorf("invalid wake words: unknown action %q of %q", w.Action, w.Name)
+		}
+	}
+	return words, nil
+}
+
+// Engines of the wake word listener created by FromConfig.
+const (
+	EnginePorcupine    = "porcupine"
+	EngineOpenWakeWord = "openwakeword"
+	EngineStdin        = "stdin"
+	EngineFile         = "file"
+)
+
+// Config selects the wake word engine of a device, its wake words and the
+// triggers waking the assistant up otherwise.
+type Config struct {
+	Engine string // one of the engines, e.g. EnginePorcupine
+
+	// WakeWords is the JSON file of the wake words, see LoadWakeWords,
+	// overriding PorcupineKeywordPath and OpenWakeWordModels.
+	WakeWords string
+
+	PorcupineAccessKey   string
+	PorcupineLibPath     string
+	PorcupineModelPath   string
+	PorcupineKeywordPath string
+
+	OpenWakeWordAddr   string
+	OpenWakeWordModels []string // empty detects all models of the server
+
+	WakeFile string // lines waking the assistant up with EngineFile
+
+	PushToTalk  bool   // lines on stdin wake the assistant up as well
+	TriggerAddr string // address of the trigger endpoint, empty disables it
+}
+
+// FromConfig creates the listener of the configured wake word engine,
+// combined with the triggers waking the assistant up otherwise:
+// push-to-talk on stdin, the HTTP endpoint and the returned trigger
+// function, e.g. for the backend. The audio engines listen to the capture.
+func FromConfig(ctx context.Context, capture ports.AudioSource, echo *vad.EchoGate, config Config) (ports.WakeListener, func(name string) bool, error) {
+	var words []domain.WakeWord
+	if config.WakeWords != "" {
+		loaded, err := LoadWakeWords(config.WakeWords)
+		if err != nil {
+			return nil, nil, err
+		}
+		words = loaded
+	}
+
+	var engine ports.WakeListener
+	switch config.Engine {
+	case EnginePorcupine:
+		if words == nil {
+			words = []domain.WakeWord{{Name: "wake word", Model: config.PorcupineKeywordPath}}
+		}
+		engine = NewPorcupineListener(capture, config.PorcupineAccessKey, config.PorcupineModelPath, config.PorcupineLibPath, words, echo)
+	case EngineOpenWakeWord:
+		if words == nil {
+			for _, model := range config.OpenWakeWordModels {
+				if model = strings.TrimSpace(model); model != "" {
+					words = append(words, domain.WakeWord{Name: model})
+				}
+			}
+		}
+		l, err := NewOpenWakeWordListener(capture, config.OpenWakeWordAddr, words, echo)
+		if err != nil {
+			return nil, nil, err
+		}
+		engine = l
+	case EngineStdin:
+		engine = NewLineListener(os.Stdin, words)
+	case EngineFile:
+		f, err := os.Open(config.WakeFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		engine = NewLineListener(f, words)
+	default:
+		return nil, nil, fmt.Errorf("unknown wake word engine %q", config.Engine)
+	}
+
+	trigger := NewTriggerListener(words)
+	listeners := []ports.WakeListener{engine, trigger}
+	if config.PushToTalk && config.Engine != EngineStdin {
+		listeners = append(listeners, NewLineListener(os.Stdin, words))
+	}
+	if config.TriggerAddr != "" {
+		go func() {
+			if err := trigger.Serve(ctx, config.TriggerAddr); err != nil {
+				slog.Error("Unable to serve triggers", "error", err)
+			}
+		}()
+	}
+
+	return NewMultiListener(listeners...), trigger.Trigger, nil
+}
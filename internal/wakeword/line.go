@@ -0,0 +1,87 @@
+package wakeword
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// lineListener is triggered by lines of text instead of audio, e.g. by
+// pressing Enter on stdin or by a scripted file in tests.
+//
+// A line may hold a delay, e.g. "2s", to wait for before the detection,
+// followed by the name of the detected wake word, e.g. "2s stop". Lines
+// without a name detect the first wake word, as does any line if no wake
+// words are configured.
+type lineListener struct {
+	lines <-chan string
+	words []domain.WakeWord
+}
+
+// NewLineListener creates a listener detecting one of the wake words for
+// every line read from r. Once r is exhausted, Listen fails with io.EOF.
+func NewLineListener(r io.Reader, words []domain.WakeWord) *lineListener {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- strings.TrimSpace(scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			slog.Error("Failed to read wake word input", "err", err)
+		}
+	}()
+
+	return &lineListener{lines: lines, words: words}
+}
+
+// Listen waits for the next line naming a known wake word, if any, and
+// its delay.
+func (l *lineListener) Listen(ctx context.Context) (*domain.WakeEvent, error) {
+	slog.Debug("Waiting for a line to wake up...")
+
+	for {
+		var line string
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case next, ok := <-l.lines:
+			if !ok {
+				return nil, fmt.Errorf("wake word input ended: %w", io.EOF)
+			}
+			line = next
+		}
+
+		name := line
+		if delay, rest, _ := strings.Cut(line, " "); delay != "" {
+			if d, err := time.ParseDuration(delay); err == nil {
+				name = strings.TrimSpace(rest)
+				select {
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				case <-time.After(d):
+				}
+			}
+		}
+
+		keyword := 0
+		if name != "" && len(l.words) > 0 {
+			keyword = indexOf(l.words, name)
+		}
+		if keyword < 0 {
+			slog.Warn("Unknown wake word", "name", name)
+			continue
+		}
+
+		ev := detected(l.words, keyword, 1)
+		slog.Debug("Wake word detected!", "line", line, "name", ev.Word.Name)
+		return ev, nil
+	}
+}
@@ -0,0 +1,144 @@
+package wakeword
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+)
+
+// multiListener combines several listeners, e.g. a wake word engine with
+// push-to-talk and remote triggers, into one.
+type multiListener struct {
+	listeners []ports.WakeListener
+}
+
+// NewMultiListener creates a listener woken up by whichever of the
+// listeners detects a wake word first.
+func NewMultiListener(listeners ...ports.WakeListener) *multiListener {
+	return &multiListener{listeners: listeners}
+}
+
+// listenerRetryInterval is the time a failed listener waits before
+// listening again while the other listeners keep running.
+const listenerRetryInterval = time.Second
+
+// Listen runs all listeners until one of them detects a wake word, whose
+// event it returns. A failing listener is logged and retried on its own
+// while the others keep listening; Listen fails only once every listener
+// has failed. Listeners whose input is exhausted (io.EOF), e.g. stdin, are
+// dropped; once all of them are, Listen fails with io.EOF. Triggers only
+// supplement the inputs, so the inputs have ended as well once nothing but
+// triggers remains. Detections of other listeners at the same time are
+// dropped.
+func (m *multiListener) Listen(ctx context.Context) (*domain.WakeEvent, error) {
+	if len(m.listeners) == 0 {
+		return nil, fmt.Errorf("wake word inputs ended: %w", io.EOF)
+	}
+
+	listenCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		i   int
+		ev  *domain.WakeEvent
+		err error
+	}
+	results := make(chan result, len(m.listeners))
+	listen := func(i int, delay time.Duration) {
+		go func() {
+			if delay > 0 {
+				select {
+				case <-listenCtx.Done():
+					results <- result{i: i, err: listenCtx.Err()}
+					return
+				case <-time.After(delay):
+				}
+			}
+			ev, err := m.listeners[i].Listen(listenCtx)
+			results <- result{i: i, ev: ev, err: err}
+		}()
+	}
+	for i := range m.listeners {
+		listen(i, 0)
+	}
+
+	// every listener has returned before the next call, so none listens twice
+	var (
+		wake      *domain.WakeEvent
+		failures  = make(map[int]error)
+		exhausted = make(map[int]bool)
+	)
+	for running := len(m.listeners); running > 0; running-- {
+		res := <-results
+		switch {
+		case res.err == nil:
+			if wake == nil {
+				wake = res.ev
+				cancel()
+			}
+		case errors.Is(res.err, io.EOF):
+			exhausted[res.i] = true
+			delete(failures, res.i)
+			if m.inputsEnded(exhausted) {
+				cancel()
+			}
+		case listenCtx.Err() != nil:
+		default:
+			slog.Error("Wake word listener failed", "listener", res.i, "error", res.err)
+			failures[res.i] = res.err
+			if len(failures)+len(exhausted) == len(m.listeners) {
+				cancel()
+				continue
+			}
+			running++
+			listen(res.i, listenerRetryInterval)
+		}
+	}
+
+	var (
+		remaining []ports.WakeListener
+		errs      []error
+	)
+	for i, l := range m.listeners {
+		if !exhausted[i] {
+			remaining = append(remaining, l)
+		}
+		if err, ok := failures[i]; ok {
+			errs = append(errs, err)
+		}
+	}
+	if len(exhausted) > 0 {
+		if m.inputsEnded(exhausted) {
+			remaining = nil
+		}
+		slog.Info("Wake word input ended", "remaining", len(remaining))
+	}
+	m.listeners = remaining
+
+	switch {
+	case wake != nil:
+		return wake, nil
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case len(errs) > 0:
+		return nil, fmt.Errorf("all wake word listeners failed: %w", errors.Join(errs...))
+	default:
+		return nil, fmt.Errorf("wake word inputs ended: %w", io.EOF)
+	}
+}
+
+// inputsEnded reports whether every listener but the triggers is exhausted.
+func (m *multiListener) inputsEnded(exhausted map[int]bool) bool {
+	for i, l := range m.listeners {
+		if _, ok := l.(*triggerListener); !ok && !exhausted[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,173 @@
+package wakeword
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"github.com/ownerofglory/raspi-agent/internal/vad"
+)
+
+// DefaultOpenWakeWordAddr is the address wyoming-openwakeword listens on by default.
+const DefaultOpenWakeWordAddr = "localhost:10400"
+
+// openWakeWordListener detects wake words with openWakeWord, see
+// https://github.com/dscripka/openWakeWord, running its open-source ONNX
+// models in a wyoming-openwakeword server, e.g. next to the device.
+//
+// The captured audio is streamed to the server over the Wyoming protocol
+// until it reports a detection.
+type openWakeWordListener struct {
+	addr   string
+	words  []domain.WakeWord
+	source ports.AudioSource
+	echo   *vad.EchoGate
+}
+
+// NewOpenWakeWordListener creates a listener streaming the audio of source
+// to the wyoming-openwakeword server at addr (host:port). Only the wake
+// words whose models are named, e.g. "ok_nabu", are detected; all models of
+// the server if there are none. The server reports detections without their
+// score and detects above its own threshold, so wake words with a
+// sensitivity are rejected. The echo gate is optional, see
+// NewPorcupineListener.
+func NewOpenWakeWordListener(source ports.AudioSource, addr string, words []domain.WakeWord, echo *vad.EchoGate) (*openWakeWordListener, error) {
+	for _, w := range words {
+		if w.Sensitivity != 0 {
+			return nil, fmt.Errorf("openwakeword: the sensitivity of %q cannot be applied, set the threshold of the server instead", w.Name)
+		}
+	}
+
+	return &openWakeWordListener{
+		addr:   addr,
+		words:  words,
+		source: source,
+		echo:   echo,
+	}, nil
+}
+
+// Listen streams the captured audio to the server until it detects a wake
+// word, the context is canceled or the connection fails. Wake words the
+// server detects but which are not configured are reported by name only,
+// with keyword -1.
+func (l *openWakeWordListener) Listen(ctx context.Context) (*domain.WakeEvent, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", l.addr)
+	if err != nil {
+		slog.Error("openwakeword: unable to connect", "addr", l.addr, "err", err)
+		return nil, fmt.Errorf("openwakeword: unable to connect: %w", err)
+	}
+	defer conn.Close()
+	go func() {
+		// unblocks reading the events
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	if len(l.words) > 0 {
+		names := make([]string, 0, len(l.words))
+		for _, w := range l.words {
+			names = append(names, modelOf(w))
+		}
+		if err := writeWyomingEvent(conn, wyomingEvent{Type: "detect", Data: map[string]any{"names": names}}); err != nil {
+			return nil, fmt.Errorf("openwakeword: %w", err)
+		}
+	}
+
+	frames, err := l.source.Subscribe(ctx, 0)
+	if err != nil {
+		return nil, fmt.Errorf("openwakeword: unable to capture audio: %w", err)
+	}
+
+	detections := make(chan string, 1)
+	readErr := make(chan error, 1)
+	go func() {
+		r := bufio.NewReader(conn)
+		for {
+			ev, err := readWyomingEvent(r)
+			if err != nil {
+				readErr <- err
+				return
+			}
+			if ev.Type != "detection" {
+				continue
+			}
+			name, _ := ev.Data["name"].(string)
+			select {
+			case detections <- name:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	slog.Debug("Listening for wake word...", "engine", "openwakeword", "addr", l.addr)
+
+	var (
+		energies *energyWindow
+		sent     time.Duration
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+
+		case err := <-readErr:
+			return nil, fmt.Errorf("openwakeword: connection lost: %w", err)
+
+		case name := <-detections:
+			if energies != nil && !l.echo.Allow(energies.mean()) {
+				slog.Info("Wake word ignored as echo of the playback", "name", name)
+				continue
+			}
+			ev := detected(l.words, indexOf(l.words, name), 1)
+			if ev.Keyword < 0 {
+				ev.Word = domain.WakeWord{Name: name}
+			}
+			slog.Debug("Wake word detected!", "name", ev.Word.Name)
+			return ev, nil
+
+		case frame, ok := <-frames:
+			if !ok {
+				return nil, fmt.Errorf("openwakeword: audio capture stopped")
+			}
+			if frame.Err != nil {
+				return nil, fmt.Errorf("openwakeword: %w", frame.Err)
+			}
+
+			format := map[string]any{"rate": frame.SampleRate, "width": 2, "channels": frame.Channels}
+			duration := time.Duration(len(frame.Samples)/frame.Channels) * time.Second / time.Duration(frame.SampleRate)
+			if energies == nil {
+				energies = newEnergyWindow(duration)
+				if err := writeWyomingEvent(conn, wyomingEvent{Type: "audio-start", Data: format}); err != nil {
+					return nil, fmt.Errorf("openwakeword: %w", err)
+				}
+			}
+			energies.push(frame.Samples)
+
+			format["timestamp"] = sent.Milliseconds()
+			sent += duration
+			if err := writeWyomingEvent(conn, wyomingEvent{Type: "audio-chunk", Data: format, Payload: pcmBytes(frame)}); err != nil {
+				return nil, fmt.Errorf("openwakeword: %w", err)
+			}
+		}
+	}
+}
+
+// pcmBytes returns the samples of the frame as 16-bit little-endian PCM.
+func pcmBytes(frame domain.AudioFrame) []byte {
+	b := make([]byte, 0, 2*len(frame.Samples))
+	for _, s := range frame.Samples {
+		b = binary.LittleEndian.AppendUint16(b, uint16(s))
+	}
+	return b
+}
@@ -4,49 +4,71 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"time"
 
-	"github.com/gordonklaus/portaudio"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/core/ports"
+	"github.com/ownerofglory/raspi-agent/internal/vad"
 	porcupine "github.com/sigidagi/porcupine/binding/go/v2"
 )
 
 // porcupineListener provides real-time wake-word detection using the
 // Picovoice Porcupine engine.
 //
-// It encapsulates the lifecycle of the Porcupine instance and manages
-// an audio input stream from the system microphone via PortAudio.
+// It encapsulates the lifecycle of the Porcupine instance and processes
+// the audio of the shared capture of the system microphone.
 type porcupineListener struct {
-	p *porcupine.Porcupine
+	p      *porcupine.Porcupine
+	words  []domain.WakeWord
+	source ports.AudioSource
+	echo   *vad.EchoGate
 }
 
+// defaultPorcupineSensitivity is the sensitivity of wake words without one.
+const defaultPorcupineSensitivity = 0.5
+
 // NewPorcupineListener creates and configures a new Porcupine listener.
 //
 // Parameters:
+//   - source:       Audio input, captured at porcupine.SampleRate (16 kHz mono).
 //   - accessKey:    Picovoice access key used to authenticate the SDK.
 //   - modelPath:    Path to the Porcupine model file (e.g. "porcupine_params.pv").
 //   - libraryPath:  Path to the shared library (e.g. "libpv_porcupine.so").
-//   - keywordPath:  Path to the keyword file (.ppn) for the desired wake word.
+//   - words:        Wake words to detect, whose models are the paths of
+//     their keyword files (.ppn), each with its own sensitivity.
+//   - echo:         Optional gate ignoring detections caused by the echo of
+//     the device's own playback, so the listener can run while it talks.
 //
 // Returns:
 //
 //	A ready-to-use *porcupineListener instance.
-func NewPorcupineListener(accessKey, modelPath, libraryPath, keywordPath string) *porcupineListener {
+func NewPorcupineListener(source ports.AudioSource, accessKey, modelPath, libraryPath string, words []domain.WakeWord, echo *vad.EchoGate) *porcupineListener {
 	var p = &porcupine.Porcupine{
 		AccessKey:   accessKey,
 		ModelPath:   modelPath,
 		LibraryPath: libraryPath,
-		KeywordPaths: []string{
-			keywordPath,
-		},
+	}
+	for _, w := range words {
+		sensitivity := w.Sensitivity
+		if sensitivity <= 0 {
+			sensitivity = defaultPorcupineSensitivity
+		}
+		p.KeywordPaths = append(p.KeywordPaths, modelOf(w))
+		p.Sensitivities = append(p.Sensitivities, float32(sensitivity))
 	}
 	return &porcupineListener{
-		p: p,
+		p:      p,
+		words:  words,
+		source: source,
+		echo:   echo,
 	}
 }
 
-// Listen continuously captures audio input and processes it using Porcupine
+// Listen continuously processes the captured audio using Porcupine
 // until a wake word is detected or the provided context is cancelled.
 //
-// When the wake word is detected, Listen returns nil. If an error occurs
+// When a wake word is detected, Listen returns its event; Porcupine does
+// not score detections, so their confidence is 1. If an error occurs
 // during initialization or audio processing, it returns a descriptive error.
 //
 // Typical usage:
@@ -54,58 +76,72 @@ func NewPorcupineListener(accessKey, modelPath, libraryPath, keywordPath string)
 //	ctx, cancel := context.WithCancel(context.Background())
 //	defer cancel()
 //	listener := NewPorcupineListener(...)
-//	err := listener.Listen(ctx)
+//	ev, err := listener.Listen(ctx)
 //	if err != nil {
 //	    log.Fatal(err)
 //	}
-//	fmt.Println("Wake word detected!")
-func (l *porcupineListener) Listen(ctx context.Context) error {
+//	fmt.Println("Wake word detected:", ev.Word.Name)
+func (l *porcupineListener) Listen(ctx context.Context) (*domain.WakeEvent, error) {
 	err := l.p.Init()
 	if err != nil {
 		slog.Error("porcupine: failed to initialize porcupine", "err", err)
-		return fmt.Errorf("porcupine: failed to initialize porcupine: %w", err)
+		return nil, fmt.Errorf("porcupine: failed to initialize porcupine: %w", err)
 	}
 	defer l.p.Delete()
 
 	slog.Debug("Porcupine ready", "SampleRate", porcupine.SampleRate, "FrameLength", porcupine.FrameLength)
-	if err := portaudio.Initialize(); err != nil {
-		slog.Error("portaudio init:", "err", err)
-		return fmt.Errorf("portaudio init:: %w", err)
-	}
-	defer portaudio.Terminate()
 
-	buf := make([]int16, porcupine.FrameLength)
-	stream, err := portaudio.OpenDefaultStream(1, 0, float64(porcupine.SampleRate), len(buf), buf)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	frames, err := l.source.Subscribe(ctx, 0)
 	if err != nil {
-		slog.Error("OpenDefaultStream:", "err", err)
-		return fmt.Errorf("porcupine: OpenDefaultStream:: %w", err)
-	}
-	defer stream.Close()
-
-	if err := stream.Start(); err != nil {
-		slog.Error("stream start:", "err", err)
-		return fmt.Errorf("porcupine: stream start:: %w", err)
+		slog.Error("porcupine: unable to capture audio", "err", err)
+		return nil, fmt.Errorf("porcupine: unable to capture audio: %w", err)
 	}
 
 	slog.Debug("Listening for wake word...")
 
+	// captured samples are processed in frames of the length Porcupine expects
+	pending := make([]int16, 0, 2*porcupine.FrameLength)
+	energies := newEnergyWindow(time.Duration(porcupine.FrameLength) * time.Second / time.Duration(porcupine.SampleRate))
 	for {
+		var frame domain.AudioFrame
 		select {
 		case <-ctx.Done():
-		default:
-			if err := stream.Read(); err != nil {
-				slog.Warn("stream read error:", "err", err)
-				continue
+			return nil, ctx.Err()
+		case f, ok := <-frames:
+			if !ok {
+				return nil, fmt.Errorf("porcupine: audio capture stopped")
 			}
+			frame = f
+		}
+		if frame.Err != nil {
+			return nil, fmt.Errorf("porcupine: %w", frame.Err)
+		}
+		if frame.SampleRate != porcupine.SampleRate || frame.Channels != 1 {
+			return nil, fmt.Errorf("porcupine: needs mono audio at %d Hz, got %d channels at %d Hz",
+				porcupine.SampleRate, frame.Channels, frame.SampleRate)
+		}
+
+		pending = append(pending, frame.Samples...)
+		for len(pending) >= porcupine.FrameLength {
+			buf := pending[:porcupine.FrameLength]
+			energies.push(buf)
 
 			res, err := l.p.Process(buf)
+			pending = append(pending[:0], pending[porcupine.FrameLength:]...)
 			if err != nil {
 				slog.Warn("porcupine process error:", "err", err)
 				continue
 			}
 			if res >= 0 {
-				slog.Debug("Wake word detected!")
-				return nil
+				if !l.echo.Allow(energies.mean()) {
+					slog.Info("Wake word ignored as echo of the playback", "keyword", res)
+					continue
+				}
+				ev := detected(l.words, res, 1)
+				slog.Debug("Wake word detected!", "name", ev.Word.Name)
+				return ev, nil
 			}
 		}
 	}
@@ -0,0 +1,117 @@
+package wakeword
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// triggerQueryParam names the wake word a trigger request acts as.
+const triggerQueryParam = "wake"
+
+// triggerListener is woken up by remote triggers instead of audio, e.g. a
+// push button wired to an HTTP request, a script on the device or a
+// "listen" notification of the backend.
+type triggerListener struct {
+	words    []domain.WakeWord
+	triggers chan int
+}
+
+// NewTriggerListener creates a listener woken up by Trigger, e.g. through
+// its HTTP endpoint, as if one of the wake words was detected.
+func NewTriggerListener(words []domain.WakeWord) *triggerListener {
+	return &triggerListener{
+		words:    words,
+		triggers: make(chan int, 1),
+	}
+}
+
+// Trigger wakes the listener up as if the wake word with the given name,
+// or the first one if the name is empty, was detected. It reports false for
+// unknown wake words. A trigger arriving while another is pending is
+// dropped.
+func (t *triggerListener) Trigger(name string) bool {
+	keyword := 0
+	if name != "" && len(t.words) > 0 {
+		keyword = indexOf(t.words, name)
+	}
+	if keyword < 0 {
+		return false
+	}
+
+	select {
+	case t.triggers <- keyword:
+	default:
+	}
+	return true
+}
+
+// Listen waits for the next trigger.
+func (t *triggerListener) Listen(ctx context.Context) (*domain.WakeEvent, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case keyword := <-t.triggers:
+		ev := detected(t.words, keyword, 1)
+		slog.Debug("Wake word triggered", "name", ev.Word.Name)
+		return ev, nil
+	}
+}
+
+// ServeHTTP triggers the listener.
+//
+// Endpoint: POST /, with the optional query parameter "wake" naming the
+// wake word to act as, e.g.
+//
+//	curl -X POST --unix-socket /run/raspi-agent/trigger.sock http://localhost/?wake=stop
+//
+// Response 202 Accepted, or 400 Bad Request for an unknown wake word.
+func (t *triggerListener) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if !t.Trigger(r.URL.Query().Get(triggerQueryParam)) {
+		rw.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rw.WriteHeader(http.StatusAccepted)
+}
+
+// Serve serves the HTTP endpoint of the listener on addr until the context
+// is canceled. The address is either "unix:" followed by the path of a
+// Unix socket, only reachable on the device, or host:port.
+func (t *triggerListener) Serve(ctx context.Context, addr string) error {
+	network, address := "tcp", addr
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		network, address = "unix", path
+		// the socket of a previous run is left behind
+		if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			_ = os.Remove(path)
+		}
+	}
+
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return fmt.Errorf("unable to listen for triggers: %w", err)
+	}
+
+	srv := &http.Server{Handler: t}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	slog.Info("Listening for triggers", "addr", addr)
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("unable to serve triggers: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Package wakeword implements ports.WakeListener with several engines:
+// Picovoice Porcupine, openWakeWord served over the Wyoming protocol, and
+// a listener triggered by lines of text, e.g. from stdin, for tests and
+// setups without a microphone.
+package wakeword
+
+import (
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+	"github.com/ownerofglory/raspi-agent/internal/vad"
+)
+
+// echoWindow is how much audio, about the length of a wake word, is
+// checked against the echo gate when the wake word is detected.
+const echoWindow = 750 * time.Millisecond
+
+// energyWindow tracks the energy of the latest audio, to tell the user
+// from the echo of the playback when the wake word is detected.
+type energyWindow struct {
+	energies []float64
+	next     int
+}
+
+// newEnergyWindow creates a window over frames of the given duration.
+func newEnergyWindow(frameDuration time.Duration) *energyWindow {
+	return &energyWindow{energies: make([]float64, max(int(echoWindow/frameDuration), 1))}
+}
+
+// push adds the energy of the next frame.
+func (w *energyWindow) push(frame []int16) {
+	w.energies[w.next%len(w.energies)] = vad.Energy(frame)
+	w.next++
+}
+
+// mean returns the mean energy of the window.
+func (w *energyWindow) mean() float64 {
+	var sum float64
+	for _, e := range w.energies {
+		sum += e
+	}
+	return sum / float64(len(w.energies))
+}
+
+// modelOf returns the model locating the wake word for the engine.
+func modelOf(word domain.WakeWord) string {
+	if word.Model != "" {
+		return word.Model
+	}
+	return word.Name
+}
+
+// indexOf returns the index of the wake word with the given name or
+// model, or -1 if there is none.
+func indexOf(words []domain.WakeWord, name string) int {
+	for i, w := range words {
+		if w.Name == name || modelOf(w) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// detected returns the event of the detection of the i-th wake word.
+func detected(words []domain.WakeWord, i int, confidence float64) *domain.WakeEvent {
+	ev := &domain.WakeEvent{Keyword: i, Time: time.Now(), Confidence: confidence}
+	if i >= 0 && i < len(words) {
+		ev.Word = words[i]
+	}
+	return ev
+}
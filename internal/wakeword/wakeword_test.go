@@ -0,0 +1,324 @@
+package wakeword
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// fakeSource streams the same frame every few milliseconds.
+type fakeSource struct{}
+
+func (fakeSource) Subscribe(ctx context.Context, _ time.Duration) (<-chan domain.AudioFrame, error) {
+	frames := make(chan domain.AudioFrame)
+	go func() {
+		defer close(frames)
+		for {
+			frame := domain.AudioFrame{Samples: make([]int16, 320), SampleRate: 16000, Channels: 1}
+			select {
+			case <-ctx.Done():
+				return
+			case frames <- frame:
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+	return frames, nil
+}
+
+func TestWyomingEvent(t *testing.T) {
+	var buf bytes.Buffer
+	err := writeWyomingEvent(&buf, wyomingEvent{
+		Type:    "audio-chunk",
+		Data:    map[string]any{"rate": 16000},
+		Payload: []byte{1, 2, 3, 4},
+	})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	// data sent separately from the header
+	buf.WriteString(`{"type":"detection","data_length":15}` + "\n" + `{"name":"hey"}` + "\n")
+
+	r := bufio.NewReader(&buf)
+	ev, err := readWyomingEvent(r)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if ev.Type != "audio-chunk" || ev.Data["rate"] != float64(16000) || !bytes.Equal(ev.Payload, []byte{1, 2, 3, 4}) {
+		t.Errorf("unexpected event %+v", ev)
+	}
+
+	ev, err = readWyomingEvent(r)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if ev.Type != "detection" || ev.Data["name"] != "hey" {
+		t.Errorf("unexpected event %+v", ev)
+	}
+}
+
+func TestOpenWakeWordListener(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var types []string
+		r := bufio.NewReader(conn)
+		for len(types) < 3 {
+			ev, err := readWyomingEvent(r)
+			if err != nil {
+				return
+			}
+			types = append(types, ev.Type)
+		}
+		received <- types
+		writeWyomingEvent(conn, wyomingEvent{Type: "detection", Data: map[string]any{"name": "hey_jarvis"}})
+		io.Copy(io.Discard, r)
+	}()
+
+	words := []domain.WakeWord{{Name: "ok_nabu"}, {Name: "jarvis", Model: "hey_jarvis"}}
+	l, err := NewOpenWakeWordListener(fakeSource{}, ln.Addr().String(), words, nil)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ev, err := l.Listen(ctx)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if ev.Keyword != 1 || ev.Word.Name != "jarvis" {
+		t.Errorf("expected wake word 1 (jarvis), got %d (%s)", ev.Keyword, ev.Word.Name)
+	}
+
+	types := <-received
+	expected := []string{"detect", "audio-start", "audio-chunk"}
+	if strings.Join(types, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected events %v, got %v", expected, types)
+	}
+}
+
+func TestOpenWakeWordListenerSensitivity(t *testing.T) {
+	words := []domain.WakeWord{{Name: "jarvis", Model: "hey_jarvis", Sensitivity: 0.7}}
+	if _, err := NewOpenWakeWordListener(fakeSource{}, DefaultOpenWakeWordAddr, words, nil); err == nil {
+		t.Error("expected the sensitivity to be rejected")
+	}
+}
+
+func TestLineListener(t *testing.T) {
+	words := []domain.WakeWord{{Name: "jarvis"}, {Name: "stop", Action: domain.WakeActionStop}}
+	l := NewLineListener(strings.NewReader("\n10ms stop\nunknown\n10ms\n"), words)
+	ctx := context.Background()
+
+	for _, expected := range []int{0, 1, 0} {
+		ev, err := l.Listen(ctx)
+		if err != nil {
+			t.Fatalf("expected error to be nil got %v", err)
+		}
+		if ev.Keyword != expected || ev.Word.Name != words[expected].Name {
+			t.Errorf("expected wake word %d, got %d (%s)", expected, ev.Keyword, ev.Word.Name)
+		}
+	}
+
+	if _, err := l.Listen(ctx); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF once the input ended, got %v", err)
+	}
+}
+
+func TestLoadWakeWords(t *testing.T) {
+	testCases := []struct {
+		name    string
+		content string
+		words   []domain.WakeWord
+		wantErr bool
+	}{
+		{
+			name:    "personas and actions",
+			content: `[{"model":"jarvis.ppn","sensitivity":0.6,"voice":{"voice":"onyx","language":"en"}},{"name":"stop","model":"stop.ppn","action":"stop"}]`,
+			words: []domain.WakeWord{
+				{Name: "jarvis.ppn", Model: "jarvis.ppn", Sensitivity: 0.6, Voice: domain.VoiceOptions{Voice: "onyx", Language: "en"}},
+				{Name: "stop", Model: "stop.ppn", Action: domain.WakeActionStop},
+			},
+		},
+		{
+			name:    "invalid sensitivity",
+			content: `[{"name":"jarvis","sensitivity":2}]`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown action",
+			content: `[{"name":"jarvis","action":"dance"}]`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "wakewords.json")
+			if err := os.WriteFile(path, []byte(tc.content), 0o644); err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+
+			words, err := LoadWakeWords(path)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+			if !reflect.DeepEqual(words, tc.words) {
+				t.Errorf("expected %+v, got %+v", tc.words, words)
+			}
+		})
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	wakeWords := filepath.Join(dir, "wakewords.json")
+	if err := os.WriteFile(wakeWords, []byte(`[{"name": "jarvis"}, {"name": "stop", "action": "stop"}]`), 0o644); err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	wakeFile := filepath.Join(dir, "wake.txt")
+	if err := os.WriteFile(wakeFile, []byte("stop\n"), 0o644); err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	if _, _, err := FromConfig(context.Background(), fakeSource{}, nil, Config{Engine: "snowboy"}); err == nil {
+		t.Errorf("expected an error for an unknown engine")
+	}
+
+	l, _, err := FromConfig(context.Background(), fakeSource{}, nil, Config{Engine: EngineFile, WakeWords: wakeWords, WakeFile: wakeFile})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	ev, err := l.Listen(context.Background())
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if ev.Word.Name != "stop" || ev.Word.Action != domain.WakeActionStop {
+		t.Errorf("expected the stop wake word, got %+v", ev.Word)
+	}
+}
+
+func TestTriggerListener(t *testing.T) {
+	words := []domain.WakeWord{{Name: "jarvis"}, {Name: "stop", Action: domain.WakeActionStop}}
+	l := NewTriggerListener(words)
+
+	testCases := []struct {
+		name       string
+		method     string
+		query      string
+		statusCode int
+		keyword    int
+	}{
+		{name: "first wake word", method: http.MethodPost, statusCode: http.StatusAccepted, keyword: 0},
+		{name: "named wake word", method: http.MethodPost, query: "?wake=stop", statusCode: http.StatusAccepted, keyword: 1},
+		{name: "unknown wake word", method: http.MethodPost, query: "?wake=dance", statusCode: http.StatusBadRequest},
+		{name: "wrong method", method: http.MethodGet, statusCode: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			l.ServeHTTP(rec, httptest.NewRequest(tc.method, "/"+tc.query, nil))
+			if rec.Code != tc.statusCode {
+				t.Fatalf("expected status %d, got %d", tc.statusCode, rec.Code)
+			}
+			if tc.statusCode != http.StatusAccepted {
+				return
+			}
+
+			ev, err := l.Listen(context.Background())
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+			if ev.Keyword != tc.keyword {
+				t.Errorf("expected wake word %d, got %d", tc.keyword, ev.Keyword)
+			}
+		})
+	}
+}
+
+func TestMultiListener(t *testing.T) {
+	trigger := NewTriggerListener(nil)
+	m := NewMultiListener(NewLineListener(strings.NewReader(""), nil), trigger)
+
+	// the pending trigger still wakes up, but triggers alone are no input
+	trigger.Trigger("")
+	if _, err := m.Listen(context.Background()); err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if _, err := m.Listen(context.Background()); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF once only triggers remain, got %v", err)
+	}
+
+	// the trigger keeps waking up while an input is left
+	m = NewMultiListener(NewLineListener(strings.NewReader(""), nil), NewLineListener(strings.NewReader("\n\n"), nil), trigger)
+	for range 2 {
+		if _, err := m.Listen(context.Background()); err != nil {
+			t.Fatalf("expected error to be nil got %v", err)
+		}
+	}
+	trigger.Trigger("")
+	if _, err := m.Listen(context.Background()); err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	m = NewMultiListener(NewLineListener(strings.NewReader(""), nil))
+	if _, err := m.Listen(context.Background()); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF once all inputs ended, got %v", err)
+	}
+}
+
+// failingListener fails every time it listens.
+type failingListener struct{}
+
+func (failingListener) Listen(ctx context.Context) (*domain.WakeEvent, error) {
+	return nil, errors.New("engine unreachable")
+}
+
+func TestMultiListenerFailures(t *testing.T) {
+	// the trigger keeps listening while the failing engine is retried
+	trigger := NewTriggerListener(nil)
+	m := NewMultiListener(failingListener{}, trigger)
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		trigger.Trigger("")
+	}()
+	if _, err := m.Listen(context.Background()); err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	m = NewMultiListener(failingListener{}, failingListener{})
+	if _, err := m.Listen(context.Background()); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("expected an error once all listeners failed, got %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package wakeword
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// maxWyomingPayload bounds the data and payload of a received event.
+const maxWyomingPayload = 1 << 20
+
+// wyomingEvent is a message of the Wyoming protocol, see
+// https://github.com/OHF-Voice/wyoming: a JSON header line, optionally
+// followed by additional JSON data and a binary payload, e.g. audio.
+type wyomingEvent struct {
+	Type    string
+	Data    map[string]any
+	Payload []byte
+}
+
+// wyomingHeader is the JSON header line of an event.
+type wyomingHeader struct {
+	Type          string         `json:"type"`
+	Data          map[string]any `json:"data,omitempty"`
+	DataLength    int            `json:"data_length,omitempty"`
+	PayloadLength int            `json:"payload_length,omitempty"`
+}
+
+// writeWyomingEvent writes the event with its data in the header line.
+func writeWyomingEvent(w io.Writer, ev wyomingEvent) error {
+	header, err := json.Marshal(wyomingHeader{
+		Type:          ev.Type,
+		Data:          ev.Data,
+		PayloadLength: len(ev.Payload),
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(append(header, '\n')); err != nil {
+		return err
+	}
+	if len(ev.Payload) > 0 {
+		if _, err := w.Write(ev.Payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readWyomingEvent reads the next event. Data sent after the header line
+// is merged into the data of the header.
+func readWyomingEvent(r *bufio.Reader) (*wyomingEvent, error) {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	var header wyomingHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, fmt.Errorf("invalid wyoming event: %w", err)
+	}
+	if header.DataLength > maxWyomingPayload || header.PayloadLength > maxWyomingPayload {
+		return nil, fmt.Errorf("wyoming event %q too large", header.Type)
+	}
+
+	ev := &wyomingEvent{Type: header.Type, Data: header.Data}
+	if ev.Data == nil {
+		ev.Data = map[string]any{}
+	}
+
+	if header.DataLength > 0 {
+		data := make([]byte, header.DataLength)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		var extra map[string]any
+		if err := json.Unmarshal(data, &extra); err != nil {
+			return nil, fmt.Errorf("invalid wyoming event data: %w", err)
+		}
+		for k, v := range extra {
+			ev.Data[k] = v
+		}
+	}
+
+	if header.PayloadLength > 0 {
+		ev.Payload = make([]byte, header.PayloadLength)
+		if _, err := io.ReadFull(r, ev.Payload); err != nil {
+			return nil, err
+		}
+	}
+	return ev, nil
+}
@@ -0,0 +1,111 @@
+package websocket
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConn(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(rw, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// echo every message back
+		for {
+			msgType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(msgType, data); err != nil {
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := Dial(ctx, "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	defer conn.Close()
+
+	testCases := []struct {
+		name    string
+		msgType MessageType
+		data    []byte
+	}{
+		{name: "text", msgType: TextMessage, data: []byte(`{"type":"start"}`)},
+		{name: "empty binary", msgType: BinaryMessage, data: []byte{}},
+		{name: "16 bit length", msgType: BinaryMessage, data: bytes.Repeat([]byte{0xAB}, 4096)},
+		{name: "64 bit length", msgType: BinaryMessage, data: bytes.Repeat([]byte{0xCD}, 70000)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := conn.Ping(); err != nil {
+				t.Fatalf("expected ping error to be nil got %v", err)
+			}
+			if err := conn.WriteMessage(tc.msgType, tc.data); err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+
+			msgType, data, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("expected error to be nil got %v", err)
+			}
+			if msgType != tc.msgType {
+				t.Errorf("expected message type %d, got %d", tc.msgType, msgType)
+			}
+			if !bytes.Equal(data, tc.data) {
+				t.Errorf("expected %d bytes echoed, got %d", len(tc.data), len(data))
+			}
+		})
+	}
+}
+
+func TestUpgradeRejectsPlainRequest(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	if _, err := Upgrade(rec, req); err == nil {
+		t.Fatal("expected upgrade error")
+	}
+	if rec.Code != http.StatusUpgradeRequired {
+		t.Errorf("expected status %d, got %d", http.StatusUpgradeRequired, rec.Code)
+	}
+}
+
+func TestReadMessageAfterClose(t *testing.T) {
+	closed := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(rw, r)
+		if err != nil {
+			return
+		}
+		conn.Close()
+		close(closed)
+	}))
+	defer srv.Close()
+
+	conn, err := Dial(context.Background(), "ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	<-closed
+
+	if _, _, err := conn.ReadMessage(); !errors.Is(err, ErrClosed) {
+		t.Errorf("expected error %v, got %v", ErrClosed, err)
+	}
+}
@@ -0,0 +1,89 @@
+package whisper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// DefaultCLI is the name of the whisper.cpp command line tool.
+const DefaultCLI = "whisper-cli"
+
+// cliClient implements ports.TranscriptionProvider by running the command
+// line tool of whisper.cpp for each recording.
+type cliClient struct {
+	binary    string
+	modelPath string
+	options   domain.TranscriptionOptions
+}
+
+// NewCLIClient creates a transcription provider running binary, DefaultCLI
+// if empty, with the ggml model at modelPath, e.g. "models/ggml-base.en.bin".
+func NewCLIClient(binary, modelPath string, options domain.TranscriptionOptions) *cliClient {
+	if binary == "" {
+		binary = DefaultCLI
+	}
+	return &cliClient{
+		binary:    binary,
+		modelPath: modelPath,
+		options:   options,
+	}
+}
+
+// Transcribe writes the audio to a temporary file, runs the tool on it and
+// returns the text it prints.
+func (c *cliClient) Transcribe(ctx context.Context, req domain.TranscribeRequest) (*domain.TranscribeResult, error) {
+	audio, err := prepareAudio(req.Audio)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.CreateTemp("", "raspi-agent-*.wav")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create audio file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(audio)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write audio file: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, c.binary, c.args(f.Name(), language(req, c.options))...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		slog.Error("Failed to transcribe audio", "err", err, "output", stderr.String())
+		return nil, fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+
+	// the text is printed segment by segment, one per line
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimSpace(l)
+	}
+	return &domain.TranscribeResult{Text: strings.Join(lines, " ")}, nil
+}
+
+// args returns the arguments to transcribe the file in the language
+// without timestamps and progress output.
+func (c *cliClient) args(file, language string) []string {
+	if language == "" {
+		language = "auto"
+	}
+
+	args := []string{"-m", c.modelPath, "-f", file, "-l", language, "-nt", "-np"}
+	if c.options.Prompt != "" {
+		args = append(args, "--prompt", c.options.Prompt)
+	}
+	return args
+}
@@ -0,0 +1,103 @@
+package whisper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// DefaultURL is the address the whisper.cpp server is expected at unless
+// configured otherwise. The server listens on port 8080 by default, which the
+// backend listens on itself, so it is to be started with "--port 8081".
+const DefaultURL = "http://localhost:8081"
+
+// serverClient implements ports.TranscriptionProvider using the HTTP server
+// of whisper.cpp.
+type serverClient struct {
+	url        string
+	options    domain.TranscriptionOptions
+	httpClient *http.Client
+}
+
+// NewServerClient creates a transcription provider for the whisper.cpp
+// server at baseURL, e.g. DefaultURL.
+func NewServerClient(baseURL string, options domain.TranscriptionOptions) *serverClient {
+	return &serverClient{
+		url:        strings.TrimSuffix(baseURL, "/") + "/inference",
+		options:    options,
+		httpClient: &http.Client{},
+	}
+}
+
+// Transcribe sends the audio to the server and returns the recognized text.
+//
+// Endpoint: POST /inference (multipart form)
+func (s *serverClient) Transcribe(ctx context.Context, req domain.TranscribeRequest) (*domain.TranscribeResult, error) {
+	audio, err := prepareAudio(req.Audio)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, err := form.CreateFormFile("file", "speech.wav")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(audio); err != nil {
+		return nil, err
+	}
+	fields := map[string]string{
+		"response_format": "json",
+		"language":        language(req, s.options),
+		"prompt":          s.options.Prompt,
+	}
+	if fields["language"] == "" {
+		fields["language"] = "auto"
+	}
+	for k, v := range fields {
+		if v == "" {
+			continue
+		}
+		if err := form.WriteField(k, v); err != nil {
+			return nil, err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, &body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create whisper request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", form.FormDataContentType())
+
+	res, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		slog.Error("Failed to transcribe audio", "err", err)
+		return nil, fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+	defer res.Body.Close()
+
+	// errors are reported as {"error": "..."}, with status 200 by older servers
+	var result struct {
+		Text  string `json:"text"`
+		Error string `json:"error"`
+	}
+	b, _ := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err := json.Unmarshal(b, &result); err != nil || res.StatusCode != http.StatusOK || result.Error != "" {
+		slog.Error("Whisper server error", "status", res.Status, "body", string(b))
+		return nil, fmt.Errorf("failed to transcribe audio: whisper server error: %s", res.Status)
+	}
+
+	return &domain.TranscribeResult{Text: strings.TrimSpace(result.Text)}, nil
+}
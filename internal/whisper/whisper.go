@@ -0,0 +1,46 @@
+// Package whisper implements speech-to-text on top of whisper.cpp, see
+// https://github.com/ggml-org/whisper.cpp, either by calling its HTTP server
+// or by running its command line tool, so speech is recognized locally
+// without internet.
+package whisper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/ownerofglory/raspi-agent/internal/audio/wav"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// prepareAudio reads the audio and converts WAV to 16 kHz mono, the only
+// input whisper.cpp accepts unless built with ffmpeg. Other formats are
+// passed on as they are.
+func prepareAudio(audio io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(audio)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio: %w", err)
+	}
+
+	sampleRate, channels, samples, err := wav.Decode(bytes.NewReader(data))
+	if err != nil {
+		slog.Debug("Audio is not PCM WAV, passing it on unconverted", "err", err)
+		return data, nil
+	}
+
+	var buf bytes.Buffer
+	if err := wav.Encode(&buf, 16000, 1, wav.ToMono16k(samples, sampleRate, channels)); err != nil {
+		return nil, fmt.Errorf("failed to encode audio: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// language returns the language of the request, if any, or else the one
+// of the options.
+func language(req domain.TranscribeRequest, options domain.TranscriptionOptions) string {
+	if req.Language != "" {
+		return req.Language
+	}
+	return options.Language
+}
@@ -0,0 +1,81 @@
+package whisper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ownerofglory/raspi-agent/internal/audio/wav"
+	"github.com/ownerofglory/raspi-agent/internal/core/domain"
+)
+
+// recording returns a WAV recording of 100 ms of stereo audio at 48 kHz.
+func recording() *bytes.Buffer {
+	var buf bytes.Buffer
+	_ = wav.Encode(&buf, 48000, 2, make([]int16, 2*4800))
+	return &buf
+}
+
+func TestServerClient(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/inference" || r.FormValue("language") != "de" || r.FormValue("prompt") != "Wohnzimmer" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		sampleRate, channels, samples, err := wav.Decode(f)
+		if err != nil || sampleRate != 16000 || channels != 1 || len(samples) != 1600 {
+			_ = json.NewEncoder(rw).Encode(map[string]string{"error": "invalid audio"})
+			return
+		}
+		_ = json.NewEncoder(rw).Encode(map[string]string{"text": " Licht im Wohnzimmer an.\n"})
+	}))
+	defer srv.Close()
+
+	c := NewServerClient(srv.URL, domain.TranscriptionOptions{Language: "de", Prompt: "Wohnzimmer"})
+	res, err := c.Transcribe(context.Background(), domain.TranscribeRequest{Audio: recording()})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if res.Text != "Licht im Wohnzimmer an." {
+		t.Errorf("unexpected text %q", res.Text)
+	}
+
+	// the language of the request overrides the configured one
+	c = NewServerClient(srv.URL, domain.TranscriptionOptions{Language: "en", Prompt: "Wohnzimmer"})
+	if _, err := c.Transcribe(context.Background(), domain.TranscribeRequest{Audio: recording(), Language: "de"}); err != nil {
+		t.Errorf("expected error to be nil got %v", err)
+	}
+
+	c = NewServerClient(srv.URL, domain.TranscriptionOptions{Language: "en"})
+	if _, err := c.Transcribe(context.Background(), domain.TranscribeRequest{Audio: recording()}); err == nil {
+		t.Error("expected an error for a rejected request")
+	}
+}
+
+func TestCLIClient(t *testing.T) {
+	// the fake tool prints its arguments, one segment per line
+	binary := filepath.Join(t.TempDir(), "whisper-cli")
+	script := "#!/bin/sh\nfor a in \"$@\"; do case \"$a\" in *.wav) ;; *) echo \" $a\";; esac; done\n"
+	if err := os.WriteFile(binary, []byte(script), 0o755); err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+
+	c := NewCLIClient(binary, "model.bin", domain.TranscriptionOptions{Prompt: "Rhaspy"})
+	res, err := c.Transcribe(context.Background(), domain.TranscribeRequest{Audio: recording()})
+	if err != nil {
+		t.Fatalf("expected error to be nil got %v", err)
+	}
+	if want := "-m model.bin -f -l auto -nt -np --prompt Rhaspy"; res.Text != want {
+		t.Errorf("expected %q, got %q", want, res.Text)
+	}
+}
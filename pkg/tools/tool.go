@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 )
 
@@ -30,6 +31,21 @@ type Tool interface {
 	Execute(ctx context.Context, args string) (any, error)
 }
 
+// Restricted is implemented by tools that are only available to some
+// callers, e.g. tools enabled per user.
+type Restricted interface {
+	// Allowed reports whether the tool may be offered to and run by the
+	// caller identified by ctx.
+	Allowed(ctx context.Context) bool
+}
+
+// Allowed reports whether the tool may be used in ctx: unrestricted tools
+// always may, restricted ones if they allow it.
+func Allowed(ctx context.Context, tool Tool) bool {
+	r, ok := tool.(Restricted)
+	return !ok || r.Allowed(ctx)
+}
+
 // Tools is a thread-safe registry of all available Tool instances.
 type Tools struct {
 	mu    sync.RWMutex
@@ -77,6 +93,21 @@ func (t *Tools) ExecuteByName(ctx context.Context, name, args string) (any, erro
 	return tool.Execute(ctx, args)
 }
 
+// All returns all registered tools, ordered by name.
+func (t *Tools) All() []Tool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	all := make([]Tool, 0, len(t.store))
+	for _, tool := range t.store {
+		all = append(all, tool)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Name() < all[j].Name()
+	})
+	return all
+}
+
 // List returns all registered tools with metadata.
 func (t *Tools) List() []map[string]string {
 	t.mu.RLock()